@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	autospotting "github.com/cristim/autospotting/core"
 	lambda "github.com/eawsy/aws-lambda-go/service/lambda/runtime"
@@ -18,13 +20,52 @@ type cfgData struct {
 var conf *cfgData
 
 func main() {
-	run()
+	if flag.Arg(0) == "probe" {
+		probe(flag.Args()[1:])
+		return
+	}
+	if conf.PrintConfigSchema {
+		schema, err := autospotting.ConfigSchema()
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		fmt.Println(string(schema))
+		return
+	}
+	if conf.Daemon {
+		fmt.Printf("Starting autospotting daemon, build %s\n", conf.BuildNumber)
+		autospotting.RunDaemon(conf.Config)
+		return
+	}
+	run(conf.Config)
 }
 
-func run() {
-	fmt.Printf("Starting autospotting agent, build %s", conf.BuildNumber)
-	autospotting.Run(conf.Config)
+func run(cfg autospotting.Config) autospotting.RunResult {
+	fmt.Printf("Starting autospotting agent, build %s", cfg.BuildNumber)
+	result := autospotting.Run(cfg)
 	fmt.Println("Execution completed, nothing left to do")
+	return result
+}
+
+// probe runs on the instances, reporting their readiness to the groups using
+// readiness probes.
+func probe(args []string) {
+
+	var p autospotting.ProbeConfig
+
+	fs := flag.NewFlagSet("probe", flag.ExitOnError)
+	fs.StringVar(&p.Command, "command", "",
+		"Shell command exiting successfully once the application is ready, "+
+			"by default the instance is reported ready right away")
+	fs.DurationVar(&p.Interval, "interval", 10*time.Second,
+		"Delay between the attempts of running the readiness command")
+	fs.DurationVar(&p.Timeout, "timeout", 15*time.Minute,
+		"How long to wait for the readiness command to succeed")
+	fs.Parse(args)
+
+	if err := autospotting.Probe(conf.Config, p); err != nil {
+		log.Fatal(err.Error())
+	}
 }
 
 // this is the equivalent of a main for when running from Lambda, but on Lambda the
@@ -44,8 +85,67 @@ func init() {
 }
 
 func handle(evt json.RawMessage, ctx *lambda.Context) (interface{}, error) {
-	run()
-	return nil, nil
+
+	// The ID of the scheduled event is used for detecting duplicate
+	// invocations, while the optional regions and AutoScaling groups restrict
+	// the scope of the current invocation, for example when invoked by a
+	// dispatcher once for each region.
+	var event struct {
+		ID                string   `json:"id"`
+		Regions           []string `json:"regions"`
+		AutoScalingGroups []string `json:"autoscaling_groups"`
+
+		// set by the EventBridge spot interruption warnings and rebalance
+		// recommendations
+		DetailType string `json:"detail-type"`
+		Region     string `json:"region"`
+		Detail     struct {
+			InstanceID string `json:"instance-id"`
+		} `json:"detail"`
+	}
+	if err := json.Unmarshal(evt, &event); err != nil {
+		log.Println("Couldn't parse the event", err.Error())
+	}
+
+	// the global configuration is kept unchanged for the next invocations
+	cfg := conf.Config
+	cfg.EventID = event.ID
+
+	// the interrupted instances and those at risk of being interrupted are
+	// replaced right away, processing only their region
+	if event.Detail.InstanceID != "" {
+		switch event.DetailType {
+		case autospotting.InterruptionWarning:
+			cfg.InterruptedInstances = event.Detail.InstanceID
+			event.Regions = []string{event.Region}
+		case autospotting.RebalanceRecommendation:
+			cfg.AtRiskInstances = event.Detail.InstanceID
+			event.Regions = []string{event.Region}
+		}
+	}
+
+	if ctx != nil && ctx.RemainingTimeInMillis != nil {
+		remaining := time.Duration(ctx.RemainingTimeInMillis()) * time.Millisecond
+		cfg.Deadline = time.Now().Add(remaining - cfg.DeadlineMargin)
+	}
+
+	if len(event.Regions) > 0 {
+		cfg.Regions = strings.Join(event.Regions, ",")
+	}
+	if len(event.AutoScalingGroups) > 0 {
+		cfg.AutoScalingGroups = strings.Join(event.AutoScalingGroups, ",")
+	}
+
+	// the scheduled events are fanned out to worker invocations of limited
+	// scope, which are then processed as usual
+	if cfg.Dispatch && len(event.Regions) == 0 &&
+		len(event.AutoScalingGroups) == 0 {
+		autospotting.Dispatch(cfg)
+		return nil, nil
+	}
+
+	// returned to the callers invoking the function synchronously
+	return run(cfg), nil
 }
 
 // Configuration handling
@@ -56,10 +156,25 @@ func (c *cfgData) initialize() {
 	c.parseCommandLineFlags()
 	c.BuildNumber = string(build)
 
-	err := c.RawInstanceData.LoadFromAssetContent(instanceInfo)
+	var err error
+	c.RawInstanceData, err = autospotting.LoadInstanceData(c.InstanceData,
+		c.Regions, instanceInfo)
 	if err != nil {
 		log.Fatal(err.Error())
 	}
+
+	if c.SettingsFile != "" {
+		c.FileSettings, err = autospotting.LoadSettingsFile(c.SettingsFile)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	// the data file is normally embedded without its modification time
+	if info, err := AssetInfo("data/instances.json"); err == nil &&
+		info.ModTime().Unix() > 0 && c.InstanceData == "" {
+		c.PricingDataTime = info.ModTime()
+	}
 }
 
 func (c *cfgData) parseCommandLineFlags() {
@@ -67,6 +182,449 @@ func (c *cfgData) parseCommandLineFlags() {
 	flag.StringVar(&c.Regions, "regions", "", "Regions(comma separated list)"+
 		"where it should run, by default runs on all regions")
 
+	flag.StringVar(&c.AutoScalingGroups, "autoscaling_groups", "",
+		"AutoScaling groups(comma separated list) to be processed among the "+
+			"enabled ones, by default all the enabled groups are processed")
+
+	flag.BoolVar(&c.Dispatch, "dispatch", false,
+		"Instead of processing all the regions, invoke the worker Lambda "+
+			"function asynchronously for each enabled region")
+
+	flag.IntVar(&c.DispatchBatchSize, "dispatch_batch_size", 0,
+		"When dispatching, invoke the worker function for batches of up to "+
+			"this many enabled AutoScaling groups instead of whole regions")
+
+	flag.StringVar(&c.WorkerFunction, "worker_function",
+		os.Getenv("AWS_LAMBDA_FUNCTION_NAME"),
+		"Name of the Lambda function invoked by the dispatcher, by default the "+
+			"current function")
+
+	flag.DurationVar(&c.DeadlineMargin, "deadline_margin", time.Minute,
+		"Time left to the started groups for completing their processing "+
+			"before the Lambda invocation times out, no new regions or groups "+
+			"being started afterwards")
+
+	flag.DurationVar(&c.TimeBudget, "time_budget", 0,
+		"Maximum duration of a run when not running on Lambda, after which no "+
+			"new regions or groups are started. 0 means unlimited")
+
+	flag.StringVar(&c.ContinuationTable, "continuation_table", "",
+		"DynamoDB table keeping the first group each region's next run starts "+
+			"from, when the previous run stopped at its deadline")
+
+	flag.IntVar(&c.MaxConcurrentGroups, "max_concurrent_groups", 0,
+		"Maximum number of groups of a region processed at once, 0 means "+
+			"unlimited. When limited, the groups left unprocessed at the "+
+			"deadline of the run are processed first by the next run")
+
+	flag.BoolVar(&c.Plan, "plan", false,
+		"Without taking any action, evaluate all the AutoScaling groups, "+
+			"enabled or not, and export the plan report ranking them by the "+
+			"projected monthly savings, with their candidate instance types and "+
+			"risk notes")
+
+	flag.BoolVar(&c.Daemon, "daemon", false,
+		"Run as a long-running process instead of exiting after processing "+
+			"all regions once, serving runtime statistics on /metrics and pprof "+
+			"profiles on /debug/pprof/")
+
+	flag.StringVar(&c.Profile, "profile", "",
+		"Named profile of the shared AWS configuration files, which may use "+
+			"AWS SSO, web identity tokens or roles requiring MFA, the token code "+
+			"being prompted for on the standard input. AWS_PROFILE or the "+
+			"default profile is used if unset")
+
+	flag.StringVar(&c.HomeRegion, "home_region", "",
+		"Region hosting the state tables and Lambda workers, which all the "+
+			"other regions are listed from, explicitly configured so that no EC2 "+
+			"instance metadata is needed. AWS_REGION, AWS_DEFAULT_REGION or "+
+			"us-east-1 is used if unset")
+
+	flag.StringVar(&c.LogLevel, "log_level", "info",
+		"Minimum level of the logged entries: debug, info, warn or error. "+
+			"AUTOSPOTTING_DEBUG=true also enables the debug level")
+
+	flag.StringVar(&c.LogFormat, "log_format", "text",
+		"Format of the log entries: \"text\" lines with the region, asg, "+
+			"instance_id and action fields appended as key=value pairs when "+
+			"known, or \"json\" objects, one per line")
+
+	flag.BoolVar(&c.QuietWhenIdle, "quiet_when_idle", false,
+		"Buffer the log of each run and only write it out if any instances "+
+			"were changed, errors occurred or warnings were logged, collapsing "+
+			"the idle runs into a single summary line per region to keep the "+
+			"logging costs low")
+
+	flag.BoolVar(&c.PrintConfigSchema, "config_schema", false,
+		"Print the JSON schema of all the supported global settings and "+
+			"AutoScaling group tags, then exit. It's also served in daemon mode "+
+			"on /config-schema")
+
+	flag.DurationVar(&c.DaemonInterval, "daemon_interval", 5*time.Minute,
+		"Time to wait between runs in daemon mode")
+
+	flag.StringVar(&c.ListenAddress, "listen_address", "127.0.0.1:8080",
+		"Address of the HTTP server started in daemon mode, only reachable "+
+			"locally by default")
+
+	flag.IntVar(&c.HistorySize, "history_size", 50,
+		"Number of recent actions kept for each group, served in daemon mode on "+
+			"/asgs/{name}/history")
+
+	flag.BoolVar(&c.DryRun, "dry_run", false,
+		"Only log the exact API calls changing the groups, their instances "+
+			"or the resources carried over between them, such as bidding for "+
+			"spot instances, attaching them, moving instances to Standby and "+
+			"detaching and terminating the on-demand instances, instead of "+
+			"making them. Can be overridden per group using the dry_run tag")
+
+	flag.DurationVar(&c.ProbeTimeout, "probe_timeout", 30*time.Minute,
+		"The spot instances of the groups having the readiness_probe tag set "+
+			"to true are terminated if they don't report their readiness using "+
+			"'autospotting probe' within this long after being launched")
+
+	flag.BoolVar(&c.Adopt, "adopt", false,
+		"Adopt the running spot instances launched outside AutoScaling which "+
+			"match the AMI, subnets and security groups of an enabled group, "+
+			"attaching them in place of its on-demand instances, one per group on "+
+			"each run, before launching new spot instances once there are none "+
+			"left to adopt")
+
+	flag.StringVar(&c.SpotTags, "spot_tags", "",
+		"Additional tags(comma separated list of key=value pairs) set on the "+
+			"launched spot instances and their volumes, for cost allocation")
+
+	flag.StringVar(&c.ReplacementMethod, "replacement_method", "detach",
+		"How the replaced on-demand instances are removed from the group: "+
+			"'detach' detaches and terminates them immediately, 'standby' moves "+
+			"them to Standby until the new spot instance is healthy")
+
+	flag.Float64Var(&c.PriceTieEpsilon, "price_tie_epsilon", 0.001,
+		"Hourly spot prices differing by at most this amount are considered "+
+			"equal, in which case the newest generation instance type is preferred")
+
+	flag.Float64Var(&c.StickyPriceBand, "sticky_price_band", 0,
+		"Keep launching the instance type used last time by a group while its "+
+			"spot price is at most this fraction, such as 0.1 for 10%, above the "+
+			"cheapest compatible instance type. 0 always picks the cheapest")
+
+	flag.StringVar(&c.SpotMonitoring, "spot_monitoring", "copy",
+		"Detailed monitoring of the spot instances: 'copy' keeps the setting of "+
+			"the launch configuration, while 'enable' and 'disable' override it, "+
+			"since detailed monitoring is charged per instance. Can be overridden "+
+			"per group using the spot_monitoring tag")
+
+	flag.Float64Var(&c.MaxPoolConcentration, "max_pool_concentration", 20,
+		"Maximum percentage of a group's desired capacity which can run as spot "+
+			"instances of the same instance type in the same availability zone, "+
+			"counting the ones being launched, before that pool is avoided. The "+
+			"first spot instance of a pool is always allowed. 0 disables the limit")
+
+	flag.Float64Var(&c.SpotDiversification, "spot_diversification", 0,
+		"Number of the cheapest compatible instance types the spot instances "+
+			"of each group are spread across, each replacement going to the pool "+
+			"having the fewest of the group's spot instances in the availability "+
+			"zones of its on-demand instances. Values below 2 always pick the "+
+			"cheapest instance type. Can be overridden per group using the "+
+			"spot_diversification tag")
+
+	flag.Float64Var(&c.MaxRegionPoolConcentration,
+		"max_region_pool_concentration", 0,
+		"Maximum percentage of the instances of all the enabled groups of a "+
+			"region which can run as spot instances of the same instance type in "+
+			"the same availability zone, so that the groups don't all pile up in "+
+			"the same cheapest spot pool. 0 disables the limit")
+
+	flag.Float64Var(&c.PrewarmPoolConcentration, "prewarm_pool_concentration",
+		10, "Maximum percentage of the desired capacity of the groups being "+
+			"pre-warmed which can run in the same spot pool, used instead of "+
+			"max_pool_concentration when lower")
+
+	flag.IntVar(&c.PrewarmLaunches, "prewarm_launches", 3,
+		"How many spot instances the groups being pre-warmed launch on each "+
+			"run, each replacing another on-demand instance")
+
+	flag.Float64Var(&c.BidSpotPriceFactor, "bid_spot_price_factor", 0,
+		"When set, bid the current spot price multiplied by this factor, such as "+
+			"1.25, instead of the on-demand price, which remains the ceiling")
+
+	flag.StringVar(&c.BidStrategy, "bid_strategy", "",
+		"Bid pricing strategy: \"on-demand\", a percentage of the on-demand "+
+			"price such as \"on-demand-percentage:80\", the current spot price "+
+			"plus a percentage buffer such as \"spot-buffer:20\", or a fixed "+
+			"hourly price such as \"fixed:0.12\". The on-demand price remains "+
+			"the ceiling. Takes precedence over bid_spot_price_factor and can be "+
+			"overridden per group using the bid_strategy tag")
+
+	flag.BoolVar(&c.HealthBlackout, "health_blackout", false,
+		"Suspend replacements in the regions and availability zones affected by "+
+			"open AWS Health issues of EC2 or AutoScaling, or events about EC2 "+
+			"capacity or spot, requires a Business or Enterprise support plan")
+
+	flag.BoolVar(&c.CheckInteractiveSessions, "check_interactive_sessions", false,
+		"Delay the replacement of on-demand instances used interactively "+
+			"through SSM Session Manager")
+
+	flag.DurationVar(&c.InteractiveSessionWindow, "interactive_session_window",
+		30*time.Minute, "Also delay the replacement of on-demand instances "+
+			"having SSM sessions started within this time window")
+
+	flag.DurationVar(&c.LifetimeMargin, "lifetime_margin", time.Hour,
+		"Recycle the spot instances this long before they reach the maximum "+
+			"instance lifetime configured on their AutoScaling group")
+
+	flag.IntVar(&c.CapacityFailureThreshold, "capacity_failure_threshold", 2,
+		"Stop bidding for an instance type in an availability zone after this "+
+			"many spot requests failed there for lack of capacity, 0 disables it")
+
+	flag.IntVar(&c.CapacityZoneFailureThreshold,
+		"capacity_zone_failure_threshold", 5,
+		"Stop bidding in an availability zone after this many spot requests "+
+			"failed there for lack of capacity, across all instance types. "+
+			"0 disables it")
+
+	flag.DurationVar(&c.CapacityFailureWindow, "capacity_failure_window",
+		time.Hour, "Time window in which the spot capacity failures are counted")
+
+	flag.DurationVar(&c.BidFailureWindow, "bid_failure_window", 4*time.Hour,
+		"After a failed spot request, the following bids of the group skip its "+
+			"instance type and availability zone for this long, in favor of the "+
+			"next cheapest compatible instance type")
+
+	flag.BoolVar(&c.SavingsPlansAware, "savings_plans_aware", false,
+		"Defer the replacement of instances from families covered by active or "+
+			"recommended Savings Plans in their region, or of all the instances "+
+			"if any Compute Savings Plan is active, and report the hypothetical "+
+			"spot savings")
+
+	flag.BoolVar(&c.ExportFleetState, "export_fleet_state", false,
+		"Export the tags, per-group overrides and computed spot instance type "+
+			"candidates of all the managed groups as a CloudFormation-style JSON "+
+			"report, for detecting drift from the infrastructure code")
+
+	flag.StringVar(&c.IdempotencyTable, "idempotency_table", "",
+		"DynamoDB table, having the 'id' string partition key and TTL enabled on "+
+			"the 'expires' attribute, used for ignoring duplicate invocations "+
+			"triggered by the same event")
+
+	flag.DurationVar(&c.IdempotencyTTL, "idempotency_ttl", time.Hour,
+		"How long the IDs of the processed events are kept in the idempotency "+
+			"table")
+
+	flag.StringVar(&c.ApplicationTag, "application_tag", "",
+		"Group tag defining logical applications, such as 'app', for which the "+
+			"capacity, costs, savings and replacement activity of all their "+
+			"groups are aggregated in the applications report")
+
+	flag.StringVar(&c.NotificationTarget, "notification_target", "",
+		"SNS topic ARN, Slack incoming webhook URL or mailto: email address "+
+			"receiving the platform-wide errors and the notifications about "+
+			"groups not matched by any route")
+
+	flag.StringVar(&c.NotificationRoutes, "notification_routes", "",
+		"Comma-separated list of key=value:target routes, sending the "+
+			"notifications about the groups tagged with key=value to the target, "+
+			"for example team=payments:arn:aws:sns:us-east-1:123456789012:payments")
+
+	flag.StringVar(&c.AlertTargets, "alert_targets", "",
+		"Comma-separated list of targets receiving the alerts about conditions "+
+			"threatening the capacity of the groups, such as "+
+			"pagerduty:<routing key> or opsgenie:<API key>")
+
+	flag.IntVar(&c.AttachFailureThreshold, "attach_failure_threshold", 3,
+		"Number of failed attempts to attach a spot instance after which an "+
+			"alert is raised, 0 disables the alert")
+
+	flag.StringVar(&c.EmailSender, "email_sender", "",
+		"SES verified address sending the email digests to the mailto: "+
+			"notification targets")
+
+	flag.StringVar(&c.EmailRegion, "email_region", "us-east-1",
+		"Region of the SES service sending the email digests")
+
+	flag.StringVar(&c.EmailTemplate, "email_template", "",
+		"Optional html/template file overriding the layout of the email digests")
+
+	flag.DurationVar(&c.SpotPricingMaxAge, "spot_pricing_max_age", time.Hour,
+		"Maximum age of the spot prices, beyond which no instances are "+
+			"replaced in the region, 0 disables the check")
+
+	flag.StringVar(&c.InstanceData, "instance_data", "",
+		"Location of the instance type and on-demand pricing data, as an "+
+			"s3://bucket/key URL or a local file, optionally gzip-compressed, "+
+			"used instead of the data embedded in the binary. Only the pricing "+
+			"of the regions given by the regions option is kept in memory")
+
+	flag.StringVar(&c.SpotPriceFetch, "spot_price_fetch", "lazy",
+		"How the spot prices are fetched: \"lazy\" fetches those of each "+
+			"availability zone and instance type when first needed, in parallel "+
+			"batches, while \"eager\" fetches all of them before processing "+
+			"each region. They're always fetched eagerly when the arbitrage "+
+			"report is enabled")
+
+	flag.DurationVar(&c.WarmCacheTTL, "warm_cache_ttl", 5*time.Minute,
+		"How long the list of regions and the instance type catalogs, "+
+			"including their spot prices, are reused by the next runs of the "+
+			"same process, such as warm Lambda invocations. 0 disables the cache")
+
+	flag.DurationVar(&c.OnDemandPricingMaxAge, "on_demand_pricing_max_age",
+		90*24*time.Hour,
+		"Maximum age of the embedded on-demand prices, when known, beyond which "+
+			"no instances are replaced, 0 disables the check")
+
+	flag.Float64Var(&c.EBSOptimizationSurcharge, "ebs_optimization_surcharge",
+		0.025,
+		"Hourly fee charged for EBS optimization by the instance types older "+
+			"than the 4th generation, added to their prices when comparing them "+
+			"for EBS-optimized instances")
+
+	flag.Float64Var(&c.DedicatedTenancyFactor, "dedicated_tenancy_factor", 1.1,
+		"Price multiplier of the dedicated tenancy, applied when comparing the "+
+			"prices for dedicated instances")
+
+	flag.StringVar(&c.SavingsTable, "savings_table", "",
+		"DynamoDB table storing the daily savings estimates of each group, "+
+			"rolled up into weekly and monthly totals in the savings-trend "+
+			"report and the email digests")
+
+	flag.StringVar(&c.CloudWatchNamespace, "cloudwatch_namespace", "",
+		"CloudWatch namespace, such as AutoSpotting, of the custom metrics "+
+			"published after each run for every enabled group: Replacements, "+
+			"FailedBids, SpotInstances, OnDemandInstances and HourlySavings. No "+
+			"metrics are published if unset")
+
+	flag.StringVar(&c.AuditTopic, "audit_topic", "",
+		"SNS topic ARN receiving a JSON audit message whenever an on-demand "+
+			"instance is replaced, a spot bid fails or a replacement is rolled "+
+			"back, which can be overridden per group by the audit_topic tag")
+
+	flag.StringVar(&c.ReportCurrency, "report_currency", "USD",
+		"Currency the savings-trend report and the email digests present the "+
+			"savings in, such as EUR, GBP or JPY, converted from US dollars")
+
+	flag.Float64Var(&c.CurrencyRate, "currency_rate", 0,
+		"Static exchange rate, in units of the report currency worth one US "+
+			"dollar. If unset, the rate is fetched from currency_rates_url")
+
+	flag.StringVar(&c.CurrencyRatesURL, "currency_rates_url", "",
+		"Exchange rates API returning JSON rates relative to the US dollar in "+
+			"a \"rates\" object keyed by currency code, such as "+
+			"https://open.er-api.com/v6/latest/USD, fetched once per run")
+
+	flag.Float64Var(&c.MaxSpotPercentage, "max_spot_percentage", 0,
+		"Maximum percentage of the instances of all the enabled groups allowed "+
+			"to run on spot, checked before launching each spot instance. "+
+			"0 means unlimited")
+
+	flag.Float64Var(&c.InterruptionThreshold, "interruption_threshold", 0,
+		"Percentage of the launched spot instances of a spot pool which were "+
+			"interrupted above which no new bids are placed in that pool for "+
+			"the deny_list_ttl duration. 0 disables the deny-list")
+
+	flag.DurationVar(&c.DenyListTTL, "deny_list_ttl", 24*time.Hour,
+		"How long the frequently interrupted spot pools are denied")
+
+	flag.StringVar(&c.DenyListTable, "deny_list_table", "",
+		"DynamoDB table persisting the denied spot pools across runs")
+
+	flag.StringVar(&c.SpotAdvisorURL, "spot_advisor_url", "",
+		"URL of the Spot Advisor interruption frequency dataset, such as "+
+			"https://spot-bid-advisor.s3.amazonaws.com/spot-advisor-data.json, "+
+			"disabled by default")
+
+	flag.Float64Var(&c.InterruptionPenalty, "interruption_penalty", 0,
+		"Percentage by which the spot price of an instance type is increased "+
+			"for each step of its Spot Advisor interruption frequency rating "+
+			"when choosing among the compatible instance types")
+
+	flag.DurationVar(&c.AttachGracePeriod, "attach_grace_period", 0,
+		"Health check grace period used for the spot instances being "+
+			"attached, temporarily set on the groups while attaching them and "+
+			"then restored. 0 keeps using the groups' own grace periods")
+
+	flag.BoolVar(&c.ReconcileCapacity, "reconcile_capacity", false,
+		"Set the desired capacity of the groups back to its value from before "+
+			"the replacement when it drifted by one instance, launching an "+
+			"on-demand instance when the spot instance failed to be attached")
+
+	flag.DurationVar(&c.CanaryWindow, "canary_window", 0,
+		"How long the first spot instance attached to a group is observed "+
+			"before any further replacements, pausing the group if it doesn't "+
+			"stay healthy. 0 disables the canary replacements")
+
+	flag.Float64Var(&c.ArbitrageThreshold, "arbitrage_threshold", 0,
+		"Minimum percentage by which the spot price of an instance type used "+
+			"by a group needs to be cheaper in another processed region in "+
+			"order to be listed in the arbitrage report. 0 disables the report")
+
+	flag.DurationVar(&c.TerminationDelay, "termination_delay", 0,
+		"How long the replaced on-demand instances are kept running, detached "+
+			"from their group, before being terminated, during which their "+
+			"termination can be cancelled in order to restore them to their "+
+			"group. 0 terminates them right away")
+
+	flag.DurationVar(&c.StopGracePeriod, "stop_grace_period", 0,
+		"How long the replaced on-demand instances are kept stopped before "+
+			"being terminated, during which their termination can be cancelled "+
+			"in order to restore them to their group. Used instead of "+
+			"termination_delay when set")
+
+	flag.StringVar(&c.StateBackend, "state_backend", "dynamodb",
+		"Where the idempotency, savings and deny-list tables are kept: "+
+			"\"dynamodb\" tables, JSON documents in the \"s3\" bucket or in the "+
+			"local directory given as state_location for \"file\"")
+
+	flag.StringVar(&c.StateLocation, "state_location", "",
+		"S3 bucket or local directory holding the state tables when using the "+
+			"s3 or file state backends")
+
+	flag.StringVar(&c.ChangesTable, "changes_table", "",
+		"DynamoDB table keeping a fingerprint of each group, used for skipping "+
+			"the groups running only spot instances which didn't change since "+
+			"the previous run")
+
+	flag.DurationVar(&c.ChangesMaxAge, "changes_max_age", time.Hour,
+		"How long the unchanged groups may be skipped before being processed "+
+			"again, 0 skips them indefinitely")
+
+	flag.StringVar(&c.SettingsFile, "settings_file", "",
+		"JSON file with the default values of the AutoScaling group settings, "+
+			"keyed by their tag names. They're overridden by the "+
+			"AUTOSPOTTING_<NAME> environment variables and the group's tags")
+
+	flag.StringVar(&c.ReportBucket, "report_bucket", "",
+		"S3 bucket where the JSON reports are uploaded, by default they are logged")
+
+	flag.StringVar(&c.ReportDir, "report_dir", "",
+		"Local directory where the JSON reports are written when no "+
+			"report_bucket is set, by default they are logged")
+
+	flag.StringVar(&c.CandidateExport, "candidate_export", "",
+		"Export the candidate instance types evaluated for each group, with "+
+			"their hardware, spot price, score and rejection reason, as a 'json' "+
+			"or 'csv' report. Disabled by default")
+
+	flag.IntVar(&c.DebugMaxBytes, "debug_max_bytes", 16384,
+		"Maximum size in bytes of each debug dump of the large internal data "+
+			"structures, larger dumps are truncated. 0 disables the limit")
+
+	flag.IntVar(&c.DebugSampleRate, "debug_sample_rate", 1,
+		"Only emit one out of this many debug dumps of the same kind of data "+
+			"structure, counted across all the regions and groups")
+
+	flag.StringVar(&c.DebugDumpBucket, "debug_dump_bucket", "",
+		"S3 bucket where the full debug dumps are uploaded instead of being "+
+			"logged, by default they are logged")
+
+	flag.StringVar(&c.DebugGroups, "debug_asgs", "",
+		"Comma separated AutoScaling groups whose debug output and dumps are "+
+			"emitted, for troubleshooting them without enabling the debug output "+
+			"of all groups with AUTOSPOTTING_DEBUG=true")
+
+	flag.StringVar(&c.DebugRegions, "debug_regions", "",
+		"Comma separated regions whose debug output and dumps are emitted, "+
+			"including those of all their AutoScaling groups")
+
 	// flag.StringVar(&cfg.Regions, "region", "", "Regions(comma separated list)"+
 	//    "where it should run, by default runs on all regions")
 
@@ -0,0 +1,154 @@
+package autospotting
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+// adoptionClaims makes sure a spot instance is only adopted by a single group,
+// since the groups of a region are processed concurrently.
+type adoptionClaims struct {
+	sync.Mutex
+	claimed map[string]bool
+}
+
+func (c *adoptionClaims) claim(instanceID string) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.claimed == nil {
+		c.claimed = make(map[string]bool)
+	}
+
+	if c.claimed[instanceID] {
+		return false
+	}
+	c.claimed[instanceID] = true
+	return true
+}
+
+// adoptSpotInstance attaches one matching stand-alone spot instance to the
+// group, replacing an on-demand instance, and tells if any was adopted.
+func (a *autoScalingGroup) adoptSpotInstance() bool {
+
+	lc := a.getLaunchConfiguration()
+	if lc == nil {
+		a.log().Println(a.name, "has no launch configuration, nothing to adopt")
+		return false
+	}
+
+	for _, inst := range a.region.instances.catalog {
+
+		if !a.canAdopt(inst, lc) {
+			continue
+		}
+
+		odInst := a.findOndemandInstanceInAZ(inst.Placement.AvailabilityZone)
+		if odInst == nil {
+			a.debugLog().Println(a.name, "has no on-demand instances in",
+				*inst.Placement.AvailabilityZone, "to be replaced by",
+				*inst.InstanceId)
+			continue
+		}
+
+		if !a.region.adoptions.claim(*inst.InstanceId) {
+			continue
+		}
+
+		a.recordAction("adopted", "spot instance", *inst.InstanceId,
+			"in place of on-demand instance", *odInst.InstanceId)
+
+		if groupInst := a.getAnyInstance(); groupInst != nil {
+			a.tagInstance(inst.InstanceId, groupInst.filterTags())
+		}
+
+		a.replaceOnDemandInstanceWithSpot(inst.InstanceId)
+		return true
+	}
+
+	a.log().Println(a.name, "found no spot instances to adopt")
+	return false
+}
+
+// canAdopt tells if the instance is a running spot instance outside any
+// AutoScaling group, launched from the same AMI, in one of the group's subnets
+// and with the same security groups as set in the launch configuration. The
+// spot instances launched by AutoSpotting are left to the groups they were
+// launched for.
+func (a *autoScalingGroup) canAdopt(inst *instance,
+	lc *autoscaling.LaunchConfiguration) bool {
+
+	if !inst.isSpot() || *inst.State.Name != "running" {
+		return false
+	}
+
+	if findTagValue(inst.Tags, "aws:autoscaling:groupName") != nil ||
+		findTagValue(inst.Tags, "launched-for-asg") != nil {
+		return false
+	}
+
+	if managedBy := findTagValue(inst.Tags, "managed-by"); managedBy != nil &&
+		*managedBy == "autospotting" {
+		return false
+	}
+
+	if inst.ImageId == nil || lc.ImageId == nil || *inst.ImageId != *lc.ImageId {
+		return false
+	}
+
+	if !a.inGroupNetwork(inst) {
+		return false
+	}
+
+	return sameSecurityGroups(inst, lc.SecurityGroups)
+}
+
+// inGroupNetwork checks the instance's subnet against the group's subnets, or
+// its availability zone against the group's zones for groups outside VPC.
+func (a *autoScalingGroup) inGroupNetwork(inst *instance) bool {
+
+	if a.VPCZoneIdentifier != nil && *a.VPCZoneIdentifier != "" {
+		if inst.SubnetId == nil {
+			return false
+		}
+		for _, subnet := range strings.Split(*a.VPCZoneIdentifier, ",") {
+			if strings.TrimSpace(subnet) == *inst.SubnetId {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, az := range a.AvailabilityZones {
+		if *az == *inst.Placement.AvailabilityZone {
+			return true
+		}
+	}
+	return false
+}
+
+// sameSecurityGroups compares the instance's security groups with those of a
+// launch configuration, which may be given either by ID or by name.
+func sameSecurityGroups(inst *instance, lcGroups []*string) bool {
+
+	if len(inst.SecurityGroups) != len(lcGroups) {
+		return false
+	}
+
+	for _, lcGroup := range lcGroups {
+		found := false
+		for _, sg := range inst.SecurityGroups {
+			if (sg.GroupId != nil && *sg.GroupId == *lcGroup) ||
+				(sg.GroupName != nil && *sg.GroupName == *lcGroup) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
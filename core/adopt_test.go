@@ -0,0 +1,78 @@
+package autospotting
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_autoScalingGroup_canAdopt(t *testing.T) {
+
+	lc := &autoscaling.LaunchConfiguration{
+		ImageId:        aws.String("ami-1"),
+		SecurityGroups: []*string{aws.String("sg-1")},
+	}
+
+	tests := []struct {
+		name  string
+		image string
+		tags  []*ec2.Tag
+		want  bool
+	}{
+		{name: "stand-alone spot instance",
+			image: "ami-1",
+			want:  true,
+		},
+		{name: "different AMI",
+			image: "ami-2",
+			want:  false,
+		},
+		{name: "member of another group",
+			image: "ami-1",
+			tags: []*ec2.Tag{{Key: aws.String("aws:autoscaling:groupName"),
+				Value: aws.String("api")}},
+			want: false,
+		},
+		{name: "launched by AutoSpotting for a group",
+			image: "ami-1",
+			tags: []*ec2.Tag{{Key: aws.String("launched-for-asg"),
+				Value: aws.String("api")}},
+			want: false,
+		},
+		{name: "managed by AutoSpotting",
+			image: "ami-1",
+			tags: []*ec2.Tag{{Key: aws.String("managed-by"),
+				Value: aws.String("autospotting")}},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			a := &autoScalingGroup{
+				Group: &autoscaling.Group{
+					VPCZoneIdentifier: aws.String("subnet-1,subnet-2"),
+				},
+				name: "web",
+			}
+
+			inst := &instance{Instance: &ec2.Instance{
+				InstanceId:        aws.String("i-1"),
+				InstanceLifecycle: aws.String("spot"),
+				State:             &ec2.InstanceState{Name: aws.String("running")},
+				ImageId:           aws.String(tt.image),
+				SubnetId:          aws.String("subnet-2"),
+				SecurityGroups: []*ec2.GroupIdentifier{
+					{GroupId: aws.String("sg-1")},
+				},
+				Tags: tt.tags,
+			}}
+
+			if got := a.canAdopt(inst, lc); got != tt.want {
+				t.Errorf("canAdopt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
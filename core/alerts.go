@@ -0,0 +1,215 @@
+package autospotting
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// the number of failed attempts to attach a spot instance, kept on the instance
+// across runs
+const attachFailuresTag = "autospotting-attach-failures"
+
+var (
+	pagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+	opsGenieURL  = "https://api.opsgenie.com/v2/alerts"
+)
+
+// alert sends a critical notification about the group, both to its routed
+// target and to the alert targets.
+func (a *autoScalingGroup) alert(event string, details ...interface{}) {
+
+	n := notification{
+		Time:             time.Now().UTC(),
+		Region:           a.region.name,
+		AutoScalingGroup: a.name,
+		Event:            event,
+		Message:          strings.TrimSpace(fmt.Sprintln(details...)),
+		Critical:         true,
+	}
+
+	a.log().Println(a.name, "ALERT:", event, n.Message)
+
+	if target := a.notificationTarget(); target != "" {
+		notifications.send(target, n)
+	}
+	for _, target := range notifications.alertTargets {
+		notifications.send(target, n)
+	}
+}
+
+// alertPlatform sends a critical notification not concerning any particular
+// group to the default target and to the alert targets.
+func alertPlatform(region, event string, details ...interface{}) {
+
+	n := notification{
+		Time:     time.Now().UTC(),
+		Region:   region,
+		Event:    event,
+		Message:  strings.TrimSpace(fmt.Sprintln(details...)),
+		Critical: true,
+	}
+
+	logger.Println("ALERT:", event, n.Message)
+
+	if notifications.defaultTarget != "" {
+		notifications.send(notifications.defaultTarget, n)
+	}
+	for _, target := range notifications.alertTargets {
+		notifications.send(target, n)
+	}
+}
+
+// recordAttachFailure counts the failed attempt to attach the spot instance in
+// a tag set on it, and alerts once it failed too many times.
+func (a *autoScalingGroup) recordAttachFailure(spotInstanceID *string,
+	err error) {
+
+	failures := 1
+	if inst := a.region.instances.get(*spotInstanceID); inst != nil {
+		if tag := findTagValue(inst.Tags, attachFailuresTag); tag != nil {
+			if n, convErr := strconv.Atoi(*tag); convErr == nil {
+				failures = n + 1
+			}
+		}
+	}
+
+	a.tagInstance(spotInstanceID, []*ec2.Tag{{
+		Key:   aws.String(attachFailuresTag),
+		Value: aws.String(strconv.Itoa(failures)),
+	}})
+
+	threshold := a.region.conf.AttachFailureThreshold
+	if threshold > 0 && failures >= threshold {
+		a.alert("repeated-attach-failures", "spot instance", *spotInstanceID,
+			"failed to be attached", failures, "times, last error:", err.Error())
+	}
+}
+
+// capacityShortfall returns how many instances the group is missing in order
+// to reach its desired capacity, counting the instances being launched.
+func capacityShortfall(group *autoscaling.Group) int64 {
+
+	var count int64
+	for _, inst := range group.Instances {
+		if inst.LifecycleState == nil {
+			continue
+		}
+		state := *inst.LifecycleState
+		if state == "InService" || strings.HasPrefix(state, "Pending") {
+			count++
+		}
+	}
+
+	if shortfall := *group.DesiredCapacity - count; shortfall > 0 {
+		return shortfall
+	}
+	return 0
+}
+
+// checkCapacity alerts if the group is left below its desired capacity after
+// replacing one of its instances.
+func (a *autoScalingGroup) checkCapacity(group *autoscaling.Group) {
+
+	if shortfall := capacityShortfall(group); shortfall > 0 {
+		a.alert("below-desired-capacity", "the group is missing", shortfall,
+			"of its", *group.DesiredCapacity, "desired instances after the",
+			"replacement")
+	}
+}
+
+// pagerDutySink triggers PagerDuty incidents using the Events API v2.
+type pagerDutySink struct {
+	routingKey string
+}
+
+func (s *pagerDutySink) send(n notification) error {
+
+	if !n.Critical {
+		return nil
+	}
+
+	return postJSON(pagerDutyURL, nil, map[string]interface{}{
+		"routing_key":  s.routingKey,
+		"event_action": "trigger",
+		"dedup_key":    alertKey(n),
+		"payload": map[string]interface{}{
+			"summary":        n.subject() + ": " + n.Message,
+			"source":         "autospotting",
+			"severity":       "critical",
+			"component":      n.AutoScalingGroup,
+			"group":          n.Region,
+			"class":          n.Event,
+			"custom_details": n,
+		},
+	})
+}
+
+// opsGenieSink creates OpsGenie alerts.
+type opsGenieSink struct {
+	apiKey string
+}
+
+func (s *opsGenieSink) send(n notification) error {
+
+	if !n.Critical {
+		return nil
+	}
+
+	return postJSON(opsGenieURL,
+		map[string]string{"Authorization": "GenieKey " + s.apiKey},
+		map[string]interface{}{
+			"message":     n.subject(),
+			"alias":       alertKey(n),
+			"description": n.Message,
+			"priority":    "P1",
+			"source":      "autospotting",
+			"details": map[string]string{
+				"region":            n.Region,
+				"autoscaling_group": n.AutoScalingGroup,
+				"event":             n.Event,
+			},
+		})
+}
+
+// alertKey deduplicates the alerts about the same condition.
+func alertKey(n notification) string {
+	return strings.Join([]string{"autospotting", n.Region, n.AutoScalingGroup,
+		n.Event}, "/")
+}
+
+func postJSON(url string, headers map[string]string, v interface{}) error {
+
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+	return nil
+}
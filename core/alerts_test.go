@@ -0,0 +1,104 @@
+package autospotting
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+func Test_capacityShortfall(t *testing.T) {
+
+	instance := func(state string) *autoscaling.Instance {
+		return &autoscaling.Instance{LifecycleState: aws.String(state)}
+	}
+
+	tests := []struct {
+		name      string
+		group     *autoscaling.Group
+		shortfall int64
+	}{
+		{
+			name: "at desired capacity",
+			group: &autoscaling.Group{
+				DesiredCapacity: aws.Int64(2),
+				Instances: []*autoscaling.Instance{
+					instance("InService"), instance("Pending:Wait"),
+				},
+			},
+			shortfall: 0,
+		},
+		{
+			name: "instances leaving the group aren't counted",
+			group: &autoscaling.Group{
+				DesiredCapacity: aws.Int64(3),
+				Instances: []*autoscaling.Instance{
+					instance("InService"), instance("Detaching"),
+					instance("Terminating"),
+				},
+			},
+			shortfall: 2,
+		},
+		{
+			name: "above desired capacity",
+			group: &autoscaling.Group{
+				DesiredCapacity: aws.Int64(1),
+				Instances: []*autoscaling.Instance{
+					instance("InService"), instance("InService"),
+				},
+			},
+			shortfall: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := capacityShortfall(tt.group); got != tt.shortfall {
+				t.Errorf("capacityShortfall() = %v, want %v", got, tt.shortfall)
+			}
+		})
+	}
+}
+
+func Test_pagerDutySink_send(t *testing.T) {
+
+	var received []map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			var event map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&event)
+			received = append(received, event)
+			w.WriteHeader(http.StatusAccepted)
+		}))
+	defer server.Close()
+
+	defer func(url string) { pagerDutyURL = url }(pagerDutyURL)
+	pagerDutyURL = server.URL
+
+	s := &pagerDutySink{routingKey: "key"}
+
+	if err := s.send(notification{Event: "attach-failed"}); err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+	if len(received) != 0 {
+		t.Errorf("non-critical notification was paged: %v", received)
+	}
+
+	err := s.send(notification{
+		Region:           "us-east-1",
+		AutoScalingGroup: "asg",
+		Event:            "below-desired-capacity",
+		Critical:         true,
+	})
+	if err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+
+	if len(received) != 1 || received[0]["routing_key"] != "key" ||
+		received[0]["dedup_key"] != "autospotting/us-east-1/asg/below-desired-capacity" {
+		t.Errorf("unexpected PagerDuty events %v", received)
+	}
+}
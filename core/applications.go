@@ -0,0 +1,121 @@
+package autospotting
+
+import "sync"
+
+var applications applicationReport
+
+// name of the application of the groups lacking the application tag
+const unassignedApplication = "unassigned"
+
+type applicationReport struct {
+	sync.Mutex
+
+	// the group tag defining the applications, reporting is disabled if empty
+	tag string
+
+	stats map[string]*applicationStats
+}
+
+type applicationStats struct {
+	Regions           []string       `json:"regions"`
+	AutoScalingGroups int            `json:"autoscaling_groups"`
+	OnDemandInstances int            `json:"on_demand_instances"`
+	SpotInstances     int            `json:"spot_instances"`
+	HourlyCost        float64        `json:"hourly_cost"`
+	HourlySavings     float64        `json:"hourly_savings"`
+	Actions           map[string]int `json:"actions"`
+}
+
+func (r *applicationReport) init(cfg Config) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.tag = cfg.ApplicationTag
+	r.stats = make(map[string]*applicationStats)
+}
+
+// get returns the stats of the application, creating them if needed, and must
+// be called with the lock held.
+func (r *applicationReport) get(app string) *applicationStats {
+	s := r.stats[app]
+	if s == nil {
+		s = &applicationStats{Regions: []string{}, Actions: make(map[string]int)}
+		r.stats[app] = s
+	}
+	return s
+}
+
+// application returns the name of the group's application, or an empty string
+// if applications are not reported.
+func (a *autoScalingGroup) application() string {
+
+	if applications.tag == "" {
+		return ""
+	}
+
+	if app := a.getTagValue(applications.tag); app != nil && *app != "" {
+		return *app
+	}
+	return unassignedApplication
+}
+
+// recordApplicationStats adds the group's capacity, costs and savings to its
+// application.
+func (a *autoScalingGroup) recordApplicationStats() {
+
+	app := a.application()
+	if app == "" {
+		return
+	}
+
+	applications.Lock()
+	defer applications.Unlock()
+
+	s := applications.get(app)
+	s.AutoScalingGroups++
+
+	found := false
+	for _, region := range s.Regions {
+		found = found || region == a.region.name
+	}
+	if !found {
+		s.Regions = append(s.Regions, a.region.name)
+	}
+
+	for _, inst := range a.instances.catalog {
+
+		s.HourlyCost += inst.hourlyCost()
+
+		if !inst.isSpot() {
+			s.OnDemandInstances++
+			continue
+		}
+		s.SpotInstances++
+		s.HourlySavings += inst.hourlySavings()
+	}
+}
+
+// recordApplicationAction counts an action taken on the group for its
+// application.
+func (a *autoScalingGroup) recordApplicationAction(action string) {
+
+	app := a.application()
+	if app == "" {
+		return
+	}
+
+	applications.Lock()
+	defer applications.Unlock()
+
+	applications.get(app).Actions[action]++
+}
+
+func (r *applicationReport) export() {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.tag == "" || len(r.stats) == 0 {
+		return
+	}
+	writeReport("applications", r.stats)
+}
@@ -0,0 +1,65 @@
+package autospotting
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_recordApplicationStats(t *testing.T) {
+
+	applications.init(Config{ApplicationTag: "app"})
+	defer applications.init(Config{})
+
+	newGroup := func(regionName string, tags ...*autoscaling.TagDescription) autoScalingGroup {
+		return autoScalingGroup{
+			Group:  &autoscaling.Group{Tags: tags},
+			region: &region{name: regionName},
+			instances: instances{catalog: map[string]*instance{
+				"i-od": {
+					Instance: &ec2.Instance{InstanceId: aws.String("i-od")},
+					price:    0.1,
+				},
+				"i-spot": {
+					Instance: &ec2.Instance{
+						InstanceId:        aws.String("i-spot"),
+						InstanceLifecycle: aws.String("spot"),
+						Tags: []*ec2.Tag{{
+							Key:   aws.String("original-instance-price"),
+							Value: aws.String("0.1"),
+						}},
+					},
+					price: 0.03,
+				},
+			}},
+		}
+	}
+
+	checkout := &autoscaling.TagDescription{
+		Key: aws.String("app"), Value: aws.String("checkout")}
+
+	for _, region := range []string{"us-east-1", "eu-west-1"} {
+		a := newGroup(region, checkout)
+		a.recordApplicationStats()
+		a.recordApplicationAction("bid")
+	}
+	untagged := newGroup("us-east-1")
+	untagged.recordApplicationStats()
+
+	s := applications.stats["checkout"]
+	if s == nil || s.AutoScalingGroups != 2 || len(s.Regions) != 2 ||
+		s.SpotInstances != 2 || s.OnDemandInstances != 2 ||
+		s.Actions["bid"] != 2 {
+		t.Fatalf("unexpected checkout stats %+v", s)
+	}
+
+	if s.HourlySavings < 0.139 || s.HourlySavings > 0.141 {
+		t.Errorf("HourlySavings = %v, want 0.14", s.HourlySavings)
+	}
+
+	if applications.stats[unassignedApplication] == nil {
+		t.Errorf("untagged group not reported as %s", unassignedApplication)
+	}
+}
@@ -0,0 +1,141 @@
+package autospotting
+
+import (
+	"sort"
+	"sync"
+)
+
+var arbitrage arbitrageReport
+
+type arbitrageUsage struct {
+	region, group, instanceType string
+	instances                   int
+}
+
+type arbitrageOpportunity struct {
+	Region           string  `json:"region"`
+	AutoScalingGroup string  `json:"autoscaling_group"`
+	InstanceType     string  `json:"instance_type"`
+	Instances        int     `json:"instances"`
+	SpotPrice        float64 `json:"spot_price"`
+	CheapestRegion   string  `json:"cheapest_region"`
+	CheapestZone     string  `json:"cheapest_availability_zone"`
+	CheapestPrice    float64 `json:"cheapest_spot_price"`
+	SavingsPercent   float64 `json:"savings_percent"`
+}
+
+type arbitrageReport struct {
+	sync.Mutex
+
+	// minimum savings percentage worth reporting, 0 disables the report
+	threshold float64
+
+	usage []arbitrageUsage
+}
+
+func (r *arbitrageReport) init(cfg Config) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.threshold = cfg.ArbitrageThreshold
+	r.usage = nil
+}
+
+func (r *arbitrageReport) enabled() bool {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.threshold > 0
+}
+
+// recordRegionalPrices makes the spot prices of the region comparable with
+// the other regions.
+func (r *region) recordRegionalPrices() {
+	if arbitrage.enabled() {
+		regionalPrices.add(r.name, r.instanceTypeInformation)
+	}
+}
+
+// recordArbitrageCandidates records the instance types used by the group.
+func (a *autoScalingGroup) recordArbitrageCandidates() {
+
+	if !arbitrage.enabled() {
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, inst := range a.instances.catalog {
+		counts[*inst.InstanceType]++
+	}
+
+	arbitrage.Lock()
+	defer arbitrage.Unlock()
+
+	for instanceType, count := range counts {
+		arbitrage.usage = append(arbitrage.usage, arbitrageUsage{
+			region:       a.region.name,
+			group:        a.name,
+			instanceType: instanceType,
+			instances:    count,
+		})
+	}
+}
+
+// opportunities compares the prices of the recorded instance types, the
+// largest savings first.
+func (r *arbitrageReport) opportunities(
+	prices *regionalPriceIndex) []arbitrageOpportunity {
+	r.Lock()
+	defer r.Unlock()
+
+	result := []arbitrageOpportunity{}
+
+	for _, u := range r.usage {
+
+		local, found := prices.get(u.instanceType, u.region)
+		if !found {
+			continue
+		}
+		cheapest, found := prices.cheapestElsewhere(u.instanceType, u.region)
+		if !found {
+			continue
+		}
+
+		savings := (local.spot - cheapest.spot) / local.spot * 100
+		if savings < r.threshold {
+			continue
+		}
+
+		result = append(result, arbitrageOpportunity{
+			Region:           u.region,
+			AutoScalingGroup: u.group,
+			InstanceType:     u.instanceType,
+			Instances:        u.instances,
+			SpotPrice:        local.spot,
+			CheapestRegion:   cheapest.region,
+			CheapestZone:     cheapest.az,
+			CheapestPrice:    cheapest.spot,
+			SavingsPercent:   savings,
+		})
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].SavingsPercent != result[j].SavingsPercent {
+			return result[i].SavingsPercent > result[j].SavingsPercent
+		}
+		if result[i].Region != result[j].Region {
+			return result[i].Region < result[j].Region
+		}
+		if result[i].AutoScalingGroup != result[j].AutoScalingGroup {
+			return result[i].AutoScalingGroup < result[j].AutoScalingGroup
+		}
+		return result[i].InstanceType < result[j].InstanceType
+	})
+	return result
+}
+
+func (r *arbitrageReport) export() {
+	if r.enabled() {
+		writeReport("arbitrage", r.opportunities(&regionalPrices))
+	}
+}
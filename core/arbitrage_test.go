@@ -0,0 +1,79 @@
+package autospotting
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_cheapestSpotPrice(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		spot      spotPriceMap
+		wantPrice float64
+		wantZone  string
+	}{
+		{name: "no prices"},
+		{
+			name:      "cheapest zone",
+			spot:      spotPriceMap{"us-east-1a": 0.05, "us-east-1b": 0.03, "us-east-1c": 0},
+			wantPrice: 0.03,
+			wantZone:  "us-east-1b",
+		},
+		{
+			name:      "tied zones",
+			spot:      spotPriceMap{"us-east-1b": 0.03, "us-east-1a": 0.03},
+			wantPrice: 0.03,
+			wantZone:  "us-east-1a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, zone := cheapestSpotPrice(instanceTypeInformation{
+				pricing: prices{spot: tt.spot},
+			})
+			if price != tt.wantPrice || zone != tt.wantZone {
+				t.Errorf("cheapestSpotPrice() = %v, %v, want %v, %v", price,
+					zone, tt.wantPrice, tt.wantZone)
+			}
+		})
+	}
+}
+
+func Test_arbitrageReport_opportunities(t *testing.T) {
+
+	index := &regionalPriceIndex{}
+	index.init()
+
+	index.add("us-east-1", map[string]instanceTypeInformation{
+		"m5.large": {pricing: prices{spot: spotPriceMap{"us-east-1a": 0.04}}},
+		"c5.large": {pricing: prices{spot: spotPriceMap{"us-east-1a": 0.03}}},
+	})
+	index.add("us-east-2", map[string]instanceTypeInformation{
+		"m5.large": {pricing: prices{spot: spotPriceMap{"us-east-2b": 0.02}}},
+		"c5.large": {pricing: prices{spot: spotPriceMap{"us-east-2a": 0.029}}},
+	})
+
+	r := &arbitrageReport{threshold: 20, usage: []arbitrageUsage{
+		{region: "us-east-1", group: "web", instanceType: "m5.large", instances: 3},
+		{region: "us-east-1", group: "web", instanceType: "c5.large", instances: 1},
+		{region: "us-east-2", group: "api", instanceType: "m5.large", instances: 2},
+		{region: "us-east-1", group: "db", instanceType: "r5.large", instances: 1},
+	}}
+
+	want := []arbitrageOpportunity{{
+		Region:           "us-east-1",
+		AutoScalingGroup: "web",
+		InstanceType:     "m5.large",
+		Instances:        3,
+		SpotPrice:        0.04,
+		CheapestRegion:   "us-east-2",
+		CheapestZone:     "us-east-2b",
+		CheapestPrice:    0.02,
+		SavingsPercent:   50,
+	}}
+
+	if got := r.opportunities(index); !reflect.DeepEqual(got, want) {
+		t.Errorf("opportunities() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,54 @@
+package autospotting
+
+import (
+	"strings"
+)
+
+// parseArchitectureAMIs converts a comma separated list of architecture=AMI
+// pairs into a map, skipping any malformed entries.
+func parseArchitectureAMIs(list string) map[string]string {
+
+	amis := make(map[string]string)
+
+	for _, pair := range strings.Split(list, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" ||
+			strings.TrimSpace(kv[1]) == "" {
+			continue
+		}
+		amis[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return amis
+}
+
+func (a *autoScalingGroup) architectureAMIs() map[string]string {
+	if amis := a.stringSetting("architecture_amis"); amis != "" {
+		return parseArchitectureAMIs(amis)
+	}
+	return nil
+}
+
+// architectureAMI tells if an instance type supporting the given architectures
+// can replace an instance of the base architecture, and returns the AMI it
+// should be launched from, or an empty string for using the base instance's
+// AMI. Instance types of unknown architectures are assumed to be compatible.
+func architectureAMI(candidate []string, base *string,
+	amis map[string]string) (string, bool) {
+
+	if len(candidate) == 0 || base == nil {
+		return "", true
+	}
+
+	for _, arch := range candidate {
+		if arch == *base {
+			return "", true
+		}
+	}
+
+	for _, arch := range candidate {
+		if ami, found := amis[arch]; found {
+			return ami, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,68 @@
+package autospotting
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func Test_parseArchitectureAMIs(t *testing.T) {
+
+	got := parseArchitectureAMIs(" x86_64=ami-0123 , arm64=ami-4567,bogus,=ami-8,i386=")
+	want := map[string]string{"x86_64": "ami-0123", "arm64": "ami-4567"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseArchitectureAMIs() = %v, want %v", got, want)
+	}
+}
+
+func Test_architectureAMI(t *testing.T) {
+
+	amis := map[string]string{"x86_64": "ami-x86", "arm64": "ami-arm"}
+
+	tests := []struct {
+		name           string
+		candidate      []string
+		base           *string
+		amis           map[string]string
+		wantAMI        string
+		wantCompatible bool
+	}{
+		{
+			name:           "same architecture",
+			candidate:      []string{"i386", "x86_64"},
+			base:           aws.String("x86_64"),
+			amis:           amis,
+			wantCompatible: true,
+		},
+		{
+			name:           "other architecture without AMIs",
+			candidate:      []string{"arm64"},
+			base:           aws.String("x86_64"),
+			wantCompatible: false,
+		},
+		{
+			name:           "other architecture having an AMI",
+			candidate:      []string{"arm64"},
+			base:           aws.String("x86_64"),
+			amis:           amis,
+			wantAMI:        "ami-arm",
+			wantCompatible: true,
+		},
+		{
+			name:           "unknown candidate architecture",
+			base:           aws.String("x86_64"),
+			wantCompatible: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ami, compatible := architectureAMI(tt.candidate, tt.base, tt.amis)
+			if ami != tt.wantAMI || compatible != tt.wantCompatible {
+				t.Errorf("architectureAMI() = %q, %v, want %q, %v", ami,
+					compatible, tt.wantAMI, tt.wantCompatible)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package autospotting
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+const (
+	auditReplaced   = "replaced"
+	auditBidFailed  = "bid-failed"
+	auditRolledBack = "rolled-back"
+)
+
+type auditEvent struct {
+	Time             time.Time `json:"time"`
+	Region           string    `json:"region"`
+	AutoScalingGroup string    `json:"autoscaling_group"`
+	Event            string    `json:"event"`
+
+	// the on-demand instance being replaced or restored
+	OldInstanceID   string  `json:"old_instance_id,omitempty"`
+	OldInstanceType string  `json:"old_instance_type,omitempty"`
+	OldPrice        float64 `json:"old_price,omitempty"`
+
+	// the spot instance, or the bid for it
+	NewInstanceID    string  `json:"new_instance_id,omitempty"`
+	NewInstanceType  string  `json:"new_instance_type,omitempty"`
+	NewPrice         float64 `json:"new_price,omitempty"`
+	AvailabilityZone string  `json:"availability_zone,omitempty"`
+
+	Message string `json:"message,omitempty"`
+}
+
+// replacementAudit describes the replacement done by the spot instance, based
+// on the details of the on-demand instance tagged on it when it was launched.
+func replacementAudit(spot *instance) auditEvent {
+
+	e := auditEvent{Event: auditReplaced}
+
+	if spot.InstanceId != nil {
+		e.NewInstanceID = *spot.InstanceId
+	}
+	if spot.InstanceType != nil {
+		e.NewInstanceType = *spot.InstanceType
+	}
+	if spot.Placement != nil && spot.Placement.AvailabilityZone != nil {
+		e.AvailabilityZone = *spot.Placement.AvailabilityZone
+	}
+	e.NewPrice = spot.price
+
+	if id := findTagValue(spot.Tags, "original-instance-id"); id != nil {
+		e.OldInstanceID = *id
+	}
+	if t := findTagValue(spot.Tags, "original-instance-type"); t != nil {
+		e.OldInstanceType = *t
+	}
+	if p := findTagValue(spot.Tags, "original-instance-price"); p != nil {
+		e.OldPrice, _ = strconv.ParseFloat(*p, 64)
+	}
+
+	return e
+}
+
+// auditReplacement publishes the replacement done by the freshly attached spot
+// instance.
+func (a *autoScalingGroup) auditReplacement(spotInstanceID *string) {
+
+	e := auditEvent{Event: auditReplaced, NewInstanceID: *spotInstanceID}
+
+	if spot := a.region.instances.get(*spotInstanceID); spot != nil {
+		e = replacementAudit(spot)
+	}
+
+	e.Message = "on-demand instance " + e.OldInstanceID +
+		" replaced by spot instance " + e.NewInstanceID
+	a.audit(e)
+}
+
+// auditRollback publishes the restoration of the on-demand instance, undoing
+// its replacement by the spot instance, if known.
+func (a *autoScalingGroup) auditRollback(od *ec2.Instance, price float64,
+	spotInstanceID, reason string) {
+
+	e := auditEvent{
+		Event:           auditRolledBack,
+		OldInstanceID:   aws.StringValue(od.InstanceId),
+		OldPrice:        price,
+		OldInstanceType: aws.StringValue(od.InstanceType),
+		NewInstanceID:   spotInstanceID,
+		Message: "on-demand instance " + aws.StringValue(od.InstanceId) +
+			" restored " + reason,
+	}
+	a.audit(e)
+}
+
+// audit publishes the event to the group's audit topic, if any.
+func (a *autoScalingGroup) audit(e auditEvent) {
+
+	topic := a.stringSetting("audit_topic")
+	if topic == "" {
+		return
+	}
+
+	e.Time = time.Now().UTC()
+	e.Region = a.region.name
+	e.AutoScalingGroup = a.name
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		a.log().at(LevelError).Println(a.name,
+			"Failed to encode the audit event", err.Error())
+		return
+	}
+
+	sink := notifications.sink(topic)
+	if sink == nil {
+		return
+	}
+
+	err = sink.send(notification{
+		Time:             e.Time,
+		Region:           e.Region,
+		AutoScalingGroup: e.AutoScalingGroup,
+		Event:            e.Event,
+		Message:          string(body),
+	})
+	if err != nil {
+		a.log().at(LevelError).Println(a.name, "Failed to publish the", e.Event,
+			"audit event to", topic, err.Error())
+	}
+}
@@ -0,0 +1,71 @@
+package autospotting
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_replacementAudit(t *testing.T) {
+
+	tests := []struct {
+		name string
+		spot *instance
+		want auditEvent
+	}{
+		{
+			name: "tagged spot instance",
+			spot: &instance{
+				Instance: &ec2.Instance{
+					InstanceId:   aws.String("i-spot"),
+					InstanceType: aws.String("m5.large"),
+					Placement: &ec2.Placement{
+						AvailabilityZone: aws.String("us-east-1a"),
+					},
+					Tags: []*ec2.Tag{
+						{Key: aws.String("original-instance-id"),
+							Value: aws.String("i-od")},
+						{Key: aws.String("original-instance-type"),
+							Value: aws.String("m4.large")},
+						{Key: aws.String("original-instance-price"),
+							Value: aws.String("0.1")},
+					},
+				},
+				price: 0.03,
+			},
+			want: auditEvent{
+				Event:            auditReplaced,
+				OldInstanceID:    "i-od",
+				OldInstanceType:  "m4.large",
+				OldPrice:         0.1,
+				NewInstanceID:    "i-spot",
+				NewInstanceType:  "m5.large",
+				NewPrice:         0.03,
+				AvailabilityZone: "us-east-1a",
+			},
+		},
+		{
+			name: "untagged spot instance",
+			spot: &instance{
+				Instance: &ec2.Instance{
+					InstanceId:   aws.String("i-spot"),
+					InstanceType: aws.String("m5.large"),
+				},
+			},
+			want: auditEvent{
+				Event:           auditReplaced,
+				NewInstanceID:   "i-spot",
+				NewInstanceType: "m5.large",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replacementAudit(tt.spot); got != tt.want {
+				t.Errorf("replacementAudit() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -10,7 +10,6 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/davecgh/go-spew/spew"
 )
 
 type autoScalingGroup struct {
@@ -23,80 +22,199 @@ type autoScalingGroup struct {
 
 	// spot instance requests generated for the current group
 	spotInstanceRequests []*ec2.SpotInstanceRequest
+
+	// names of the launch lifecycle hooks, nil until described
+	launchHooks []string
+	// candidates evaluated for the latest replacement, when exported
+	candidates *candidateTable
+
+	// instances attached, detached or moved to Standby by the current run
+	capacityChanges int
 }
 
 func (a *autoScalingGroup) process() {
 
-	logger.Println("Finding spot instance requests created for", a.name)
+	if a.reportsOnly() {
+		return
+	}
+
+	a.detachInterruptedInstances()
+
+	if a.unchanged() {
+		a.recordAction("skipped", "the group runs only spot instances and",
+			"didn't change since the previous run")
+		return
+	}
+
+	// left behind by a previous run interrupted while attaching an instance
+	a.restoreGracePeriod()
+
+	a.endExpiredPrewarm()
+
+	if a.isBeingDeployed() {
+		a.recordAction("deferred", "the group is being deployed")
+		return
+	}
+
+	if a.handleZeroCapacity() {
+		a.recordAction("skipped", "the group is scaled to zero")
+		return
+	}
+
+	if a.skipsSingleInstanceReplacement() {
+		a.recordAction("skipped", "the group is limited to a single instance,",
+			"not replacing it unless allow_single_instance_replacement is set")
+		return
+	}
+
+	if a.isPaused() {
+		a.recordAction("skipped", "the group is paused:",
+			*a.getTagValue(pausedTag))
+		return
+	}
+
+	if a.observingCanary() {
+		return
+	}
+
+	a.log().Println("Finding spot instance requests created for", a.name)
 	a.findSpotInstanceRequests()
 	a.scanInstances()
+	a.collectBenchmarks()
+	a.exportState()
+	a.recordApplicationStats()
+	a.notifySavings()
+	a.recordSavings()
+	a.recordMetrics()
+	a.recordArbitrageCandidates()
+
+	a.debugLog().Println("Found spot instance requests:",
+		a.spotInstanceRequests)
+
+	if a.processStandbyInstances() {
+		a.log().Println(a.name, "Waiting for the spot instances replacing the",
+			"Standby instances to become healthy")
+		return
+	}
+
+	if a.recycleAgingSpotInstance() {
+		a.log().Println(a.name, "Waiting for the recycled spot instance to be",
+			"replaced")
+		return
+	}
 
-	debug.Println("Found spot instance requests:", a.spotInstanceRequests)
+	if a.replaceAtRiskSpotInstances() {
+		return
+	}
+
+	// one replacement per run, the usual flow goes on if nothing was adopted
+	if a.region.conf.Adopt && a.adoptSpotInstance() {
+		return
+	}
 
 	spotInstanceID, waitForNextRun := a.havingReadyToAttachSpotInstance()
 
 	if waitForNextRun == true {
-		logger.Println("Waiting for next run while processing", a.name)
+		a.log().Println("Waiting for next run while processing", a.name)
 		return
 	}
 
 	if spotInstanceID != nil {
-		logger.Println(a.region.name, "Attaching spot instance",
+		a.log().Println(a.region.name, "Attaching spot instance",
 			*spotInstanceID, "to", a.name)
 
+		expectedCapacity := a.currentDesiredCapacity()
+
 		a.replaceOnDemandInstanceWithSpot(spotInstanceID)
+		if a.capacityChanges > 0 {
+			a.reconcileCapacity(expectedCapacity)
+		}
 	} else {
 		// find any given on-demand instance and try to replace it with a spot one
-		onDemandInstance := a.getInstance(nil, true)
+		onDemandInstance := a.getAnyOnDemandInstance()
 
 		if onDemandInstance == nil {
-			logger.Println(a.region.name, a.name,
+			a.log().Println(a.region.name, a.name,
 				"No running on-demand instances were found, nothing to do here...")
 			return
 		}
 
+		if a.keepsOnDemandCapacity() {
+			return
+		}
+
 		azToLaunchSpotIn := onDemandInstance.Placement.AvailabilityZone
-		logger.Println(a.region.name, a.name,
+		a.log().Println(a.region.name, a.name,
 			"Would launch a spot instance in ", *azToLaunchSpotIn)
 
-		a.launchCheapestSpotInstance(azToLaunchSpotIn)
+		if !spotShare.reserve() {
+			a.recordAction("deferred", "the maximum spot share of",
+				a.region.conf.MaxSpotPercentage, "percent of the managed capacity",
+				"was reached")
+			return
+		}
+
+		if !a.launchCheapestSpotInstance(azToLaunchSpotIn) {
+			spotShare.release()
+			return
+		}
+		a.prewarmSpotInstances(onDemandInstance)
 	}
 }
 
-func (a *autoScalingGroup) findSpotInstanceRequests() error {
+// getTagValue returns the value of the group's tag having the given key, or nil
+// if the group has no such tag.
+func (a *autoScalingGroup) getTagValue(key string) *string {
+	for _, tag := range a.Tags {
+		if tag.Key != nil && *tag.Key == key {
+			return tag.Value
+		}
+	}
+	return nil
+}
 
-	resp, err := a.region.services.ec2.DescribeSpotInstanceRequests(
-		&ec2.DescribeSpotInstanceRequestsInput{
-			Filters: []*ec2.Filter{
-				{
-					Name:   aws.String("tag:launched-for-asg"),
-					Values: []*string{a.AutoScalingGroupName},
-				},
-			},
-		})
+// findSpotInstanceRequests picks the group's spot requests among those of the
+// region, described at the start of the run.
+func (a *autoScalingGroup) findSpotInstanceRequests() error {
 
-	if err != nil {
+	if err := a.region.spotRequestsErr; err != nil {
 		return err
 	}
-	logger.Println("Spot instance requests were previously created for", a.name)
-	a.spotInstanceRequests = resp.SpotInstanceRequests
+	a.spotInstanceRequests = a.region.groupSpotRequests(a.name)
 	return nil
 }
 
 func (a *autoScalingGroup) scanInstances() {
 
-	logger.Println("Adding instances to", a.name)
+	a.log().Println("Adding instances to", a.name)
 	a.instances.catalog = make(map[string]*instance)
 
+	a.prefetchInstancePrices()
+
 	for _, inst := range a.Instances {
 		i := a.region.instances.get(*inst.InstanceId)
-		debug.Println(i)
+		a.debugLog().Println(i)
+
+		// instances that are neither running nor pending were not scanned
+		if i == nil {
+			continue
+		}
+
+		// instances moved to Standby are being replaced, they are only
+		// handled by the Standby replacement flow
+		if inst.LifecycleState != nil &&
+			*inst.LifecycleState == autoscaling.LifecycleStateStandby {
+			continue
+		}
 
 		if i.isSpot() {
-			i.price = i.typeInfo.pricing.spot[*i.Placement.AvailabilityZone]
+			i.price = a.region.spotPrice(*i.InstanceType,
+				*i.Placement.AvailabilityZone)
 		} else {
 			i.price = i.typeInfo.pricing.onDemand
 		}
+		i.price = a.region.normalizedPrice(i.typeInfo, i.price,
+			i.pricingProfile())
 
 		a.instances.add(i)
 	}
@@ -109,47 +227,169 @@ func (a *autoScalingGroup) replaceOnDemandInstanceWithSpot(
 	minSize, maxSize := *a.MinSize, *a.MaxSize
 	desiredCapacity := *a.DesiredCapacity
 
-	// temporarily increase AutoScaling group in case it's of static size
-	if minSize == maxSize {
-		logger.Println(a.name, "Temporarily increasing MaxSize")
-		a.setAutoScalingMaxSize(maxSize + 1)
-		defer a.setAutoScalingMaxSize(maxSize)
+	attachFirst, allowed := replacementOrder(desiredCapacity, minSize,
+		a.healthyInstanceCount(), a.InstanceMaintenancePolicy)
+
+	if !allowed {
+		a.recordAction("deferred", "waiting for more healthy instances to",
+			"satisfy the instance maintenance policy")
+		return
+	}
+
+	// the Standby replacement also attaches the spot instance first
+	standbyFirst := false
+	attachesFirst := attachFirst || a.usesStandbyReplacement()
+
+	// temporarily increase the group's MaxSize when attaching the spot
+	// instance first would exceed it, falling back to moving the on-demand
+	// instance to Standby first if that fails, for example because of limits
+	if needsMaxSizeBump(desiredCapacity, maxSize, attachesFirst) {
+		a.log().Println(a.name, "Temporarily increasing MaxSize")
+
+		if err := a.setAutoScalingMaxSize(maxSize + 1); err == nil {
+			defer a.setAutoScalingMaxSize(maxSize)
+		} else if desiredCapacity > minSize {
+			a.log().at(LevelWarn).Println(a.name, "Couldn't increase MaxSize,",
+				"moving the on-demand instance to Standby before attaching the",
+				"spot instance")
+			standbyFirst = true
+		} else {
+			a.recordActionAt(LevelWarn, "deferred", "couldn't increase MaxSize",
+				"to attach spot instance", *spotInstanceID, "and the group is at",
+				"its minimum size, keeping it for the next run", err.Error())
+			return
+		}
 	}
 
 	// get the details of our spot instance so we can see its AZ
-	logger.Println(a.name, "Retrieving instance details for ", *spotInstanceID)
+	a.log().Println(a.name, "Retrieving instance details for ", *spotInstanceID)
 	if spotInst := a.region.instances.get(*spotInstanceID); spotInst != nil {
 
 		az := spotInst.Placement.AvailabilityZone
 
-		logger.Println(a.name, *spotInstanceID, "is in the availability zone",
+		if healthEvents.blocks(a.region.name, az) {
+			a.recordAction("deferred", "AWS Health event affecting", *az)
+			return
+		}
+
+		if atRisk := a.atRiskSpotInstanceFor(spotInst); atRisk != nil {
+			a.replaceAtRiskSpotInstance(atRisk, spotInstanceID)
+			return
+		}
+
+		a.log().Println(a.name, *spotInstanceID, "is in the availability zone",
 			*az, "looking for an on-demand instance there")
 
 		// find an on-demand instance from the same AZ as our spot instance
-		if odInst := a.findOndemandInstanceInAZ(az); odInst != nil {
+		odInst := a.findOndemandInstanceInAZ(az)
+
+		if odInst == nil && a.toleratesAZImbalance() {
+			if odInst = a.findOndemandInstanceInOtherAZ(az); odInst != nil {
+				a.log().Println(a.name, "found no on-demand instance in", *az,
+					"replacing", *odInst.InstanceId, "from",
+					*odInst.Placement.AvailabilityZone, "instead")
+			}
+		}
+
+		if odInst != nil {
 
-			logger.Println(a.name, "found on-demand instance", *odInst.InstanceId,
+			a.log().Println(a.name, "found on-demand instance", *odInst.InstanceId,
 				"replacing with new spot instance", *spotInst.InstanceId)
 
-			// revert attach/detach order when running on minimum capacity
-			if desiredCapacity == minSize {
-				a.attachSpotInstance(spotInstanceID)
+			if a.region.hasInteractiveSessions(odInst.InstanceId) {
+				a.log().at(LevelWarn).Println(a.name,
+					"WARNING: on-demand instance", *odInst.InstanceId,
+					"is used interactively, delaying its",
+					"replacement until the next run")
+				a.recordAction("deferred", "on-demand instance",
+					*odInst.InstanceId, "has interactive sessions")
+				return
+			}
+
+			// restored after attaching the spot instance
+			defer a.bumpGracePeriod()()
+
+			if standbyFirst {
+				a.replaceOnDemandInstanceStandbyFirst(odInst, spotInstanceID)
+				return
+			}
+
+			if a.usesStandbyReplacement() {
+				a.replaceOnDemandInstanceUsingStandby(odInst, spotInstanceID)
+				return
+			}
+
+			// revert attach/detach order when running on minimum capacity, or
+			// when required by the instance maintenance policy
+			if attachFirst {
+				if a.attachSpotInstance(spotInstanceID) != nil {
+					return
+				}
 			} else {
 				defer a.attachSpotInstance(spotInstanceID)
 			}
 
+			a.carryOverState(odInst, spotInstanceID)
 			a.detachAndTerminateOnDemandInstance(odInst.InstanceId)
-		} else {
-			logger.Println(a.name, "found no on-demand instances that could be",
+		} else if a.handOverSpotInstance(spotInst) {
+			a.log().Println(a.name,
+				"found no on-demand instances that could be",
 				"replaced with the new spot instance", *spotInst.InstanceId,
-				"terminating the spot instance.")
+				"leaving it to another group")
+		} else {
+			a.recordAction("terminated", "spot instance", *spotInst.InstanceId,
+				"found no on-demand instance to replace in", *az)
 			si := a.region.instances.get(*spotInst.InstanceId)
-			si.terminate(a.region.services.ec2)
+			a.terminateInstance(si)
 
 		}
 	}
 }
 
+// needsMaxSizeBump tells if attaching an instance to a group would exceed its
+// MaxSize, since AttachInstances also increments the desired capacity.
+func needsMaxSizeBump(desiredCapacity, maxSize int64, attachFirst bool) bool {
+	return attachFirst && desiredCapacity+1 > maxSize
+}
+
+// healthyInstanceCount returns the number of in-service and healthy instances
+// from the group, as seen by AutoScaling.
+func (a *autoScalingGroup) healthyInstanceCount() int64 {
+	return healthyInServiceCount(a.Group)
+}
+
+// replacementOrder decides if the spot instance needs to be attached before
+// the on-demand instance is detached, and if the replacement is allowed at all.
+// Attaching first is required when running at minimum capacity, otherwise the
+// on-demand instance is detached first. The instance maintenance policy of the
+// group, when set, constrains the number of healthy instances during the
+// replacement to the same guardrails used by instance refresh.
+func replacementOrder(desiredCapacity, minSize, healthy int64,
+	policy *autoscaling.InstanceMaintenancePolicy) (attachFirst, allowed bool) {
+
+	detachFirstAllowed, attachFirstAllowed := true, true
+
+	if policy != nil {
+		// negative values mean the setting was cleared on the group
+		if p := policy.MinHealthyPercentage; p != nil && *p >= 0 {
+			detachFirstAllowed = (healthy-1)*100 >= *p*desiredCapacity
+		}
+		if p := policy.MaxHealthyPercentage; p != nil && *p >= 0 {
+			attachFirstAllowed = (healthy+1)*100 <= *p*desiredCapacity
+		}
+	}
+
+	switch {
+	case desiredCapacity == minSize:
+		return true, attachFirstAllowed
+	case detachFirstAllowed:
+		return false, true
+	case attachFirstAllowed:
+		return true, true
+	}
+	return false, false
+}
+
 // Returns the information about the first running instance found in
 // the group, while iterating over all instances from the
 // group. It can also filter by AZ and Lifecycle.
@@ -179,11 +419,11 @@ func (a *autoScalingGroup) getInstance(
 }
 
 func (a *autoScalingGroup) findOndemandInstanceInAZ(az *string) *instance {
-	return a.getInstance(az, true)
+	return a.selectVictim(az)
 }
 
 func (a *autoScalingGroup) getAnyOnDemandInstance() *instance {
-	return a.getInstance(nil, true)
+	return a.selectVictim(nil)
 }
 
 func (a *autoScalingGroup) getAnyInstance() *instance {
@@ -199,24 +439,30 @@ func (a *autoScalingGroup) havingReadyToAttachSpotInstance() (*string, bool) {
 	// if there are on-demand instances but no spot instance requests yet,
 	// then we can launch a new spot instance
 	if len(a.spotInstanceRequests) == 0 {
-		logger.Println(a.name, "no spot bids were found")
+		a.log().Println(a.name, "no spot bids were found")
 		if inst := a.getAnyOnDemandInstance(); inst != nil {
-			logger.Println(a.name, "on-demand instances were found, proceeding to "+
+			a.log().Println(a.name, "on-demand instances were found, proceeding to "+
 				"launch a replacement spot instance")
 			return nil, false
 		}
 		// Looks like we have no instances in the group, so we can stop here
-		logger.Println(a.name, "no on-demand instances were found, nothing to do")
+		a.log().Println(a.name, "no on-demand instances were found, nothing to do")
 		return nil, true
 	}
 
-	logger.Println("spot bids were found, continuing")
+	a.log().Println("spot bids were found, continuing")
 
 	// Here we search for open spot requests created for the current ASG, and try
 	// to wait for their instances to start.
 	for _, req := range a.spotInstanceRequests {
-		if *req.State == "open" && *req.Tags[0].Value == a.name {
-			logger.Println(a.name, "Open bid found for current AutoScaling Group, "+
+		asgName := findTagValue(req.Tags, "launched-for-asg")
+
+		if a.handleUnfulfillableSpotRequest(req) {
+			continue
+		}
+
+		if *req.State == "open" && asgName != nil && *asgName == a.name {
+			a.log().Println(a.name, "Open bid found for current AutoScaling Group, "+
 				"waiting for the instance to start so it can be tagged...")
 
 			// Here we resume the wait for instances, initiated after requesting the
@@ -235,30 +481,30 @@ func (a *autoScalingGroup) havingReadyToAttachSpotInstance() (*string, bool) {
 		// We found a spot request with a running instance.
 		if *req.State == "active" &&
 			*req.Status.Code == "fulfilled" {
-			logger.Println(a.name, "Active bid was found, with instance already "+
+			a.log().Println(a.name, "Active bid was found, with instance already "+
 				"started:", *req.InstanceId)
 
 			// If the instance is already in the group we don't need to do anything.
 			if a.instances.get(*req.InstanceId) != nil {
-				logger.Println(a.name, "Instance", *req.InstanceId,
+				a.log().Println(a.name, "Instance", *req.InstanceId,
 					"is already attached to the ASG, skipping...")
 				continue
 
 				// In case the instance wasn't yet attached, we prepare to attach it.
 			} else {
-				logger.Println(a.name, "Instance", *req.InstanceId,
+				a.log().Println(a.name, "Instance", *req.InstanceId,
 					"is not yet attached to the ASG, checking if it's running")
 
 				if i := a.instances.get(*req.InstanceId); i != nil &&
 					i.State != nil &&
 					*i.State.Name == "running" {
-					logger.Println(a.name, "Active bid was found, with running "+
+					a.log().Println(a.name, "Active bid was found, with running "+
 						"instances not yet attached to the ASG",
 						*req.InstanceId)
 					activeSpotInstanceRequest = req
 					break
 				} else {
-					logger.Println(a.name, "Active bid was found, with no running "+
+					a.log().Println(a.name, "Active bid was found, with no running "+
 						"instances, waiting for an instance to start ...")
 					a.waitForAndTagSpotInstance(req)
 					activeSpotInstanceRequest = req
@@ -271,45 +517,133 @@ func (a *autoScalingGroup) havingReadyToAttachSpotInstance() (*string, bool) {
 	// process of starting or already ready to be attached to the group, we can
 	// launch a new spot instance.
 	if activeSpotInstanceRequest == nil {
-		logger.Println(a.name, "No active unfulfilled bid was found")
+		a.log().Println(a.name, "No active unfulfilled bid was found")
 		return nil, false
 	}
 
 	spotInstanceID := activeSpotInstanceRequest.InstanceId
 
-	logger.Println("Considering ", *spotInstanceID, "for attaching to", a.name)
+	a.log().Println("Considering ", *spotInstanceID, "for attaching to", a.name)
 
 	instData := a.region.instances.get(*spotInstanceID)
-	gracePeriod := *a.HealthCheckGracePeriod
+	gracePeriod := a.attachGracePeriod()
 
-	debug.Println(instData)
+	a.debugLog().Println(instData)
 
 	if instData == nil || instData.LaunchTime == nil {
-		logger.Println("Apparently", *spotInstanceID, "is no longer running, moving on...")
+		a.log().Println("Apparently", *spotInstanceID, "is no longer running, moving on...")
+		return nil, true
+	}
+
+	if instData.isRecoverable() {
+		a.log().Println("The spot instance", *spotInstanceID, "was stopped by an",
+			"interruption, waiting for it to be resumed...")
 		return nil, true
 	}
 
 	instanceUpTime := time.Now().Unix() - instData.LaunchTime.Unix()
 
-	logger.Println("Instance uptime:", time.Duration(instanceUpTime)*time.Second)
+	a.log().Println("Instance uptime:", time.Duration(instanceUpTime)*time.Second)
 
 	// Check if the spot instance is out of the grace period, so in that case we
 	// can replace an on-demand instance with it
 	if *instData.State.Name == "running" &&
 		instanceUpTime < gracePeriod {
-		logger.Println("The new spot instance", *spotInstanceID,
+		a.log().Println("The new spot instance", *spotInstanceID,
 			"is still in the grace period,",
 			"waiting for it to be ready before we can attach it to the group...")
 		return nil, true
 	}
+
+	if a.waitsForProbe(instData) {
+		return nil, true
+	}
 	return spotInstanceID, false
 }
 
+// handleUnfulfillableSpotRequest takes care of the spot requests that can't
+// give us an instance to attach, and tells if the request was one of them. The
+// open requests that would wait forever are cancelled, so that a new bid can be
+// placed right away, normally for another instance type. The failed requests
+// are tagged once handled, since the closed ones are still listed in the runs
+// of the next few hours.
+func (a *autoScalingGroup) handleUnfulfillableSpotRequest(
+	req *ec2.SpotInstanceRequest) bool {
+
+	if req.Status == nil || req.Status.Code == nil {
+		return false
+	}
+
+	id, code := *req.SpotInstanceRequestId, *req.Status.Code
+
+	if bidFailureCodes[code] && findTagValue(req.Tags, bidFailureTag) != nil {
+		return true
+	}
+
+	switch code {
+	case "price-too-low":
+		a.log().Println(a.name, "Spot request", id, "is bidding below the",
+			"current spot price, cancelling it in order to bid again")
+		a.cancelSpotInstanceRequest(req)
+		a.tagBidFailure(req)
+
+	case "capacity-not-available", "capacity-oversubscribed":
+		a.log().Println(a.name, "Spot request", id, "has no spot capacity",
+			"available, cancelling it in order to bid for another instance type")
+		a.region.recordCapacityFailure(req)
+		a.cancelSpotInstanceRequest(req)
+		a.tagBidFailure(req)
+
+	case "schedule-expired":
+		a.log().Println(a.name, "Spot request", id, "expired, ignoring it")
+		return true
+
+	case "marked-for-termination":
+		a.log().Println(a.name, "The instance of spot request", id,
+			"is about to be interrupted, not attaching it to the group")
+
+	default:
+		return false
+	}
+
+	a.recordAction("bid-"+code, "spot request", id)
+	return true
+}
+
+// cancelSpotInstanceRequest cancels the spot request if still open, requests in
+// other states are either fulfilled or already closed. Since cancelling
+// replaces the status code of the request, the reason of a failure is kept
+// in a tag, so the failed bid is still known in the next runs.
+func (a *autoScalingGroup) cancelSpotInstanceRequest(
+	req *ec2.SpotInstanceRequest) {
+
+	if *req.State != "open" {
+		return
+	}
+
+	input := &ec2.CancelSpotInstanceRequestsInput{
+		SpotInstanceRequestIds: []*string{req.SpotInstanceRequestId},
+	}
+
+	if a.skipsCall("CancelSpotInstanceRequests", input) {
+		return
+	}
+
+	a.tagBidFailure(req)
+
+	_, err := a.region.services.ec2.CancelSpotInstanceRequests(input)
+
+	if err != nil {
+		a.log().at(LevelError).Println(a.name, "Failed to cancel spot request",
+			*req.SpotInstanceRequestId, err.Error())
+	}
+}
+
 // This function returns an Instance ID
 func (a *autoScalingGroup) waitForAndTagSpotInstance(
 	spotRequest *ec2.SpotInstanceRequest) {
 
-	logger.Println(a.name, "Waiting for spot instance for spot instance request",
+	a.log().Println(a.name, "Waiting for spot instance for spot instance request",
 		*spotRequest.SpotInstanceRequestId)
 
 	ec2Client := a.region.services.ec2
@@ -320,67 +654,176 @@ func (a *autoScalingGroup) waitForAndTagSpotInstance(
 
 	err := ec2Client.WaitUntilSpotInstanceRequestFulfilled(&params)
 	if err != nil {
-		logger.Println(a.name, "Error waiting for instance:", err.Error())
+		a.log().at(LevelError).Println(a.name, "Error waiting for instance:",
+			err.Error())
+
+		// let the other groups know if the request failed for lack of capacity
+		if resp, err := ec2Client.DescribeSpotInstanceRequests(&params); err == nil {
+			for _, req := range resp.SpotInstanceRequests {
+				a.region.recordCapacityFailure(req)
+			}
+		}
 		return
 	}
 
-	logger.Println(a.name, "Done waiting for an instance.")
+	a.log().Println(a.name, "Done waiting for an instance.")
 
 	// Now we try to get the InstanceID of the instance we got
 	requestDetails, err := ec2Client.DescribeSpotInstanceRequests(&params)
 	if err != nil {
-		logger.Println(a.name, "Failed to describe spot instance requests")
+		a.log().at(LevelError).Println(a.name,
+			"Failed to describe spot instance requests")
 	}
 
 	// due to the waiter we can now safely assume all this data is available
 	spotInstanceID := requestDetails.SpotInstanceRequests[0].InstanceId
 
-	logger.Println(a.name, "found new spot instance", *spotInstanceID,
+	var tags []*ec2.Tag
+	if inst := a.getAnyInstance(); inst != nil {
+		tags = inst.filterTags()
+	}
+
+	costTags := a.costAllocationTags(requestDetails.SpotInstanceRequests[0])
+
+	a.log().Println(a.name, "found new spot instance", *spotInstanceID,
 		"\nTagging it to match the other instances from the group")
-	a.region.tagInstance(spotInstanceID, a.getAnyInstance().filterTags())
+	a.tagInstance(spotInstanceID, mergeTags(tags, costTags))
+
+	var volumeTags map[string][]*ec2.Tag
+	if id := findTagValue(requestDetails.SpotInstanceRequests[0].Tags,
+		"original-instance-id"); id != nil {
+		volumeTags = a.region.volumeTagsByDevice(id)
+	}
+
+	a.tagInstanceVolumes(spotInstanceID, costTags, volumeTags)
 }
 
-func (a *autoScalingGroup) launchCheapestSpotInstance(azToLaunchIn *string) {
+// costAllocationTags returns the tags set on every launched spot instance and
+// its volumes, so that the spot spend can be attributed back to workloads.
+func (a *autoScalingGroup) costAllocationTags(
+	spotRequest *ec2.SpotInstanceRequest) []*ec2.Tag {
+
+	tags := []*ec2.Tag{
+		{
+			Key:   aws.String("managed-by"),
+			Value: aws.String("autospotting"),
+		},
+	}
+
+	// details about the replaced on-demand instance, recorded on the spot
+	// instance request when bidding
+	for _, key := range []string{
+		"original-instance-id",
+		"original-instance-type",
+		"original-instance-price",
+	} {
+		if value := findTagValue(spotRequest.Tags, key); value != nil {
+			tags = append(tags, &ec2.Tag{
+				Key:   aws.String(key),
+				Value: value,
+			})
+		}
+	}
+
+	requested := time.Now()
+	if spotRequest.CreateTime != nil {
+		requested = *spotRequest.CreateTime
+	}
+
+	return mergeTags(tags, a.spotTags(requested))
+}
+
+// launchCheapestSpotInstance tells if a spot instance was requested to replace
+// an on-demand instance from the given availability zone. No bid is placed for
+// instances used interactively, which would keep the spot instance waiting.
+func (a *autoScalingGroup) launchCheapestSpotInstance(
+	azToLaunchIn *string) bool {
+
+	azToLaunchIn = a.diversifiedAvailabilityZone(azToLaunchIn)
 
 	if azToLaunchIn == nil {
-		logger.Println("Can't launch instances in any AZ, nothing to do here...")
-		return
+		a.log().Println("Can't launch instances in any AZ, nothing to do here...")
+		return false
+	}
+
+	if healthEvents.blocks(a.region.name, azToLaunchIn) {
+		a.recordAction("deferred", "AWS Health event affecting", *azToLaunchIn)
+		return false
 	}
 
-	logger.Println("Trying to launch spot instance in", *azToLaunchIn,
+	a.log().Println("Trying to launch spot instance in", *azToLaunchIn,
 		"\nfirst finding an on-demand instance to use as a template")
 
 	baseInstance := a.findOndemandInstanceInAZ(azToLaunchIn)
 
 	if baseInstance == nil {
-		logger.Println("Found no on-demand instances, nothing to do here...")
-		return
+		a.log().Println("Found no on-demand instances, nothing to do here...")
+		return false
+	}
+	a.log().Println("Found on-demand instance", *baseInstance.InstanceId)
+
+	if a.region.hasInteractiveSessions(baseInstance.InstanceId) {
+		a.recordAction("deferred", "on-demand instance",
+			*baseInstance.InstanceId, "has interactive sessions, not bidding",
+			"for its replacement")
+		return false
 	}
-	logger.Println("Found on-demand instance", *baseInstance.InstanceId)
 
-	newInstanceType, err := a.getCheapestCompatibleSpotInstanceType(
+	return a.launchSpotInstanceFor(baseInstance, azToLaunchIn)
+}
+
+// launchSpotInstanceFor bids for the cheapest compatible spot instance
+// replacing the base instance, in the given availability zone, and tells if
+// the bid was placed.
+func (a *autoScalingGroup) launchSpotInstanceFor(baseInstance *instance,
+	azToLaunchIn *string) bool {
+
+	// the errors are logged while searching
+	newInstanceType, _ := a.getCheapestCompatibleSpotInstanceType(
 		*azToLaunchIn,
 		baseInstance)
 
 	if newInstanceType == nil {
-		logger.Println("No cheaper compatible instance type was found, "+
-			"nothing to do here...", err)
-		return
+		a.recordAction("skipped", "no cheaper compatible spot instance type",
+			"in", *azToLaunchIn, "for", *baseInstance.InstanceType)
+		return false
 	}
 
 	baseOnDemandPrice := baseInstance.price
 
-	currentSpotPrice := a.region.
-		instanceTypeInformation[*newInstanceType].pricing.spot[*azToLaunchIn]
+	newTypeInfo := a.region.instanceTypeInformation[*newInstanceType]
+
+	currentSpotPrice := a.region.normalizedPrice(newTypeInfo,
+		a.region.spotPrice(*newInstanceType, *azToLaunchIn),
+		baseInstance.pricingProfile())
 
-	logger.Println("Finished searching for best spot instance in ",
+	if baseOnDemandPrice <= 0 || currentSpotPrice <= 0 {
+		a.recordActionAt(LevelWarn, "skipped", "missing prices for",
+			*baseInstance.InstanceType, "or", *newInstanceType, "refusing to bid")
+		return false
+	}
+
+	a.log().Println("Finished searching for best spot instance in ",
 		*azToLaunchIn,
 		"\nreplacing an on-demand", *baseInstance.InstanceType,
 		"instance having the ondemand price", baseOnDemandPrice,
 		"\nLaunching best compatible instance:", *newInstanceType,
 		"with current spot price:", currentSpotPrice)
 
-	lc := a.getLaunchConfiguration()
+	if a.exceedsBudget(baseInstance, currentSpotPrice) {
+		return false
+	}
+
+	if a.deferForSavingsPlans(baseInstance, *newInstanceType, currentSpotPrice) {
+		a.recordAction("deferred", *baseInstance.InstanceType,
+			"is covered by Savings Plans")
+		return false
+	}
+
+	lc := a.launchConfigurationFor(baseInstance)
+	if lc == nil {
+		lc = a.launchConfigurationFromInstance(baseInstance)
+	}
 
 	spotLS := convertLaunchConfigurationToSpotSpecification(
 		lc,
@@ -388,54 +831,146 @@ func (a *autoScalingGroup) launchCheapestSpotInstance(azToLaunchIn *string) {
 		*newInstanceType,
 		*azToLaunchIn)
 
-	logger.Println("Bidding for spot instance for ", a.name)
-	a.bidForSpotInstance(spotLS, baseOnDemandPrice)
+	applyDeleteOnTermination(spotLS.BlockDeviceMappings, baseInstance)
+	applyMonitoring(spotLS, a.stringSetting("spot_monitoring"))
+
+	if err := a.applyLaunchTemplatePlacement(spotLS); err != nil {
+		a.recordActionAt(LevelWarn, "skipped", "not launching a spot instance:",
+			err.Error())
+		return false
+	}
+
+	if len(spotLS.NetworkInterfaces) > 0 {
+		subnet, ok := a.launchSubnet(*azToLaunchIn, baseInstance)
+		if !ok {
+			a.recordAction("deferred", "no subnet has free IP addresses in",
+				*azToLaunchIn)
+			return false
+		}
+		spotLS.NetworkInterfaces[0].SubnetId = subnet
+	}
+
+	if ami, _ := architectureAMI(newTypeInfo.architectures,
+		baseInstance.Architecture, a.architectureAMIs()); ami != "" {
+		a.log().Println(a.name, "Launching", *newInstanceType, "from", ami)
+		spotLS.ImageId = aws.String(ami)
+	}
+
+	bidPrice := a.bidPrice(baseOnDemandPrice, currentSpotPrice)
+
+	a.log().Println("Bidding for spot instance for ", a.name, "at", bidPrice)
+	return a.bidForSpotInstance(spotLS, bidPrice, baseInstance)
+}
+
+// bidPrice returns the maximum price we're willing to pay for the spot
+// instance, determined by the group's bid strategy. Without one, this is the
+// on-demand price, but it can be configured as a multiple of the current spot
+// price, which still protects against price spikes without accepting to pay
+// as much as on-demand, which stays the ceiling.
+func (a *autoScalingGroup) bidPrice(onDemandPrice, spotPrice float64) float64 {
+
+	if setting := a.stringSetting("bid_strategy"); setting != "" {
+		strategy, err := parseBidStrategy(setting)
+		if err == nil {
+			return strategy.price(onDemandPrice, spotPrice)
+		}
+		a.log().at(LevelWarn).Println(a.name, "Ignoring the bid strategy",
+			setting, err.Error())
+	}
+
+	factor := a.region.conf.BidSpotPriceFactor
+
+	if factor <= 0 || spotPrice <= 0 {
+		return onDemandPrice
+	}
+
+	return math.Min(spotPrice*factor, onDemandPrice)
 }
 
-func (a *autoScalingGroup) setAutoScalingMaxSize(maxSize int64) {
+func (a *autoScalingGroup) setAutoScalingMaxSize(maxSize int64) error {
 	svc := a.region.services.autoScaling
 
-	_, err := svc.UpdateAutoScalingGroup(
-		&autoscaling.UpdateAutoScalingGroupInput{
-			AutoScalingGroupName: aws.String(a.name),
-			MaxSize:              aws.Int64(maxSize),
-		})
+	input := &autoscaling.UpdateAutoScalingGroupInput{
+		AutoScalingGroupName: aws.String(a.name),
+		MaxSize:              aws.Int64(maxSize),
+	}
+
+	if a.skipsCall("UpdateAutoScalingGroup", input) {
+		return nil
+	}
+
+	_, err := svc.UpdateAutoScalingGroup(input)
 
 	if err != nil {
 		// Print the error, cast err to awserr.Error to get the Code and
 		// Message from an error.
-		logger.Println(err.Error())
-		return
+		a.log().at(LevelError).Println(err.Error())
+		return err
 	}
+	return nil
 }
 
 func (a *autoScalingGroup) bidForSpotInstance(
 	ls *ec2.RequestSpotLaunchSpecification,
-	price float64) {
+	price float64,
+	baseInstance *instance) bool {
 
 	svc := a.region.services.ec2
 
-	resp, err := svc.RequestSpotInstances(&ec2.RequestSpotInstancesInput{
+	if !a.region.pools.reserve(*ls.InstanceType,
+		*ls.Placement.AvailabilityZone,
+		a.region.conf.MaxRegionPoolConcentration) {
+		a.recordAction("deferred", "spot pool", *ls.InstanceType, "in",
+			*ls.Placement.AvailabilityZone, "reached the region's concentration",
+			"limit")
+		return false
+	}
+
+	input := &ec2.RequestSpotInstancesInput{
 		SpotPrice:           aws.String(strconv.FormatFloat(price, 'f', -1, 64)),
 		LaunchSpecification: ls,
-	})
+	}
+
+	if a.skipsCall("RequestSpotInstances", input) {
+		a.region.pools.release(*ls.InstanceType, *ls.Placement.AvailabilityZone)
+		return false
+	}
+
+	resp, err := svc.RequestSpotInstances(input)
 
 	if err != nil {
-		logger.Println("Failed to create spot instance request for",
-			a.name, err.Error(), ls)
-		return
+		a.region.pools.release(*ls.InstanceType, *ls.Placement.AvailabilityZone)
+		debug.Println(a.name, "Failed launch specification", ls)
+		a.recordActionAt(LevelError, "bid-failed", *ls.InstanceType, "in",
+			*ls.Placement.AvailabilityZone, err.Error())
+		a.audit(auditEvent{
+			Event:            auditBidFailed,
+			OldInstanceID:    aws.StringValue(baseInstance.InstanceId),
+			OldInstanceType:  aws.StringValue(baseInstance.InstanceType),
+			OldPrice:         baseInstance.price,
+			NewInstanceType:  *ls.InstanceType,
+			NewPrice:         price,
+			AvailabilityZone: *ls.Placement.AvailabilityZone,
+			Message:          err.Error(),
+		})
+		return false
 	}
 
 	spotRequest := resp.SpotInstanceRequests[0]
 	spotRequestID := spotRequest.SpotInstanceRequestId
 
-	logger.Println(a.name, "Created spot instance request", *spotRequestID)
+	// counted as a pending launch by the next bids of the run
+	a.spotInstanceRequests = append(a.spotInstanceRequests, spotRequest)
+
+	a.recordAction("bid", "spot request", *spotRequestID, "for",
+		*ls.InstanceType, "in", *ls.Placement.AvailabilityZone, "at", price,
+		"to replace", *baseInstance.InstanceId)
 
 	// tag the spot instance request to associate it with the current ASG, so we
 	// know where to attach the instance later. In case the waiter failed, it may
 	// happen that the instance is actually tagged in the next run, but the spot
 	// instance request needs to be tagged anyway.
-	a.tagSpotInstanceRequest(*spotRequestID)
+	a.tagSpotInstanceRequest(*spotRequestID, baseInstance)
 
 	// Waiting for the instance to start so that we can then later tag it with
 	// the same tags originally set on the on-demand instances.
@@ -445,31 +980,56 @@ func (a *autoScalingGroup) bidForSpotInstance(
 	// the next run if we have any open spot requests with no instances and
 	// resume the wait there.
 	a.waitForAndTagSpotInstance(spotRequest)
+	return true
 }
 
-func (a *autoScalingGroup) tagSpotInstanceRequest(requestID string) {
+// The spot instance request is also tagged with the ID, type and hourly price of
+// the on-demand instance it was launched to replace, these are later copied
+// over to the spot instance in case the tagging only happens in a subsequent
+// run. The configured spot tags and the group's tags propagated at launch are
+// also set on the request, since some billing exports attribute the costs to
+// the spot instance requests.
+func (a *autoScalingGroup) tagSpotInstanceRequest(requestID string,
+	baseInstance *instance) {
 	svc := a.region.services.ec2
 
 	_, err := svc.CreateTags(&ec2.CreateTagsInput{
 		Resources: []*string{aws.String(requestID)},
-		Tags: []*ec2.Tag{
+		Tags: mergeTags(mergeTags(a.propagatedTags(), a.spotTags(time.Now())), []*ec2.Tag{
+			{
+				Key:   aws.String("managed-by"),
+				Value: aws.String("autospotting"),
+			},
 			{
 				Key:   aws.String("launched-for-asg"),
 				Value: aws.String(a.name),
 			},
-		},
+			{
+				Key:   aws.String("original-instance-id"),
+				Value: baseInstance.InstanceId,
+			},
+			{
+				Key:   aws.String("original-instance-type"),
+				Value: baseInstance.InstanceType,
+			},
+			{
+				Key: aws.String("original-instance-price"),
+				Value: aws.String(
+					strconv.FormatFloat(baseInstance.price, 'f', -1, 64)),
+			},
+		}),
 	})
 
 	if err != nil {
 		// Print the error, cast err to awserr.Error to get the Code and
 		// Message from an error.
-		logger.Println(a.name,
+		a.log().at(LevelError).Println(a.name,
 			"Failed to create tags for the spot instance request",
 			err.Error())
 		return
 	}
 
-	logger.Println(a.name, "successfully tagged spot instance request", requestID)
+	a.log().Println(a.name, "successfully tagged spot instance request", requestID)
 }
 
 func (a *autoScalingGroup) getLaunchConfiguration() *autoscaling.LaunchConfiguration {
@@ -480,19 +1040,7 @@ func (a *autoScalingGroup) getLaunchConfiguration() *autoscaling.LaunchConfigura
 		return nil
 	}
 
-	svc := a.region.services.autoScaling
-
-	params := &autoscaling.DescribeLaunchConfigurationsInput{
-		LaunchConfigurationNames: []*string{lcName},
-	}
-	resp, err := svc.DescribeLaunchConfigurations(params)
-
-	if err != nil {
-		logger.Println(err.Error())
-		return nil
-	}
-
-	return resp.LaunchConfigurations[0]
+	return a.describeLaunchConfiguration(lcName)
 }
 
 func convertLaunchConfigurationToSpotSpecification(
@@ -570,9 +1118,10 @@ func copyBlockDeviceMappings(
 	lcBDMs []*autoscaling.BlockDeviceMapping) []*ec2.BlockDeviceMapping {
 
 	var ec2BDMlist []*ec2.BlockDeviceMapping
-	var ec2BDM ec2.BlockDeviceMapping
 
 	for _, lcBDM := range lcBDMs {
+		var ec2BDM ec2.BlockDeviceMapping
+
 		ec2BDM.DeviceName = lcBDM.DeviceName
 
 		// EBS volume information
@@ -601,7 +1150,9 @@ func copyBlockDeviceMappings(
 	return ec2BDMlist
 }
 
-func (a *autoScalingGroup) attachSpotInstance(spotInstanceID *string) {
+// attachSpotInstance attaches the spot instance to the group, and returns the
+// error of a failed attachment so that the replacement can be abandoned.
+func (a *autoScalingGroup) attachSpotInstance(spotInstanceID *string) error {
 
 	svc := a.region.services.autoScaling
 
@@ -612,14 +1163,27 @@ func (a *autoScalingGroup) attachSpotInstance(spotInstanceID *string) {
 		},
 	}
 
-	resp, err := svc.AttachInstances(&params)
+	if a.skipsCall("AttachInstances", &params) {
+		return nil
+	}
+
+	_, err := svc.AttachInstances(&params)
 
 	if err != nil {
-		logger.Println(err.Error())
-		// Pretty-print the response data.
-		logger.Println(resp)
+		a.recordActionAt(LevelError, "attach-failed", "spot instance",
+			*spotInstanceID, err.Error())
+		a.recordAttachFailure(spotInstanceID, err)
+		return err
 	}
-
+	a.capacityChanges++
+	a.recordAction("attached", "spot instance", *spotInstanceID)
+	a.auditReplacement(spotInstanceID)
+	a.waitUntilAttached(spotInstanceID)
+	a.protectFromScaleIn(spotInstanceID)
+	a.registerIPTargets(spotInstanceID)
+	a.startCanary(spotInstanceID)
+	a.startBenchmark(spotInstanceID)
+	return nil
 }
 
 // Terminates an on-demand instance from the group,
@@ -627,7 +1191,7 @@ func (a *autoScalingGroup) attachSpotInstance(spotInstanceID *string) {
 func (a *autoScalingGroup) detachAndTerminateOnDemandInstance(
 	instanceID *string) {
 
-	logger.Println(a.region.name,
+	a.log().Println(a.region.name,
 		a.name,
 		"Detaching and terminating instance:",
 		*instanceID)
@@ -643,19 +1207,33 @@ func (a *autoScalingGroup) detachAndTerminateOnDemandInstance(
 
 	asSvc := a.region.services.autoScaling
 
-	if _, err := asSvc.DetachInstances(&detachParams); err != nil {
-		logger.Println(err.Error())
+	if a.skipsCall("DetachInstances", &detachParams) {
+		a.skipsCall("TerminateInstances", &ec2.TerminateInstancesInput{
+			InstanceIds: []*string{instanceID},
+		})
+		return
 	}
 
-	a.instances.get(*instanceID).terminate(a.region.services.ec2)
+	a.deregisterIPTargets(instanceID)
 
+	if _, err := asSvc.DetachInstances(&detachParams); err != nil {
+		a.log().at(LevelError).Println(a.region.name, a.name,
+			"Failed to detach instance", *instanceID, err.Error())
+		return
+	}
+	a.capacityChanges++
+	a.waitUntilDetached(instanceID)
+
+	if !a.retireOnDemandInstance(a.instances.get(*instanceID)) {
+		a.recordAction("terminated", "on-demand instance", *instanceID)
+	}
 }
 
 func (a *autoScalingGroup) getCheapestCompatibleSpotInstanceType(
 	availabilityZone string,
 	baseInstance *instance) (*string, error) {
 
-	logger.Println("Getting cheapest spot instance compatible to ",
+	a.log().Println("Getting cheapest spot instance compatible to ",
 		*baseInstance.InstanceId, " of type", *baseInstance.InstanceType)
 
 	filteredInstanceTypes, err := a.getCompatibleSpotInstanceTypes(
@@ -663,33 +1241,151 @@ func (a *autoScalingGroup) getCheapestCompatibleSpotInstanceType(
 		baseInstance)
 
 	if err != nil {
-		logger.Println("Couldn't find any compatible instance types", err)
+		a.log().at(LevelWarn).Println(
+			"Couldn't find any compatible instance types",
+			err)
 		return nil, err
 	}
 
 	minPrice := math.MaxFloat64
 	var chosenInstanceType string
 
+	prices := a.spotCandidatePrices(availabilityZone, baseInstance,
+		filteredInstanceTypes)
+
 	for _, instanceType := range filteredInstanceTypes {
-		price := a.region.instanceTypeInformation[instanceType].pricing.spot[availabilityZone]
+		price := prices[instanceType]
 
 		if price < minPrice {
 			minPrice, chosenInstanceType = price, instanceType
-			logger.Println(a.name, "changed current minimum to ", minPrice)
+			a.log().Println(a.name, "changed current minimum to ", minPrice)
 		}
-		logger.Println(a.name, "cheapest instance type so far is ",
+		a.log().Println(a.name, "cheapest instance type so far is ",
 			chosenInstanceType, "priced at", minPrice)
 	}
 
 	if chosenInstanceType != "" {
-		logger.Println("Chose cheapest instance type", chosenInstanceType)
+		chosenInstanceType = preferNewestGeneration(chosenInstanceType, prices,
+			a.region.conf.PriceTieEpsilon)
+
+		chosenInstanceType = preferStickyType(chosenInstanceType,
+			a.lastSpotInstanceType(), prices, a.stickyPriceBand())
+
+		chosenInstanceType = a.diversifiedInstanceType(availabilityZone,
+			chosenInstanceType, prices)
+
+		a.candidates.score(prices, chosenInstanceType)
+
+		a.log().Println("Chose cheapest instance type", chosenInstanceType)
 		return &chosenInstanceType, nil
 	}
-	logger.Println("Couldn't find any cheaper spot instance type")
+	a.log().Println("Couldn't find any cheaper spot instance type")
 	return nil, fmt.Errorf("No cheaper spot instance types could be found")
 
 }
 
+// spotCandidatePrices returns the effective prices of the compatible instance
+// types in the availability zone, adjusted for their interruption risk and
+// benchmark scores.
+func (a *autoScalingGroup) spotCandidatePrices(availabilityZone string,
+	baseInstance *instance, instanceTypes []string) map[string]float64 {
+
+	prices := make(map[string]float64)
+	scores := a.benchmarkScores()
+
+	for _, instanceType := range instanceTypes {
+		info := a.region.instanceTypeInformation[instanceType]
+		price := a.region.normalizedPrice(info,
+			a.region.spotPrice(instanceType, availabilityZone),
+			baseInstance.pricingProfile())
+		price = a.region.riskAdjustedPrice(instanceType, price,
+			baseInstance.pricingProfile())
+		prices[instanceType] = benchmarkAdjustedPrice(instanceType, price, scores)
+	}
+	return prices
+}
+
+// preferNewestGeneration picks, among the instance types priced within epsilon
+// of the cheapest one, the one of the newest generation, so that for
+// practically the same price we get better performance. Further ties are broken
+// by price and then by name, in order to get a deterministic choice.
+func preferNewestGeneration(cheapest string, prices map[string]float64,
+	epsilon float64) string {
+
+	chosen := cheapest
+
+	for instanceType, price := range prices {
+
+		if price-prices[cheapest] > epsilon {
+			continue
+		}
+
+		gen, chosenGen := instanceGeneration(instanceType),
+			instanceGeneration(chosen)
+
+		if gen > chosenGen ||
+			(gen == chosenGen && price < prices[chosen]) ||
+			(gen == chosenGen && price == prices[chosen] && instanceType < chosen) {
+			chosen = instanceType
+		}
+	}
+
+	if chosen != cheapest {
+		logger.Println("Preferring the newer generation", chosen, "priced at",
+			prices[chosen], "over", cheapest, "priced at", prices[cheapest])
+	}
+	return chosen
+}
+
+// preferStickyType keeps using the instance type chosen last time, as long as
+// it's still compatible and priced within the band, given as a fraction of the
+// chosen instance type's price, in order to reduce the churn of instance types
+// seen by warm caches, prebuilt AMIs and autoscalers.
+func preferStickyType(chosen, last string, prices map[string]float64,
+	band float64) string {
+
+	if band <= 0 || last == "" || last == chosen {
+		return chosen
+	}
+
+	lastPrice, compatible := prices[last]
+	if !compatible || lastPrice > prices[chosen]*(1+band) {
+		return chosen
+	}
+
+	logger.Println("Sticking to the previously used", last, "priced at",
+		lastPrice, "instead of", chosen, "priced at", prices[chosen])
+	return last
+}
+
+// lastSpotInstanceType returns the type of the most recently launched spot
+// instance of the group, or an empty string if there are none.
+func (a *autoScalingGroup) lastSpotInstanceType() string {
+
+	var last *instance
+
+	for _, inst := range a.instances.catalog {
+		if !inst.isSpot() || inst.LaunchTime == nil {
+			continue
+		}
+		if last == nil || inst.LaunchTime.After(*last.LaunchTime) {
+			last = inst
+		}
+	}
+
+	if last == nil {
+		return ""
+	}
+	return *last.InstanceType
+}
+
+// stickyPriceBand returns the group's sticky_price_band setting, defaulting to
+// the globally configured band.
+func (a *autoScalingGroup) stickyPriceBand() float64 {
+
+	return a.numberSetting("sticky_price_band")
+}
+
 // Why the heck isn't this in the Go standard library?
 func min(x, y int) int {
 	if x < y {
@@ -701,18 +1397,19 @@ func min(x, y int) int {
 func (a *autoScalingGroup) getCompatibleSpotInstanceTypes(
 	availabilityZone string, refInstance *instance) ([]string, error) {
 
-	logger.Println("Getting spot instances compatible to ",
+	a.log().Println("Getting spot instances compatible to ",
 		*refInstance.InstanceId, " of type", *refInstance.InstanceType)
 
-	debug.Println("Using this data as reference", spew.Sdump(refInstance))
+	debugDump(a.region.name, a.name, "refInstance", refInstance)
 
 	var filteredInstanceTypes []string
 
 	existing := refInstance.typeInfo
 
-	debug.Println("Using this data as reference", existing)
+	a.debugLog().Println("Using this data as reference", existing)
 
-	debug.Println("Instance Data", spew.Sdump(a.region.instanceTypeInformation))
+	debugDump(a.region.name, a.name, "instanceTypeInformation",
+		a.region.instanceTypeInformation)
 
 	// Count the ephemeral volumes attached to the original instance's block
 	// device mappings, this number is used later when comparing with each
@@ -720,44 +1417,115 @@ func (a *autoScalingGroup) getCompatibleSpotInstanceTypes(
 	lcMappings, err := a.countLaunchConfigEphemeralVolumes()
 
 	if err == nil {
-		logger.Println("Couldn't determine the launch configuration device mapping",
-			"configuration")
+		a.log().at(LevelWarn).Println("Couldn't determine the launch",
+			"configuration device mapping configuration")
 	}
 
 	attachedVolumesNumber := min(lcMappings, existing.instanceStoreDeviceCount)
 
+	performanceFactor := a.performanceFactor()
+	amis := a.architectureAMIs()
+
+	requirements := a.instanceRequirements()
+	if requirements != nil {
+		a.log().Println(a.name, "Using the instance requirements of the group",
+			"instead of the CPU and memory of", *refInstance.InstanceId)
+	}
+
+	a.region.prefetchSpotPrices(availabilityZone, a.region.regionInstanceTypes())
+
+	candidates := a.newCandidateTable(refInstance, availabilityZone)
+	defer candidateExports.add(candidates)
+
+	allowedTypes := splitInstanceTypes(a.stringSetting("allowed-instance-types"))
+	disallowedTypes := splitInstanceTypes(
+		a.stringSetting("disallowed-instance-types"))
+
 	//filtering compatible instance types
 	for _, candidate := range a.region.instanceTypeInformation {
 
-		logger.Println("\nComparing ", candidate, " with ", existing)
+		a.log().Println("\nComparing ", candidate, " with ", existing)
+
+		if !allowedInstanceType(candidate.instanceType,
+			*refInstance.InstanceType, allowedTypes) {
+			a.log().Println("not an allowed instance type, skipping",
+				candidate.instanceType)
+			candidates.reject(candidate, 0, "not an allowed instance type")
+			continue
+		}
+
+		if disallowedInstanceType(candidate.instanceType, disallowedTypes) {
+			a.log().Println("disallowed instance type, skipping",
+				candidate.instanceType)
+			candidates.reject(candidate, 0, "disallowed instance type")
+			continue
+		}
 
-		spotPriceNewInstance := candidate.pricing.spot[availabilityZone]
+		spotPriceNewInstance := a.region.spotPrice(candidate.instanceType,
+			availabilityZone)
 
 		if spotPriceNewInstance == 0 {
-			logger.Println("Missing spot pricing information, skipping",
+			a.log().Println("Missing spot pricing information, skipping",
+				candidate.instanceType)
+			candidates.reject(candidate, spotPriceNewInstance,
+				"missing spot price")
+			continue
+		}
+
+		// compare the effective costs, as if the candidate was running with
+		// the platform, tenancy and EBS optimization of the reference instance
+		spotPriceNewInstance = a.region.normalizedPrice(candidate,
+			spotPriceNewInstance, refInstance.pricingProfile())
+
+		if reason := a.poolSkipReason(candidate.instanceType,
+			availabilityZone); reason != "" {
+			a.log().Println(reason, "in", availabilityZone, "skipping",
 				candidate.instanceType)
+			candidates.reject(candidate, spotPriceNewInstance, reason)
+			continue
+		}
+
+		if a.region.frequentlyInterrupted(candidate.instanceType,
+			refInstance.pricingProfile()) {
+			a.log().Println("rated by the Spot Advisor as frequently interrupted,",
+				"skipping", candidate.instanceType)
+			candidates.reject(candidate, spotPriceNewInstance,
+				"frequently interrupted according to the Spot Advisor")
 			continue
 		}
 
 		if spotPriceNewInstance <= refInstance.price {
-			logger.Println("pricing compatible, continuing evaluation: ",
-				candidate.pricing.spot[availabilityZone], "<=",
-				refInstance.price)
+			a.log().Println("pricing compatible, continuing evaluation: ",
+				spotPriceNewInstance, "<=", refInstance.price)
 		} else {
-			logger.Println("price too high, skipping", candidate.instanceType)
+			a.log().Println("price too high, skipping", candidate.instanceType)
+			candidates.reject(candidate, spotPriceNewInstance,
+				"price too high")
 			continue
 		}
 
-		if candidate.instanceType == "m4.16xlarge" {
-			logger.Println("This is a m4.16xlarge, continuing evaluation")
-		} else if candidate.instanceType == "m4.10xlarge" { 
-			logger.Println("This is a m4.10xlarge, continuing evaluation")
-    	} else if candidate.instanceType == "c4.8xlarge" { 
-            logger.Println("This is a c4.8xlarge, continuing evaluation")
-        } else if candidate.instanceType == "cc2.8xlarge" { 
-            logger.Println("This is a cc2.8xlarge, continuing evaluation")
-        } else {
-			logger.Println("Not a m4.16xlarge, m4.10xlarge, c4.8xlarge, cc2.8xlarge, skipping", candidate.instanceType)
+		// The candidate needs to satisfy the group's declared instance
+		// requirements if any, otherwise at least as much CPU and memory as the
+		// original instance, multiplied by the group's performance factor. It
+		// also needs at least as many GPUs as the original instance.
+		if requirements != nil {
+			if requirements.matches(candidate) && candidate.gpu >= existing.gpu {
+				a.log().Println("instance requirements satisfied, continuing",
+					"evaluation")
+			} else {
+				a.log().Println("instance requirements not satisfied, skipping",
+					candidate.instanceType)
+				candidates.reject(candidate, spotPriceNewInstance,
+					"instance requirements not satisfied")
+				continue
+			}
+		} else if candidate.hasCapacityOf(existing, performanceFactor) {
+			a.log().Println("CPU, memory and GPUs compatible, continuing evaluation")
+		} else {
+			a.log().Println("not enough CPU, memory or GPUs, skipping",
+				candidate.instanceType)
+			candidates.reject(candidate, spotPriceNewInstance,
+				"not enough CPU, memory or GPUs")
 			continue
 		}
 
@@ -771,25 +1539,29 @@ func (a *autoScalingGroup) getCompatibleSpotInstanceTypes(
 		//   original instance's volumes
 
 		if attachedVolumesNumber > 0 {
-			logger.Println("Checking the new instance's ephemeral storage",
+			a.log().Println("Checking the new instance's ephemeral storage",
 				"configuration because the initial instance had attached",
 				"ephemeral instance store volumes")
 
 			if candidate.instanceStoreDeviceCount >= attachedVolumesNumber {
-				logger.Println("instance store volume count compatible,",
+				a.log().Println("instance store volume count compatible,",
 					"continuing	evaluation")
 			} else {
-				logger.Println("instance store volume count incompatible, skipping",
+				a.log().Println("instance store volume count incompatible, skipping",
 					candidate.instanceType)
+				candidates.reject(candidate, spotPriceNewInstance,
+					"not enough instance store volumes")
 				continue
 			}
 
 			if candidate.instanceStoreDeviceSize >= existing.instanceStoreDeviceSize {
-				logger.Println("instance store volume size compatible,",
+				a.log().Println("instance store volume size compatible,",
 					"continuing evaluation")
 			} else {
-				logger.Println("instance store volume size incompatible, skipping",
+				a.log().Println("instance store volume size incompatible, skipping",
 					candidate.instanceType)
+				candidates.reject(candidate, spotPriceNewInstance,
+					"instance store volumes too small")
 				continue
 			}
 
@@ -797,54 +1569,84 @@ func (a *autoScalingGroup) getCompatibleSpotInstanceTypes(
 			// ephemeral SSDs, but accept spinning disks if we had those before.
 			if candidate.instanceStoreIsSSD ||
 				(candidate.instanceStoreIsSSD == existing.instanceStoreIsSSD) {
-				logger.Println("instance store type(SSD/spinning) compatible,",
+				a.log().Println("instance store type(SSD/spinning) compatible,",
 					"continuing evaluation")
 			} else {
-				logger.Println("instance store type(SSD/spinning) incompatible,",
+				a.log().Println("instance store type(SSD/spinning) incompatible,",
 					"skipping", candidate.instanceType)
+				candidates.reject(candidate, spotPriceNewInstance,
+					"spinning instance store disks")
 				continue
 			}
 		}
 
+		if _, compatible := architectureAMI(candidate.architectures,
+			refInstance.Architecture, amis); compatible {
+			a.log().Println("architecture compatible, continuing evaluation")
+		} else {
+			a.log().Println("architecture incompatible, skipping",
+				candidate.instanceType)
+			candidates.reject(candidate, spotPriceNewInstance,
+				"incompatible architecture")
+			continue
+		}
+
 		if compatibleVirtualization(*refInstance.VirtualizationType,
 			candidate.virtualizationTypes) {
-			logger.Println("virtualization compatible, continuing evaluation")
+			a.log().Println("virtualization compatible, continuing evaluation")
 		} else {
-			logger.Println("virtualization incompatible, skipping",
+			a.log().Println("virtualization incompatible, skipping",
 				candidate.instanceType)
+			candidates.reject(candidate, spotPriceNewInstance,
+				"incompatible virtualization")
 			continue
 		}
 
-		// checking how many spot instances of this type we already have, so that
-		// we can see how risky it is to launch a new one.
-		spotInstanceCount := a.alreadyRunningSpotInstanceCount(
-			candidate.instanceType, availabilityZone)
+		// checking how many spot instances of this type we already have or are
+		// launching, so that we can see how risky it is to launch a new one.
+		if !a.region.pools.allowed(candidate.instanceType, availabilityZone,
+			a.region.conf.MaxRegionPoolConcentration) {
+			a.log().Println("too many of the region's managed instances in",
+				availabilityZone, "are already of type", candidate.instanceType,
+				"skipping")
+			candidates.reject(candidate, spotPriceNewInstance,
+				"region's pool concentration limit reached")
+			continue
+		}
 
-		// We skip it in case we have more than 20% instances of this type already
-		// running
-		if spotInstanceCount == 0 ||
-			(*a.DesiredCapacity/spotInstanceCount > 4) {
-			logger.Println(a.name,
+		if a.poolConcentrationAllowed(candidate.instanceType, availabilityZone) {
+			a.log().Println(a.name,
 				"no redundancy issues found for", candidate.instanceType,
-				"existing", spotInstanceCount,
-				"spot instances, adding for comparison",
+				"adding for comparison",
 			)
 
 			filteredInstanceTypes = append(filteredInstanceTypes, candidate.instanceType)
+			candidates.accept(candidate, spotPriceNewInstance)
 		} else {
-			logger.Println("\nInstances ", candidate, " and ", existing,
+			a.log().Println("\nInstances ", candidate, " and ", existing,
 				"are not compatible or resulting redundancy for the availability zone",
 				"would be dangerously low")
+			candidates.reject(candidate, spotPriceNewInstance,
+				"group's pool concentration limit reached")
 
 		}
 
 	}
-	logger.Printf("\n Found following compatible instances: %#v\n",
+	a.log().Printf("\n Found following compatible instances: %#v\n",
 		filteredInstanceTypes)
 	return filteredInstanceTypes, nil
 
 }
 
+// performanceFactor returns the multiplier applied to the CPU and memory of the
+// original instance when looking for compatible instance types, read from the
+// group's performance_factor setting. Values above 1 require more powerful
+// instances than the original, to get some extra headroom.
+func (a *autoScalingGroup) performanceFactor() float64 {
+
+	return a.numberSetting("performance_factor")
+}
+
 func compatibleVirtualization(virtualizationType string,
 	availableVirtualizationTypes []string) bool {
 
@@ -877,11 +1679,11 @@ func (a *autoScalingGroup) countLaunchConfigEphemeralVolumes() (int, error) {
 	for _, mapping := range lc.BlockDeviceMappings {
 		if mapping.VirtualName != nil &&
 			strings.Contains(*mapping.VirtualName, "ephemeral") {
-			logger.Println("Found ephemeral device mapping", *mapping.VirtualName)
+			a.log().Println("Found ephemeral device mapping", *mapping.VirtualName)
 			count++
 		}
 	}
-	logger.Printf("Launch configuration would attach %d ephemeral volumes",
+	a.log().Printf("Launch configuration would attach %d ephemeral volumes "+
 		"if available", count)
 	return count, nil
 }
@@ -891,17 +1693,17 @@ func (a *autoScalingGroup) alreadyRunningSpotInstanceCount(
 	instanceType, availabilityZone string) int64 {
 
 	var count int64
-	logger.Println(a.name, "Counting already running spot instances of type ",
+	a.log().Println(a.name, "Counting already running spot instances of type ",
 		instanceType, " in AZ ", availabilityZone)
 	for _, inst := range a.instances.catalog {
 		if *inst.InstanceType == instanceType &&
 			*inst.Placement.AvailabilityZone == availabilityZone &&
 			inst.isSpot() {
-			logger.Println(a.name, "Found running spot instance ",
+			a.log().Println(a.name, "Found running spot instance ",
 				*inst.InstanceId, "of the same type:", instanceType)
 			count++
 		}
 	}
-	logger.Println(a.name, "Found", count, instanceType, "instances")
+	a.log().Println(a.name, "Found", count, instanceType, "instances")
 	return count
 }
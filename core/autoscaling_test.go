@@ -0,0 +1,443 @@
+package autospotting
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_replacementOrder(t *testing.T) {
+	tests := []struct {
+		name            string
+		desiredCapacity int64
+		minSize         int64
+		healthy         int64
+		policy          *autoscaling.InstanceMaintenancePolicy
+		wantAttachFirst bool
+		wantAllowed     bool
+	}{
+		{name: "No policy, above minimum capacity",
+			desiredCapacity: 4, minSize: 2, healthy: 4,
+			wantAttachFirst: false, wantAllowed: true,
+		},
+		{name: "No policy, at minimum capacity",
+			desiredCapacity: 2, minSize: 2, healthy: 2,
+			wantAttachFirst: true, wantAllowed: true,
+		},
+		{name: "Minimum healthy percentage forces attaching first",
+			desiredCapacity: 4, minSize: 2, healthy: 4,
+			policy: &autoscaling.InstanceMaintenancePolicy{
+				MinHealthyPercentage: aws.Int64(100),
+				MaxHealthyPercentage: aws.Int64(150),
+			},
+			wantAttachFirst: true, wantAllowed: true,
+		},
+		{name: "Both percentages at 100 block the replacement",
+			desiredCapacity: 4, minSize: 2, healthy: 4,
+			policy: &autoscaling.InstanceMaintenancePolicy{
+				MinHealthyPercentage: aws.Int64(100),
+				MaxHealthyPercentage: aws.Int64(100),
+			},
+			wantAttachFirst: false, wantAllowed: false,
+		},
+		{name: "Maximum healthy percentage blocks attaching at minimum capacity",
+			desiredCapacity: 2, minSize: 2, healthy: 2,
+			policy: &autoscaling.InstanceMaintenancePolicy{
+				MinHealthyPercentage: aws.Int64(-1),
+				MaxHealthyPercentage: aws.Int64(100),
+			},
+			wantAttachFirst: true, wantAllowed: false,
+		},
+		{name: "Cleared settings are ignored",
+			desiredCapacity: 4, minSize: 2, healthy: 4,
+			policy: &autoscaling.InstanceMaintenancePolicy{
+				MinHealthyPercentage: aws.Int64(-1),
+				MaxHealthyPercentage: aws.Int64(-1),
+			},
+			wantAttachFirst: false, wantAllowed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotAttachFirst, gotAllowed := replacementOrder(tt.desiredCapacity,
+				tt.minSize, tt.healthy, tt.policy)
+			if gotAttachFirst != tt.wantAttachFirst || gotAllowed != tt.wantAllowed {
+				t.Errorf("replacementOrder() = %v, %v, want %v, %v",
+					gotAttachFirst, gotAllowed, tt.wantAttachFirst, tt.wantAllowed)
+			}
+		})
+	}
+}
+
+func Test_needsMaxSizeBump(t *testing.T) {
+	tests := []struct {
+		name            string
+		desiredCapacity int64
+		maxSize         int64
+		attachFirst     bool
+		want            bool
+	}{
+		{name: "Static size group attaching first",
+			desiredCapacity: 2, maxSize: 2, attachFirst: true, want: true,
+		},
+		{name: "Room left for attaching first",
+			desiredCapacity: 2, maxSize: 3, attachFirst: true, want: false,
+		},
+		{name: "Detaching first never exceeds MaxSize",
+			desiredCapacity: 3, maxSize: 3, attachFirst: false, want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := needsMaxSizeBump(tt.desiredCapacity, tt.maxSize,
+				tt.attachFirst); got != tt.want {
+				t.Errorf("needsMaxSizeBump() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_preferNewestGeneration(t *testing.T) {
+	tests := []struct {
+		name     string
+		cheapest string
+		prices   map[string]float64
+		epsilon  float64
+		want     string
+	}{
+		{name: "Newer generation within epsilon wins",
+			cheapest: "m4.large",
+			prices:   map[string]float64{"m4.large": 0.030, "m5.large": 0.0305},
+			epsilon:  0.001,
+			want:     "m5.large",
+		},
+		{name: "Newer generation outside epsilon loses",
+			cheapest: "m4.large",
+			prices:   map[string]float64{"m4.large": 0.030, "m5.large": 0.035},
+			epsilon:  0.001,
+			want:     "m4.large",
+		},
+		{name: "Same generation keeps the cheapest",
+			cheapest: "c5.large",
+			prices: map[string]float64{
+				"c5.large": 0.030, "m5.large": 0.0305, "c4.large": 0.030},
+			epsilon: 0.001,
+			want:    "c5.large",
+		},
+		{name: "Zero epsilon only considers exact ties",
+			cheapest: "c5.large",
+			prices:   map[string]float64{"c5.large": 0.030, "c6i.large": 0.030},
+			epsilon:  0,
+			want:     "c6i.large",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := preferNewestGeneration(tt.cheapest, tt.prices,
+				tt.epsilon); got != tt.want {
+				t.Errorf("preferNewestGeneration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_handleUnfulfillableSpotRequest(t *testing.T) {
+	actions = actionHistory{}
+	actions.init(Config{HistorySize: 10})
+
+	tests := []struct {
+		name       string
+		state      string
+		status     *ec2.SpotInstanceStatus
+		tags       []*ec2.Tag
+		want       bool
+		wantTagged bool
+	}{
+		{name: "Missing status",
+			state: "open",
+			want:  false,
+		},
+		{name: "Fulfilled request",
+			state:  "active",
+			status: &ec2.SpotInstanceStatus{Code: aws.String("fulfilled")},
+			want:   false,
+		},
+		{name: "Pending evaluation",
+			state:  "open",
+			status: &ec2.SpotInstanceStatus{Code: aws.String("pending-evaluation")},
+			want:   false,
+		},
+		{name: "Expired request",
+			state:  "cancelled",
+			status: &ec2.SpotInstanceStatus{Code: aws.String("schedule-expired")},
+			want:   true,
+		},
+		{name: "Instance about to be interrupted",
+			state:  "active",
+			status: &ec2.SpotInstanceStatus{Code: aws.String("marked-for-termination")},
+			want:   true,
+		},
+		{name: "Closed request with price too low",
+			state:      "closed",
+			status:     &ec2.SpotInstanceStatus{Code: aws.String("price-too-low")},
+			want:       true,
+			wantTagged: true,
+		},
+		{name: "Closed request handled in a previous run",
+			state:  "closed",
+			status: &ec2.SpotInstanceStatus{Code: aws.String("price-too-low")},
+			tags: []*ec2.Tag{{Key: aws.String(bidFailureTag),
+				Value: aws.String("price-too-low")}},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			services, calls := fakeConnections(nil)
+			asg := autoScalingGroup{
+				Group:  &autoscaling.Group{},
+				name:   "web",
+				region: &region{name: "us-east-1", services: services},
+			}
+
+			req := &ec2.SpotInstanceRequest{
+				SpotInstanceRequestId: aws.String("sir-1"),
+				State:                 aws.String(tt.state),
+				Status:                tt.status,
+				Tags:                  tt.tags,
+			}
+			if got := asg.handleUnfulfillableSpotRequest(req); got != tt.want {
+				t.Errorf("handleUnfulfillableSpotRequest() = %v, want %v",
+					got, tt.want)
+			}
+
+			tagged := false
+			for _, call := range *calls {
+				tagged = tagged || call == "ec2:CreateTags"
+			}
+			if tagged != tt.wantTagged {
+				t.Errorf("handleUnfulfillableSpotRequest() tagged the request: "+
+					"%v, want %v", tagged, tt.wantTagged)
+			}
+		})
+	}
+}
+
+func Test_detachAndTerminateOnDemandInstance(t *testing.T) {
+	actions = actionHistory{}
+	actions.init(Config{HistorySize: 10})
+
+	services, calls := fakeConnections(func(r *request.Request) {
+		if _, ok := r.Params.(*autoscaling.DetachInstancesInput); ok {
+			r.Error = awserr.New("ValidationError", "instance not in service", nil)
+		}
+	})
+
+	a := autoScalingGroup{
+		Group: &autoscaling.Group{AutoScalingGroupName: aws.String("web")},
+		name:  "web",
+		region: &region{
+			name:     "us-east-1",
+			services: services,
+			instances: instances{catalog: map[string]*instance{
+				"i-od": {Instance: &ec2.Instance{InstanceId: aws.String("i-od")}},
+			}},
+		},
+	}
+
+	a.detachAndTerminateOnDemandInstance(aws.String("i-od"))
+
+	for _, call := range *calls {
+		if call == "ec2:TerminateInstances" || call == "ec2:CreateTags" {
+			t.Errorf("retired the on-demand instance after the failed detachment")
+		}
+	}
+	if a.capacityChanges != 0 {
+		t.Errorf("capacityChanges = %d, want 0", a.capacityChanges)
+	}
+}
+
+func Test_preferStickyType(t *testing.T) {
+	prices := map[string]float64{
+		"m4.large": 0.030, "m5.large": 0.032, "c5.large": 0.040}
+
+	tests := []struct {
+		name   string
+		chosen string
+		last   string
+		band   float64
+		want   string
+	}{
+		{name: "Disabled",
+			chosen: "m4.large", last: "m5.large", band: 0,
+			want: "m4.large",
+		},
+		{name: "Last type within the band",
+			chosen: "m4.large", last: "m5.large", band: 0.1,
+			want: "m5.large",
+		},
+		{name: "Last type above the band",
+			chosen: "m4.large", last: "c5.large", band: 0.1,
+			want: "m4.large",
+		},
+		{name: "Last type no longer compatible",
+			chosen: "m4.large", last: "r4.large", band: 1,
+			want: "m4.large",
+		},
+		{name: "No spot instances launched yet",
+			chosen: "m4.large", last: "", band: 0.1,
+			want: "m4.large",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := preferStickyType(tt.chosen, tt.last, prices,
+				tt.band); got != tt.want {
+				t.Errorf("preferStickyType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_copyBlockDeviceMappings(t *testing.T) {
+	got := copyBlockDeviceMappings([]*autoscaling.BlockDeviceMapping{
+		{DeviceName: aws.String("/dev/xvda")},
+		{DeviceName: aws.String("/dev/xvdb")},
+	})
+
+	if len(got) != 2 || *got[0].DeviceName != "/dev/xvda" ||
+		*got[1].DeviceName != "/dev/xvdb" {
+		t.Errorf("copyBlockDeviceMappings() = %v", got)
+	}
+}
+
+func Test_autoScalingGroup_originalInstanceTags(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		tagRequest bool
+		want       map[string]string
+	}{
+		{name: "request tagged when bidding",
+			tagRequest: true,
+			want: map[string]string{
+				"original-instance-id":    "i-od",
+				"original-instance-type":  "m5.large",
+				"original-instance-price": "0.096",
+			},
+		},
+		{name: "request without the original instance details",
+			want: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			var requestTags []*ec2.Tag
+			services, _ := fakeConnections(func(r *request.Request) {
+				if in, ok := r.Params.(*ec2.CreateTagsInput); ok {
+					requestTags = in.Tags
+				}
+			})
+
+			a := autoScalingGroup{
+				Group:  &autoscaling.Group{},
+				name:   "web",
+				region: &region{name: "us-east-1", services: services},
+			}
+
+			if tt.tagRequest {
+				a.tagSpotInstanceRequest("sir-1", &instance{
+					Instance: &ec2.Instance{
+						InstanceId:   aws.String("i-od"),
+						InstanceType: aws.String("m5.large"),
+					},
+					price: 0.096,
+				})
+			}
+
+			got := make(map[string]string)
+			for _, tag := range a.costAllocationTags(&ec2.SpotInstanceRequest{
+				Tags: requestTags}) {
+				if strings.HasPrefix(*tag.Key, "original-instance-") {
+					got[*tag.Key] = *tag.Value
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("costAllocationTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_autoScalingGroup_performanceFactor(t *testing.T) {
+
+	tests := []struct {
+		name string
+		tag  *string
+		want float64
+	}{
+		{name: "not set", want: 1},
+		{name: "extra headroom", tag: aws.String("1.2"), want: 1.2},
+		{name: "smaller instances", tag: aws.String("0.5"), want: 0.5},
+		{name: "zero", tag: aws.String("0"), want: 1},
+		{name: "not a number", tag: aws.String("fast"), want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := autoScalingGroup{
+				Group:  &autoscaling.Group{},
+				name:   "web",
+				region: &region{name: "us-east-1"},
+			}
+			if tt.tag != nil {
+				a.Tags = []*autoscaling.TagDescription{{
+					Key: aws.String("performance_factor"), Value: tt.tag}}
+			}
+			if got := a.performanceFactor(); got != tt.want {
+				t.Errorf("performanceFactor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_autoScalingGroup_bidPrice(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		factor    float64
+		onDemand  float64
+		spotPrice float64
+		want      float64
+	}{
+		{name: "on-demand price by default",
+			onDemand: 0.1, spotPrice: 0.04, want: 0.1},
+		{name: "multiple of the spot price",
+			factor: 1.25, onDemand: 0.1, spotPrice: 0.04, want: 0.05},
+		{name: "capped at the on-demand price",
+			factor: 3, onDemand: 0.1, spotPrice: 0.04, want: 0.1},
+		{name: "unknown spot price",
+			factor: 1.25, onDemand: 0.1, want: 0.1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := autoScalingGroup{
+				Group: &autoscaling.Group{},
+				name:  "web",
+				region: &region{name: "us-east-1",
+					conf: Config{BidSpotPriceFactor: tt.factor}},
+			}
+			if got := a.bidPrice(tt.onDemand, tt.spotPrice); math.Abs(
+				got-tt.want) > 1e-9 {
+				t.Errorf("bidPrice() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package autospotting
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+const (
+	benchmarkCommandTag = "autospotting-benchmark-command"
+	benchmarkScoreTag   = "autospotting-benchmark-score"
+)
+
+// startBenchmark runs the group's benchmark document on the spot instance.
+func (a *autoScalingGroup) startBenchmark(spotInstanceID *string) {
+
+	document := aws.String(a.stringSetting("benchmark_document"))
+	if *document == "" {
+		return
+	}
+
+	input := &ssm.SendCommandInput{
+		DocumentName: document,
+		InstanceIds:  []*string{spotInstanceID},
+		Comment:      aws.String("AutoSpotting benchmark for " + a.name),
+	}
+
+	if a.skipsCall("SendCommand", input) {
+		return
+	}
+
+	resp, err := a.region.services.ssm.SendCommand(input)
+
+	if err != nil {
+		a.log().at(LevelError).Println(a.name, "Failed to start benchmark",
+			*document, "on", *spotInstanceID, err.Error())
+		return
+	}
+
+	a.log().Println(a.name, "Started benchmark", *document, "on",
+		*spotInstanceID)
+
+	a.tagInstance(spotInstanceID, []*ec2.Tag{{
+		Key:   aws.String(benchmarkCommandTag),
+		Value: resp.Command.CommandId,
+	}})
+}
+
+// parseBenchmarkScore returns the score printed on the last line of the
+// benchmark's output.
+func parseBenchmarkScore(output string) (float64, bool) {
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	score, err := strconv.ParseFloat(
+		strings.TrimSpace(lines[len(lines)-1]), 64)
+
+	if err != nil || score <= 0 {
+		return 0, false
+	}
+	return score, true
+}
+
+// collectBenchmarks records the scores of the completed benchmarks of the
+// group's spot instances.
+func (a *autoScalingGroup) collectBenchmarks() {
+
+	for _, inst := range a.instances.catalog {
+
+		commandID := findTagValue(inst.Tags, benchmarkCommandTag)
+		if commandID == nil || findTagValue(inst.Tags, benchmarkScoreTag) != nil {
+			continue
+		}
+
+		resp, err := a.region.services.ssm.GetCommandInvocation(
+			&ssm.GetCommandInvocationInput{
+				CommandId:  commandID,
+				InstanceId: inst.InstanceId,
+			})
+
+		if err != nil {
+			a.log().at(LevelWarn).Println(a.name,
+				"Couldn't get the benchmark results of", *inst.InstanceId,
+				err.Error())
+			continue
+		}
+
+		if resp.Status == nil || *resp.Status == ssm.CommandInvocationStatusPending ||
+			*resp.Status == ssm.CommandInvocationStatusInProgress ||
+			*resp.Status == ssm.CommandInvocationStatusDelayed {
+			continue
+		}
+
+		value := "failed"
+		if *resp.Status == ssm.CommandInvocationStatusSuccess &&
+			resp.StandardOutputContent != nil {
+			if score, ok := parseBenchmarkScore(
+				*resp.StandardOutputContent); ok {
+				value = strconv.FormatFloat(score, 'f', -1, 64)
+			}
+		}
+
+		a.recordAction("benchmarked", *inst.InstanceType, "spot instance",
+			*inst.InstanceId, "scored", value)
+
+		a.tagInstance(inst.InstanceId, []*ec2.Tag{{
+			Key:   aws.String(benchmarkScoreTag),
+			Value: aws.String(value),
+		}})
+	}
+}
+
+// benchmarkScores returns the average benchmark score of each instance type,
+// from the scores of the group's instances.
+func (a *autoScalingGroup) benchmarkScores() map[string]float64 {
+
+	sums := make(map[string]float64)
+	counts := make(map[string]float64)
+
+	for _, inst := range a.instances.catalog {
+		tag := findTagValue(inst.Tags, benchmarkScoreTag)
+		if tag == nil {
+			continue
+		}
+		score, err := strconv.ParseFloat(*tag, 64)
+		if err != nil {
+			continue
+		}
+		sums[*inst.InstanceType] += score
+		counts[*inst.InstanceType]++
+	}
+
+	scores := make(map[string]float64)
+	for t, sum := range sums {
+		scores[t] = sum / counts[t]
+	}
+	return scores
+}
+
+// benchmarkAdjustedPrice scales the price of the instance type by how much
+// worse it scored compared to the best scoring instance type.
+func benchmarkAdjustedPrice(instanceType string, price float64,
+	scores map[string]float64) float64 {
+
+	score, found := scores[instanceType]
+	if !found || score <= 0 {
+		return price
+	}
+
+	best := 0.0
+	for _, s := range scores {
+		if s > best {
+			best = s
+		}
+	}
+	return price * best / score
+}
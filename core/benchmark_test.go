@@ -0,0 +1,58 @@
+package autospotting
+
+import (
+	"testing"
+)
+
+func Test_parseBenchmarkScore(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		output    string
+		want      float64
+		wantFound bool
+	}{
+		{name: "score only", output: "1234.5\n", want: 1234.5, wantFound: true},
+		{
+			name:      "score on the last line",
+			output:    "running benchmark\ndone\n  42 \n",
+			want:      42,
+			wantFound: true,
+		},
+		{name: "no score", output: "benchmark failed\n"},
+		{name: "empty output", output: ""},
+		{name: "negative score", output: "-3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := parseBenchmarkScore(tt.output)
+			if got != tt.want || found != tt.wantFound {
+				t.Errorf("parseBenchmarkScore() = %v, %v, want %v, %v", got,
+					found, tt.want, tt.wantFound)
+			}
+		})
+	}
+}
+
+func Test_benchmarkAdjustedPrice(t *testing.T) {
+
+	scores := map[string]float64{"m5.large": 100, "m5a.large": 80}
+
+	tests := []struct {
+		name         string
+		instanceType string
+		want         float64
+	}{
+		{name: "best scoring", instanceType: "m5.large", want: 0.1},
+		{name: "scored worse", instanceType: "m5a.large", want: 0.125},
+		{name: "not benchmarked", instanceType: "c5.large", want: 0.1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := benchmarkAdjustedPrice(tt.instanceType, 0.1,
+				scores); got != tt.want {
+				t.Errorf("benchmarkAdjustedPrice() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package autospotting
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const bidStrategyPattern = "^(on-demand|(on-demand-percentage|spot-buffer|" +
+	"fixed):[0-9]+(\\.[0-9]+)?)$"
+
+type bidStrategy struct {
+	kind  string
+	value float64
+}
+
+// parseBidStrategy parses a bid strategy, given as its kind optionally
+// followed by a colon and its value.
+func parseBidStrategy(s string) (bidStrategy, error) {
+
+	kind, value := s, ""
+	if i := strings.Index(s, ":"); i >= 0 {
+		kind, value = s[:i], s[i+1:]
+	}
+
+	switch kind {
+	case "on-demand":
+		if value != "" {
+			return bidStrategy{}, fmt.Errorf("unexpected value %q", value)
+		}
+		return bidStrategy{kind: kind}, nil
+	case "on-demand-percentage", "spot-buffer", "fixed":
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil || v < 0 {
+			return bidStrategy{}, fmt.Errorf("invalid value %q of %s", value,
+				kind)
+		}
+		return bidStrategy{kind: kind, value: v}, nil
+	}
+	return bidStrategy{}, fmt.Errorf("unknown bid strategy %q", kind)
+}
+
+// price returns the bid price given the on-demand and current spot prices.
+func (s bidStrategy) price(onDemandPrice, spotPrice float64) float64 {
+
+	bid := onDemandPrice
+
+	switch s.kind {
+	case "on-demand-percentage":
+		bid = onDemandPrice * s.value / 100
+	case "spot-buffer":
+		if spotPrice > 0 {
+			bid = spotPrice * (1 + s.value/100)
+		}
+	case "fixed":
+		bid = s.value
+	}
+
+	return math.Min(bid, onDemandPrice)
+}
@@ -0,0 +1,45 @@
+package autospotting
+
+import "testing"
+
+func Test_bidStrategy_price(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		strategy string
+		spot     float64
+		want     float64
+		wantErr  bool
+	}{
+		{name: "on-demand", strategy: "on-demand", spot: 0.03, want: 0.1},
+		{name: "on-demand percentage", strategy: "on-demand-percentage:80",
+			spot: 0.03, want: 0.08},
+		{name: "spot buffer", strategy: "spot-buffer:50", spot: 0.04,
+			want: 0.06},
+		{name: "spot buffer above on-demand", strategy: "spot-buffer:200",
+			spot: 0.04, want: 0.1},
+		{name: "spot buffer without spot price", strategy: "spot-buffer:20",
+			want: 0.1},
+		{name: "fixed", strategy: "fixed:0.05", spot: 0.03, want: 0.05},
+		{name: "fixed above on-demand", strategy: "fixed:0.5", want: 0.1},
+		{name: "unknown", strategy: "cheapest", wantErr: true},
+		{name: "missing value", strategy: "fixed", wantErr: true},
+		{name: "unexpected value", strategy: "on-demand:80", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseBidStrategy(tt.strategy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseBidStrategy() error = %v, wantErr %v", err,
+					tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := s.price(0.1, tt.spot); got < tt.want-1e-9 ||
+				got > tt.want+1e-9 {
+				t.Errorf("price() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package autospotting
+
+// projectedHourlyCost returns the hourly cost of the group's instances after
+// replacing the given instance with one having the new price.
+func (a *autoScalingGroup) projectedHourlyCost(replacedID string,
+	newPrice float64) float64 {
+
+	total := newPrice
+	for id, inst := range a.instances.catalog {
+		if id != replacedID {
+			total += inst.hourlyCost()
+		}
+	}
+	return total
+}
+
+// exceedsBudget tells if replacing the instance with one having the new price
+// would exceed the group's hourly budget, reporting the skipped replacement.
+func (a *autoScalingGroup) exceedsBudget(replaced *instance,
+	newPrice float64) bool {
+
+	budget := a.numberSetting("max_hourly_budget")
+	if budget <= 0 {
+		return false
+	}
+
+	cost := a.projectedHourlyCost(*replaced.InstanceId, newPrice)
+	if cost <= budget {
+		return false
+	}
+
+	a.recordAction("skipped", "replacing", *replaced.InstanceId,
+		"would exceed the hourly budget of", budget, "with", cost)
+	return true
+}
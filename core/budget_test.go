@@ -0,0 +1,41 @@
+package autospotting
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_autoScalingGroup_projectedHourlyCost(t *testing.T) {
+
+	inst := func(id string, price float64) *instance {
+		return &instance{Instance: &ec2.Instance{InstanceId: aws.String(id)},
+			price: price}
+	}
+
+	a := autoScalingGroup{instances: instances{catalog: map[string]*instance{
+		"i-od":   inst("i-od", 0.2),
+		"i-spot": inst("i-spot", 0.05),
+	}}}
+
+	tests := []struct {
+		name     string
+		replaced string
+		price    float64
+		want     float64
+	}{
+		{name: "replacing the on-demand instance", replaced: "i-od",
+			price: 0.06, want: 0.11},
+		{name: "replacing the spot instance", replaced: "i-spot",
+			price: 0.1, want: 0.3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := a.projectedHourlyCost(tt.replaced, tt.price)
+			if diff := got - tt.want; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("projectedHourlyCost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
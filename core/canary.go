@@ -0,0 +1,133 @@
+package autospotting
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+const (
+	canaryTag    = "autospotting-canary"
+	canaryPassed = "passed"
+	pausedTag    = "autospotting-paused"
+)
+
+type canaryVerdict int
+
+const (
+	canaryObserving canaryVerdict = iota
+	canarySucceeded
+	canaryFailed
+)
+
+func formatCanary(spotInstanceID string, attached time.Time) string {
+	return spotInstanceID + " " + strconv.FormatInt(attached.Unix(), 10)
+}
+
+func parseCanary(value string) (string, time.Time, error) {
+
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return "", time.Time{}, fmt.Errorf("invalid canary %q", value)
+	}
+
+	epoch, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return fields[0], time.Unix(epoch, 0), nil
+}
+
+// judgeCanary decides the outcome of the canary observation, given the group
+// member of the canary spot instance, or nil if it left the group.
+func judgeCanary(member *autoscaling.Instance, attached, now time.Time,
+	window time.Duration) canaryVerdict {
+
+	if member == nil ||
+		(member.HealthStatus != nil && *member.HealthStatus != "Healthy") ||
+		(member.LifecycleState != nil &&
+			(strings.HasPrefix(*member.LifecycleState, "Terminating") ||
+				strings.HasPrefix(*member.LifecycleState, "Detaching"))) {
+		return canaryFailed
+	}
+
+	if now.Sub(attached) < window {
+		return canaryObserving
+	}
+
+	if member.LifecycleState != nil &&
+		*member.LifecycleState == autoscaling.LifecycleStateInService {
+		return canarySucceeded
+	}
+	return canaryFailed
+}
+
+// startCanary starts observing the attached spot instance if it's the first
+// one of a group which had no spot instances yet.
+func (a *autoScalingGroup) startCanary(spotInstanceID *string) {
+
+	if a.region.conf.CanaryWindow <= 0 || a.getTagValue(canaryTag) != nil {
+		return
+	}
+
+	for _, inst := range a.instances.catalog {
+		if inst.isSpot() {
+			return
+		}
+	}
+
+	a.log().Println(a.name, "Observing the first spot instance",
+		*spotInstanceID,
+		"for", a.region.conf.CanaryWindow, "before any further replacements")
+
+	a.setGroupTag(canaryTag, formatCanary(*spotInstanceID, time.Now()))
+}
+
+// isPaused tells if the group was paused after a failed canary replacement.
+func (a *autoScalingGroup) isPaused() bool {
+	return a.getTagValue(pausedTag) != nil
+}
+
+// observingCanary checks the ongoing canary observation, and tells if the
+// group's replacements should wait for it.
+func (a *autoScalingGroup) observingCanary() bool {
+
+	value := a.getTagValue(canaryTag)
+	if value == nil || *value == canaryPassed {
+		return false
+	}
+
+	spotInstanceID, attached, err := parseCanary(*value)
+	if err != nil {
+		a.log().at(LevelWarn).Println(a.name, "Ignoring the", canaryTag, "tag",
+			err.Error())
+		return false
+	}
+
+	switch judgeCanary(a.findGroupInstance(spotInstanceID), attached,
+		time.Now(), a.region.conf.CanaryWindow) {
+
+	case canaryObserving:
+		a.recordAction("deferred", "observing the canary spot instance",
+			spotInstanceID, "attached at", attached)
+		return true
+
+	case canarySucceeded:
+		a.log().Println(a.name, "The canary spot instance", spotInstanceID,
+			"stayed healthy, resuming the replacements")
+		a.setGroupTag(canaryTag, canaryPassed)
+		return false
+
+	default:
+		reason := fmt.Sprint("the canary spot instance ", spotInstanceID,
+			" didn't stay healthy")
+		a.alert("canary-failed", reason, "pausing the group until the",
+			pausedTag, "tag is removed")
+		a.setGroupTag(pausedTag, reason)
+		a.deleteGroupTag(canaryTag)
+		return true
+	}
+}
@@ -0,0 +1,91 @@
+package autospotting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+func Test_parseCanary(t *testing.T) {
+
+	attached := time.Unix(1500000000, 0)
+
+	id, got, err := parseCanary(formatCanary("i-spot", attached))
+	if err != nil || id != "i-spot" || !got.Equal(attached) {
+		t.Errorf("parseCanary() = %v, %v, %v, want i-spot, %v", id, got, err,
+			attached)
+	}
+
+	for _, value := range []string{"", "i-spot", "i-spot yesterday"} {
+		if _, _, err := parseCanary(value); err == nil {
+			t.Errorf("parseCanary(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func Test_judgeCanary(t *testing.T) {
+
+	attached := time.Unix(1500000000, 0)
+	window := time.Hour
+
+	member := func(state, health string) *autoscaling.Instance {
+		return &autoscaling.Instance{
+			InstanceId:     aws.String("i-spot"),
+			LifecycleState: aws.String(state),
+			HealthStatus:   aws.String(health),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		member *autoscaling.Instance
+		now    time.Time
+		want   canaryVerdict
+	}{
+		{
+			name:   "healthy within the window",
+			member: member("InService", "Healthy"),
+			now:    attached.Add(10 * time.Minute),
+			want:   canaryObserving,
+		},
+		{
+			name:   "healthy for the whole window",
+			member: member("InService", "Healthy"),
+			now:    attached.Add(2 * time.Hour),
+			want:   canarySucceeded,
+		},
+		{
+			name:   "unhealthy within the window",
+			member: member("InService", "Unhealthy"),
+			now:    attached.Add(10 * time.Minute),
+			want:   canaryFailed,
+		},
+		{
+			name:   "being terminated",
+			member: member("Terminating", "Healthy"),
+			now:    attached.Add(10 * time.Minute),
+			want:   canaryFailed,
+		},
+		{
+			name: "no longer in the group",
+			now:  attached.Add(10 * time.Minute),
+			want: canaryFailed,
+		},
+		{
+			name:   "still not in service after the window",
+			member: member("Pending", "Healthy"),
+			now:    attached.Add(2 * time.Hour),
+			want:   canaryFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := judgeCanary(tt.member, attached, tt.now,
+				window); got != tt.want {
+				t.Errorf("judgeCanary() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package autospotting
+
+import (
+	"bytes"
+	"encoding/csv"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+var candidateExports candidateCollector
+
+// candidateRow is an instance type evaluated for replacing an instance.
+type candidateRow struct {
+	AvailabilityZone string  `json:"availability_zone"`
+	ReplacedInstance string  `json:"replaced_instance"`
+	InstanceType     string  `json:"instance_type"`
+	VCPU             int     `json:"vcpu"`
+	Memory           float32 `json:"memory"`
+	GPU              int     `json:"gpu"`
+	SpotPrice        float64 `json:"spot_price"`
+	Score            float64 `json:"score,omitempty"`
+	Chosen           bool    `json:"chosen"`
+	RejectionReason  string  `json:"rejection_reason,omitempty"`
+}
+
+// candidateTable collects the candidates evaluated for a replacement, nil when
+// the export is disabled.
+type candidateTable struct {
+	region, group string
+	az, replaced  string
+	rows          []candidateRow
+}
+
+func (a *autoScalingGroup) newCandidateTable(refInstance *instance,
+	az string) *candidateTable {
+
+	if a.region.conf.CandidateExport == "" {
+		return nil
+	}
+
+	t := &candidateTable{
+		region:   a.region.name,
+		group:    a.name,
+		az:       az,
+		replaced: *refInstance.InstanceId,
+	}
+	a.candidates = t
+	return t
+}
+
+func (t *candidateTable) add(info instanceTypeInformation, price float64,
+	reason string) {
+
+	if t == nil {
+		return
+	}
+
+	t.rows = append(t.rows, candidateRow{
+		AvailabilityZone: t.az,
+		ReplacedInstance: t.replaced,
+		InstanceType:     info.instanceType,
+		VCPU:             info.vCPU,
+		Memory:           info.memory,
+		GPU:              info.gpu,
+		SpotPrice:        price,
+		RejectionReason:  reason,
+	})
+}
+
+func (t *candidateTable) accept(info instanceTypeInformation, price float64) {
+	t.add(info, price, "")
+}
+
+func (t *candidateTable) reject(info instanceTypeInformation, price float64,
+	reason string) {
+	t.add(info, price, reason)
+}
+
+// score records the adjusted prices used for ranking the compatible instance
+// types, and the chosen one.
+func (t *candidateTable) score(scores map[string]float64, chosen string) {
+
+	if t == nil {
+		return
+	}
+
+	for i := range t.rows {
+		row := &t.rows[i]
+		if row.RejectionReason != "" {
+			continue
+		}
+		row.Score = scores[row.InstanceType]
+		row.Chosen = row.InstanceType == chosen
+	}
+}
+
+type candidateCollector struct {
+	sync.Mutex
+
+	format string
+
+	// keyed by region/group
+	tables map[string][]*candidateTable
+}
+
+func (c *candidateCollector) init(cfg Config) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.format = cfg.CandidateExport
+	c.tables = make(map[string][]*candidateTable)
+}
+
+func (c *candidateCollector) add(t *candidateTable) {
+
+	if t == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	key := t.region + "/" + t.group
+	c.tables[key] = append(c.tables[key], t)
+}
+
+// rows returns all the candidates evaluated for the group.
+func (c *candidateCollector) rows(key string) []candidateRow {
+	rows := []candidateRow{}
+	for _, t := range c.tables[key] {
+		rows = append(rows, t.rows...)
+	}
+	return rows
+}
+
+// export writes the candidates of each group in the configured format.
+func (c *candidateCollector) export() {
+	c.Lock()
+	defer c.Unlock()
+
+	var keys []string
+	for key := range c.tables {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		name := "candidates/" + key
+
+		if c.format != "csv" {
+			writeReport(name, c.rows(key))
+			continue
+		}
+
+		content, err := candidatesCSV(c.rows(key))
+		if err != nil {
+			logger.at(LevelError).Println("Couldn't encode the", name, "report",
+				err.Error())
+			continue
+		}
+		writeReportFile(name, "csv", content)
+	}
+}
+
+// candidatesCSV encodes the candidates as CSV, with a header line.
+func candidatesCSV(rows []candidateRow) ([]byte, error) {
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	w.Write([]string{"availability_zone", "replaced_instance",
+		"instance_type", "vcpu", "memory", "gpu", "spot_price", "score",
+		"chosen", "rejection_reason"})
+
+	for _, r := range rows {
+		w.Write([]string{
+			r.AvailabilityZone,
+			r.ReplacedInstance,
+			r.InstanceType,
+			strconv.Itoa(r.VCPU),
+			strconv.FormatFloat(float64(r.Memory), 'f', -1, 32),
+			strconv.Itoa(r.GPU),
+			formatNumber(r.SpotPrice),
+			formatNumber(r.Score),
+			strconv.FormatBool(r.Chosen),
+			r.RejectionReason,
+		})
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
@@ -0,0 +1,41 @@
+package autospotting
+
+import (
+	"testing"
+)
+
+func Test_candidateTable(t *testing.T) {
+
+	table := &candidateTable{az: "us-east-1a", replaced: "i-od"}
+
+	table.accept(instanceTypeInformation{instanceType: "m5.large", vCPU: 2,
+		memory: 8}, 0.04)
+	table.accept(instanceTypeInformation{instanceType: "c5.large", vCPU: 2,
+		memory: 4}, 0.03)
+	table.reject(instanceTypeInformation{instanceType: "t3.micro", vCPU: 2,
+		memory: 1}, 0.01, "not enough CPU, memory or GPUs")
+
+	table.score(map[string]float64{"m5.large": 0.04, "c5.large": 0.035},
+		"c5.large")
+
+	content, err := candidatesCSV(table.rows)
+	if err != nil {
+		t.Fatalf("candidatesCSV() error = %v", err)
+	}
+
+	want := "availability_zone,replaced_instance,instance_type,vcpu,memory," +
+		"gpu,spot_price,score,chosen,rejection_reason\n" +
+		"us-east-1a,i-od,m5.large,2,8,0,0.04,0.04,false,\n" +
+		"us-east-1a,i-od,c5.large,2,4,0,0.03,0.035,true,\n" +
+		"us-east-1a,i-od,t3.micro,2,1,0,0.01,0,false," +
+		"\"not enough CPU, memory or GPUs\"\n"
+
+	if string(content) != want {
+		t.Errorf("candidatesCSV() = %q, want %q", content, want)
+	}
+
+	// disabled export
+	var disabled *candidateTable
+	disabled.accept(instanceTypeInformation{instanceType: "m5.large"}, 0.04)
+	disabled.score(nil, "m5.large")
+}
@@ -0,0 +1,128 @@
+package autospotting
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// spot request status codes showing the lack of spot capacity
+var capacityFailureCodes = map[string]bool{
+	"capacity-not-available":  true,
+	"capacity-oversubscribed": true,
+}
+
+type capacityBreaker struct {
+	sync.Mutex
+
+	// failures in the current time window, keyed by instance type and
+	// availability zone
+	pools map[string]int
+
+	// failures in the current time window, keyed by availability zone
+	zones map[string]int
+
+	// spot requests already counted, keyed by request ID
+	seen map[string]bool
+}
+
+func poolKey(instanceType, az string) string {
+	return instanceType + "/" + az
+}
+
+// record counts a capacity failure for a spot request, the same request is
+// only counted once even if it's seen by multiple groups.
+func (c *capacityBreaker) record(requestID, instanceType, az string) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.pools == nil {
+		c.pools = make(map[string]int)
+		c.zones = make(map[string]int)
+		c.seen = make(map[string]bool)
+	}
+
+	if c.seen[requestID] {
+		return
+	}
+	c.seen[requestID] = true
+
+	c.pools[poolKey(instanceType, az)]++
+	c.zones[az]++
+}
+
+// tripped tells if bidding is currently suspended in a spot pool, either
+// because of the pool's own failures or those of its availability zone. A
+// threshold of 0 disables the corresponding breaker.
+func (c *capacityBreaker) tripped(instanceType, az string,
+	poolThreshold, zoneThreshold int) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	if poolThreshold > 0 && c.pools[poolKey(instanceType, az)] >= poolThreshold {
+		return true
+	}
+	return zoneThreshold > 0 && c.zones[az] >= zoneThreshold
+}
+
+// failedSpotRequestPool returns the instance type and availability zone of a
+// spot request which failed for lack of capacity, and false for any other
+// request.
+func failedSpotRequestPool(req *ec2.SpotInstanceRequest) (string, string, bool) {
+
+	reason := spotRequestFailure(req)
+	if reason == nil || !capacityFailureCodes[*reason] {
+		return "", "", false
+	}
+
+	ls := req.LaunchSpecification
+	if ls == nil || ls.InstanceType == nil ||
+		ls.Placement == nil || ls.Placement.AvailabilityZone == nil {
+		return "", "", false
+	}
+
+	return *ls.InstanceType, *ls.Placement.AvailabilityZone, true
+}
+
+// scanCapacityFailures loads the capacity failures of the spot requests placed
+// by AutoSpotting within the configured time window.
+func (r *region) scanCapacityFailures() {
+
+	if r.conf.CapacityFailureThreshold <= 0 &&
+		r.conf.CapacityZoneFailureThreshold <= 0 {
+		return
+	}
+
+	since := time.Now().Add(-r.conf.CapacityFailureWindow)
+
+	for _, req := range r.spotRequests {
+		if req.Status == nil || req.Status.UpdateTime == nil ||
+			req.Status.UpdateTime.Before(since) {
+			continue
+		}
+		r.recordCapacityFailure(req)
+	}
+}
+
+// recordCapacityFailure counts the spot request against its pool if it failed
+// for lack of capacity.
+func (r *region) recordCapacityFailure(req *ec2.SpotInstanceRequest) {
+
+	instanceType, az, failed := failedSpotRequestPool(req)
+	if !failed {
+		return
+	}
+
+	r.log().Println(r.name, "Spot request", *req.SpotInstanceRequestId,
+		"failed with", *spotRequestFailure(req), "for", instanceType, "in", az)
+
+	r.capacity.record(*req.SpotInstanceRequestId, instanceType, az)
+}
+
+// capacityExhausted tells if no new spot bids should be placed for the given
+// instance type and availability zone.
+func (r *region) capacityExhausted(instanceType, az string) bool {
+	return r.capacity.tripped(instanceType, az,
+		r.conf.CapacityFailureThreshold, r.conf.CapacityZoneFailureThreshold)
+}
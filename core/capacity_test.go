@@ -0,0 +1,77 @@
+package autospotting
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_capacityBreaker(t *testing.T) {
+	var c capacityBreaker
+
+	c.record("sir-1", "m4.large", "us-east-1a")
+	c.record("sir-1", "m4.large", "us-east-1a")
+	c.record("sir-2", "c4.large", "us-east-1a")
+
+	tests := []struct {
+		name          string
+		instanceType  string
+		az            string
+		poolThreshold int
+		zoneThreshold int
+		want          bool
+	}{
+		{name: "Duplicate requests are counted once",
+			instanceType: "m4.large", az: "us-east-1a",
+			poolThreshold: 2, zoneThreshold: 0,
+			want: false,
+		},
+		{name: "Pool threshold reached",
+			instanceType: "m4.large", az: "us-east-1a",
+			poolThreshold: 1, zoneThreshold: 0,
+			want: true,
+		},
+		{name: "Zone threshold reached by other pools",
+			instanceType: "r4.large", az: "us-east-1a",
+			poolThreshold: 1, zoneThreshold: 2,
+			want: true,
+		},
+		{name: "Other availability zone unaffected",
+			instanceType: "m4.large", az: "us-east-1b",
+			poolThreshold: 1, zoneThreshold: 1,
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.tripped(tt.instanceType, tt.az,
+				tt.poolThreshold, tt.zoneThreshold); got != tt.want {
+				t.Errorf("tripped() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_failedSpotRequestPool(t *testing.T) {
+	req := &ec2.SpotInstanceRequest{
+		Status: &ec2.SpotInstanceStatus{Code: aws.String("price-too-low")},
+		LaunchSpecification: &ec2.LaunchSpecification{
+			InstanceType: aws.String("m4.large"),
+			Placement: &ec2.SpotPlacement{
+				AvailabilityZone: aws.String("us-east-1a"),
+			},
+		},
+	}
+
+	if _, _, failed := failedSpotRequestPool(req); failed {
+		t.Errorf("failedSpotRequestPool() reported a pricing failure")
+	}
+
+	req.Status.Code = aws.String("capacity-not-available")
+
+	it, az, failed := failedSpotRequestPool(req)
+	if !failed || it != "m4.large" || az != "us-east-1a" {
+		t.Errorf("failedSpotRequestPool() = %v, %v, %v", it, az, failed)
+	}
+}
@@ -0,0 +1,194 @@
+package autospotting
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var groupChanges changeDetector
+
+// groupSnapshot is the fingerprint of a group taken by a run.
+type groupSnapshot struct {
+	fingerprint float64
+	taken       time.Time
+}
+
+type changeDetector struct {
+	sync.Mutex
+
+	table  string
+	maxAge time.Duration
+
+	// keyed by region/group
+	previous map[string]groupSnapshot
+	current  map[string]groupSnapshot
+}
+
+// load reads the snapshots taken by the previous runs from the changes table,
+// whose DynamoDB table needs a "group" string partition key.
+func (c *changeDetector) load(cfg Config) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.table = cfg.ChangesTable
+	c.maxAge = cfg.ChangesMaxAge
+	c.previous = make(map[string]groupSnapshot)
+	c.current = make(map[string]groupSnapshot)
+
+	if c.table == "" {
+		return
+	}
+
+	items, err := state.scan(c.table)
+	for _, item := range items {
+		group, found := item.Keys["group"]
+		if !found {
+			continue
+		}
+		c.previous[group] = groupSnapshot{
+			fingerprint: item.Numbers["fingerprint"],
+			taken:       time.Unix(int64(item.Numbers["taken"]), 0),
+		}
+	}
+
+	if err != nil {
+		logger.at(LevelError).Println("Failed to load the group snapshots from",
+			c.table, err.Error())
+	}
+}
+
+// unchanged tells if the group can be skipped since it runs only spot
+// instances and didn't change since its recent snapshot, otherwise records its
+// current snapshot.
+func (c *changeDetector) unchanged(key string, fingerprint float64,
+	settled bool, now time.Time) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.table == "" {
+		return false
+	}
+
+	previous, found := c.previous[key]
+	if found && settled && previous.fingerprint == fingerprint &&
+		(c.maxAge <= 0 || now.Sub(previous.taken) < c.maxAge) {
+		return true
+	}
+
+	c.current[key] = groupSnapshot{fingerprint: fingerprint, taken: now}
+	return false
+}
+
+// store persists the snapshots of the groups processed by the current run.
+func (c *changeDetector) store() {
+	c.Lock()
+	defer c.Unlock()
+
+	for group, snapshot := range c.current {
+		err := state.put(c.table, stateItem{
+			Keys: map[string]string{"group": group},
+			Numbers: map[string]float64{
+				"fingerprint": snapshot.fingerprint,
+				"taken":       float64(snapshot.taken.Unix()),
+			},
+		})
+		if err != nil {
+			logger.at(LevelError).Println("Failed to store the snapshot of",
+				group, "in", c.table, err.Error())
+		}
+	}
+}
+
+// fingerprint hashes the group's configuration, tags, effective settings and
+// the state of its instances. It's stored as a number, so it's truncated to
+// the 52 bits exactly represented by a float64.
+func (a *autoScalingGroup) fingerprint() float64 {
+
+	var parts []string
+	add := func(values ...interface{}) {
+		var fields []string
+		for _, v := range values {
+			fields = append(fields, fmt.Sprint(derefString(v)))
+		}
+		parts = append(parts, strings.Join(fields, "="))
+	}
+
+	add("capacity", derefInt64(a.MinSize), derefInt64(a.DesiredCapacity),
+		derefInt64(a.MaxSize))
+	add("launch-configuration", a.LaunchConfigurationName)
+	if lt := a.LaunchTemplate; lt != nil {
+		add("launch-template", lt.LaunchTemplateId, lt.LaunchTemplateName,
+			lt.Version)
+	}
+	add("grace-period", derefInt64(a.HealthCheckGracePeriod))
+
+	for _, p := range a.SuspendedProcesses {
+		add("suspended", p.ProcessName)
+	}
+	for _, arn := range a.TargetGroupARNs {
+		add("target-group", arn)
+	}
+	for _, lb := range a.LoadBalancerNames {
+		add("load-balancer", lb)
+	}
+	for _, tag := range a.Tags {
+		add("tag", tag.Key, tag.Value)
+	}
+	for name, s := range a.effectiveSettings() {
+		add("setting", name, s.Value)
+	}
+	for _, inst := range a.Instances {
+		add("instance", inst.InstanceId, inst.LifecycleState, inst.HealthStatus)
+	}
+
+	// the API doesn't guarantee the order of any of these lists
+	sort.Strings(parts)
+
+	h := fnv.New64a()
+	h.Write([]byte(strings.Join(parts, "\n")))
+	return float64(h.Sum64() >> 12)
+}
+
+func derefString(v interface{}) interface{} {
+	if s, ok := v.(*string); ok {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	return v
+}
+
+func derefInt64(i *int64) int64 {
+	if i == nil {
+		return 0
+	}
+	return *i
+}
+
+// runsOnlySpot tells if all the group's instances are known spot instances, so
+// there's nothing left to replace.
+func (a *autoScalingGroup) runsOnlySpot() bool {
+	for _, inst := range a.Instances {
+		i := a.region.instances.get(*inst.InstanceId)
+		if i == nil || !i.isSpot() {
+			return false
+		}
+	}
+	return true
+}
+
+// unchanged tells if the group can be skipped by the current run.
+func (a *autoScalingGroup) unchanged() bool {
+
+	if groupChanges.table == "" {
+		return false
+	}
+
+	return groupChanges.unchanged(a.region.name+"/"+a.name, a.fingerprint(),
+		a.runsOnlySpot(), time.Now())
+}
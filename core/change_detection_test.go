@@ -0,0 +1,139 @@
+package autospotting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+func Test_changeDetector_unchanged(t *testing.T) {
+
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		table       string
+		previous    map[string]groupSnapshot
+		fingerprint float64
+		settled     bool
+		want        bool
+	}{
+		{name: "change detection disabled",
+			previous: map[string]groupSnapshot{
+				"us-east-1/asg": {fingerprint: 1, taken: now},
+			},
+			fingerprint: 1,
+			settled:     true,
+			want:        false,
+		},
+		{name: "first run",
+			table:       "changes",
+			fingerprint: 1,
+			settled:     true,
+			want:        false,
+		},
+		{name: "unchanged group running only spot instances",
+			table: "changes",
+			previous: map[string]groupSnapshot{
+				"us-east-1/asg": {fingerprint: 1, taken: now.Add(-time.Minute)},
+			},
+			fingerprint: 1,
+			settled:     true,
+			want:        true,
+		},
+		{name: "unchanged group running on-demand instances",
+			table: "changes",
+			previous: map[string]groupSnapshot{
+				"us-east-1/asg": {fingerprint: 1, taken: now.Add(-time.Minute)},
+			},
+			fingerprint: 1,
+			want:        false,
+		},
+		{name: "changed group",
+			table: "changes",
+			previous: map[string]groupSnapshot{
+				"us-east-1/asg": {fingerprint: 1, taken: now.Add(-time.Minute)},
+			},
+			fingerprint: 2,
+			settled:     true,
+			want:        false,
+		},
+		{name: "snapshot too old",
+			table: "changes",
+			previous: map[string]groupSnapshot{
+				"us-east-1/asg": {fingerprint: 1, taken: now.Add(-2 * time.Hour)},
+			},
+			fingerprint: 1,
+			settled:     true,
+			want:        false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := changeDetector{
+				table:    tt.table,
+				maxAge:   time.Hour,
+				previous: tt.previous,
+				current:  make(map[string]groupSnapshot),
+			}
+			got := c.unchanged("us-east-1/asg", tt.fingerprint, tt.settled, now)
+			if got != tt.want {
+				t.Errorf("unchanged() = %v, want %v", got, tt.want)
+			}
+			if _, recorded := c.current["us-east-1/asg"]; recorded == got &&
+				tt.table != "" {
+				t.Errorf("snapshot recorded = %v, want %v", recorded, !got)
+			}
+		})
+	}
+}
+
+func Test_autoScalingGroup_fingerprint(t *testing.T) {
+
+	group := func(desired int64, instances ...string) autoScalingGroup {
+		g := &autoscaling.Group{DesiredCapacity: aws.Int64(desired)}
+		for _, id := range instances {
+			g.Instances = append(g.Instances, &autoscaling.Instance{
+				InstanceId:     aws.String(id),
+				LifecycleState: aws.String("InService"),
+			})
+		}
+		return autoScalingGroup{Group: g}
+	}
+
+	tests := []struct {
+		name string
+		a, b autoScalingGroup
+		want bool
+	}{
+		{name: "same group",
+			a:    group(2, "i-1", "i-2"),
+			b:    group(2, "i-1", "i-2"),
+			want: true,
+		},
+		{name: "instances in a different order",
+			a:    group(2, "i-1", "i-2"),
+			b:    group(2, "i-2", "i-1"),
+			want: true,
+		},
+		{name: "replaced instance",
+			a:    group(2, "i-1", "i-2"),
+			b:    group(2, "i-1", "i-3"),
+			want: false,
+		},
+		{name: "different capacity",
+			a:    group(2, "i-1", "i-2"),
+			b:    group(3, "i-1", "i-2"),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.fingerprint() == tt.b.fingerprint(); got != tt.want {
+				t.Errorf("same fingerprint = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
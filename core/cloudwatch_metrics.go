@@ -0,0 +1,175 @@
+package autospotting
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// maximum number of metric values sent by each PutMetricData call
+const metricDataBatch = 500
+
+var cloudWatch cloudWatchMetrics
+
+type cloudWatchMetrics struct {
+	sync.Mutex
+
+	// metrics are published only if set
+	namespace string
+
+	// keyed by region and group
+	groups map[string]map[string]*groupMetrics
+}
+
+type groupMetrics struct {
+	Replacements      float64
+	FailedBids        float64
+	SpotInstances     float64
+	OnDemandInstances float64
+	HourlySavings     float64
+}
+
+func (m *cloudWatchMetrics) init(cfg Config) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.namespace = cfg.CloudWatchNamespace
+	m.groups = make(map[string]map[string]*groupMetrics)
+}
+
+// get returns the metrics of the group, creating them if needed, and must be
+// called with the lock held.
+func (m *cloudWatchMetrics) get(region, group string) *groupMetrics {
+	if m.groups[region] == nil {
+		m.groups[region] = make(map[string]*groupMetrics)
+	}
+	g := m.groups[region][group]
+	if g == nil {
+		g = &groupMetrics{}
+		m.groups[region][group] = g
+	}
+	return g
+}
+
+// recordMetrics samples the group's instances and savings.
+func (a *autoScalingGroup) recordMetrics() {
+
+	cloudWatch.Lock()
+	defer cloudWatch.Unlock()
+
+	if cloudWatch.namespace == "" {
+		return
+	}
+
+	g := cloudWatch.get(a.region.name, a.name)
+	g.SpotInstances, g.OnDemandInstances, g.HourlySavings = 0, 0, 0
+
+	for _, inst := range a.instances.catalog {
+		if !inst.isSpot() {
+			g.OnDemandInstances++
+			continue
+		}
+		g.SpotInstances++
+		g.HourlySavings += inst.hourlySavings()
+	}
+}
+
+// recordMetricAction counts the replacements and failed bids of the group.
+func (a *autoScalingGroup) recordMetricAction(action string) {
+
+	cloudWatch.Lock()
+	defer cloudWatch.Unlock()
+
+	if cloudWatch.namespace == "" {
+		return
+	}
+
+	switch action {
+	case "attached":
+		cloudWatch.get(a.region.name, a.name).Replacements++
+	case "bid-failed":
+		cloudWatch.get(a.region.name, a.name).FailedBids++
+	}
+}
+
+// metricData returns the metric values of the groups of a region, ordered by
+// group name.
+func metricData(groups map[string]*groupMetrics,
+	now time.Time) []*cloudwatch.MetricDatum {
+
+	var names []string
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var data []*cloudwatch.MetricDatum
+	for _, name := range names {
+		g := groups[name]
+
+		for _, m := range []struct {
+			name  string
+			value float64
+			unit  string
+		}{
+			{"Replacements", g.Replacements, cloudwatch.StandardUnitCount},
+			{"FailedBids", g.FailedBids, cloudwatch.StandardUnitCount},
+			{"SpotInstances", g.SpotInstances, cloudwatch.StandardUnitCount},
+			{"OnDemandInstances", g.OnDemandInstances,
+				cloudwatch.StandardUnitCount},
+			{"HourlySavings", g.HourlySavings, cloudwatch.StandardUnitNone},
+		} {
+			data = append(data, &cloudwatch.MetricDatum{
+				MetricName: aws.String(m.name),
+				Dimensions: []*cloudwatch.Dimension{{
+					Name:  aws.String("AutoScalingGroup"),
+					Value: aws.String(name),
+				}},
+				Timestamp: aws.Time(now),
+				Unit:      aws.String(m.unit),
+				Value:     aws.Float64(m.value),
+			})
+		}
+	}
+	return data
+}
+
+// publish sends the metrics of each region to CloudWatch in that region.
+func (m *cloudWatchMetrics) publish() {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.namespace == "" {
+		return
+	}
+
+	now := time.Now()
+
+	for region, groups := range m.groups {
+
+		svc := cloudwatch.New(instrumentSession(
+			newSession(&aws.Config{Region: aws.String(region)})))
+
+		data := metricData(groups, now)
+		for len(data) > 0 {
+			batch := data
+			if len(batch) > metricDataBatch {
+				batch = batch[:metricDataBatch]
+			}
+			data = data[len(batch):]
+
+			_, err := svc.PutMetricData(&cloudwatch.PutMetricDataInput{
+				Namespace:  aws.String(m.namespace),
+				MetricData: batch,
+			})
+			if err != nil {
+				logger.at(LevelError).Println(region,
+					"Failed to publish the CloudWatch metrics", err.Error())
+				break
+			}
+		}
+	}
+}
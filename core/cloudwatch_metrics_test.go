@@ -0,0 +1,53 @@
+package autospotting
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_metricData(t *testing.T) {
+
+	groups := map[string]*groupMetrics{
+		"web": {Replacements: 2, SpotInstances: 3, HourlySavings: 0.5},
+		"api": {FailedBids: 1, OnDemandInstances: 4},
+	}
+
+	data := metricData(groups, time.Now())
+
+	if len(data) != 10 {
+		t.Fatalf("metricData() returned %d values, want 10", len(data))
+	}
+
+	want := []struct {
+		group, name string
+		value       float64
+	}{
+		{"api", "Replacements", 0},
+		{"api", "FailedBids", 1},
+		{"api", "OnDemandInstances", 4},
+		{"web", "Replacements", 2},
+		{"web", "SpotInstances", 3},
+		{"web", "HourlySavings", 0.5},
+	}
+
+	for _, w := range want {
+		found := false
+		for i, d := range data {
+			group := *d.Dimensions[0].Value
+			if group != w.group || *d.MetricName != w.name {
+				continue
+			}
+			found = true
+			if *d.Value != w.value {
+				t.Errorf("%s %s = %v, want %v", w.group, w.name, *d.Value,
+					w.value)
+			}
+			if (group == "api") != (i < 5) {
+				t.Errorf("%s %s isn't ordered by group", w.group, w.name)
+			}
+		}
+		if !found {
+			t.Errorf("missing %s %s", w.group, w.name)
+		}
+	}
+}
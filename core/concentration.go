@@ -0,0 +1,61 @@
+package autospotting
+
+// maxPoolConcentration returns the group's max_pool_concentration setting,
+// defaulting to the global setting.
+func (a *autoScalingGroup) maxPoolConcentration() float64 {
+
+	return a.numberSetting("max_pool_concentration")
+}
+
+// concentrationAllowed tells if one more spot instance can be launched in a
+// pool already having the given number of the group's spot instances, a
+// maximum percentage of 0 disabling the limit.
+func concentrationAllowed(count, desiredCapacity int64, max float64) bool {
+	return count == 0 || max <= 0 ||
+		float64(count)*100 <= max*float64(desiredCapacity)
+}
+
+// pendingPoolLaunches counts the spot instances requested for the group in the
+// pool which aren't yet part of the group.
+func (a *autoScalingGroup) pendingPoolLaunches(instanceType,
+	availabilityZone string) int64 {
+
+	var count int64
+
+	for _, req := range a.spotInstanceRequests {
+
+		ls := req.LaunchSpecification
+		if req.State == nil || ls == nil || ls.InstanceType == nil ||
+			ls.Placement == nil || ls.Placement.AvailabilityZone == nil ||
+			*ls.InstanceType != instanceType ||
+			*ls.Placement.AvailabilityZone != availabilityZone {
+			continue
+		}
+
+		switch *req.State {
+		case "open":
+			count++
+		case "active":
+			if req.InstanceId != nil &&
+				a.instances.get(*req.InstanceId) == nil {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// poolConcentrationAllowed tells if launching one more spot instance in the
+// pool keeps the group within its concentration limit.
+func (a *autoScalingGroup) poolConcentrationAllowed(instanceType,
+	availabilityZone string) bool {
+
+	count := a.alreadyRunningSpotInstanceCount(instanceType, availabilityZone) +
+		a.pendingPoolLaunches(instanceType, availabilityZone)
+
+	a.log().Println(a.name, "Found", count, "running or pending spot instances",
+		"of type", instanceType, "in", availabilityZone)
+
+	return concentrationAllowed(count, *a.DesiredCapacity,
+		a.prewarmPoolConcentration(a.maxPoolConcentration()))
+}
@@ -0,0 +1,66 @@
+package autospotting
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_concentrationAllowed(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		count, desired int64
+		max            float64
+		want           bool
+	}{
+		{name: "first instance of the pool", count: 0, desired: 2, max: 20, want: true},
+		{name: "within the limit", count: 2, desired: 10, max: 20, want: true},
+		{name: "above the limit", count: 3, desired: 10, max: 20, want: false},
+		{name: "higher limit", count: 3, desired: 10, max: 50, want: true},
+		{name: "disabled limit", count: 9, desired: 10, max: 0, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := concentrationAllowed(tt.count, tt.desired, tt.max); got != tt.want {
+				t.Errorf("concentrationAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_pendingPoolLaunches(t *testing.T) {
+
+	request := func(state, instanceType, az, instanceID string) *ec2.SpotInstanceRequest {
+		req := &ec2.SpotInstanceRequest{
+			State: aws.String(state),
+			LaunchSpecification: &ec2.LaunchSpecification{
+				InstanceType: aws.String(instanceType),
+				Placement:    &ec2.SpotPlacement{AvailabilityZone: aws.String(az)},
+			},
+		}
+		if instanceID != "" {
+			req.InstanceId = aws.String(instanceID)
+		}
+		return req
+	}
+
+	a := &autoScalingGroup{
+		instances: instances{catalog: map[string]*instance{
+			"i-attached": {Instance: &ec2.Instance{InstanceId: aws.String("i-attached")}},
+		}},
+		spotInstanceRequests: []*ec2.SpotInstanceRequest{
+			request("open", "m5.large", "us-east-1a", ""),
+			request("active", "m5.large", "us-east-1a", "i-launched"),
+			request("active", "m5.large", "us-east-1a", "i-attached"),
+			request("closed", "m5.large", "us-east-1a", "i-gone"),
+			request("open", "m5.large", "us-east-1b", ""),
+			request("open", "c5.large", "us-east-1a", ""),
+		},
+	}
+
+	if got := a.pendingPoolLaunches("m5.large", "us-east-1a"); got != 2 {
+		t.Errorf("pendingPoolLaunches() = %v, want 2", got)
+	}
+}
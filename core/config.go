@@ -2,6 +2,7 @@ package autospotting
 
 import (
 	"io"
+	"time"
 )
 
 // Config contains a number of feature flags and static data storing the EC2
@@ -20,11 +21,344 @@ type Config struct {
 	// Static data fetched from ec2instances.info
 	RawInstanceData RawInstanceData
 
+	// Victim selectors implemented outside AutoSpotting when used as a
+	// library, selected by name per group like the built-in ones.
+	VictimSelectors map[string]VictimSelector
+
 	// Logging
 	LogFile io.Writer
 	LogFlag int
 
+	// Structured logging: minimum level of the logged entries, among debug,
+	// info, warn and error, and format of the built-in handler, text or json.
+	// A custom LogHandler replaces the built-in ones, such as when used as a
+	// library.
+	LogLevel   string
+	LogFormat  string
+	LogHandler LogHandler
+
+	// Only log a summary line per region for the runs which changed nothing.
+	QuietWhenIdle bool
+
+	// Debug output controls: size cap in bytes for each dump of the large
+	// data structures, sampling rate of such dumps, and an optional S3 bucket
+	// where the full dumps are uploaded instead of being logged.
+	DebugMaxBytes   int
+	DebugSampleRate int
+	DebugDumpBucket string
+
+	// Comma separated AutoScaling groups and regions whose debug output is
+	// emitted even when debugging isn't enabled for everything.
+	DebugGroups  string
+	DebugRegions string
+
 	BuildNumber string
 
+	// Fan out the processing by asynchronously invoking the WorkerFunction for
+	// each enabled region, or for batches of DispatchBatchSize enabled groups.
+	Dispatch          bool
+	DispatchBatchSize int
+	WorkerFunction    string
+
+	// No new regions or groups are processed after the Deadline, set from the
+	// remaining time of the Lambda invocation minus the DeadlineMargin, or
+	// after the TimeBudget. The groups of each region are processed in name
+	// order from the cursor kept in the ContinuationTable, at most
+	// MaxConcurrentGroups at once if set.
+	Deadline            time.Time
+	DeadlineMargin      time.Duration
+	TimeBudget          time.Duration
+	ContinuationTable   string
+	MaxConcurrentGroups int
+
+	// Run as a long-running process instead of a Lambda function, processing
+	// all regions every DaemonInterval and serving runtime statistics and
+	// pprof profiles over HTTP on ListenAddress.
+	Daemon         bool
+	DaemonInterval time.Duration
+	ListenAddress  string
+
+	// Named profile of the shared AWS configuration files used outside
+	// Lambda, which may use AWS SSO, web identity tokens or roles requiring
+	// MFA. The default profile, or AWS_PROFILE, is used if empty.
+	Profile string
+
+	// Region hosting the state tables and Lambda workers, which the other
+	// regions are listed from. AWS_REGION, AWS_DEFAULT_REGION or us-east-1 is
+	// used if empty.
+	HomeRegion string
+
+	// Print the JSON schema of the global settings and group tags, then exit.
+	PrintConfigSchema bool
+
+	// Number of recent actions kept in memory for each group, served over HTTP
+	// in daemon mode.
+	HistorySize int
+
 	Regions string
+
+	// Comma separated list of AutoScaling group names, when set only these
+	// groups are processed among the enabled ones.
+	AutoScalingGroups string
+
+	// Instead of launching new spot instances, adopt the running spot
+	// instances launched outside AutoScaling that match the launch
+	// configuration of an enabled group, replacing its on-demand instances.
+	Adopt bool
+
+	// Only log the API calls replacing the instances, instead of making them.
+	DryRun bool
+
+	// The spot instances of the groups using readiness probes are terminated
+	// if they don't report their readiness within this long after launch.
+	ProbeTimeout time.Duration
+
+	// Additional tags set on the launched spot instances and their volumes,
+	// given as comma separated key=value pairs, such as cost center or team.
+	SpotTags string
+
+	// How the on-demand instances are taken out of the group when replaced:
+	// "detach" detaches and terminates them right away, while "standby" moves
+	// them to Standby and only terminates them once the spot instance that
+	// replaced them is healthy.
+	ReplacementMethod string
+
+	// Defer the replacement of instances from families covered by active or
+	// recommended Savings Plans, reporting the hypothetical spot savings.
+	SavingsPlansAware bool
+
+	// Spot prices differing by at most this amount are considered equal, in
+	// which case the newest generation instance type is preferred.
+	PriceTieEpsilon float64
+
+	// Keep launching the instance type used last time by a group as long as its
+	// spot price is within this fraction above the cheapest candidate's price.
+	StickyPriceBand float64
+
+	// Maximum percentage of a group's desired capacity which can run as spot
+	// instances of the same instance type and availability zone, 0 disables
+	// the limit.
+	MaxPoolConcentration float64
+
+	// Number of the cheapest compatible instance types the spot instances of
+	// a group are spread across, picking their least used pool, less than 2
+	// disabling the diversification.
+	SpotDiversification float64
+
+	// Detailed monitoring of the spot instances: "copy" keeps the setting of
+	// the launch configuration, "enable" and "disable" override it.
+	SpotMonitoring string
+
+	// Maximum percentage of all the managed instances of a region which can
+	// run as spot instances of the same instance type and availability zone,
+	// across all the enabled groups, 0 disables the limit.
+	MaxRegionPoolConcentration float64
+
+	// Pool concentration limit of the groups being pre-warmed, and how many
+	// spot instances they launch on each run.
+	PrewarmPoolConcentration float64
+	PrewarmLaunches          int
+
+	// When set, the spot bid price is the current spot price multiplied by this
+	// factor instead of the on-demand price, but never above on-demand.
+	BidSpotPriceFactor float64
+
+	// Bid pricing strategy, such as "on-demand", "on-demand-percentage:80",
+	// "spot-buffer:20" or "fixed:0.12", taking precedence over
+	// BidSpotPriceFactor.
+	BidStrategy string
+
+	// Suspend the replacements in the regions and availability zones affected
+	// by open AWS Health events about EC2 capacity or spot issues.
+	HealthBlackout bool
+
+	// Delay the replacement of on-demand instances having active SSM sessions,
+	// or sessions started within the given time window.
+	CheckInteractiveSessions bool
+	InteractiveSessionWindow time.Duration
+
+	// Spot instances are recycled this long before they reach the maximum
+	// instance lifetime configured on their group.
+	LifetimeMargin time.Duration
+
+	// Stop bidding in a spot pool once this many spot requests failed there for
+	// lack of capacity within the time window, and in a whole availability zone
+	// after this many such failures across all its pools. 0 disables them.
+	CapacityFailureThreshold     int
+	CapacityZoneFailureThreshold int
+	CapacityFailureWindow        time.Duration
+
+	// Export the AutoSpotting configuration and the computed spot candidates
+	// of all the managed groups as a JSON report on each run.
+	ExportFleetState bool
+
+	// A spot request failing for an instance type and availability zone makes
+	// the following bids of the group skip that combination for this long.
+	BidFailureWindow time.Duration
+
+	// ID of the event which triggered the current Lambda invocation, claimed in
+	// the DynamoDB table for IdempotencyTTL, so that duplicate invocations for
+	// the same event exit without doing anything.
+	EventID          string
+	IdempotencyTable string
+	IdempotencyTTL   time.Duration
+
+	// Comma separated IDs of the spot instances about to be interrupted, given
+	// by the interruption warning event which triggered the current run.
+	InterruptedInstances string
+
+	// Comma separated IDs of the spot instances at an elevated risk of being
+	// interrupted, given by the rebalance recommendation event which triggered
+	// the current run.
+	AtRiskInstances string
+
+	// Group tag defining logical applications, such as "app", whose stats are
+	// aggregated across groups and regions in the applications report.
+	ApplicationTag string
+
+	// Target receiving the platform-wide errors and the notifications about
+	// groups not matched by any of the NotificationRoutes, either an SNS topic
+	// ARN, a Slack incoming webhook URL or a mailto: email address.
+	NotificationTarget string
+
+	// Comma-separated list of key=value:target routes, sending the
+	// notifications about the groups tagged with key=value to the target.
+	NotificationRoutes string
+
+	// Comma-separated list of targets receiving all the alerts, such as
+	// pagerduty:<routing key> or opsgenie:<API key>.
+	AlertTargets string
+
+	// Number of failed attempts to attach a spot instance after which an alert
+	// is raised, 0 disables the alert.
+	AttachFailureThreshold int
+
+	// Verified SES sender address and SES region used for the email digests
+	// sent to the mailto: notification targets.
+	EmailSender string
+	EmailRegion string
+
+	// Optional html/template file overriding the layout of the email digests.
+	EmailTemplate string
+
+	// Maximum age of the spot and on-demand prices, beyond which no instances
+	// are replaced. The on-demand prices date from PricingDataTime, unknown if
+	// zero.
+	SpotPricingMaxAge     time.Duration
+	OnDemandPricingMaxAge time.Duration
+	PricingDataTime       time.Time
+
+	// How the spot prices are fetched: "lazy" fetches those of each
+	// availability zone and instance type when first needed, "eager" fetches
+	// all of them before processing the region.
+	SpotPriceFetch string
+
+	// Location of the instance data used instead of the embedded one, as an
+	// s3://bucket/key URL or a local file, optionally gzip-compressed.
+	InstanceData string
+
+	// How long the list of regions and the instance type catalogs, including
+	// their spot prices, are reused by the next runs of the same process, such
+	// as the warm invocations of the Lambda function. 0 disables the cache.
+	WarmCacheTTL time.Duration
+
+	// Hourly fee charged for EBS optimization by the instance types which
+	// aren't EBS-optimized by default, and price multiplier of the dedicated
+	// tenancy, used for normalizing the prices.
+	EBSOptimizationSurcharge float64
+	DedicatedTenancyFactor   float64
+
+	// DynamoDB table storing the daily savings estimates, rolled up into the
+	// savings trend.
+	SavingsTable string
+
+	// CloudWatch namespace of the custom metrics about the replacements and
+	// savings of each group, which are only published if set.
+	CloudWatchNamespace string
+
+	// SNS topic ARN receiving an audit message for every replacement, failed
+	// bid and rollback, unless overridden by the group's audit_topic tag.
+	AuditTopic string
+
+	// Currency the savings reports are presented in, converted from US
+	// dollars using either the static CurrencyRate or the rate fetched from
+	// the CurrencyRatesURL exchange rates API.
+	ReportCurrency   string
+	CurrencyRate     float64
+	CurrencyRatesURL string
+
+	// Evaluate all the groups without taking any action, exporting the ranked
+	// projected savings in the plan report.
+	Plan bool
+
+	// Maximum percentage of the instances of all the enabled groups allowed to
+	// run on spot, 0 means unlimited.
+	MaxSpotPercentage float64
+
+	// Interruption frequency, in percent of the launched spot instances, above
+	// which a spot pool is denied for DenyListTTL, 0 disables the deny-list.
+	// The entries are persisted in the DenyListTable DynamoDB table if given.
+	InterruptionThreshold float64
+	DenyListTTL           time.Duration
+	DenyListTable         string
+
+	// URL of the Spot Advisor dataset, and the percentage by which the spot
+	// prices are increased for each step of its interruption frequency
+	// ratings when choosing the instance types.
+	SpotAdvisorURL      string
+	InterruptionPenalty float64
+
+	// Health check grace period used for the spot instances being attached,
+	// temporarily set on the groups while attaching them. 0 keeps using the
+	// groups' own grace periods.
+	AttachGracePeriod time.Duration
+
+	// Set the desired capacity of the groups back to its value from before
+	// the replacement when it drifted by one instance.
+	ReconcileCapacity bool
+
+	// How long the first spot instance of a group is observed before any
+	// further replacements in the group, 0 disables the canary replacements.
+	CanaryWindow time.Duration
+
+	// Minimum percentage by which the spot price of an instance type used by a
+	// group needs to be cheaper in another region in order to be listed in the
+	// arbitrage report, 0 disables the report.
+	ArbitrageThreshold float64
+
+	// Where the state tables are kept: "dynamodb", "s3" or "file".
+	StateBackend string
+
+	// S3 bucket or local directory holding the state tables of the s3 and file
+	// state backends.
+	StateLocation string
+
+	// How long the replaced on-demand instances are kept running before being
+	// terminated, 0 terminates them right away.
+	TerminationDelay time.Duration
+
+	// How long the replaced on-demand instances are kept stopped before being
+	// terminated, used instead of the termination delay when set.
+	StopGracePeriod time.Duration
+
+	// DynamoDB table keeping the fingerprint of each group, the groups which
+	// run only spot instances and didn't change since the previous run being
+	// skipped, unless their last snapshot is older than ChangesMaxAge.
+	ChangesTable  string
+	ChangesMaxAge time.Duration
+
+	// Default values of the group settings, read from the JSON SettingsFile
+	SettingsFile string
+	FileSettings map[string]string
+
+	// S3 bucket where the JSON reports are uploaded, they are logged otherwise.
+	ReportBucket string
+
+	// Local directory where the JSON reports are written when they're not
+	// uploaded to S3.
+	ReportDir string
+
+	// Format of the export of the candidate instance types evaluated for each
+	// group, "json" or "csv", disabled when empty.
+	CandidateExport string
 }
@@ -7,14 +7,22 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/codedeploy"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elasticbeanstalk"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/ssm"
 )
 
 type connections struct {
-	session     *session.Session
-	autoScaling *autoscaling.AutoScaling
-	ec2         *ec2.EC2
-	region      string
+	session          *session.Session
+	autoScaling      *autoscaling.AutoScaling
+	ec2              *ec2.EC2
+	codeDeploy       *codedeploy.CodeDeploy
+	elasticBeanstalk *elasticbeanstalk.ElasticBeanstalk
+	ssm              *ssm.SSM
+	elbv2            *elbv2.ELBV2
+	region           string
 }
 
 func (c *connections) connect(region string) {
@@ -23,18 +31,28 @@ func (c *connections) connect(region string) {
 
 	// concurrently connect to all the services we need
 
-	c.session = session.New(
+	c.session = instrumentSession(newSession(
 		&aws.Config{
 			Region: aws.String(region)},
-	)
+	))
 
 	asConn := make(chan *autoscaling.AutoScaling)
 	ec2Conn := make(chan *ec2.EC2)
+	cdConn := make(chan *codedeploy.CodeDeploy)
+	ebConn := make(chan *elasticbeanstalk.ElasticBeanstalk)
+	ssmConn := make(chan *ssm.SSM)
+	elbv2Conn := make(chan *elbv2.ELBV2)
 
 	go func() { asConn <- autoscaling.New(c.session) }()
 	go func() { ec2Conn <- ec2.New(c.session) }()
+	go func() { cdConn <- codedeploy.New(c.session) }()
+	go func() { ebConn <- elasticbeanstalk.New(c.session) }()
+	go func() { ssmConn <- ssm.New(c.session) }()
+	go func() { elbv2Conn <- elbv2.New(c.session) }()
 
 	c.autoScaling, c.ec2, c.region = <-asConn, <-ec2Conn, region
+	c.codeDeploy, c.elasticBeanstalk, c.ssm = <-cdConn, <-ebConn, <-ssmConn
+	c.elbv2 = <-elbv2Conn
 
 	logger.Println("Created service connections in", region)
 }
@@ -0,0 +1,97 @@
+package autospotting
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+const (
+	// number of times the group is described while waiting for a change
+	consistencyAttempts = 5
+
+	// delay between these attempts
+	consistencyDelay = 3 * time.Second
+)
+
+// describeGroup returns the current state of the group.
+func (a *autoScalingGroup) describeGroup() (*autoscaling.Group, error) {
+
+	resp, err := a.region.services.autoScaling.DescribeAutoScalingGroups(
+		&autoscaling.DescribeAutoScalingGroupsInput{
+			AutoScalingGroupNames: []*string{aws.String(a.name)},
+		})
+
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.AutoScalingGroups) == 0 {
+		return nil, fmt.Errorf("the group %s wasn't found", a.name)
+	}
+	return resp.AutoScalingGroups[0], nil
+}
+
+// groupInstanceState returns the lifecycle state of the instance in the group,
+// or an empty string if the instance isn't part of the group.
+func groupInstanceState(group *autoscaling.Group, instanceID string) string {
+	for _, inst := range group.Instances {
+		if inst.InstanceId != nil && *inst.InstanceId == instanceID &&
+			inst.LifecycleState != nil {
+			return *inst.LifecycleState
+		}
+	}
+	return ""
+}
+
+// isAttached tells if the group lists the instance as one of its members.
+func isAttached(group *autoscaling.Group, instanceID string) bool {
+	switch groupInstanceState(group, instanceID) {
+	case "", autoscaling.LifecycleStateDetaching,
+		autoscaling.LifecycleStateDetached:
+		return false
+	}
+	return true
+}
+
+// waitForGroup describes the group until the check passes, refreshing the
+// group's data, and tells if it passed within the allowed attempts, since
+// AutoScaling is eventually consistent.
+func (a *autoScalingGroup) waitForGroup(change string,
+	check func(*autoscaling.Group) bool) bool {
+
+	for attempt := 1; ; attempt++ {
+
+		group, err := a.describeGroup()
+		if err == nil && check(group) {
+			a.Group = group
+			return true
+		}
+
+		if attempt == consistencyAttempts {
+			a.log().Println(a.name, "The group doesn't reflect that", change,
+				"after", attempt, "attempts, going on with its last known state")
+			return false
+		}
+
+		a.log().Println(a.name, "Waiting for the group to reflect that", change)
+		time.Sleep(consistencyDelay)
+	}
+}
+
+// waitUntilAttached waits for the instance to show up in the group.
+func (a *autoScalingGroup) waitUntilAttached(instanceID *string) bool {
+	return a.waitForGroup("instance "+*instanceID+" was attached",
+		func(group *autoscaling.Group) bool {
+			return isAttached(group, *instanceID)
+		})
+}
+
+// waitUntilDetached waits for the instance to be gone from the group.
+func (a *autoScalingGroup) waitUntilDetached(instanceID *string) bool {
+	return a.waitForGroup("instance "+*instanceID+" was detached",
+		func(group *autoscaling.Group) bool {
+			return !isAttached(group, *instanceID)
+		})
+}
@@ -0,0 +1,39 @@
+package autospotting
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+func Test_isAttached(t *testing.T) {
+
+	group := &autoscaling.Group{
+		Instances: []*autoscaling.Instance{
+			{InstanceId: aws.String("i-pending"), LifecycleState: aws.String("Pending")},
+			{InstanceId: aws.String("i-inservice"), LifecycleState: aws.String("InService")},
+			{InstanceId: aws.String("i-standby"), LifecycleState: aws.String("Standby")},
+			{InstanceId: aws.String("i-detaching"), LifecycleState: aws.String("Detaching")},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		instanceID string
+		want       bool
+	}{
+		{name: "pending instance", instanceID: "i-pending", want: true},
+		{name: "in service instance", instanceID: "i-inservice", want: true},
+		{name: "standby instance", instanceID: "i-standby", want: true},
+		{name: "detaching instance", instanceID: "i-detaching", want: false},
+		{name: "missing instance", instanceID: "i-missing", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isAttached(group, tt.instanceID); got != tt.want {
+				t.Errorf("isAttached() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
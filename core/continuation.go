@@ -0,0 +1,148 @@
+package autospotting
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+var continuation continuationMarker
+
+type continuationMarker struct {
+	sync.Mutex
+
+	table    string
+	deadline time.Time
+
+	// index of the first group to process in each region, in name order
+	cursors map[string]int
+
+	// regions or groups left unprocessed by the current run
+	unfinished bool
+}
+
+// load reads the cursors left by the previous run from the continuation
+// table, whose DynamoDB table needs a "region" string partition key. The
+// deadline only stops the groups from starting when their concurrency is
+// limited by MaxConcurrentGroups.
+func (c *continuationMarker) load(cfg Config) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.table = cfg.ContinuationTable
+	c.deadline = cfg.Deadline
+	c.cursors = make(map[string]int)
+	c.unfinished = false
+
+	if c.table == "" {
+		return
+	}
+
+	items, err := state.scan(c.table)
+	for _, item := range items {
+		if region, found := item.Keys["region"]; found {
+			c.cursors[region] = int(item.Numbers["next"])
+		}
+	}
+
+	if err != nil {
+		logger.at(LevelError).Println(
+			"Failed to load the continuation cursors from",
+			c.table, err.Error())
+	}
+}
+
+// expired tells if no new work should be started.
+func (c *continuationMarker) expired(now time.Time) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	return !c.deadline.IsZero() && now.After(c.deadline)
+}
+
+// skipRegion records that the region was left unprocessed.
+func (c *continuationMarker) skipRegion(region string) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.unfinished = true
+	logger.Println(region, "Not processing the AutoScaling groups since the",
+		"deadline of the run passed")
+}
+
+func (c *continuationMarker) cursor(region string) int {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.cursors[region]
+}
+
+// advance records the index of the first group of the region to be processed
+// by the next run, and persists it.
+func (c *continuationMarker) advance(region string, next int, finished bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	if !finished {
+		c.unfinished = true
+	}
+
+	if c.cursors[region] == next {
+		return
+	}
+	c.cursors[region] = next
+
+	if c.table == "" {
+		return
+	}
+
+	err := state.put(c.table, stateItem{
+		Keys:    map[string]string{"region": region},
+		Numbers: map[string]float64{"next": float64(next)},
+	})
+	if err != nil {
+		logger.at(LevelError).Println(
+			"Failed to store the continuation cursor of",
+			region, "in", c.table, err.Error())
+	}
+}
+
+// marker returns the cursors of the regions and if any work was left
+// unprocessed by the current run.
+func (c *continuationMarker) marker() (map[string]int, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	result := make(map[string]int)
+	for region, next := range c.cursors {
+		if next > 0 {
+			result[region] = next
+		}
+	}
+	return result, c.unfinished
+}
+
+// rotateGroups orders the groups by name starting from the cursor, returning
+// the indexes of the groups in name order alongside them.
+func rotateGroups(groups []autoScalingGroup,
+	cursor int) ([]autoScalingGroup, []int) {
+
+	sorted := append([]autoScalingGroup{}, groups...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].name < sorted[j].name
+	})
+
+	if len(sorted) == 0 {
+		return nil, nil
+	}
+	cursor = cursor % len(sorted)
+
+	var rotated []autoScalingGroup
+	var indexes []int
+	for i := range sorted {
+		index := (cursor + i) % len(sorted)
+		rotated = append(rotated, sorted[index])
+		indexes = append(indexes, index)
+	}
+	return rotated, indexes
+}
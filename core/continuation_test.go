@@ -0,0 +1,107 @@
+package autospotting
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_rotateGroups(t *testing.T) {
+
+	groups := []autoScalingGroup{{name: "c"}, {name: "a"}, {name: "b"}}
+
+	tests := []struct {
+		name        string
+		cursor      int
+		wantNames   []string
+		wantIndexes []int
+	}{
+		{name: "from the first group",
+			cursor:      0,
+			wantNames:   []string{"a", "b", "c"},
+			wantIndexes: []int{0, 1, 2},
+		},
+		{name: "from the cursor",
+			cursor:      2,
+			wantNames:   []string{"c", "a", "b"},
+			wantIndexes: []int{2, 0, 1},
+		},
+		{name: "cursor beyond the groups removed since",
+			cursor:      4,
+			wantNames:   []string{"b", "c", "a"},
+			wantIndexes: []int{1, 2, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rotated, indexes := rotateGroups(groups, tt.cursor)
+
+			var names []string
+			for _, g := range rotated {
+				names = append(names, g.name)
+			}
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("rotateGroups() names = %v, want %v", names, tt.wantNames)
+			}
+			if !reflect.DeepEqual(indexes, tt.wantIndexes) {
+				t.Errorf("rotateGroups() indexes = %v, want %v", indexes,
+					tt.wantIndexes)
+			}
+		})
+	}
+}
+
+func Test_continuationMarker(t *testing.T) {
+
+	now := time.Now()
+
+	tests := []struct {
+		name           string
+		deadline       time.Time
+		wantExpired    bool
+		finished       bool
+		next           int
+		wantMarker     map[string]int
+		wantUnfinished bool
+	}{
+		{name: "no deadline",
+			finished:   true,
+			wantMarker: map[string]int{},
+		},
+		{name: "before the deadline",
+			deadline:   now.Add(time.Minute),
+			finished:   true,
+			wantMarker: map[string]int{},
+		},
+		{name: "after the deadline",
+			deadline:       now.Add(-time.Minute),
+			wantExpired:    true,
+			next:           2,
+			wantMarker:     map[string]int{"us-east-1": 2},
+			wantUnfinished: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := continuationMarker{
+				deadline: tt.deadline,
+				cursors:  map[string]int{"us-east-1": 1},
+			}
+
+			if got := c.expired(now); got != tt.wantExpired {
+				t.Errorf("expired() = %v, want %v", got, tt.wantExpired)
+			}
+
+			c.advance("us-east-1", tt.next, tt.finished)
+
+			marker, unfinished := c.marker()
+			if !reflect.DeepEqual(marker, tt.wantMarker) {
+				t.Errorf("marker() = %v, want %v", marker, tt.wantMarker)
+			}
+			if unfinished != tt.wantUnfinished {
+				t.Errorf("marker() unfinished = %v, want %v", unfinished,
+					tt.wantUnfinished)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package autospotting
+
+import (
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+var awsSessions sessionFactory
+
+type sessionFactory struct {
+	sync.Mutex
+
+	// named profile of the shared configuration, the default or AWS_PROFILE
+	// if empty
+	profile string
+
+	// region hosting the resources used by AutoSpotting itself
+	home string
+
+	base *session.Session
+}
+
+func (f *sessionFactory) init(cfg Config) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.home = resolveHomeRegion(cfg)
+
+	// keep the base session, and its cached credentials, across the runs of
+	// the daemon mode
+	if f.base != nil && f.profile == cfg.Profile {
+		return
+	}
+	f.profile, f.base = cfg.Profile, nil
+}
+
+// baseSession returns the session holding the credentials shared by all the
+// sessions, created when first needed.
+func (f *sessionFactory) baseSession() *session.Session {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.base != nil {
+		return f.base
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Profile:                 f.profile,
+		SharedConfigState:       session.SharedConfigEnable,
+		AssumeRoleTokenProvider: stscreds.StdinTokenProvider,
+	})
+
+	if err != nil {
+		logger.at(LevelWarn).Println(
+			"Couldn't load the credentials of the profile",
+			f.profile, err.Error(), "using the default credentials")
+		sess = session.New()
+	}
+	f.base = sess
+	return sess
+}
+
+// newSession returns a session using the shared credentials, configured with
+// the given settings.
+func newSession(cfg *aws.Config) *session.Session {
+	return awsSessions.baseSession().Copy(cfg)
+}
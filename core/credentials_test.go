@@ -0,0 +1,74 @@
+package autospotting
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func Test_newSession(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	credentialsFile := filepath.Join(dir, "credentials")
+	err = ioutil.WriteFile(credentialsFile, []byte(`[default]
+aws_access_key_id = DEFAULTKEY
+aws_secret_access_key = secret
+
+[team]
+aws_access_key_id = TEAMKEY
+aws_secret_access_key = secret
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, env := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY",
+		"AWS_PROFILE", "AWS_CONFIG_FILE"} {
+		defer os.Setenv(env, os.Getenv(env))
+		os.Unsetenv(env)
+	}
+	defer os.Setenv("AWS_SHARED_CREDENTIALS_FILE",
+		os.Getenv("AWS_SHARED_CREDENTIALS_FILE"))
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", credentialsFile)
+	os.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+
+	defer func() { awsSessions = sessionFactory{} }()
+
+	tests := []struct {
+		name    string
+		profile string
+		want    string
+	}{
+		{name: "default profile", want: "DEFAULTKEY"},
+		{name: "named profile", profile: "team", want: "TEAMKEY"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			awsSessions = sessionFactory{}
+			awsSessions.init(Config{Profile: tt.profile})
+
+			sess := newSession(&aws.Config{Region: aws.String("eu-west-1")})
+			if *sess.Config.Region != "eu-west-1" {
+				t.Errorf("newSession() region = %s", *sess.Config.Region)
+			}
+
+			creds, err := sess.Config.Credentials.Get()
+			if err != nil {
+				t.Fatalf("newSession() credentials error = %v", err)
+			}
+			if creds.AccessKeyID != tt.want {
+				t.Errorf("newSession() access key = %s, want %s",
+					creds.AccessKeyID, tt.want)
+			}
+		})
+	}
+}
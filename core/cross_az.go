@@ -0,0 +1,54 @@
+package autospotting
+
+// toleratesAZImbalance tells if the group accepts replacements across
+// availability zones, which requires both the cross_az_replacement tag and the
+// AZRebalance process to be suspended, otherwise AutoScaling would rebalance
+// the group right away by terminating instances.
+func (a *autoScalingGroup) toleratesAZImbalance() bool {
+
+	if !a.boolSetting("cross_az_replacement") {
+		return false
+	}
+
+	for _, p := range a.SuspendedProcesses {
+		if p.ProcessName != nil && *p.ProcessName == "AZRebalance" {
+			return true
+		}
+	}
+
+	a.log().at(LevelWarn).Println(a.name,
+		"Ignoring cross_az_replacement since the",
+		"AZRebalance process isn't suspended")
+	return false
+}
+
+// findOndemandInstanceInOtherAZ returns an on-demand instance from the
+// availability zone having the most running instances, other than the given
+// one, so that the replacement worsens the imbalance as little as possible.
+func (a *autoScalingGroup) findOndemandInstanceInOtherAZ(az *string) *instance {
+
+	population := make(map[string]int)
+	for _, i := range a.instances.catalog {
+		if *i.State.Name == "running" {
+			population[*i.Placement.AvailabilityZone]++
+		}
+	}
+
+	var victim *instance
+	for _, i := range a.instances.catalog {
+
+		instanceAZ := *i.Placement.AvailabilityZone
+
+		if *i.State.Name != "running" || i.isSpot() || instanceAZ == *az {
+			continue
+		}
+
+		if victim == nil ||
+			population[instanceAZ] > population[*victim.Placement.AvailabilityZone] ||
+			(population[instanceAZ] == population[*victim.Placement.AvailabilityZone] &&
+				*i.InstanceId < *victim.InstanceId) {
+			victim = i
+		}
+	}
+	return victim
+}
@@ -0,0 +1,81 @@
+package autospotting
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_toleratesAZImbalance(t *testing.T) {
+
+	tag := []*autoscaling.TagDescription{{
+		Key: aws.String("cross_az_replacement"), Value: aws.String("true"),
+	}}
+	suspended := []*autoscaling.SuspendedProcess{{
+		ProcessName: aws.String("AZRebalance"),
+	}}
+
+	tests := []struct {
+		name  string
+		group *autoscaling.Group
+		want  bool
+	}{
+		{
+			name:  "not enabled",
+			group: &autoscaling.Group{SuspendedProcesses: suspended},
+			want:  false,
+		},
+		{
+			name:  "enabled but AZRebalance is active",
+			group: &autoscaling.Group{Tags: tag},
+			want:  false,
+		},
+		{
+			name:  "enabled with AZRebalance suspended",
+			group: &autoscaling.Group{Tags: tag, SuspendedProcesses: suspended},
+			want:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &autoScalingGroup{Group: tt.group}
+			if got := a.toleratesAZImbalance(); got != tt.want {
+				t.Errorf("toleratesAZImbalance() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_findOndemandInstanceInOtherAZ(t *testing.T) {
+
+	newInstance := func(id, az string, spot bool) *instance {
+		i := &instance{Instance: &ec2.Instance{
+			InstanceId: aws.String(id),
+			State:      &ec2.InstanceState{Name: aws.String("running")},
+			Placement:  &ec2.Placement{AvailabilityZone: aws.String(az)},
+		}}
+		if spot {
+			i.InstanceLifecycle = aws.String("spot")
+		}
+		return i
+	}
+
+	a := &autoScalingGroup{instances: instances{catalog: map[string]*instance{
+		"i-a1": newInstance("i-a1", "us-east-1a", true),
+		"i-b1": newInstance("i-b1", "us-east-1b", false),
+		"i-c1": newInstance("i-c1", "us-east-1c", false),
+		"i-c2": newInstance("i-c2", "us-east-1c", true),
+	}}}
+
+	got := a.findOndemandInstanceInOtherAZ(aws.String("us-east-1a"))
+	if got == nil || *got.InstanceId != "i-c1" {
+		t.Errorf("findOndemandInstanceInOtherAZ() = %v, want i-c1", got)
+	}
+
+	got = a.findOndemandInstanceInOtherAZ(aws.String("us-east-1c"))
+	if got == nil || *got.InstanceId != "i-b1" {
+		t.Errorf("findOndemandInstanceInOtherAZ() = %v, want i-b1", got)
+	}
+}
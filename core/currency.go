@@ -0,0 +1,202 @@
+package autospotting
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// currencyFormat describes how the amounts of a currency are written.
+type currencyFormat struct {
+	symbol    string
+	prefix    bool
+	thousands string
+	decimal   string
+
+	// number of decimals of the currency's minor unit
+	minor int
+}
+
+var currencyFormats = map[string]currencyFormat{
+	"USD": {symbol: "$", prefix: true, thousands: ",", decimal: ".", minor: 2},
+	"EUR": {symbol: "€", thousands: ".", decimal: ",", minor: 2},
+	"GBP": {symbol: "£", prefix: true, thousands: ",", decimal: ".", minor: 2},
+	"JPY": {symbol: "¥", prefix: true, thousands: ",", decimal: ".", minor: 0},
+}
+
+var currency currencyConverter
+
+var exchangeRatesClient = &http.Client{Timeout: 10 * time.Second}
+
+type currencyConverter struct {
+	sync.Mutex
+
+	code string
+
+	// amount of the currency worth one US dollar
+	rate float64
+}
+
+func (c *currencyConverter) init(cfg Config) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.code, c.rate = "USD", 1
+
+	code := strings.ToUpper(strings.TrimSpace(cfg.ReportCurrency))
+	if code == "" || code == "USD" {
+		return
+	}
+
+	rate := cfg.CurrencyRate
+	if rate <= 0 && cfg.CurrencyRatesURL != "" {
+		var err error
+		if rate, err = fetchExchangeRate(cfg.CurrencyRatesURL, code); err != nil {
+			logger.at(LevelWarn).Println("Couldn't fetch the exchange rate of",
+				code, "from", cfg.CurrencyRatesURL, err.Error())
+		}
+	}
+
+	if rate <= 0 {
+		logger.at(LevelWarn).Println("No exchange rate for", code,
+			"reporting the savings in USD")
+		return
+	}
+	c.code, c.rate = code, rate
+}
+
+// fetchExchangeRate fetches the rates relative to the US dollar, returned as
+// JSON by the exchange rates API in a "rates" object keyed by currency code,
+// and returns the rate of the given currency.
+func fetchExchangeRate(url, code string) (float64, error) {
+
+	resp, err := exchangeRatesClient.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+
+	var data struct {
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return 0, err
+	}
+
+	rate, ok := data.Rates[code]
+	if !ok {
+		return 0, fmt.Errorf("no rate for %s", code)
+	}
+	return rate, nil
+}
+
+// convert converts the amount given in US dollars into the report currency.
+func (c *currencyConverter) convert(usd float64) float64 {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.rate <= 0 {
+		return usd
+	}
+	return usd * c.rate
+}
+
+// format converts the amount given in US dollars and formats it in the report
+// currency, using as many decimals as given for the US dollar amounts, adjusted
+// to the minor unit of the currency.
+func (c *currencyConverter) format(usd float64, decimals int) string {
+
+	amount := c.convert(usd)
+
+	c.Lock()
+	code := c.code
+	c.Unlock()
+
+	if code == "" {
+		code = "USD"
+	}
+	return formatAmount(amount, code, decimals)
+}
+
+// formatAmount formats the amount of the given currency, with the decimals
+// adjusted from those of the US dollar to the minor unit of the currency.
+func formatAmount(amount float64, code string, decimals int) string {
+
+	f, known := currencyFormats[code]
+	if !known {
+		f = currencyFormat{thousands: ",", decimal: ".", minor: 2}
+	}
+
+	if decimals += f.minor - 2; decimals < 0 {
+		decimals = 0
+	}
+
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", math.Abs(amount)
+	}
+
+	scale := math.Pow(10, float64(decimals))
+	digits := fmt.Sprintf("%.*f", decimals, math.Round(amount*scale)/scale)
+	whole, fraction := digits, ""
+	if i := strings.Index(digits, "."); i >= 0 {
+		whole, fraction = digits[:i], digits[i+1:]
+	}
+
+	var grouped strings.Builder
+	for i, d := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteString(f.thousands)
+		}
+		grouped.WriteRune(d)
+	}
+
+	number := grouped.String()
+	if fraction != "" {
+		number += f.decimal + fraction
+	}
+
+	switch {
+	case !known:
+		return sign + number + " " + code
+	case f.prefix:
+		return sign + f.symbol + number
+	default:
+		return sign + number + " " + f.symbol
+	}
+}
+
+// savingsTrend returns the savings trend converted into the report currency.
+func (c *currencyConverter) savingsTrend(t *savingsTrend) *savingsTrend {
+
+	if t == nil {
+		return nil
+	}
+
+	convert := func(periods []savingsPeriod) []savingsPeriod {
+		result := []savingsPeriod{}
+		for _, p := range periods {
+			p.Savings = c.convert(p.Savings)
+			result = append(result, p)
+		}
+		return result
+	}
+
+	c.Lock()
+	code := c.code
+	c.Unlock()
+
+	return &savingsTrend{
+		Currency: code,
+		Weekly:   convert(t.Weekly),
+		Monthly:  convert(t.Monthly),
+	}
+}
@@ -0,0 +1,75 @@
+package autospotting
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_formatAmount(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		amount   float64
+		code     string
+		decimals int
+		want     string
+	}{
+		{name: "dollars", amount: 1234.5, code: "USD", decimals: 2,
+			want: "$1,234.50"},
+		{name: "hourly dollars", amount: 0.25, code: "USD", decimals: 4,
+			want: "$0.2500"},
+		{name: "euros", amount: 1234567.891, code: "EUR", decimals: 2,
+			want: "1.234.567,89 €"},
+		{name: "pounds", amount: -12.5, code: "GBP", decimals: 2,
+			want: "-£12.50"},
+		{name: "yen", amount: 1234.5, code: "JPY", decimals: 2,
+			want: "¥1,235"},
+		{name: "other currency", amount: 999.5, code: "CHF", decimals: 2,
+			want: "999.50 CHF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatAmount(tt.amount, tt.code,
+				tt.decimals); got != tt.want {
+				t.Errorf("formatAmount() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_currencyConverter_init(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"result":"success","rates":{"USD":1,"EUR":0.5}}`)
+		}))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{name: "dollars by default", cfg: Config{}, want: "$10.00"},
+		{name: "static rate",
+			cfg:  Config{ReportCurrency: "gbp", CurrencyRate: 0.8},
+			want: "£8.00"},
+		{name: "fetched rate",
+			cfg:  Config{ReportCurrency: "EUR", CurrencyRatesURL: server.URL},
+			want: "5,00 €"},
+		{name: "unknown rate",
+			cfg:  Config{ReportCurrency: "JPY", CurrencyRatesURL: server.URL},
+			want: "$10.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c currencyConverter
+			c.init(tt.cfg)
+			if got := c.format(10, 2); got != tt.want {
+				t.Errorf("format() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
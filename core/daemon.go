@@ -0,0 +1,75 @@
+package autospotting
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"strings"
+	"time"
+)
+
+const (
+	httpReadTimeout = 10 * time.Second
+
+	// long enough for the default 30 seconds CPU profiles and traces
+	httpWriteTimeout = 2 * time.Minute
+)
+
+// RunDaemon processes all the regions every DaemonInterval, forever, serving
+// the /metrics and /debug/pprof/ endpoints on ListenAddress in the meantime.
+func RunDaemon(cfg Config) {
+
+	initLogging(cfg)
+
+	go serveHTTP(cfg.ListenAddress)
+
+	for {
+		Run(cfg)
+
+		logger.Println("Sleeping for", cfg.DaemonInterval, "until the next run")
+		time.Sleep(cfg.DaemonInterval)
+	}
+}
+
+func newServeMux() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/metrics", serveStats)
+	mux.HandleFunc("/asgs/", serveGroup)
+	mux.HandleFunc("/instances/", serveRestore)
+	mux.HandleFunc("/config-schema", serveConfigSchema)
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return mux
+}
+
+// serveGroup routes the requests concerning a given group.
+func serveGroup(w http.ResponseWriter, r *http.Request) {
+
+	if strings.HasSuffix(r.URL.Path, "/prewarm") {
+		servePrewarm(w, r)
+		return
+	}
+	serveHistory(w, r)
+}
+
+func serveHTTP(address string) {
+
+	logger.Println("Serving runtime statistics and profiles on", address)
+
+	server := &http.Server{
+		Addr:         address,
+		Handler:      newServeMux(),
+		ReadTimeout:  httpReadTimeout,
+		WriteTimeout: httpWriteTimeout,
+	}
+
+	if err := server.ListenAndServe(); err != nil {
+		logger.at(LevelError).Println("Failed to serve HTTP on", address,
+			err.Error())
+	}
+}
@@ -0,0 +1,162 @@
+package autospotting
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+const (
+	dataVolumesSnapshot = "snapshot"
+	dataVolumesReattach = "reattach"
+)
+
+// dataVolumesMode returns the value of the group's data_volumes tag, or an
+// empty string if the data volumes aren't carried over. The volumes of the
+// groups using the stateful profile are reattached by default.
+func (a *autoScalingGroup) dataVolumesMode() string {
+
+	mode := a.stringSetting("data_volumes")
+	if mode == "" && a.isStateful() {
+		return dataVolumesReattach
+	}
+	return mode
+}
+
+// dataVolumes returns the non-root EBS volumes of the instance, keyed by the
+// device name they're attached on.
+func (it *instance) dataVolumes() map[string]*string {
+
+	volumes := make(map[string]*string)
+
+	for _, bdm := range it.BlockDeviceMappings {
+		if bdm.DeviceName == nil || bdm.Ebs == nil || bdm.Ebs.VolumeId == nil {
+			continue
+		}
+		if it.RootDeviceName != nil && *bdm.DeviceName == *it.RootDeviceName {
+			continue
+		}
+		volumes[*bdm.DeviceName] = bdm.Ebs.VolumeId
+	}
+	return volumes
+}
+
+// carryOverDataVolumes snapshots or moves the data volumes of the on-demand
+// instance to the spot instance replacing it, as configured on the group.
+func (a *autoScalingGroup) carryOverDataVolumes(odInst *instance,
+	spotInstanceID *string) {
+
+	mode := a.dataVolumesMode()
+	if mode == "" {
+		return
+	}
+
+	spotInst := a.region.instances.get(*spotInstanceID)
+
+	for device, volumeID := range odInst.dataVolumes() {
+
+		if mode == dataVolumesReattach && spotInst != nil {
+			if _, used := spotInst.dataVolumes()[device]; !used {
+				a.reattachVolume(volumeID, device, odInst, spotInstanceID)
+				continue
+			}
+			a.log().Println(a.name, "Device", device, "is already used on",
+				*spotInstanceID, "snapshotting volume", *volumeID, "instead")
+		}
+
+		a.snapshotVolume(volumeID, device, odInst, spotInstanceID)
+	}
+}
+
+func (a *autoScalingGroup) snapshotVolume(volumeID *string, device string,
+	odInst *instance, spotInstanceID *string) {
+
+	svc := a.region.services.ec2
+
+	input := &ec2.CreateSnapshotInput{
+		VolumeId: volumeID,
+		Description: aws.String("Data volume " + device + " of " +
+			*odInst.InstanceId + " replaced by " + *spotInstanceID),
+	}
+
+	if a.skipsCall("CreateSnapshot", input) {
+		return
+	}
+
+	snapshot, err := svc.CreateSnapshot(input)
+
+	if err != nil {
+		a.log().at(LevelError).Println(a.name, "Failed to snapshot volume",
+			*volumeID, err.Error())
+		return
+	}
+
+	_, err = svc.CreateTags(&ec2.CreateTagsInput{
+		Resources: []*string{snapshot.SnapshotId},
+		Tags: []*ec2.Tag{
+			{Key: aws.String("original-instance-id"), Value: odInst.InstanceId},
+			{Key: aws.String("replaced-by"), Value: spotInstanceID},
+			{Key: aws.String("device"), Value: aws.String(device)},
+		},
+	})
+
+	if err != nil {
+		a.log().at(LevelError).Println(a.name, "Failed to tag snapshot",
+			*snapshot.SnapshotId, err.Error())
+	}
+
+	a.recordAction("snapshotted", "volume", *volumeID, "of", *odInst.InstanceId,
+		"as", *snapshot.SnapshotId)
+}
+
+func (a *autoScalingGroup) reattachVolume(volumeID *string, device string,
+	odInst *instance, spotInstanceID *string) {
+
+	svc := a.region.services.ec2
+
+	a.log().Println(a.name, "Moving volume", *volumeID, "from",
+		*odInst.InstanceId, "to", *spotInstanceID, "on", device)
+
+	detachInput := &ec2.DetachVolumeInput{
+		VolumeId:   volumeID,
+		InstanceId: odInst.InstanceId,
+	}
+	attachInput := &ec2.AttachVolumeInput{
+		VolumeId:   volumeID,
+		InstanceId: spotInstanceID,
+		Device:     aws.String(device),
+	}
+
+	if a.skipsCall("DetachVolume", detachInput) {
+		a.skipsCall("AttachVolume", attachInput)
+		return
+	}
+
+	_, err := svc.DetachVolume(detachInput)
+
+	if err != nil {
+		a.log().at(LevelError).Println(a.name, "Failed to detach volume",
+			*volumeID, err.Error())
+		return
+	}
+
+	err = svc.WaitUntilVolumeAvailable(&ec2.DescribeVolumesInput{
+		VolumeIds: []*string{volumeID},
+	})
+
+	if err != nil {
+		a.log().at(LevelError).Println(a.name, "Error waiting for volume",
+			*volumeID, "to be detached", err.Error())
+		return
+	}
+
+	_, err = svc.AttachVolume(attachInput)
+
+	if err != nil {
+		a.log().at(LevelError).Println(a.name, "Failed to attach volume",
+			*volumeID, "to", *spotInstanceID, err.Error())
+		return
+	}
+
+	a.recordAction("reattached", "volume", *volumeID, "from",
+		*odInst.InstanceId, "to", *spotInstanceID)
+}
@@ -0,0 +1,32 @@
+package autospotting
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_dataVolumes(t *testing.T) {
+
+	inst := &instance{Instance: &ec2.Instance{
+		RootDeviceName: aws.String("/dev/xvda"),
+		BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
+			{
+				DeviceName: aws.String("/dev/xvda"),
+				Ebs:        &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-root")},
+			},
+			{
+				DeviceName: aws.String("/dev/xvdf"),
+				Ebs:        &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-data")},
+			},
+		},
+	}}
+
+	want := map[string]*string{"/dev/xvdf": aws.String("vol-data")}
+
+	if got := inst.dataVolumes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("dataVolumes() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,113 @@
+package autospotting
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+var dumps debugDumps
+
+type debugDumps struct {
+	sync.Mutex
+
+	enabled bool
+
+	// Maximum size of a single dump written to the logs, 0 means unlimited.
+	maxBytes int
+
+	// Only one out of this many dumps of a given kind is emitted.
+	sampleRate int
+
+	// When set, the full dumps are uploaded to this bucket instead of logged.
+	bucket *s3Bucket
+
+	// number of dumps requested so far for each kind
+	seen map[string]int
+}
+
+func (d *debugDumps) init(cfg Config, enabled bool) {
+	d.Lock()
+	defer d.Unlock()
+
+	d.enabled = enabled
+	d.maxBytes = cfg.DebugMaxBytes
+	d.sampleRate = cfg.DebugSampleRate
+	d.seen = make(map[string]int)
+
+	d.bucket = nil
+	if cfg.DebugDumpBucket != "" {
+		d.bucket = &s3Bucket{name: cfg.DebugDumpBucket}
+	}
+}
+
+// sampled counts the dumps requested of a kind and tells if the current one
+// should be emitted.
+func (d *debugDumps) sampled(kind string) (bool, int) {
+	d.Lock()
+	defer d.Unlock()
+
+	d.seen[kind]++
+	n := d.seen[kind]
+
+	if d.sampleRate > 1 && (n-1)%d.sampleRate != 0 {
+		return false, n
+	}
+	return true, n
+}
+
+// debugDump pretty-prints a data structure to the debug log, subject to the
+// configured sampling rate and size cap. The dumps are sampled by their kind,
+// such as refInstance, across all the regions and groups. The spew output is
+// only generated when debugging is enabled for the region or group, since that
+// alone is expensive for the large structures.
+func debugDump(regionName, groupName, kind string, v interface{}) {
+
+	if !dumps.enabled || !debugScopes.covers(regionName, groupName) {
+		return
+	}
+	l := debugScopes.loggerFor(regionName, groupName)
+
+	emit, n := dumps.sampled(kind)
+	if !emit {
+		return
+	}
+
+	out := spew.Sdump(v)
+
+	label := kind
+	if groupName != "" {
+		label = groupName + " " + kind
+	}
+
+	if dumps.bucket != nil {
+		key := fmt.Sprintf("%s/%s/%s-%d.txt",
+			time.Now().UTC().Format("2006-01-02T15-04-05"), regionName,
+			strings.Replace(label, " ", "/", 1), n)
+
+		if err := dumps.bucket.put(key, []byte(out)); err == nil {
+			l.Println(regionName, label, "dumped to",
+				"s3://"+dumps.bucket.name+"/"+key)
+			return
+		}
+		// fall through to logging the truncated output if the upload failed
+	}
+
+	l.Println(regionName, label, truncate(out, dumps.maxBytes))
+}
+
+// truncate cuts the string to at most max bytes, on a character boundary.
+func truncate(s string, max int) string {
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s... [truncated %d bytes]", s[:cut], len(s)-cut)
+}
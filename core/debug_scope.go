@@ -0,0 +1,85 @@
+package autospotting
+
+import (
+	"strings"
+	"sync"
+)
+
+var debugScopes debugScope
+
+type debugScope struct {
+	sync.Mutex
+
+	// debugging everything, as enabled by AUTOSPOTTING_DEBUG
+	all bool
+
+	groups  map[string]bool
+	regions map[string]bool
+
+	// writes the debug output of the groups and regions in scope
+	logger *levelLogger
+}
+
+// splitNames returns the set of names from a comma separated list.
+func splitNames(list string) map[string]bool {
+	names := make(map[string]bool)
+	for _, n := range strings.Split(list, ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			names[n] = true
+		}
+	}
+	return names
+}
+
+func (s *debugScope) init(cfg Config, sink *logSink, all bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.all = all
+	s.groups = splitNames(cfg.DebugGroups)
+	s.regions = splitNames(cfg.DebugRegions)
+	s.logger = newLogger(sink, LevelDebug)
+	s.logger.forced = true
+}
+
+// enabled tells if any debug output may be emitted.
+func (s *debugScope) enabled() bool {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.all || len(s.groups) > 0 || len(s.regions) > 0
+}
+
+// covers tells if the debug output of the group, from the given region, is
+// emitted. An empty group name stands for the region itself.
+func (s *debugScope) covers(region, group string) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.all || s.regions[region] || (group != "" && s.groups[group])
+}
+
+// loggerFor returns the debug logger of the group or region.
+func (s *debugScope) loggerFor(region, group string) *levelLogger {
+	if !s.covers(region, group) {
+		return discardLogger()
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if s.logger == nil {
+		return discardLogger()
+	}
+	return s.logger.With(map[string]string{"region": region, "asg": group})
+}
+
+// debugLog returns the debug logger of the group.
+func (a *autoScalingGroup) debugLog() *levelLogger {
+	return debugScopes.loggerFor(a.region.name, a.name)
+}
+
+// debugLog returns the debug logger of the region.
+func (r *region) debugLog() *levelLogger {
+	return debugScopes.loggerFor(r.name, "")
+}
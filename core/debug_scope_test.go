@@ -0,0 +1,47 @@
+package autospotting
+
+import (
+	"bytes"
+	"testing"
+)
+
+func Test_debugScope_loggerFor(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		all           bool
+		groups        string
+		regions       string
+		region, group string
+		want          bool
+	}{
+		{name: "disabled", region: "us-east-1", group: "web"},
+		{name: "everything", all: true, region: "us-east-1", group: "web",
+			want: true},
+		{name: "scoped group", groups: "api, web", region: "us-east-1",
+			group: "web", want: true},
+		{name: "other group", groups: "api", region: "us-east-1",
+			group: "web"},
+		{name: "region of a scoped group", groups: "web",
+			region: "us-east-1"},
+		{name: "scoped region", regions: "eu-west-1,us-east-1",
+			region: "us-east-1", group: "web", want: true},
+		{name: "other region", regions: "eu-west-1", region: "us-east-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			var s debugScope
+
+			cfg := Config{LogFile: &out, DebugGroups: tt.groups,
+				DebugRegions: tt.regions}
+			s.init(cfg, newLogSink(cfg, &out, LevelInfo), tt.all)
+			s.loggerFor(tt.region, tt.group).Println("details")
+
+			if got := out.Len() > 0; got != tt.want {
+				t.Errorf("loggerFor() logged %q, want output %v", out.String(),
+					tt.want)
+			}
+		})
+	}
+}
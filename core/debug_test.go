@@ -0,0 +1,136 @@
+package autospotting
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func Test_debugDumps_sampled(t *testing.T) {
+
+	var d debugDumps
+	d.init(Config{DebugSampleRate: 3}, true)
+
+	var emitted []bool
+	for i := 0; i < 4; i++ {
+		emit, _ := d.sampled("refInstance")
+		emitted = append(emitted, emit)
+	}
+
+	want := []bool{true, false, false, true}
+	for i := range want {
+		if emitted[i] != want[i] {
+			t.Errorf("sampled() of dump %d = %v, want %v", i+1, emitted[i],
+				want[i])
+		}
+	}
+}
+
+func Test_truncate(t *testing.T) {
+
+	tests := []struct {
+		name string
+		s    string
+		max  int
+		want string
+	}{
+		{name: "unlimited", s: "abcdef", want: "abcdef"},
+		{name: "short", s: "abc", max: 5, want: "abc"},
+		{name: "long", s: "abcdef", max: 4,
+			want: "abcd... [truncated 2 bytes]"},
+		{name: "multi-byte character", s: "ab€cd", max: 3,
+			want: "ab... [truncated 5 bytes]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncate(tt.s, tt.max); got != tt.want {
+				t.Errorf("truncate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_debugDump(t *testing.T) {
+
+	defer func() {
+		dumps = debugDumps{}
+		debugScopes = debugScope{}
+	}()
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		all      bool
+		bucket   bool
+		want     string
+		wantKeys int
+	}{
+		{name: "debugging disabled",
+			cfg: Config{DebugMaxBytes: 10},
+		},
+		{name: "other group",
+			cfg: Config{DebugGroups: "api", DebugMaxBytes: 10},
+		},
+		{name: "truncated dump",
+			cfg:  Config{DebugGroups: "web", DebugMaxBytes: 10},
+			want: "[truncated",
+		},
+		{name: "sampled out dump",
+			cfg: Config{DebugGroups: "web", DebugSampleRate: 2},
+			all: true,
+		},
+		{name: "uploaded dump",
+			cfg:      Config{DebugDumpBucket: "dumps"},
+			all:      true,
+			bucket:   true,
+			want:     "s3://dumps/",
+			wantKeys: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			tt.cfg.LogFile = &out
+
+			debugScopes = debugScope{}
+			debugScopes.init(tt.cfg, newLogSink(tt.cfg, &out, LevelInfo), tt.all)
+			dumps = debugDumps{}
+			dumps.init(tt.cfg, debugScopes.enabled())
+
+			var keys []string
+			if tt.bucket {
+				services, _ := fakeConnections(nil)
+				svc := s3.New(services.session)
+				svc.Handlers.Send.Clear()
+				svc.Handlers.Unmarshal.Clear()
+				svc.Handlers.UnmarshalMeta.Clear()
+				svc.Handlers.ValidateResponse.Clear()
+				svc.Handlers.Send.PushBack(func(r *request.Request) {
+					keys = append(keys, *r.Params.(*s3.PutObjectInput).Key)
+				})
+				dumps.bucket.svc = svc
+			}
+
+			// the second dump is the one sampled out at a rate of 2
+			if tt.cfg.DebugSampleRate > 1 {
+				debugDump("us-east-1", "web", "refInstance", struct{}{})
+				out.Reset()
+			}
+
+			debugDump("us-east-1", "web", "refInstance",
+				map[string]string{"m5.large": "2 vCPUs, 8 GiB of memory"})
+
+			if got := out.String(); tt.want == "" && got != "" ||
+				!strings.Contains(got, tt.want) {
+				t.Errorf("debugDump() logged %q, want %q", got, tt.want)
+			}
+			if len(keys) != tt.wantKeys {
+				t.Errorf("debugDump() uploaded %v, want %d objects", keys,
+					tt.wantKeys)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package autospotting
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// minimum number of spot instances launched in a pool before its interruption
+// frequency is considered meaningful
+const minInterruptionSamples = 3
+
+// spot request status codes set when the spot instance was interrupted
+var interruptionCodes = map[string]bool{
+	"instance-terminated-by-price":                true,
+	"instance-terminated-no-capacity":             true,
+	"instance-terminated-capacity-oversubscribed": true,
+	"instance-stopped-by-price":                   true,
+	"instance-stopped-no-capacity":                true,
+	"instance-stopped-capacity-oversubscribed":    true,
+	"marked-for-termination":                      true,
+	"marked-for-stop":                             true,
+}
+
+var denyList poolDenyList
+
+type poolDenyList struct {
+	sync.Mutex
+
+	table string
+	ttl   time.Duration
+
+	// expiration of the denied pools, keyed by region, instance type and
+	// availability zone
+	entries map[string]time.Time
+}
+
+// poolInterruptions counts the spot instances launched in a pool and how many
+// of them were interrupted.
+type poolInterruptions struct {
+	instanceType, az      string
+	launched, interrupted int
+}
+
+func (p poolInterruptions) frequency() float64 {
+	if p.launched == 0 {
+		return 0
+	}
+	return float64(p.interrupted) * 100 / float64(p.launched)
+}
+
+func denyListKey(region, instanceType, az string) string {
+	return region + "/" + poolKey(instanceType, az)
+}
+
+// load reads the pools still denied from the deny-list table, having a "pool"
+// string key and an "expires" number attribute usable as the DynamoDB TTL.
+func (d *poolDenyList) load(cfg Config) {
+	d.Lock()
+	defer d.Unlock()
+
+	d.table = cfg.DenyListTable
+	d.ttl = cfg.DenyListTTL
+	d.entries = make(map[string]time.Time)
+
+	if d.table == "" {
+		return
+	}
+
+	now := time.Now()
+
+	items, err := state.scan(d.table)
+	for _, item := range items {
+		pool, found := item.Keys["pool"]
+		epoch := time.Unix(int64(item.Numbers["expires"]), 0)
+		if !found || epoch.Before(now) {
+			continue
+		}
+		d.entries[pool] = epoch
+	}
+
+	if err != nil {
+		logger.at(LevelError).Println(
+			"Failed to load the spot pool deny-list from",
+			d.table, err.Error())
+	}
+}
+
+// denied tells if no bids should be placed in the pool.
+func (d *poolDenyList) denied(region, instanceType, az string) bool {
+	d.Lock()
+	defer d.Unlock()
+
+	expires, found := d.entries[denyListKey(region, instanceType, az)]
+	return found && time.Now().Before(expires)
+}
+
+// deny adds the pool to the deny-list, unless it's already denied.
+func (d *poolDenyList) deny(region, instanceType, az string) {
+
+	if d.denied(region, instanceType, az) {
+		return
+	}
+
+	key := denyListKey(region, instanceType, az)
+	expires := time.Now().Add(d.ttl)
+
+	d.Lock()
+	d.entries[key] = expires
+	table := d.table
+	d.Unlock()
+
+	if table == "" {
+		return
+	}
+
+	err := state.put(table, stateItem{
+		Keys:    map[string]string{"pool": key},
+		Numbers: map[string]float64{"expires": float64(expires.Unix())},
+	})
+	if err != nil {
+		logger.at(LevelError).Println("Failed to store", key,
+			"in the deny-list table", table, err.Error())
+	}
+}
+
+// countInterruptions aggregates the launched and interrupted spot instances of
+// the given spot requests by pool.
+func countInterruptions(
+	reqs []*ec2.SpotInstanceRequest) map[string]*poolInterruptions {
+
+	pools := make(map[string]*poolInterruptions)
+
+	for _, req := range reqs {
+
+		ls := req.LaunchSpecification
+		if req.InstanceId == nil || ls == nil || ls.InstanceType == nil ||
+			ls.Placement == nil || ls.Placement.AvailabilityZone == nil {
+			continue
+		}
+
+		key := poolKey(*ls.InstanceType, *ls.Placement.AvailabilityZone)
+		p := pools[key]
+		if p == nil {
+			p = &poolInterruptions{
+				instanceType: *ls.InstanceType,
+				az:           *ls.Placement.AvailabilityZone,
+			}
+			pools[key] = p
+		}
+
+		p.launched++
+		if req.Status != nil && req.Status.Code != nil &&
+			interruptionCodes[*req.Status.Code] {
+			p.interrupted++
+		}
+	}
+	return pools
+}
+
+// scanInterruptions denies the pools of the region whose interruption
+// frequency exceeds the configured threshold.
+func (r *region) scanInterruptions() {
+
+	if r.conf.InterruptionThreshold <= 0 {
+		return
+	}
+
+	for _, p := range countInterruptions(r.spotRequests) {
+		if p.launched < minInterruptionSamples ||
+			p.frequency() < r.conf.InterruptionThreshold {
+			continue
+		}
+		r.log().Println(r.name, "Denying", p.instanceType, "in", p.az, "for",
+			r.conf.DenyListTTL, "since", p.interrupted, "of its",
+			p.launched, "spot instances were interrupted")
+		denyList.deny(r.name, p.instanceType, p.az)
+	}
+	r.debugLog().Println(r.name, "Denied spot pools:", denyList.deniedPools(r.name))
+}
+
+// deniedPools lists the pools of the region currently denied, for debugging.
+func (d *poolDenyList) deniedPools(region string) []string {
+	d.Lock()
+	defer d.Unlock()
+
+	var pools []string
+	for key, expires := range d.entries {
+		if strings.HasPrefix(key, region+"/") && time.Now().Before(expires) {
+			pools = append(pools, strings.TrimPrefix(key, region+"/"))
+		}
+	}
+	return pools
+}
@@ -0,0 +1,97 @@
+package autospotting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_countInterruptions(t *testing.T) {
+
+	request := func(id *string, instanceType, az, code string) *ec2.SpotInstanceRequest {
+		return &ec2.SpotInstanceRequest{
+			InstanceId: id,
+			LaunchSpecification: &ec2.LaunchSpecification{
+				InstanceType: aws.String(instanceType),
+				Placement: &ec2.SpotPlacement{
+					AvailabilityZone: aws.String(az),
+				},
+			},
+			Status: &ec2.SpotInstanceStatus{Code: aws.String(code)},
+		}
+	}
+
+	reqs := []*ec2.SpotInstanceRequest{
+		request(aws.String("i-1"), "m5.large", "us-east-1a", "fulfilled"),
+		request(aws.String("i-2"), "m5.large", "us-east-1a",
+			"instance-terminated-no-capacity"),
+		request(aws.String("i-3"), "m5.large", "us-east-1a",
+			"marked-for-termination"),
+		request(aws.String("i-4"), "m5.large", "us-east-1b", "fulfilled"),
+		request(nil, "m5.large", "us-east-1b", "capacity-not-available"),
+	}
+
+	pools := countInterruptions(reqs)
+
+	tests := []struct {
+		name                  string
+		pool                  string
+		launched, interrupted int
+		frequency             float64
+	}{
+		{
+			name:        "frequently interrupted pool",
+			pool:        poolKey("m5.large", "us-east-1a"),
+			launched:    3,
+			interrupted: 2,
+			frequency:   200.0 / 3,
+		},
+		{
+			name:     "unfulfilled requests aren't counted",
+			pool:     poolKey("m5.large", "us-east-1b"),
+			launched: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := pools[tt.pool]
+			if p == nil {
+				t.Fatalf("missing pool %s", tt.pool)
+			}
+			if p.launched != tt.launched || p.interrupted != tt.interrupted {
+				t.Errorf("got %d launched, %d interrupted, want %d, %d",
+					p.launched, p.interrupted, tt.launched, tt.interrupted)
+			}
+			if p.frequency() != tt.frequency {
+				t.Errorf("frequency() = %v, want %v", p.frequency(), tt.frequency)
+			}
+		})
+	}
+}
+
+func Test_poolDenyList(t *testing.T) {
+
+	d := &poolDenyList{}
+	d.load(Config{DenyListTTL: time.Hour})
+
+	d.deny("us-east-1", "m5.large", "us-east-1a")
+
+	if !d.denied("us-east-1", "m5.large", "us-east-1a") {
+		t.Errorf("denied() = false, want true for the denied pool")
+	}
+	if d.denied("us-east-1", "m5.large", "us-east-1b") {
+		t.Errorf("denied() = true, want false for another pool")
+	}
+	if d.denied("eu-west-1", "m5.large", "us-east-1a") {
+		t.Errorf("denied() = true, want false for another region")
+	}
+
+	expired := &poolDenyList{}
+	expired.load(Config{DenyListTTL: -time.Minute})
+	expired.deny("us-east-1", "m5.large", "us-east-1a")
+	if expired.denied("us-east-1", "m5.large", "us-east-1a") {
+		t.Errorf("denied() = true, want false for an expired entry")
+	}
+}
@@ -0,0 +1,164 @@
+package autospotting
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/codedeploy"
+	"github.com/aws/aws-sdk-go/service/elasticbeanstalk"
+)
+
+// BatchGetDeployments accepts at most this many deployment IDs per call
+const codeDeployBatchSize = 25
+
+// scanActiveDeployments finds the AutoScaling groups targeted by the CodeDeploy
+// deployments currently in progress in the region.
+func (r *region) scanActiveDeployments() {
+
+	r.deployingASGs = make(map[string]bool)
+
+	svc := r.services.codeDeploy
+
+	var deploymentIDs []*string
+
+	err := svc.ListDeploymentsPages(
+		&codedeploy.ListDeploymentsInput{
+			IncludeOnlyStatuses: []*string{
+				aws.String(codedeploy.DeploymentStatusCreated),
+				aws.String(codedeploy.DeploymentStatusQueued),
+				aws.String(codedeploy.DeploymentStatusInProgress),
+				aws.String(codedeploy.DeploymentStatusReady),
+			},
+		},
+		func(page *codedeploy.ListDeploymentsOutput, lastPage bool) bool {
+			deploymentIDs = append(deploymentIDs, page.Deployments...)
+			return true
+		},
+	)
+
+	if err != nil {
+		r.log().at(LevelError).Println(r.name,
+			"Failed to list CodeDeploy deployments", err.Error())
+		return
+	}
+
+	for start := 0; start < len(deploymentIDs); start += codeDeployBatchSize {
+
+		end := min(start+codeDeployBatchSize, len(deploymentIDs))
+
+		resp, err := svc.BatchGetDeployments(&codedeploy.BatchGetDeploymentsInput{
+			DeploymentIds: deploymentIDs[start:end],
+		})
+
+		if err != nil {
+			r.log().at(LevelError).Println(r.name,
+				"Failed to describe CodeDeploy deployments", err.Error())
+			continue
+		}
+
+		for _, d := range resp.DeploymentsInfo {
+			r.addDeploymentTargets(d)
+		}
+	}
+
+	for name := range r.deployingASGs {
+		r.log().Println(r.name, "Found ongoing CodeDeploy deployment for", name)
+	}
+}
+
+// scanUpdatingEnvironments finds the Elastic Beanstalk environments of the
+// region currently being launched or updated, when any of the enabled groups
+// belongs to an environment.
+func (r *region) scanUpdatingEnvironments() {
+
+	r.deployingEnvironments = make(map[string]string)
+
+	environments := false
+	for i := range r.enabledASGs {
+		environments = environments ||
+			r.enabledASGs[i].getTagValue("elasticbeanstalk:environment-id") != nil
+	}
+	if !environments {
+		return
+	}
+
+	input := &elasticbeanstalk.DescribeEnvironmentsInput{
+		IncludeDeleted: aws.Bool(false),
+	}
+
+	for {
+		resp, err := r.services.elasticBeanstalk.DescribeEnvironments(input)
+
+		if err != nil {
+			r.log().at(LevelError).Println(r.name,
+				"Failed to describe Elastic Beanstalk environments", err.Error())
+			return
+		}
+
+		for _, env := range resp.Environments {
+			if env.EnvironmentId != nil && env.Status != nil &&
+				(*env.Status == elasticbeanstalk.EnvironmentStatusLaunching ||
+					*env.Status == elasticbeanstalk.EnvironmentStatusUpdating) {
+				r.deployingEnvironments[*env.EnvironmentId] = *env.Status
+			}
+		}
+
+		if aws.StringValue(resp.NextToken) == "" {
+			return
+		}
+		input.NextToken = resp.NextToken
+	}
+}
+
+func (r *region) addDeploymentTargets(d *codedeploy.DeploymentInfo) {
+
+	// blue/green deployments explicitly list the groups they copy
+	if d.TargetInstances != nil {
+		for _, name := range d.TargetInstances.AutoScalingGroups {
+			r.deployingASGs[*name] = true
+		}
+	}
+
+	if d.ApplicationName == nil || d.DeploymentGroupName == nil {
+		return
+	}
+
+	resp, err := r.services.codeDeploy.GetDeploymentGroup(
+		&codedeploy.GetDeploymentGroupInput{
+			ApplicationName:     d.ApplicationName,
+			DeploymentGroupName: d.DeploymentGroupName,
+		})
+
+	if err != nil {
+		r.log().at(LevelError).Println(r.name,
+			"Failed to describe CodeDeploy deployment group",
+			*d.DeploymentGroupName, err.Error())
+		return
+	}
+
+	for _, asg := range resp.DeploymentGroupInfo.AutoScalingGroups {
+		if asg.Name != nil {
+			r.deployingASGs[*asg.Name] = true
+		}
+	}
+}
+
+// isBeingDeployed tells if the group is targeted by an ongoing CodeDeploy
+// deployment or belongs to an Elastic Beanstalk environment being updated.
+func (a *autoScalingGroup) isBeingDeployed() bool {
+
+	if a.region.deployingASGs[a.name] {
+		a.log().Println(a.name, "is targeted by an ongoing CodeDeploy deployment")
+		return true
+	}
+
+	envID := a.getTagValue("elasticbeanstalk:environment-id")
+	if envID == nil {
+		return false
+	}
+
+	if status, found := a.region.deployingEnvironments[*envID]; found {
+		a.log().Println(a.name, "belongs to the Elastic Beanstalk environment",
+			*envID, "which is currently", status)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package autospotting
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/codedeploy"
+	"github.com/aws/aws-sdk-go/service/elasticbeanstalk"
+)
+
+func Test_autoScalingGroup_isBeingDeployed(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		deployments []*codedeploy.DeploymentInfo
+		envStatus   string
+		failing     bool
+		want        map[string]bool
+	}{
+		{name: "idle",
+			envStatus: elasticbeanstalk.EnvironmentStatusReady,
+			want:      map[string]bool{"web": false, "api": false, "eb": false},
+		},
+		{name: "in progress",
+			deployments: []*codedeploy.DeploymentInfo{
+				{TargetInstances: &codedeploy.TargetInstances{
+					AutoScalingGroups: []*string{aws.String("web")},
+				}},
+				{
+					ApplicationName:     aws.String("app"),
+					DeploymentGroupName: aws.String("api-group"),
+				},
+			},
+			envStatus: elasticbeanstalk.EnvironmentStatusUpdating,
+			want:      map[string]bool{"web": true, "api": true, "eb": true},
+		},
+		{name: "API errors",
+			deployments: []*codedeploy.DeploymentInfo{
+				{TargetInstances: &codedeploy.TargetInstances{
+					AutoScalingGroups: []*string{aws.String("web")},
+				}},
+			},
+			envStatus: elasticbeanstalk.EnvironmentStatusUpdating,
+			failing:   true,
+			want:      map[string]bool{"web": false, "api": false, "eb": false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			services, calls := fakeConnections(func(r *request.Request) {
+				if tt.failing {
+					r.Error = awserr.New("AccessDeniedException", "not allowed", nil)
+					return
+				}
+				switch out := r.Data.(type) {
+				case *codedeploy.ListDeploymentsOutput:
+					for range tt.deployments {
+						out.Deployments = append(out.Deployments,
+							aws.String("d-1"))
+					}
+				case *codedeploy.BatchGetDeploymentsOutput:
+					out.DeploymentsInfo = tt.deployments
+				case *codedeploy.GetDeploymentGroupOutput:
+					out.DeploymentGroupInfo = &codedeploy.DeploymentGroupInfo{
+						AutoScalingGroups: []*codedeploy.AutoScalingGroup{
+							{Name: aws.String("api")},
+						},
+					}
+				case *elasticbeanstalk.EnvironmentDescriptionsMessage:
+					out.Environments = []*elasticbeanstalk.EnvironmentDescription{{
+						EnvironmentId: aws.String("e-1"),
+						Status:        aws.String(tt.envStatus),
+					}}
+				}
+			})
+
+			r := &region{name: "us-east-1", services: services}
+			for _, name := range []string{"web", "api", "eb"} {
+				group := &autoscaling.Group{}
+				if name == "eb" {
+					group.Tags = []*autoscaling.TagDescription{{
+						Key:   aws.String("elasticbeanstalk:environment-id"),
+						Value: aws.String("e-1"),
+					}}
+				}
+				r.enabledASGs = append(r.enabledASGs,
+					autoScalingGroup{Group: group, name: name, region: r})
+			}
+
+			r.scanActiveDeployments()
+			r.scanUpdatingEnvironments()
+			scanned := len(*calls)
+
+			for i := range r.enabledASGs {
+				a := &r.enabledASGs[i]
+				if got := a.isBeingDeployed(); got != tt.want[a.name] {
+					t.Errorf("isBeingDeployed() of %s = %v, want %v", a.name,
+						got, tt.want[a.name])
+				}
+			}
+
+			if len(*calls) != scanned {
+				t.Errorf("isBeingDeployed() made the calls %v, want none",
+					(*calls)[scanned:])
+			}
+		})
+	}
+}
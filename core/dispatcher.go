@@ -0,0 +1,112 @@
+package autospotting
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+// payload of the worker invocations, as parsed by the Lambda handler
+type dispatchEvent struct {
+	ID                string   `json:"id"`
+	Regions           []string `json:"regions"`
+	AutoScalingGroups []string `json:"autoscaling_groups,omitempty"`
+}
+
+// Dispatch invokes the worker function for each enabled region, or for each
+// batch of up to DispatchBatchSize enabled groups of a region.
+func Dispatch(cfg Config) {
+
+	initLogging(cfg)
+	state = newStateStore(cfg)
+	migrateState(stateTables(cfg))
+
+	if !claimDispatch(cfg) {
+		return
+	}
+
+	regions, err := warmCache.regions(cfg.WarmCacheTTL)
+	if err != nil {
+		logger.at(LevelError).Println(err.Error())
+		return
+	}
+
+	svc := lambda.New(instrumentSession(
+		homeSession()))
+
+	var wg sync.WaitGroup
+
+	for _, name := range regions {
+
+		r := &region{name: name, conf: cfg}
+		if !r.enabled() {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for _, batch := range r.dispatchBatches() {
+				invokeWorker(svc, cfg, dispatchEvent{
+					ID:                cfg.EventID,
+					Regions:           []string{r.name},
+					AutoScalingGroups: batch,
+				})
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// dispatchBatches splits the enabled groups of the region in batches, when
+// batching is enabled, otherwise the whole region is a single batch.
+func (r *region) dispatchBatches() [][]string {
+
+	if r.conf.DispatchBatchSize <= 0 {
+		return [][]string{nil}
+	}
+
+	r.services.connect(r.name)
+
+	var asgNames []*string
+	r.scanForEnabledAutoScalingGroupsByTag(&asgNames)
+
+	return batchNames(asgNames, r.conf.DispatchBatchSize)
+}
+
+func batchNames(names []*string, size int) [][]string {
+	var batches [][]string
+	for i, name := range names {
+		if i%size == 0 {
+			batches = append(batches, nil)
+		}
+		batches[len(batches)-1] = append(batches[len(batches)-1], *name)
+	}
+	return batches
+}
+
+func invokeWorker(svc *lambda.Lambda, cfg Config, event dispatchEvent) {
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		logger.at(LevelError).Println("Couldn't encode the worker event",
+			err.Error())
+		return
+	}
+
+	_, err = svc.Invoke(&lambda.InvokeInput{
+		FunctionName:   aws.String(cfg.WorkerFunction),
+		InvocationType: aws.String(lambda.InvocationTypeEvent),
+		Payload:        payload,
+	})
+
+	if err != nil {
+		logger.at(LevelError).Println("Failed to invoke", cfg.WorkerFunction,
+			"for", string(payload), err.Error())
+		return
+	}
+	logger.Println("Invoked", cfg.WorkerFunction, "for", string(payload))
+}
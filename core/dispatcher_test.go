@@ -0,0 +1,41 @@
+package autospotting
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func Test_batchNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		names []string
+		size  int
+		want  [][]string
+	}{
+		{name: "No groups",
+			names: nil,
+			size:  2,
+			want:  nil,
+		},
+		{name: "Last batch is partial",
+			names: []string{"a", "b", "c"},
+			size:  2,
+			want:  [][]string{{"a", "b"}, {"c"}},
+		},
+		{name: "Single batch",
+			names: []string{"a", "b"},
+			size:  5,
+			want:  [][]string{{"a", "b"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := batchNames(aws.StringSlice(tt.names),
+				tt.size); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("batchNames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package autospotting
+
+import (
+	"sort"
+)
+
+// spotPool is a candidate spot pool, with the number of the group's spot
+// instances already running or being launched in it.
+type spotPool struct {
+	instanceType     string
+	availabilityZone string
+	price            float64
+	usage            int64
+}
+
+// diversification returns the number of the cheapest instance types the
+// group's spot instances are spread across, diversification being disabled
+// for less than two.
+func (a *autoScalingGroup) diversification() int {
+	return int(a.numberSetting("spot_diversification"))
+}
+
+// cheapestTypes returns the n cheapest instance types, ordered by price and
+// then by name.
+func cheapestTypes(prices map[string]float64, n int) []string {
+
+	var types []string
+	for t := range prices {
+		types = append(types, t)
+	}
+
+	sort.Slice(types, func(i, j int) bool {
+		if prices[types[i]] != prices[types[j]] {
+			return prices[types[i]] < prices[types[j]]
+		}
+		return types[i] < types[j]
+	})
+
+	if len(types) > n {
+		types = types[:n]
+	}
+	return types
+}
+
+// leastUsedPool returns the pool having the fewest of the group's spot
+// instances, the cheapest one among those, and false if there are no pools.
+func leastUsedPool(pools []spotPool) (spotPool, bool) {
+
+	if len(pools) == 0 {
+		return spotPool{}, false
+	}
+
+	best := pools[0]
+	for _, p := range pools[1:] {
+		if p.usage < best.usage ||
+			(p.usage == best.usage && p.price < best.price) ||
+			(p.usage == best.usage && p.price == best.price &&
+				p.availabilityZone+p.instanceType <
+					best.availabilityZone+best.instanceType) {
+			best = p
+		}
+	}
+	return best, true
+}
+
+// candidatePools returns the pools of the n cheapest instance types of the
+// availability zone, with the group's current usage of each of them.
+func (a *autoScalingGroup) candidatePools(availabilityZone string,
+	prices map[string]float64, n int) []spotPool {
+
+	var pools []spotPool
+	for _, t := range cheapestTypes(prices, n) {
+		pools = append(pools, spotPool{
+			instanceType:     t,
+			availabilityZone: availabilityZone,
+			price:            prices[t],
+			usage: a.alreadyRunningSpotInstanceCount(t, availabilityZone) +
+				a.pendingPoolLaunches(t, availabilityZone),
+		})
+	}
+	return pools
+}
+
+// diversifiedInstanceType returns the instance type of the least used pool
+// among those of the cheapest instance types of the availability zone, or the
+// chosen instance type when diversification is disabled.
+func (a *autoScalingGroup) diversifiedInstanceType(availabilityZone,
+	chosen string, prices map[string]float64) string {
+
+	n := a.diversification()
+	if n < 2 {
+		return chosen
+	}
+
+	pool, found := leastUsedPool(a.candidatePools(availabilityZone, prices, n))
+	if !found {
+		return chosen
+	}
+
+	if pool.instanceType != chosen {
+		a.log().Println(a.name, "Diversifying to", pool.instanceType, "in",
+			availabilityZone, "priced at", pool.price, "having", pool.usage,
+			"spot instances, instead of", chosen)
+	}
+	return pool.instanceType
+}
+
+// diversifiedAvailabilityZone returns the availability zone of the least used
+// pool among those of the cheapest instance types in the availability zones of
+// the group's on-demand instances, or the given availability zone when
+// diversification is disabled.
+func (a *autoScalingGroup) diversifiedAvailabilityZone(az *string) *string {
+
+	n := a.diversification()
+	if n < 2 {
+		return az
+	}
+
+	var pools []spotPool
+	seen := make(map[string]bool)
+
+	for _, inst := range a.instances.catalog {
+
+		if inst.isSpot() || inst.Placement == nil ||
+			inst.Placement.AvailabilityZone == nil ||
+			seen[*inst.Placement.AvailabilityZone] {
+			continue
+		}
+		zone := *inst.Placement.AvailabilityZone
+		seen[zone] = true
+
+		base := a.findOndemandInstanceInAZ(&zone)
+		if base == nil {
+			continue
+		}
+
+		types, err := a.getCompatibleSpotInstanceTypes(zone, base)
+		if err != nil {
+			continue
+		}
+		pools = append(pools, a.candidatePools(zone,
+			a.spotCandidatePrices(zone, base, types), n)...)
+	}
+
+	pool, found := leastUsedPool(pools)
+	if !found {
+		return az
+	}
+
+	if az == nil || pool.availabilityZone != *az {
+		a.log().Println(a.name, "Diversifying to", pool.availabilityZone,
+			"having the least used pool", pool.instanceType)
+	}
+	zone := pool.availabilityZone
+	return &zone
+}
@@ -0,0 +1,83 @@
+package autospotting
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_cheapestTypes(t *testing.T) {
+
+	prices := map[string]float64{
+		"m5.large":  0.04,
+		"c5.large":  0.03,
+		"m4.large":  0.04,
+		"r5.large":  0.05,
+		"t3.xlarge": 0.06,
+	}
+
+	tests := []struct {
+		name string
+		n    int
+		want []string
+	}{
+		{name: "two cheapest", n: 2, want: []string{"c5.large", "m4.large"}},
+		{name: "more than available", n: 10,
+			want: []string{"c5.large", "m4.large", "m5.large", "r5.large",
+				"t3.xlarge"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cheapestTypes(prices, tt.n); !reflect.DeepEqual(got,
+				tt.want) {
+				t.Errorf("cheapestTypes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_leastUsedPool(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		pools     []spotPool
+		want      spotPool
+		wantFound bool
+	}{
+		{name: "no pools"},
+		{
+			name: "least used",
+			pools: []spotPool{
+				{instanceType: "c5.large", availabilityZone: "us-east-1a",
+					price: 0.03, usage: 2},
+				{instanceType: "m5.large", availabilityZone: "us-east-1a",
+					price: 0.04, usage: 1},
+				{instanceType: "c5.large", availabilityZone: "us-east-1b",
+					price: 0.035, usage: 1},
+			},
+			want: spotPool{instanceType: "c5.large",
+				availabilityZone: "us-east-1b", price: 0.035, usage: 1},
+			wantFound: true,
+		},
+		{
+			name: "unused pools tied on price",
+			pools: []spotPool{
+				{instanceType: "m5.large", availabilityZone: "us-east-1b",
+					price: 0.04},
+				{instanceType: "m5.large", availabilityZone: "us-east-1a",
+					price: 0.04},
+			},
+			want: spotPool{instanceType: "m5.large",
+				availabilityZone: "us-east-1a", price: 0.04},
+			wantFound: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := leastUsedPool(tt.pools)
+			if found != tt.wantFound || got != tt.want {
+				t.Errorf("leastUsedPool() = %+v, %v, want %+v, %v", got, found,
+					tt.want, tt.wantFound)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package autospotting
+
+import (
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// dryRun tells if the group is processed in dry-run mode.
+func (a *autoScalingGroup) dryRun() bool {
+	return a.boolSetting("dry_run")
+}
+
+// skipsCall logs the API call and its input instead of making it when the
+// group is processed in dry-run mode, telling if the call should be skipped.
+func (a *autoScalingGroup) skipsCall(api string, input interface{}) bool {
+
+	if !a.dryRun() {
+		return false
+	}
+
+	a.log().Println(a.region.name, a.name, "DRY RUN: would call", api, "with",
+		input)
+	a.recordAction("dry-run", "would call", api)
+	return true
+}
+
+// tagInstance tags one of the instances handled for the group, unless in
+// dry-run mode.
+func (a *autoScalingGroup) tagInstance(instanceID *string, tags []*ec2.Tag) {
+
+	if a.skipsCall("CreateTags", &ec2.CreateTagsInput{
+		Resources: []*string{instanceID},
+		Tags:      tags,
+	}) {
+		return
+	}
+	a.region.tagInstance(instanceID, tags)
+}
+
+// tagInstanceVolumes tags the volumes of one of the instances handled for the
+// group, unless in dry-run mode.
+func (a *autoScalingGroup) tagInstanceVolumes(instanceID *string,
+	tags []*ec2.Tag, deviceTags map[string][]*ec2.Tag) {
+
+	if a.skipsCall("CreateTags", &ec2.CreateTagsInput{
+		Resources: []*string{instanceID},
+		Tags:      tags,
+	}) {
+		return
+	}
+	a.region.tagInstanceVolumes(instanceID, tags, deviceTags)
+}
+
+// terminateInstance terminates one of the instances handled for the group,
+// unless in dry-run mode.
+func (a *autoScalingGroup) terminateInstance(inst *instance) {
+
+	if a.skipsCall("TerminateInstances", &ec2.TerminateInstancesInput{
+		InstanceIds: []*string{inst.InstanceId},
+	}) {
+		return
+	}
+	inst.terminate(a.region.services.ec2)
+}
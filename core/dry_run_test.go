@@ -0,0 +1,276 @@
+package autospotting
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/codedeploy"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elasticbeanstalk"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+func Test_autoScalingGroup_skipsCall(t *testing.T) {
+	actions = actionHistory{}
+	actions.init(Config{HistorySize: 10})
+
+	tests := []struct {
+		name   string
+		global bool
+		tag    *string
+		want   bool
+	}{
+		{name: "disabled", want: false},
+		{name: "enabled globally", global: true, want: true},
+		{name: "enabled for the group", tag: aws.String("true"), want: true},
+		{name: "disabled for the group", global: true, tag: aws.String("false"),
+			want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := &autoscaling.Group{}
+			if tt.tag != nil {
+				group.Tags = []*autoscaling.TagDescription{
+					{Key: aws.String("dry_run"), Value: tt.tag},
+				}
+			}
+			a := autoScalingGroup{
+				Group:  group,
+				name:   "web",
+				region: &region{name: "us-east-1", conf: Config{DryRun: tt.global}},
+			}
+
+			if got := a.skipsCall("AttachInstances",
+				&autoscaling.AttachInstancesInput{}); got != tt.want {
+				t.Errorf("skipsCall() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_autoScalingGroup_skipsCall_logsInput(t *testing.T) {
+	actions = actionHistory{}
+	actions.init(Config{HistorySize: 10})
+
+	var out bytes.Buffer
+	logger = newLogger(newLogSink(Config{}, &out, LevelInfo), LevelInfo)
+	defer func() { logger = discardLogger() }()
+
+	a := autoScalingGroup{
+		Group:  &autoscaling.Group{},
+		name:   "web",
+		region: &region{name: "us-east-1", conf: Config{DryRun: true}},
+	}
+
+	a.skipsCall("DetachInstances", &autoscaling.DetachInstancesInput{
+		AutoScalingGroupName: aws.String("web"),
+		InstanceIds:          []*string{aws.String("i-0123abcd")},
+	})
+
+	for _, want := range []string{"DRY RUN: would call DetachInstances",
+		"i-0123abcd"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("skipsCall() logged %q, want it to contain %q",
+				out.String(), want)
+		}
+	}
+}
+
+// fakeConnections returns service clients answering every API call locally,
+// filling the responses using respond and recording the operation names.
+func fakeConnections(respond func(r *request.Request)) (connections,
+	*[]string) {
+
+	var calls []string
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	}))
+
+	// the protocol handlers are added by each client
+	fake := func(h *request.Handlers) {
+		h.Sign.Clear()
+		h.Send.Clear()
+		h.ValidateResponse.Clear()
+		h.UnmarshalMeta.Clear()
+		h.Unmarshal.Clear()
+		h.UnmarshalError.Clear()
+		h.Send.PushBack(func(r *request.Request) {
+			calls = append(calls, r.ClientInfo.ServiceName+":"+r.Operation.Name)
+			if respond != nil {
+				respond(r)
+			}
+		})
+	}
+
+	c := connections{
+		session:          sess,
+		autoScaling:      autoscaling.New(sess),
+		ec2:              ec2.New(sess),
+		codeDeploy:       codedeploy.New(sess),
+		elasticBeanstalk: elasticbeanstalk.New(sess),
+		ssm:              ssm.New(sess),
+		elbv2:            elbv2.New(sess),
+		region:           "us-east-1",
+	}
+	fake(&c.autoScaling.Handlers)
+	fake(&c.ec2.Handlers)
+	fake(&c.codeDeploy.Handlers)
+	fake(&c.elasticBeanstalk.Handlers)
+	fake(&c.ssm.Handlers)
+	fake(&c.elbv2.Handlers)
+
+	return c, &calls
+}
+
+func Test_autoScalingGroup_dryRunReplacement(t *testing.T) {
+	actions = actionHistory{}
+	actions.init(Config{HistorySize: 100})
+
+	respond := func(r *request.Request) {
+		switch out := r.Data.(type) {
+		case *ec2.DescribeAddressesOutput:
+			out.Addresses = []*ec2.Address{{
+				AllocationId:       aws.String("eipalloc-1"),
+				PublicIp:           aws.String("203.0.113.1"),
+				NetworkInterfaceId: aws.String("eni-primary"),
+			}}
+		case *elbv2.DescribeTargetGroupsOutput:
+			out.TargetGroups = []*elbv2.TargetGroup{{
+				TargetGroupArn: aws.String("arn:tg"),
+				TargetType:     aws.String(elbv2.TargetTypeEnumIp),
+			}}
+		}
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		minSize int64
+	}{
+		{name: "detaching first", minSize: 1},
+		{name: "attaching first", minSize: 2},
+		{name: "using Standby", method: "standby", minSize: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			services, calls := fakeConnections(respond)
+
+			odInst := &instance{
+				Instance: &ec2.Instance{
+					InstanceId:       aws.String("i-od"),
+					InstanceType:     aws.String("m4.large"),
+					State:            &ec2.InstanceState{Name: aws.String("running")},
+					Placement:        &ec2.Placement{AvailabilityZone: aws.String("us-east-1a")},
+					PrivateIpAddress: aws.String("10.0.0.1"),
+					RootDeviceName:   aws.String("/dev/xvda"),
+					BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{{
+						DeviceName: aws.String("/dev/sdf"),
+						Ebs:        &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-1")},
+					}},
+					NetworkInterfaces: []*ec2.InstanceNetworkInterface{
+						{
+							NetworkInterfaceId: aws.String("eni-primary"),
+							Attachment: &ec2.InstanceNetworkInterfaceAttachment{
+								AttachmentId: aws.String("eni-attach-0"),
+								DeviceIndex:  aws.Int64(0),
+							},
+						},
+						{
+							NetworkInterfaceId: aws.String("eni-secondary"),
+							Attachment: &ec2.InstanceNetworkInterfaceAttachment{
+								AttachmentId: aws.String("eni-attach-1"),
+								DeviceIndex:  aws.Int64(1),
+							},
+						},
+					},
+					Tags: []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("web")}},
+				},
+				price: 0.1,
+			}
+			spotInst := &instance{
+				Instance: &ec2.Instance{
+					InstanceId:            aws.String("i-spot"),
+					InstanceType:          aws.String("m5.large"),
+					InstanceLifecycle:     aws.String("spot"),
+					State:                 &ec2.InstanceState{Name: aws.String("running")},
+					Placement:             &ec2.Placement{AvailabilityZone: aws.String("us-east-1a")},
+					PrivateIpAddress:      aws.String("10.0.0.2"),
+					SpotInstanceRequestId: aws.String("sir-1"),
+				},
+				price: 0.03,
+			}
+
+			r := &region{
+				name: "us-east-1",
+				conf: Config{
+					ReplacementMethod: tt.method,
+					AttachGracePeriod: time.Minute,
+				},
+				instances: instances{catalog: map[string]*instance{
+					"i-od":   odInst,
+					"i-spot": spotInst,
+				}},
+				services: services,
+			}
+
+			a := autoScalingGroup{
+				Group: &autoscaling.Group{
+					AutoScalingGroupName:   aws.String("web"),
+					MinSize:                aws.Int64(tt.minSize),
+					MaxSize:                aws.Int64(2),
+					DesiredCapacity:        aws.Int64(2),
+					HealthCheckGracePeriod: aws.Int64(300),
+					TargetGroupARNs:        []*string{aws.String("arn:tg")},
+					Instances: []*autoscaling.Instance{{
+						InstanceId:     aws.String("i-od"),
+						LifecycleState: aws.String(autoscaling.LifecycleStateInService),
+						HealthStatus:   aws.String("Healthy"),
+					}},
+					Tags: []*autoscaling.TagDescription{
+						{Key: aws.String("dry_run"), Value: aws.String("true")},
+						{Key: aws.String("replacement_profile"),
+							Value: aws.String("stateful")},
+						{Key: aws.String("benchmark_document"),
+							Value: aws.String("benchmark")},
+					},
+				},
+				name:   "web",
+				region: r,
+				instances: instances{catalog: map[string]*instance{
+					"i-od": odInst,
+				}},
+			}
+
+			a.replaceOnDemandInstanceWithSpot(aws.String("i-spot"))
+
+			for _, call := range *calls {
+				operation := call[strings.Index(call, ":")+1:]
+				if !strings.HasPrefix(operation, "Describe") {
+					t.Errorf("made the mutating API call %s in dry-run mode", call)
+				}
+			}
+
+			skipped := 0
+			for _, e := range actions.get("web", "us-east-1") {
+				if e.Action == "dry-run" {
+					skipped++
+				}
+			}
+			if skipped == 0 {
+				t.Errorf("no API calls were logged in dry-run mode")
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package autospotting
+
+import (
+	"bytes"
+	"html/template"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ses"
+)
+
+const defaultDigestTemplate = `<html>
+<body>
+<h2>AutoSpotting digest</h2>
+{{if .Savings}}
+<h3>Hourly savings: {{money .TotalHourlySavings 4}}</h3>
+<table border="1" cellpadding="4">
+<tr><th>Region</th><th>AutoScaling group</th><th>Hourly savings</th></tr>
+{{range .Savings}}<tr><td>{{.Region}}</td><td>{{.AutoScalingGroup}}</td><td>{{money .HourlySavings 4}}</td></tr>
+{{end}}</table>
+{{end}}
+{{with .Trend}}
+<h3>Monthly savings</h3>
+<table border="1" cellpadding="4">
+<tr><th>Month</th><th>Days</th><th>Estimated savings</th></tr>
+{{range .Monthly}}<tr><td>{{.Period}}</td><td>{{.Days}}</td><td>{{money .Savings 2}}</td></tr>
+{{end}}</table>
+{{end}}
+{{if .Events}}
+<h3>Events</h3>
+<table border="1" cellpadding="4">
+<tr><th>Time</th><th>Region</th><th>AutoScaling group</th><th>Event</th><th>Details</th></tr>
+{{range .Events}}<tr><td>{{.Time.Format "2006-01-02 15:04:05"}}</td><td>{{.Region}}</td><td>{{.AutoScalingGroup}}</td><td>{{.Event}}</td><td>{{.Message}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`
+
+// digestFuncs are the functions available to the digest templates.
+var digestFuncs = template.FuncMap{
+	"money": currency.format,
+}
+
+// emailDigest is the data the digest template is executed on.
+type emailDigest struct {
+	Events             []notification
+	Savings            []notification
+	TotalHourlySavings float64
+
+	// historic savings, when a savings table is configured
+	Trend *savingsTrend
+}
+
+// loadDigestTemplate parses the given template file, falling back to the
+// default template if none was given or it's invalid.
+func loadDigestTemplate(file string) *template.Template {
+
+	if file != "" {
+		t, err := template.New(filepath.Base(file)).Funcs(digestFuncs).
+			ParseFiles(file)
+		if err == nil {
+			return t
+		}
+		logger.at(LevelWarn).Println("Couldn't load the email template", file,
+			err.Error(), "using the default template")
+	}
+	return template.Must(template.New("digest").Funcs(digestFuncs).
+		Parse(defaultDigestTemplate))
+}
+
+type emailSink struct {
+	sync.Mutex
+
+	recipient, sender, region string
+	template                  *template.Template
+
+	digest emailDigest
+}
+
+func (s *emailSink) send(n notification) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if n.Event == "savings" {
+		s.digest.Savings = append(s.digest.Savings, n)
+		s.digest.TotalHourlySavings += n.HourlySavings
+		return nil
+	}
+	s.digest.Events = append(s.digest.Events, n)
+	return nil
+}
+
+// render returns the HTML digest of the collected notifications, or nothing if
+// there were no events and no savings.
+func (s *emailSink) render() (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	d := s.digest
+	if len(d.Events) == 0 && d.TotalHourlySavings == 0 {
+		return "", nil
+	}
+	d.Trend = savingsHistory.trend
+
+	sort.SliceStable(d.Events, func(i, j int) bool {
+		return d.Events[i].Time.Before(d.Events[j].Time)
+	})
+	sort.SliceStable(d.Savings, func(i, j int) bool {
+		return d.Savings[i].HourlySavings > d.Savings[j].HourlySavings
+	})
+
+	var body bytes.Buffer
+	if err := s.template.Execute(&body, d); err != nil {
+		return "", err
+	}
+	return body.String(), nil
+}
+
+func (s *emailSink) flush() error {
+
+	body, err := s.render()
+
+	s.Lock()
+	s.digest = emailDigest{}
+	s.Unlock()
+
+	if err != nil || body == "" {
+		return err
+	}
+
+	svc := ses.New(instrumentSession(
+		newSession(&aws.Config{Region: aws.String(s.region)})))
+
+	_, err = svc.SendEmail(&ses.SendEmailInput{
+		Source: aws.String(s.sender),
+		Destination: &ses.Destination{
+			ToAddresses: []*string{aws.String(s.recipient)},
+		},
+		Message: &ses.Message{
+			Subject: &ses.Content{Data: aws.String("AutoSpotting digest")},
+			Body: &ses.Body{
+				Html: &ses.Content{Data: aws.String(body)},
+			},
+		},
+	})
+	return err
+}
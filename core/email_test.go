@@ -0,0 +1,72 @@
+package autospotting
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_emailSink_render(t *testing.T) {
+
+	now := time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		notifications []notification
+		want          []string
+	}{
+		{
+			name:          "nothing to report",
+			notifications: []notification{{Event: "savings", Time: now}},
+			want:          nil,
+		},
+		{
+			name: "events and savings",
+			notifications: []notification{
+				{
+					Time:             now,
+					Region:           "us-east-1",
+					AutoScalingGroup: "checkout",
+					Event:            "attached",
+					Message:          "spot instance i-1 <new>",
+				},
+				{
+					Time:             now,
+					Region:           "us-east-1",
+					AutoScalingGroup: "checkout",
+					Event:            "savings",
+					HourlySavings:    0.25,
+				},
+			},
+			want: []string{
+				"Hourly savings: $0.2500",
+				"<td>checkout</td><td>$0.2500</td>",
+				"<td>2017-01-02 03:04:05</td>",
+				"<td>attached</td><td>spot instance i-1 &lt;new&gt;</td>",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			s := &emailSink{template: loadDigestTemplate("")}
+			for _, n := range tt.notifications {
+				s.send(n)
+			}
+
+			got, err := s.render()
+			if err != nil {
+				t.Fatalf("render() error = %v", err)
+			}
+
+			if tt.want == nil && got != "" {
+				t.Errorf("render() = %q, want no digest", got)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("render() = %q, missing %q", got, w)
+				}
+			}
+		})
+	}
+}
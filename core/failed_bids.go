@@ -0,0 +1,97 @@
+package autospotting
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// Tag keeping the failure status code of the spot requests we cancelled, since
+// the cancellation replaces it.
+const bidFailureTag = "autospotting-failure"
+
+// spot request status codes meaning the bid can't be fulfilled
+var bidFailureCodes = map[string]bool{
+	"az-group-constraint":        true,
+	"bad-parameters":             true,
+	"capacity-not-available":     true,
+	"capacity-oversubscribed":    true,
+	"constraint-not-fulfillable": true,
+	"launch-group-constraint":    true,
+	"placement-group-constraint": true,
+	"price-too-low":              true,
+	"system-error":               true,
+}
+
+// spotRequestFailure returns the reason why the spot request failed, or nil if
+// it didn't fail.
+func spotRequestFailure(req *ec2.SpotInstanceRequest) *string {
+
+	if reason := findTagValue(req.Tags, bidFailureTag); reason != nil {
+		return reason
+	}
+
+	if req.Status != nil && req.Status.Code != nil &&
+		bidFailureCodes[*req.Status.Code] {
+		return req.Status.Code
+	}
+	return nil
+}
+
+// tagBidFailure keeps the failure status code of the spot request in a tag,
+// unless already tagged.
+func (a *autoScalingGroup) tagBidFailure(req *ec2.SpotInstanceRequest) {
+
+	if req.Status == nil || req.Status.Code == nil ||
+		!bidFailureCodes[*req.Status.Code] ||
+		findTagValue(req.Tags, bidFailureTag) != nil {
+		return
+	}
+
+	tag := &ec2.Tag{Key: aws.String(bidFailureTag), Value: req.Status.Code}
+	input := &ec2.CreateTagsInput{
+		Resources: []*string{req.SpotInstanceRequestId},
+		Tags:      []*ec2.Tag{tag},
+	}
+
+	if a.skipsCall("CreateTags", input) {
+		return
+	}
+
+	if _, err := a.region.services.ec2.CreateTags(input); err != nil {
+		a.log().at(LevelError).Println(a.name, "Failed to tag spot request",
+			*req.SpotInstanceRequestId, err.Error())
+		return
+	}
+	req.Tags = append(req.Tags, tag)
+}
+
+// failedBid tells if a spot request placed for the group recently failed for
+// the given instance type and availability zone. The failed requests, kept by
+// EC2 for a few hours after being closed, are the only storage needed.
+func (a *autoScalingGroup) failedBid(instanceType, az string) bool {
+
+	since := time.Now().Add(-a.region.conf.BidFailureWindow)
+
+	for _, req := range a.spotInstanceRequests {
+
+		ls := req.LaunchSpecification
+		if ls == nil || ls.InstanceType == nil || *ls.InstanceType != instanceType ||
+			ls.Placement == nil || ls.Placement.AvailabilityZone == nil ||
+			*ls.Placement.AvailabilityZone != az {
+			continue
+		}
+
+		if req.CreateTime != nil && req.CreateTime.Before(since) {
+			continue
+		}
+
+		if reason := spotRequestFailure(req); reason != nil {
+			a.log().Println(a.name, "Spot request", *req.SpotInstanceRequestId,
+				"for", instanceType, "in", az, "failed with", *reason)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package autospotting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_failedBid(t *testing.T) {
+
+	request := func(instanceType, code string, age time.Duration,
+		tags ...*ec2.Tag) *ec2.SpotInstanceRequest {
+		return &ec2.SpotInstanceRequest{
+			SpotInstanceRequestId: aws.String("sir-" + instanceType),
+			CreateTime:            aws.Time(time.Now().Add(-age)),
+			Status:                &ec2.SpotInstanceStatus{Code: aws.String(code)},
+			LaunchSpecification: &ec2.LaunchSpecification{
+				InstanceType: aws.String(instanceType),
+				Placement: &ec2.SpotPlacement{
+					AvailabilityZone: aws.String("us-east-1a"),
+				},
+			},
+			Tags: tags,
+		}
+	}
+
+	asg := autoScalingGroup{
+		name:   "web",
+		region: &region{conf: Config{BidFailureWindow: time.Hour}},
+		spotInstanceRequests: []*ec2.SpotInstanceRequest{
+			request("m4.large", "price-too-low", time.Minute),
+			request("c4.large", "canceled-before-fulfillment", time.Minute,
+				&ec2.Tag{
+					Key:   aws.String(bidFailureTag),
+					Value: aws.String("capacity-not-available"),
+				}),
+			request("r4.large", "capacity-not-available", 2*time.Hour),
+			request("m5.large", "fulfilled", time.Minute),
+		},
+	}
+
+	tests := []struct {
+		name         string
+		instanceType string
+		az           string
+		want         bool
+	}{
+		{name: "Failed by status code",
+			instanceType: "m4.large", az: "us-east-1a",
+			want: true,
+		},
+		{name: "Failure kept in a tag after cancelling",
+			instanceType: "c4.large", az: "us-east-1a",
+			want: true,
+		},
+		{name: "Failure older than the window",
+			instanceType: "r4.large", az: "us-east-1a",
+			want: false,
+		},
+		{name: "Fulfilled request",
+			instanceType: "m5.large", az: "us-east-1a",
+			want: false,
+		},
+		{name: "Other availability zone",
+			instanceType: "m4.large", az: "us-east-1b",
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := asg.failedBid(tt.instanceType, tt.az); got != tt.want {
+				t.Errorf("failedBid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package autospotting
+
+import (
+	"sort"
+	"sync"
+	"unicode"
+)
+
+var fleetState fleetStateExporter
+
+type fleetStateExporter struct {
+	sync.Mutex
+
+	enabled bool
+
+	// keyed by CloudFormation logical ID
+	resources map[string]fleetStateResource
+}
+
+type fleetStateResource struct {
+	Type       string                        `json:"Type"`
+	Properties fleetStateProperties          `json:"Properties"`
+	Metadata   map[string]fleetStateMetadata `json:"Metadata"`
+}
+
+type fleetStateProperties struct {
+	AutoScalingGroupName string          `json:"AutoScalingGroupName"`
+	Tags                 []fleetStateTag `json:"Tags"`
+}
+
+type fleetStateTag struct {
+	Key               string `json:"Key"`
+	Value             string `json:"Value"`
+	PropagateAtLaunch bool   `json:"PropagateAtLaunch"`
+}
+
+// AutoSpotting metadata of a group
+type fleetStateMetadata struct {
+	Region string `json:"Region"`
+
+	// AutoSpotting settings given as group tags
+	Overrides map[string]string `json:"Overrides"`
+
+	// effective AutoSpotting settings of the group and their sources
+	Settings map[string]effectiveSetting `json:"Settings"`
+
+	// compatible spot instance types computed for each availability zone
+	Candidates map[string][]string `json:"Candidates"`
+}
+
+func (f *fleetStateExporter) init(cfg Config) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.enabled = cfg.ExportFleetState
+	f.resources = make(map[string]fleetStateResource)
+}
+
+func (f *fleetStateExporter) record(logicalID string, r fleetStateResource) {
+	f.Lock()
+	defer f.Unlock()
+	f.resources[logicalID] = r
+}
+
+func (f *fleetStateExporter) export() {
+	f.Lock()
+	defer f.Unlock()
+
+	if !f.enabled || len(f.resources) == 0 {
+		return
+	}
+	writeReport("fleet-state", map[string]interface{}{
+		"Resources": f.resources,
+	})
+}
+
+// logicalID builds a CloudFormation logical ID out of the region and group
+// names, which may only contain alphanumeric characters.
+func logicalID(region, name string) string {
+	var id []rune
+	for _, c := range region + "-" + name {
+		if c < unicode.MaxASCII && (unicode.IsLetter(c) || unicode.IsDigit(c)) {
+			id = append(id, c)
+		}
+	}
+	return string(id)
+}
+
+// exportState records the group's configuration and the spot instance types
+// currently compatible with its on-demand instances, for the fleet state.
+func (a *autoScalingGroup) exportState() {
+
+	if !fleetState.enabled {
+		return
+	}
+
+	res := fleetStateResource{
+		Type: "AWS::AutoScaling::AutoScalingGroup",
+		Properties: fleetStateProperties{
+			AutoScalingGroupName: a.name,
+			Tags:                 []fleetStateTag{},
+		},
+	}
+
+	meta := fleetStateMetadata{
+		Region:     a.region.name,
+		Overrides:  make(map[string]string),
+		Settings:   a.effectiveSettings(),
+		Candidates: make(map[string][]string),
+	}
+
+	for _, tag := range a.Tags {
+		res.Properties.Tags = append(res.Properties.Tags, fleetStateTag{
+			Key:               *tag.Key,
+			Value:             *tag.Value,
+			PropagateAtLaunch: tag.PropagateAtLaunch != nil && *tag.PropagateAtLaunch,
+		})
+	}
+
+	for _, key := range groupSettingTags() {
+		if value := a.getTagValue(key); value != nil {
+			meta.Overrides[key] = *value
+		}
+	}
+
+	for _, az := range a.AvailabilityZones {
+		odInst := a.findOndemandInstanceInAZ(az)
+		if odInst == nil {
+			continue
+		}
+
+		candidates, err := a.getCompatibleSpotInstanceTypes(*az, odInst)
+		if err != nil {
+			a.log().at(LevelWarn).Println(a.name,
+				"Couldn't compute the candidates in", *az, err.Error())
+			continue
+		}
+		sort.Strings(candidates)
+		meta.Candidates[*az] = candidates
+	}
+
+	res.Metadata = map[string]fleetStateMetadata{"AutoSpotting": meta}
+
+	fleetState.record(logicalID(a.region.name, a.name), res)
+}
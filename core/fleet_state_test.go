@@ -0,0 +1,30 @@
+package autospotting
+
+import "testing"
+
+func Test_logicalID(t *testing.T) {
+	tests := []struct {
+		name   string
+		region string
+		group  string
+		want   string
+	}{
+		{name: "Separators are removed",
+			region: "us-east-1",
+			group:  "web-prod_asg.v2",
+			want:   "useast1webprodasgv2",
+		},
+		{name: "Non-ASCII letters are removed",
+			region: "eu-west-1",
+			group:  "café",
+			want:   "euwest1caf",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := logicalID(tt.region, tt.group); got != tt.want {
+				t.Errorf("logicalID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package autospotting
+
+import (
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+const gracePeriodTag = "autospotting-original-grace-period"
+
+// attachGracePeriod returns the grace period in seconds used when attaching
+// spot instances to the group.
+func (a *autoScalingGroup) attachGracePeriod() int64 {
+
+	if period := a.region.conf.AttachGracePeriod; period > 0 {
+		return int64(period.Seconds())
+	}
+	return *a.HealthCheckGracePeriod
+}
+
+func (a *autoScalingGroup) setHealthCheckGracePeriod(seconds int64) error {
+
+	input := &autoscaling.UpdateAutoScalingGroupInput{
+		AutoScalingGroupName:   aws.String(a.name),
+		HealthCheckGracePeriod: aws.Int64(seconds),
+	}
+
+	if a.skipsCall("UpdateAutoScalingGroup", input) {
+		return nil
+	}
+
+	_, err := a.region.services.autoScaling.UpdateAutoScalingGroup(input)
+
+	if err != nil {
+		a.log().at(LevelError).Println(a.name,
+			"Failed to set the health check grace period", err.Error())
+	}
+	return err
+}
+
+// bumpGracePeriod sets the group's grace period to the attachment grace period
+// and returns the function restoring the original value. The grace period is
+// left unchanged if its original value couldn't be saved.
+func (a *autoScalingGroup) bumpGracePeriod() func() {
+
+	original := *a.HealthCheckGracePeriod
+	period := a.attachGracePeriod()
+
+	if period == original {
+		return func() {}
+	}
+
+	if a.setGroupTag(gracePeriodTag, strconv.FormatInt(original, 10)) != nil {
+		a.log().at(LevelWarn).Println(a.name,
+			"Couldn't save the health check grace period,",
+			"leaving it unchanged")
+		return func() {}
+	}
+
+	a.log().Println(a.name, "Temporarily setting the health check grace period",
+		"to", period, "seconds")
+
+	if a.setHealthCheckGracePeriod(period) != nil {
+		a.restoreGracePeriod()
+		return func() {}
+	}
+
+	return func() {
+		a.restoreGracePeriod()
+	}
+}
+
+// restoreGracePeriod sets the group's grace period back to the value saved in
+// its tag, if any.
+func (a *autoScalingGroup) restoreGracePeriod() {
+
+	saved := a.getTagValue(gracePeriodTag)
+	if saved == nil {
+		return
+	}
+
+	original, err := strconv.ParseInt(*saved, 10, 64)
+	if err != nil {
+		a.log().at(LevelWarn).Println(a.name,
+			"Invalid saved health check grace period", *saved)
+		return
+	}
+
+	a.log().Println(a.name, "Restoring the health check grace period to",
+		original, "seconds")
+
+	if a.setHealthCheckGracePeriod(original) != nil {
+		return
+	}
+	a.HealthCheckGracePeriod = aws.Int64(original)
+	a.deleteGroupTag(gracePeriodTag)
+}
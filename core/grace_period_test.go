@@ -0,0 +1,64 @@
+package autospotting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+func Test_attachGracePeriod(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		conf        Config
+		groupPeriod int64
+		want        int64
+	}{
+		{
+			name:        "group's own grace period",
+			groupPeriod: 3600,
+			want:        3600,
+		},
+		{
+			name:        "configured attachment grace period",
+			conf:        Config{AttachGracePeriod: 5 * time.Minute},
+			groupPeriod: 3600,
+			want:        300,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &autoScalingGroup{
+				Group: &autoscaling.Group{
+					HealthCheckGracePeriod: aws.Int64(tt.groupPeriod),
+				},
+				region: &region{conf: tt.conf},
+			}
+			if got := a.attachGracePeriod(); got != tt.want {
+				t.Errorf("attachGracePeriod() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_bumpGracePeriod_unchanged(t *testing.T) {
+
+	a := &autoScalingGroup{
+		Group: &autoscaling.Group{
+			HealthCheckGracePeriod: aws.Int64(300),
+		},
+		region: &region{conf: Config{AttachGracePeriod: 5 * time.Minute}},
+	}
+
+	// neither changes the group nor saves its grace period when it's already
+	// the attachment grace period
+	a.bumpGracePeriod()()
+	a.restoreGracePeriod()
+
+	if *a.HealthCheckGracePeriod != 300 || len(a.Tags) != 0 {
+		t.Errorf("the group was changed: grace period %d, tags %v",
+			*a.HealthCheckGracePeriod, a.Tags)
+	}
+}
@@ -0,0 +1,76 @@
+package autospotting
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+func (a *autoScalingGroup) groupTag(key, value string) []*autoscaling.Tag {
+	return []*autoscaling.Tag{{
+		ResourceId:        aws.String(a.name),
+		ResourceType:      aws.String("auto-scaling-group"),
+		Key:               aws.String(key),
+		Value:             aws.String(value),
+		PropagateAtLaunch: aws.Bool(false),
+	}}
+}
+
+// setGroupTag creates or updates the tag of the group.
+func (a *autoScalingGroup) setGroupTag(key, value string) error {
+
+	input := &autoscaling.CreateOrUpdateTagsInput{Tags: a.groupTag(key, value)}
+
+	if a.skipsCall("CreateOrUpdateTags", input) {
+		return nil
+	}
+
+	_, err := a.region.services.autoScaling.CreateOrUpdateTags(input)
+
+	if err != nil {
+		a.log().at(LevelError).Println(a.name, "Failed to set the", key, "tag",
+			err.Error())
+		return err
+	}
+
+	a.Tags = append(a.withoutTag(key), &autoscaling.TagDescription{
+		Key:   aws.String(key),
+		Value: aws.String(value),
+	})
+	return nil
+}
+
+// deleteGroupTag deletes the tag of the group.
+func (a *autoScalingGroup) deleteGroupTag(key string) error {
+
+	value := a.getTagValue(key)
+	if value == nil {
+		return nil
+	}
+
+	input := &autoscaling.DeleteTagsInput{Tags: a.groupTag(key, *value)}
+
+	if a.skipsCall("DeleteTags", input) {
+		return nil
+	}
+
+	_, err := a.region.services.autoScaling.DeleteTags(input)
+
+	if err != nil {
+		a.log().at(LevelError).Println(a.name, "Failed to delete the", key,
+			"tag", err.Error())
+		return err
+	}
+
+	a.Tags = a.withoutTag(key)
+	return nil
+}
+
+func (a *autoScalingGroup) withoutTag(key string) []*autoscaling.TagDescription {
+	var tags []*autoscaling.TagDescription
+	for _, tag := range a.Tags {
+		if tag.Key == nil || *tag.Key != key {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,125 @@
+package autospotting
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/health"
+)
+
+var healthEvents healthBlackout
+
+type healthBlackout struct {
+	sync.Mutex
+
+	// regions affected as a whole
+	regions map[string]string
+
+	// availability zones affected, the values are the event type codes
+	zones map[string]string
+}
+
+// the services whose Health events may suspend the replacements
+var healthEventServices = []string{"EC2", "AUTOSCALING"}
+
+// relevantHealthEvent tells if a regional EC2 or AutoScaling event is about an
+// issue, or about capacity or spot.
+func relevantHealthEvent(e *health.Event) bool {
+
+	if e.Region == nil || e.Service == nil {
+		return false
+	}
+
+	relevantService := false
+	for _, service := range healthEventServices {
+		relevantService = relevantService ||
+			strings.EqualFold(*e.Service, service)
+	}
+	if !relevantService {
+		return false
+	}
+
+	if e.EventTypeCategory != nil &&
+		*e.EventTypeCategory == health.EventTypeCategoryIssue {
+		return true
+	}
+
+	if e.EventTypeCode != nil {
+		code := strings.ToUpper(*e.EventTypeCode)
+		return strings.Contains(code, "CAPACITY") || strings.Contains(code, "SPOT")
+	}
+	return false
+}
+
+// load fetches the open EC2 Health events, the AWS Health API is only
+// available in us-east-1 and requires a Business or Enterprise support plan.
+func (h *healthBlackout) load(cfg Config) {
+	h.Lock()
+	defer h.Unlock()
+
+	h.regions = make(map[string]string)
+	h.zones = make(map[string]string)
+
+	if !cfg.HealthBlackout {
+		return
+	}
+
+	svc := health.New(instrumentSession(
+		newSession(&aws.Config{Region: aws.String("us-east-1")})))
+
+	err := svc.DescribeEventsPages(
+		&health.DescribeEventsInput{
+			Filter: &health.EventFilter{
+				Services:         aws.StringSlice(healthEventServices),
+				EventStatusCodes: []*string{aws.String(health.EventStatusCodeOpen)},
+			},
+		},
+		func(page *health.DescribeEventsOutput, lastPage bool) bool {
+			for _, e := range page.Events {
+				if !relevantHealthEvent(e) {
+					continue
+				}
+
+				logger.Println("Found AWS Health event", *e.EventTypeCode,
+					"in", *e.Region, aws.StringValue(e.AvailabilityZone))
+
+				if e.AvailabilityZone != nil {
+					h.zones[*e.AvailabilityZone] = *e.EventTypeCode
+				} else {
+					h.regions[*e.Region] = *e.EventTypeCode
+				}
+			}
+			return true
+		},
+	)
+
+	if err != nil {
+		logger.at(LevelError).Println("Failed to describe AWS Health events",
+			err.Error())
+	}
+}
+
+// blocks tells if replacements are suspended in the given region, or in the
+// given availability zone when it's not nil.
+func (h *healthBlackout) blocks(region string, az *string) bool {
+	h.Lock()
+	defer h.Unlock()
+
+	if event, found := h.regions[region]; found {
+		logger.Println("Replacements are suspended in", region,
+			"due to the AWS Health event", event)
+		return true
+	}
+
+	if az == nil {
+		return false
+	}
+
+	if event, found := h.zones[*az]; found {
+		logger.Println("Replacements are suspended in", *az,
+			"due to the AWS Health event", event)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package autospotting
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/health"
+)
+
+func Test_relevantHealthEvent(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		service  string
+		region   *string
+		category string
+		code     string
+		want     bool
+	}{
+		{name: "EC2 issue",
+			service:  "EC2",
+			region:   aws.String("us-east-1"),
+			category: health.EventTypeCategoryIssue,
+			code:     "AWS_EC2_OPERATIONAL_ISSUE",
+			want:     true,
+		},
+		{name: "AutoScaling issue",
+			service:  "AUTOSCALING",
+			region:   aws.String("us-east-1"),
+			category: health.EventTypeCategoryIssue,
+			code:     "AWS_AUTOSCALING_OPERATIONAL_ISSUE",
+			want:     true,
+		},
+		{name: "EC2 spot capacity notification",
+			service:  "EC2",
+			region:   aws.String("us-east-1"),
+			category: health.EventTypeCategoryAccountNotification,
+			code:     "AWS_EC2_SPOT_CAPACITY_NOTIFICATION",
+			want:     true,
+		},
+		{name: "EC2 scheduled maintenance",
+			service:  "EC2",
+			region:   aws.String("us-east-1"),
+			category: health.EventTypeCategoryScheduledChange,
+			code:     "AWS_EC2_INSTANCE_REBOOT_MAINTENANCE_SCHEDULED",
+			want:     false,
+		},
+		{name: "S3 issue",
+			service:  "S3",
+			region:   aws.String("us-east-1"),
+			category: health.EventTypeCategoryIssue,
+			code:     "AWS_S3_OPERATIONAL_ISSUE",
+			want:     false,
+		},
+		{name: "global IAM issue",
+			service:  "IAM",
+			category: health.EventTypeCategoryIssue,
+			code:     "AWS_IAM_OPERATIONAL_ISSUE",
+			want:     false,
+		},
+		{name: "EC2 issue without region",
+			service:  "EC2",
+			category: health.EventTypeCategoryIssue,
+			code:     "AWS_EC2_OPERATIONAL_ISSUE",
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &health.Event{
+				Service:           aws.String(tt.service),
+				Region:            tt.region,
+				EventTypeCategory: aws.String(tt.category),
+				EventTypeCode:     aws.String(tt.code),
+			}
+			if got := relevantHealthEvent(e); got != tt.want {
+				t.Errorf("relevantHealthEvent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package autospotting
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var actions actionHistory
+
+type historyEntry struct {
+	Time    time.Time `json:"time"`
+	Region  string    `json:"region"`
+	Action  string    `json:"action"`
+	Details string    `json:"details"`
+}
+
+type actionHistory struct {
+	sync.Mutex
+
+	// maximum number of entries kept for each group
+	size int
+
+	// keyed by region and group name, oldest entries first
+	entries map[[2]string][]historyEntry
+}
+
+// init sets the history size, without losing the entries of previous runs.
+func (h *actionHistory) init(cfg Config) {
+	h.Lock()
+	defer h.Unlock()
+
+	h.size = cfg.HistorySize
+	if h.entries == nil {
+		h.entries = make(map[[2]string][]historyEntry)
+	}
+}
+
+func (h *actionHistory) add(group string, e historyEntry) {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.size <= 0 || h.entries == nil {
+		return
+	}
+
+	key := [2]string{e.Region, group}
+
+	entries := append(h.entries[key], e)
+	if len(entries) > h.size {
+		entries = entries[len(entries)-h.size:]
+	}
+	h.entries[key] = entries
+}
+
+// get returns a copy of the group's history, optionally only from the given
+// region, since groups from different regions may have the same name.
+func (h *actionHistory) get(group, region string) []historyEntry {
+	h.Lock()
+	defer h.Unlock()
+
+	result := []historyEntry{}
+	for key, entries := range h.entries {
+		if key[1] == group && (region == "" || key[0] == region) {
+			result = append(result, entries...)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Time.Before(result[j].Time)
+	})
+	return result
+}
+
+// recordAction logs the action and adds an entry to the group's history, the
+// details are formatted like the log messages.
+func (a *autoScalingGroup) recordAction(action string, details ...interface{}) {
+	a.record(LevelInfo, action, details...)
+}
+
+// recordActionAt is like recordAction, logging at the given level, such as
+// for the failed actions.
+func (a *autoScalingGroup) recordActionAt(level LogLevel, action string,
+	details ...interface{}) {
+	a.record(level, action, details...)
+}
+
+func (a *autoScalingGroup) record(level LogLevel, action string,
+	details ...interface{}) {
+
+	// logged from the line calling recordAction or recordActionAt
+	logger.at(level).With(a.actionFields(action, details...)).skip(2).
+		Println(a.name, action, strings.TrimSpace(fmt.Sprintln(details...)))
+
+	a.recordApplicationAction(action)
+	a.recordMetricAction(action)
+	runResult.addAction(action)
+
+	actions.add(a.name, historyEntry{
+		Time:    time.Now().UTC(),
+		Region:  a.region.name,
+		Action:  action,
+		Details: strings.TrimSpace(fmt.Sprintln(details...)),
+	})
+
+	if notifiedActions[action] {
+		a.notify(action, details...)
+	} else if digestActions[action] {
+		a.notifyDigest(action, details...)
+	}
+}
+
+// serveHistory handles GET /asgs/{name}/history?region={region}
+func serveHistory(w http.ResponseWriter, r *http.Request) {
+
+	path := strings.TrimPrefix(r.URL.Path, "/asgs/")
+
+	if !strings.HasSuffix(path, "/history") {
+		http.NotFound(w, r)
+		return
+	}
+	name := strings.TrimSuffix(path, "/history")
+
+	if name == "" || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	err := json.NewEncoder(w).Encode(actions.get(name, r.URL.Query().Get("region")))
+	if err != nil {
+		logger.at(LevelError).Println("Failed to encode the history of", name,
+			err.Error())
+	}
+}
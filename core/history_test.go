@@ -0,0 +1,69 @@
+package autospotting
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_serveHistory(t *testing.T) {
+	actions = actionHistory{}
+	actions.init(Config{HistorySize: 2})
+
+	asg := autoScalingGroup{name: "web", region: &region{name: "us-east-1"}}
+	asg.recordAction("bid", "first")
+	asg.recordAction("attached", "second")
+	asg.recordAction("terminated", "third")
+
+	other := autoScalingGroup{name: "web", region: &region{name: "eu-west-1"}}
+	other.recordAction("bid", "elsewhere")
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		wantCount  int
+	}{
+		{name: "History is bounded",
+			url:        "/asgs/web/history?region=us-east-1",
+			wantStatus: http.StatusOK,
+			wantCount:  2,
+		},
+		{name: "All regions by default",
+			url:        "/asgs/web/history",
+			wantStatus: http.StatusOK,
+			wantCount:  3,
+		},
+		{name: "Unknown group",
+			url:        "/asgs/db/history",
+			wantStatus: http.StatusOK,
+			wantCount:  0,
+		},
+		{name: "Unknown path",
+			url:        "/asgs/web",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			newServeMux().ServeHTTP(rec, httptest.NewRequest("GET", tt.url, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got []historyEntry
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.wantCount {
+				t.Errorf("got %d entries, want %d: %v", len(got), tt.wantCount, got)
+			}
+		})
+	}
+}
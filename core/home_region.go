@@ -0,0 +1,42 @@
+package autospotting
+
+import (
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+const defaultHomeRegion = "us-east-1"
+
+// resolveHomeRegion returns the configured home region, falling back to the
+// region given by the environment, then to us-east-1.
+func resolveHomeRegion(cfg Config) string {
+
+	for _, region := range []string{
+		cfg.HomeRegion,
+		os.Getenv("AWS_REGION"),
+		os.Getenv("AWS_DEFAULT_REGION"),
+	} {
+		if region != "" {
+			return region
+		}
+	}
+	return defaultHomeRegion
+}
+
+// homeRegion returns the home region of the current run.
+func homeRegion() string {
+	awsSessions.Lock()
+	defer awsSessions.Unlock()
+
+	if awsSessions.home == "" {
+		return defaultHomeRegion
+	}
+	return awsSessions.home
+}
+
+// homeSession returns a session connecting to the home region.
+func homeSession() *session.Session {
+	return newSession(&aws.Config{Region: aws.String(homeRegion())})
+}
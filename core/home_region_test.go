@@ -0,0 +1,39 @@
+package autospotting
+
+import (
+	"os"
+	"testing"
+)
+
+func Test_resolveHomeRegion(t *testing.T) {
+
+	for _, env := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		defer os.Setenv(env, os.Getenv(env))
+	}
+
+	tests := []struct {
+		name          string
+		cfg           Config
+		region        string
+		defaultRegion string
+		want          string
+	}{
+		{name: "default", want: "us-east-1"},
+		{name: "default region variable", defaultRegion: "eu-west-1",
+			want: "eu-west-1"},
+		{name: "region variable", region: "eu-central-1",
+			defaultRegion: "eu-west-1", want: "eu-central-1"},
+		{name: "configured", cfg: Config{HomeRegion: "ap-south-1"},
+			region: "eu-central-1", want: "ap-south-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("AWS_REGION", tt.region)
+			os.Setenv("AWS_DEFAULT_REGION", tt.defaultRegion)
+
+			if got := resolveHomeRegion(tt.cfg); got != tt.want {
+				t.Errorf("resolveHomeRegion() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package autospotting
+
+import (
+	"strings"
+	"time"
+)
+
+// claimRun tells if the current invocation should go on processing the
+// regions, which is always the case if the event ID or the table are unknown,
+// since CloudWatch Events may invoke the function more than once for the same
+// event. Errors other than failing to claim the event are logged and ignored,
+// since it's better to process the same event twice than to miss it.
+func claimRun(cfg Config) bool {
+
+	// invocations for the same event restricted to different scopes are all
+	// legitimate, so the scope is part of the key
+	return claimEvent(cfg, strings.Join(
+		[]string{cfg.EventID, cfg.Regions, cfg.AutoScalingGroups}, "/"))
+}
+
+// claimDispatch tells if the current invocation should go on dispatching the
+// event to the workers. Its key never matches the ones claimed by the workers,
+// which receive the same event ID restricted to the dispatched scopes.
+func claimDispatch(cfg Config) bool {
+	return claimEvent(cfg, "dispatch/"+cfg.EventID)
+}
+
+// claimEvent claims the key of the event in the idempotency table.
+func claimEvent(cfg Config, key string) bool {
+
+	if cfg.IdempotencyTable == "" || cfg.EventID == "" {
+		return true
+	}
+
+	expires := time.Now().Add(cfg.IdempotencyTTL).Unix()
+
+	claimed, err := state.claim(cfg.IdempotencyTable, stateItem{
+		Keys:    map[string]string{"id": key},
+		Numbers: map[string]float64{"expires": float64(expires)},
+	})
+
+	if err == nil && claimed {
+		return true
+	}
+
+	if err == nil {
+		logger.Println("Event", cfg.EventID, "was already processed by another",
+			"invocation, exiting")
+		return false
+	}
+
+	logger.at(LevelError).Println("Couldn't claim event", cfg.EventID, "in",
+		cfg.IdempotencyTable, err.Error())
+	alertPlatform(homeRegion(), "state-store-failure", "Couldn't claim event",
+		cfg.EventID, "in the", cfg.IdempotencyTable, "table", err.Error())
+	return true
+}
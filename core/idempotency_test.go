@@ -0,0 +1,96 @@
+package autospotting
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// failingStore fails every state store operation.
+type failingStore struct{}
+
+func (failingStore) claim(table string, item stateItem) (bool, error) {
+	return false, errors.New("table not found")
+}
+
+func (failingStore) put(table string, item stateItem) error {
+	return errors.New("table not found")
+}
+
+func (failingStore) increment(table string, item stateItem) error {
+	return errors.New("table not found")
+}
+
+func (failingStore) scan(table string) ([]stateItem, error) {
+	return nil, errors.New("table not found")
+}
+
+func Test_claimRun(t *testing.T) {
+	defer func(s stateStore) { state = s }(state)
+
+	cfg := Config{
+		IdempotencyTable: "events",
+		IdempotencyTTL:   time.Hour,
+		EventID:          "event-1",
+		Regions:          "us-east-1",
+	}
+	otherScope := cfg
+	otherScope.Regions = "eu-west-1"
+
+	tests := []struct {
+		name  string
+		store stateStore
+		claim func(Config) bool
+		cfg   Config
+		want  bool
+	}{
+		{name: "first invocation",
+			store: &documentStore{blobs: localDirectory(t.TempDir())},
+			claim: claimRun,
+			cfg:   cfg,
+			want:  true,
+		},
+		{name: "duplicate invocation",
+			claim: claimRun,
+			cfg:   cfg,
+			want:  false,
+		},
+		{name: "invocation for another scope",
+			claim: claimRun,
+			cfg:   otherScope,
+			want:  true,
+		},
+		{name: "dispatcher of the same event",
+			claim: claimDispatch,
+			cfg:   cfg,
+			want:  true,
+		},
+		{name: "duplicate dispatcher",
+			claim: claimDispatch,
+			cfg:   cfg,
+			want:  false,
+		},
+		{name: "without event ID",
+			claim: claimRun,
+			cfg:   Config{IdempotencyTable: "events"},
+			want:  true,
+		},
+		{name: "state store error",
+			store: failingStore{},
+			claim: claimRun,
+			cfg:   cfg,
+			want:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the claims carry over to the next cases unless a store is given
+			if tt.store != nil {
+				state = tt.store
+			}
+			if got := tt.claim(tt.cfg); got != tt.want {
+				t.Errorf("claim() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package autospotting
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/service/ec2"
@@ -11,13 +12,51 @@ type instanceTypeInformation struct {
 	vCPU                     int
 	pricing                  prices
 	memory                   float32
+	gpu                      int
 	virtualizationTypes      []string
+	architectures            []string
 	hasInstanceStore         bool
 	instanceStoreDeviceSize  float32
 	instanceStoreDeviceCount int
 	instanceStoreIsSSD       bool
 }
 
+// instanceFamily returns the family of an instance type, such as "m5" for
+// "m5.large".
+func instanceFamily(instanceType string) string {
+	return strings.SplitN(instanceType, ".", 2)[0]
+}
+
+// instanceGeneration returns the generation number of an instance type, such as
+// 5 for "m5.large" or 6 for "c6i.xlarge", or 0 if it can't be determined.
+func instanceGeneration(instanceType string) int {
+	family := instanceFamily(instanceType)
+
+	start := strings.IndexAny(family, "0123456789")
+	if start < 0 {
+		return 0
+	}
+
+	end := start
+	for end < len(family) && family[end] >= '0' && family[end] <= '9' {
+		end++
+	}
+
+	generation, _ := strconv.Atoi(family[start:end])
+	return generation
+}
+
+// hasCapacityOf tells if the instance type has at least as many vCPUs and as
+// much memory as the existing instance type, multiplied by the performance
+// factor, and at least as many GPUs.
+func (info instanceTypeInformation) hasCapacityOf(
+	existing instanceTypeInformation, performanceFactor float64) bool {
+
+	return float64(info.vCPU) >= float64(existing.vCPU)*performanceFactor &&
+		float64(info.memory) >= float64(existing.memory)*performanceFactor &&
+		info.gpu >= existing.gpu
+}
+
 // The key in this map is the instance ID, useful for quick retrieval of
 // instance attributes.
 type instances struct {
@@ -45,26 +84,66 @@ func (it *instance) isSpot() bool {
 		*it.InstanceLifecycle == "spot")
 }
 
+// hourlyCost returns the price of the instance, nothing while it's stopped by
+// an interruption.
+func (it *instance) hourlyCost() float64 {
+
+	if it.isRecoverable() {
+		return 0
+	}
+	return it.price
+}
+
+// hourlySavings returns how much cheaper a spot instance launched by us is
+// than the on-demand instance it replaced, whose price is tagged on it. The
+// spot instances stopped by interruptions save nothing until resumed.
+func (it *instance) hourlySavings() float64 {
+
+	if !it.isSpot() || it.isRecoverable() {
+		return 0
+	}
+
+	tag := findTagValue(it.Tags, "original-instance-price")
+	if tag == nil {
+		return 0
+	}
+
+	price, err := strconv.ParseFloat(*tag, 64)
+	if err != nil {
+		return 0
+	}
+	return price - it.price
+}
+
+// isRecoverable tells if this is a spot instance stopped or hibernated by an
+// interruption, which still belongs to its group since it's resumed as soon as
+// spot capacity is available again.
+func (it *instance) isRecoverable() bool {
+
+	if !it.isSpot() || it.State == nil || it.State.Name == nil ||
+		(*it.State.Name != "stopping" && *it.State.Name != "stopped") {
+		return false
+	}
+
+	return it.StateReason != nil && it.StateReason.Code != nil &&
+		strings.HasPrefix(*it.StateReason.Code, "Server.Spot")
+}
+
 func (it *instance) terminate(svc *ec2.EC2) {
 
+	if volumes := it.persistentVolumes(); len(volumes) > 0 {
+		logger.at(LevelWarn).Println("WARNING: terminating instance",
+			*it.InstanceId, "leaves behind its volumes", volumes,
+			"which are not deleted on", "termination")
+	}
+
 	if _, err := svc.TerminateInstances(&ec2.TerminateInstancesInput{
 		InstanceIds: []*string{it.InstanceId},
 	}); err != nil {
-		logger.Println(err.Error())
+		logger.at(LevelError).Println(err.Error())
 	}
 }
 
 func (it *instance) filterTags() []*ec2.Tag {
-	var filteredTags []*ec2.Tag
-
-	tags := it.Tags
-
-	// filtering reserved tags, which start with the "aws:" prefix
-	for _, tag := range tags {
-		if !strings.HasPrefix(*tag.Key, "aws:") {
-			filteredTags = append(filteredTags, tag)
-		}
-	}
-
-	return filteredTags
+	return filterReservedTags(it.Tags)
 }
@@ -0,0 +1,81 @@
+package autospotting
+
+import "github.com/aws/aws-sdk-go/service/autoscaling"
+
+func (a *autoScalingGroup) usesInstanceLaunchConfiguration() bool {
+	return a.boolSetting("instance_launch_configuration")
+}
+
+// describeLaunchConfiguration returns the launch configuration having the
+// given name, or nil if it doesn't exist or can't be described.
+func (a *autoScalingGroup) describeLaunchConfiguration(
+	name *string) *autoscaling.LaunchConfiguration {
+
+	resp, err := a.region.services.autoScaling.DescribeLaunchConfigurations(
+		&autoscaling.DescribeLaunchConfigurationsInput{
+			LaunchConfigurationNames: []*string{name},
+		})
+
+	if err != nil {
+		a.log().at(LevelError).Println(err.Error())
+		return nil
+	}
+
+	if len(resp.LaunchConfigurations) == 0 {
+		return nil
+	}
+	return resp.LaunchConfigurations[0]
+}
+
+// launchConfigurationFor returns the launch configuration the spot instance
+// replacing the given on-demand instance should be based on.
+func (a *autoScalingGroup) launchConfigurationFor(
+	baseInstance *instance) *autoscaling.LaunchConfiguration {
+
+	current := a.getLaunchConfiguration()
+
+	if !a.usesInstanceLaunchConfiguration() {
+		return current
+	}
+
+	member := a.findGroupInstance(*baseInstance.InstanceId)
+	if member == nil || member.LaunchConfigurationName == nil ||
+		(a.LaunchConfigurationName != nil &&
+			*member.LaunchConfigurationName == *a.LaunchConfigurationName) {
+		return current
+	}
+
+	a.log().Println(a.name, "Instance", *baseInstance.InstanceId,
+		"was launched from launch configuration",
+		*member.LaunchConfigurationName)
+
+	if lc := a.describeLaunchConfiguration(
+		member.LaunchConfigurationName); lc != nil {
+		return lc
+	}
+
+	if current == nil {
+		return nil
+	}
+
+	a.log().Println(a.name, "Launch configuration",
+		*member.LaunchConfigurationName, "no longer exists, using the AMI and",
+		"user data of", *baseInstance.InstanceId)
+
+	return a.overlayInstanceConfiguration(current, baseInstance)
+}
+
+// overlayInstanceConfiguration returns a copy of the launch configuration
+// using the AMI and user data of the given instance.
+func (a *autoScalingGroup) overlayInstanceConfiguration(
+	lc *autoscaling.LaunchConfiguration,
+	inst *instance) *autoscaling.LaunchConfiguration {
+
+	result := *lc
+	result.ImageId = inst.ImageId
+
+	if userData, ok := a.instanceUserData(inst); ok {
+		result.UserData = userData
+	}
+	return &result
+}
@@ -0,0 +1,64 @@
+package autospotting
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_usesInstanceLaunchConfiguration(t *testing.T) {
+
+	tests := []struct {
+		name string
+		tags []*autoscaling.TagDescription
+		want bool
+	}{
+		{name: "not tagged", want: false},
+		{
+			name: "enabled",
+			tags: []*autoscaling.TagDescription{{
+				Key:   aws.String("instance_launch_configuration"),
+				Value: aws.String("true"),
+			}},
+			want: true,
+		},
+		{
+			name: "disabled",
+			tags: []*autoscaling.TagDescription{{
+				Key:   aws.String("instance_launch_configuration"),
+				Value: aws.String("false"),
+			}},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &autoScalingGroup{Group: &autoscaling.Group{Tags: tt.tags}}
+			if got := a.usesInstanceLaunchConfiguration(); got != tt.want {
+				t.Errorf("usesInstanceLaunchConfiguration() = %v, want %v",
+					got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_launchConfigurationFor_launchedFromCurrent(t *testing.T) {
+
+	// instances without a launch configuration of their own are based on the
+	// group's current launch configuration, here missing
+	a := &autoScalingGroup{Group: &autoscaling.Group{
+		Tags: []*autoscaling.TagDescription{{
+			Key:   aws.String("instance_launch_configuration"),
+			Value: aws.String("true"),
+		}},
+		Instances: []*autoscaling.Instance{{InstanceId: aws.String("i-1")}},
+	}}
+
+	base := &instance{Instance: &ec2.Instance{InstanceId: aws.String("i-1")}}
+
+	if lc := a.launchConfigurationFor(base); lc != nil {
+		t.Errorf("launchConfigurationFor() = %v, want nil", lc)
+	}
+}
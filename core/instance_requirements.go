@@ -0,0 +1,150 @@
+package autospotting
+
+import (
+	"path"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// instanceRequirements holds the supported subset of the attribute-based
+// instance type requirements, 0 meaning no limit.
+type instanceRequirements struct {
+	minVCPU, maxVCPU           int64
+	minMemoryMiB, maxMemoryMiB int64
+
+	// instance type patterns, which may contain wildcards
+	allowed, excluded []string
+}
+
+func inRange(value, min, max int64) bool {
+	return value >= min && (max == 0 || value <= max)
+}
+
+// matchesAny tells if the instance type matches any of the patterns.
+func matchesAny(instanceType string, patterns []string) bool {
+	for _, p := range patterns {
+		if matched, _ := path.Match(p, instanceType); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// matches tells if the instance type satisfies the requirements.
+func (r *instanceRequirements) matches(info instanceTypeInformation) bool {
+
+	if len(r.allowed) > 0 && !matchesAny(info.instanceType, r.allowed) {
+		return false
+	}
+	if matchesAny(info.instanceType, r.excluded) {
+		return false
+	}
+
+	return inRange(int64(info.vCPU), r.minVCPU, r.maxVCPU) &&
+		inRange(int64(info.memory*1024), r.minMemoryMiB, r.maxMemoryMiB)
+}
+
+func fromAutoScalingRequirements(
+	req *autoscaling.InstanceRequirements) *instanceRequirements {
+
+	r := &instanceRequirements{
+		allowed:  aws.StringValueSlice(req.AllowedInstanceTypes),
+		excluded: aws.StringValueSlice(req.ExcludedInstanceTypes),
+	}
+	if req.VCpuCount != nil {
+		r.minVCPU = aws.Int64Value(req.VCpuCount.Min)
+		r.maxVCPU = aws.Int64Value(req.VCpuCount.Max)
+	}
+	if req.MemoryMiB != nil {
+		r.minMemoryMiB = aws.Int64Value(req.MemoryMiB.Min)
+		r.maxMemoryMiB = aws.Int64Value(req.MemoryMiB.Max)
+	}
+	return r
+}
+
+func fromEC2Requirements(req *ec2.InstanceRequirements) *instanceRequirements {
+
+	r := &instanceRequirements{
+		allowed:  aws.StringValueSlice(req.AllowedInstanceTypes),
+		excluded: aws.StringValueSlice(req.ExcludedInstanceTypes),
+	}
+	if req.VCpuCount != nil {
+		r.minVCPU = aws.Int64Value(req.VCpuCount.Min)
+		r.maxVCPU = aws.Int64Value(req.VCpuCount.Max)
+	}
+	if req.MemoryMiB != nil {
+		r.minMemoryMiB = aws.Int64Value(req.MemoryMiB.Min)
+		r.maxMemoryMiB = aws.Int64Value(req.MemoryMiB.Max)
+	}
+	return r
+}
+
+// launchTemplate returns the launch template used by the group, if any.
+func (a *autoScalingGroup) launchTemplate() *autoscaling.LaunchTemplateSpecification {
+
+	if a.LaunchTemplate != nil {
+		return a.LaunchTemplate
+	}
+	if p := a.MixedInstancesPolicy; p != nil && p.LaunchTemplate != nil {
+		return p.LaunchTemplate.LaunchTemplateSpecification
+	}
+	return nil
+}
+
+// instanceRequirements returns the instance requirements declared for the
+// group, either in the overrides of its mixed instances policy or in its
+// launch template, or nil if it declares none.
+func (a *autoScalingGroup) instanceRequirements() *instanceRequirements {
+
+	if p := a.MixedInstancesPolicy; p != nil && p.LaunchTemplate != nil {
+		for _, o := range p.LaunchTemplate.Overrides {
+			if o.InstanceRequirements != nil {
+				return fromAutoScalingRequirements(o.InstanceRequirements)
+			}
+		}
+	}
+
+	if data := a.launchTemplateData(); data != nil &&
+		data.InstanceRequirements != nil {
+		return fromEC2Requirements(data.InstanceRequirements)
+	}
+	return nil
+}
+
+// launchTemplateData returns the data of the launch template version used by
+// the group, or nil if it doesn't use a launch template or it couldn't be
+// described.
+func (a *autoScalingGroup) launchTemplateData() *ec2.ResponseLaunchTemplateData {
+
+	lt := a.launchTemplate()
+	if lt == nil {
+		return nil
+	}
+
+	version := lt.Version
+	if version == nil {
+		version = aws.String("$Default")
+	}
+
+	resp, err := a.region.services.ec2.DescribeLaunchTemplateVersions(
+		&ec2.DescribeLaunchTemplateVersionsInput{
+			LaunchTemplateId:   lt.LaunchTemplateId,
+			LaunchTemplateName: lt.LaunchTemplateName,
+			Versions:           []*string{version},
+		})
+
+	if err != nil {
+		a.log().at(LevelError).Println(a.name,
+			"Couldn't describe the launch template", err.Error())
+		return nil
+	}
+
+	for _, v := range resp.LaunchTemplateVersions {
+		if v.LaunchTemplateData != nil {
+			return v.LaunchTemplateData
+		}
+	}
+	return nil
+}
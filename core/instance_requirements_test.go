@@ -0,0 +1,94 @@
+package autospotting
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+func Test_instanceRequirements_matches(t *testing.T) {
+
+	req := fromAutoScalingRequirements(&autoscaling.InstanceRequirements{
+		VCpuCount:             &autoscaling.VCpuCountRequest{Min: aws.Int64(2), Max: aws.Int64(8)},
+		MemoryMiB:             &autoscaling.MemoryMiBRequest{Min: aws.Int64(4096)},
+		ExcludedInstanceTypes: []*string{aws.String("t*")},
+	})
+
+	allowedOnly := &instanceRequirements{
+		minVCPU: 2, allowed: []string{"m5.*", "c5.large"},
+	}
+
+	tests := []struct {
+		name string
+		req  *instanceRequirements
+		info instanceTypeInformation
+		want bool
+	}{
+		{
+			name: "within the ranges",
+			req:  req,
+			info: instanceTypeInformation{instanceType: "m5.xlarge", vCPU: 4, memory: 16},
+			want: true,
+		},
+		{
+			name: "too many vCPUs",
+			req:  req,
+			info: instanceTypeInformation{instanceType: "m5.4xlarge", vCPU: 16, memory: 64},
+			want: false,
+		},
+		{
+			name: "not enough memory",
+			req:  req,
+			info: instanceTypeInformation{instanceType: "c5.large", vCPU: 2, memory: 2},
+			want: false,
+		},
+		{
+			name: "excluded instance type",
+			req:  req,
+			info: instanceTypeInformation{instanceType: "t3.xlarge", vCPU: 4, memory: 16},
+			want: false,
+		},
+		{
+			name: "allowed instance type",
+			req:  allowedOnly,
+			info: instanceTypeInformation{instanceType: "c5.large", vCPU: 2, memory: 4},
+			want: true,
+		},
+		{
+			name: "instance type not allowed",
+			req:  allowedOnly,
+			info: instanceTypeInformation{instanceType: "r5.large", vCPU: 2, memory: 16},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.req.matches(tt.info); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_instanceRequirements_fromMixedInstancesPolicy(t *testing.T) {
+
+	a := &autoScalingGroup{Group: &autoscaling.Group{
+		MixedInstancesPolicy: &autoscaling.MixedInstancesPolicy{
+			LaunchTemplate: &autoscaling.LaunchTemplate{
+				Overrides: []*autoscaling.LaunchTemplateOverrides{{
+					InstanceRequirements: &autoscaling.InstanceRequirements{
+						VCpuCount: &autoscaling.VCpuCountRequest{Min: aws.Int64(4)},
+						MemoryMiB: &autoscaling.MemoryMiBRequest{Min: aws.Int64(8192)},
+					},
+				}},
+			},
+		},
+	}}
+
+	got := a.instanceRequirements()
+	if got == nil || got.minVCPU != 4 || got.minMemoryMiB != 8192 ||
+		got.maxVCPU != 0 || got.maxMemoryMiB != 0 {
+		t.Errorf("instanceRequirements() = %+v", got)
+	}
+}
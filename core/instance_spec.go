@@ -0,0 +1,141 @@
+package autospotting
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// launchConfigurationFromInstance reconstructs the launch configuration the
+// given instance could have been launched from.
+func (a *autoScalingGroup) launchConfigurationFromInstance(
+	inst *instance) *autoscaling.LaunchConfiguration {
+
+	a.log().Println(a.name, "Reconstructing the launch configuration from",
+		"instance", *inst.InstanceId)
+
+	lc := &autoscaling.LaunchConfiguration{
+		ImageId:      inst.ImageId,
+		InstanceType: inst.InstanceType,
+		KeyName:      inst.KeyName,
+		EbsOptimized: inst.EbsOptimized,
+	}
+
+	for _, sg := range inst.SecurityGroups {
+		lc.SecurityGroups = append(lc.SecurityGroups, sg.GroupId)
+	}
+
+	if inst.IamInstanceProfile != nil {
+		lc.IamInstanceProfile = inst.IamInstanceProfile.Arn
+	}
+
+	if inst.Monitoring != nil && inst.Monitoring.State != nil {
+		lc.InstanceMonitoring = &autoscaling.InstanceMonitoring{
+			Enabled: aws.Bool(*inst.Monitoring.State == ec2.MonitoringStateEnabled),
+		}
+	}
+
+	if inst.SubnetId != nil {
+		lc.AssociatePublicIpAddress = aws.Bool(inst.PublicIpAddress != nil)
+	}
+
+	lc.UserData, _ = a.instanceUserData(inst)
+
+	lc.BlockDeviceMappings = a.instanceBlockDeviceMappings(inst)
+	return lc
+}
+
+// instanceUserData returns the user data of the instance, nil if it has none,
+// and tells if it could be read.
+func (a *autoScalingGroup) instanceUserData(inst *instance) (*string, bool) {
+
+	resp, err := a.region.services.ec2.DescribeInstanceAttribute(
+		&ec2.DescribeInstanceAttributeInput{
+			InstanceId: inst.InstanceId,
+			Attribute:  aws.String(ec2.InstanceAttributeNameUserData),
+		})
+
+	if err != nil {
+		a.log().at(LevelWarn).Println(a.name, "Couldn't read the user data of",
+			*inst.InstanceId, err.Error())
+		return nil, false
+	}
+
+	if resp.UserData == nil {
+		return nil, true
+	}
+	return resp.UserData.Value, true
+}
+
+// instanceBlockDeviceMappings describes the EBS volumes attached to the
+// instance as launch configuration block device mappings, creating empty
+// volumes of the same size and type.
+func (a *autoScalingGroup) instanceBlockDeviceMappings(
+	inst *instance) []*autoscaling.BlockDeviceMapping {
+
+	var ids []*string
+	deleteOnTermination := make(map[string]*bool)
+	devices := make(map[string]*string)
+
+	for _, bdm := range inst.BlockDeviceMappings {
+		if bdm.Ebs == nil || bdm.Ebs.VolumeId == nil {
+			continue
+		}
+		ids = append(ids, bdm.Ebs.VolumeId)
+		deleteOnTermination[*bdm.Ebs.VolumeId] = bdm.Ebs.DeleteOnTermination
+		devices[*bdm.Ebs.VolumeId] = bdm.DeviceName
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	resp, err := a.region.services.ec2.DescribeVolumes(
+		&ec2.DescribeVolumesInput{VolumeIds: ids})
+
+	if err != nil {
+		a.log().at(LevelWarn).Println(a.name,
+			"Couldn't describe the volumes of",
+			*inst.InstanceId, err.Error())
+		return nil
+	}
+
+	return volumeBlockDeviceMappings(resp.Volumes, devices,
+		deleteOnTermination)
+}
+
+// volumeBlockDeviceMappings converts the given volumes to block device
+// mappings on the devices they're attached to. The root volume is created
+// from the AMI's snapshot, so only its size and type are kept.
+func volumeBlockDeviceMappings(volumes []*ec2.Volume,
+	devices map[string]*string,
+	deleteOnTermination map[string]*bool) []*autoscaling.BlockDeviceMapping {
+
+	var result []*autoscaling.BlockDeviceMapping
+
+	for _, v := range volumes {
+		device := devices[*v.VolumeId]
+		if device == nil {
+			continue
+		}
+
+		ebs := &autoscaling.Ebs{
+			DeleteOnTermination: deleteOnTermination[*v.VolumeId],
+			VolumeSize:          v.Size,
+			VolumeType:          v.VolumeType,
+		}
+		if v.VolumeType != nil && (*v.VolumeType == ec2.VolumeTypeIo1 ||
+			*v.VolumeType == ec2.VolumeTypeIo2) {
+			ebs.Iops = v.Iops
+		}
+		if v.Encrypted != nil && *v.Encrypted {
+			ebs.Encrypted = v.Encrypted
+		}
+
+		result = append(result, &autoscaling.BlockDeviceMapping{
+			DeviceName: device,
+			Ebs:        ebs,
+		})
+	}
+	return result
+}
@@ -0,0 +1,84 @@
+package autospotting
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_volumeBlockDeviceMappings(t *testing.T) {
+
+	tests := []struct {
+		name                string
+		volumes             []*ec2.Volume
+		devices             map[string]*string
+		deleteOnTermination map[string]*bool
+		want                []*autoscaling.BlockDeviceMapping
+	}{
+		{
+			name: "root and data volumes",
+			volumes: []*ec2.Volume{
+				{
+					VolumeId:   aws.String("vol-root"),
+					Size:       aws.Int64(8),
+					VolumeType: aws.String("gp2"),
+					Iops:       aws.Int64(100),
+				},
+				{
+					VolumeId:   aws.String("vol-data"),
+					Size:       aws.Int64(100),
+					VolumeType: aws.String("io1"),
+					Iops:       aws.Int64(1000),
+					Encrypted:  aws.Bool(true),
+				},
+			},
+			devices: map[string]*string{
+				"vol-root": aws.String("/dev/xvda"),
+				"vol-data": aws.String("/dev/xvdb"),
+			},
+			deleteOnTermination: map[string]*bool{
+				"vol-root": aws.Bool(true),
+				"vol-data": aws.Bool(false),
+			},
+			want: []*autoscaling.BlockDeviceMapping{
+				{
+					DeviceName: aws.String("/dev/xvda"),
+					Ebs: &autoscaling.Ebs{
+						DeleteOnTermination: aws.Bool(true),
+						VolumeSize:          aws.Int64(8),
+						VolumeType:          aws.String("gp2"),
+					},
+				},
+				{
+					DeviceName: aws.String("/dev/xvdb"),
+					Ebs: &autoscaling.Ebs{
+						DeleteOnTermination: aws.Bool(false),
+						VolumeSize:          aws.Int64(100),
+						VolumeType:          aws.String("io1"),
+						Iops:                aws.Int64(1000),
+						Encrypted:           aws.Bool(true),
+					},
+				},
+			},
+		},
+		{
+			name: "volume no longer attached",
+			volumes: []*ec2.Volume{
+				{VolumeId: aws.String("vol-gone"), Size: aws.Int64(8)},
+			},
+			devices: map[string]*string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := volumeBlockDeviceMappings(tt.volumes, tt.devices,
+				tt.deleteOnTermination)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("volumeBlockDeviceMappings() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
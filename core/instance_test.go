@@ -0,0 +1,159 @@
+package autospotting
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_instanceGeneration(t *testing.T) {
+	tests := []struct {
+		instanceType string
+		want         int
+	}{
+		{instanceType: "m4.large", want: 4},
+		{instanceType: "c6i.xlarge", want: 6},
+		{instanceType: "cc2.8xlarge", want: 2},
+		{instanceType: "p3dn.24xlarge", want: 3},
+		{instanceType: "unknown", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.instanceType, func(t *testing.T) {
+			if got := instanceGeneration(tt.instanceType); got != tt.want {
+				t.Errorf("instanceGeneration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_instance_isRecoverable(t *testing.T) {
+	tests := []struct {
+		name      string
+		lifecycle *string
+		state     string
+		reason    *string
+		want      bool
+	}{
+		{name: "Running spot instance",
+			lifecycle: aws.String("spot"),
+			state:     "running",
+			want:      false,
+		},
+		{name: "Spot instance stopped by an interruption",
+			lifecycle: aws.String("spot"),
+			state:     "stopped",
+			reason:    aws.String("Server.SpotInstanceShutdown"),
+			want:      true,
+		},
+		{name: "Spot instance stopped by the user",
+			lifecycle: aws.String("spot"),
+			state:     "stopped",
+			reason:    aws.String("Client.UserInitiatedShutdown"),
+			want:      false,
+		},
+		{name: "Stopped on-demand instance",
+			state:  "stopped",
+			reason: aws.String("Server.SpotInstanceShutdown"),
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it := &instance{Instance: &ec2.Instance{
+				InstanceLifecycle: tt.lifecycle,
+				State:             &ec2.InstanceState{Name: aws.String(tt.state)},
+				StateReason:       &ec2.StateReason{Code: tt.reason},
+			}}
+			if got := it.isRecoverable(); got != tt.want {
+				t.Errorf("isRecoverable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_instance_hourlySavings(t *testing.T) {
+	tests := []struct {
+		name       string
+		state      string
+		reason     *string
+		wantCost   float64
+		wantSaving float64
+	}{
+		{name: "Running spot instance",
+			state:      "running",
+			wantCost:   0.25,
+			wantSaving: 0.75,
+		},
+		{name: "Spot instance stopped by an interruption",
+			state:      "stopped",
+			reason:     aws.String("Server.SpotInstanceShutdown"),
+			wantCost:   0,
+			wantSaving: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it := &instance{
+				Instance: &ec2.Instance{
+					InstanceLifecycle: aws.String("spot"),
+					State:             &ec2.InstanceState{Name: aws.String(tt.state)},
+					StateReason:       &ec2.StateReason{Code: tt.reason},
+					Tags: []*ec2.Tag{{Key: aws.String("original-instance-price"),
+						Value: aws.String("1")}},
+				},
+				price: 0.25,
+			}
+			if got := it.hourlyCost(); got != tt.wantCost {
+				t.Errorf("hourlyCost() = %v, want %v", got, tt.wantCost)
+			}
+			if got := it.hourlySavings(); got != tt.wantSaving {
+				t.Errorf("hourlySavings() = %v, want %v", got, tt.wantSaving)
+			}
+		})
+	}
+}
+
+func Test_instanceTypeInformation_hasCapacityOf(t *testing.T) {
+	existing := instanceTypeInformation{vCPU: 4, memory: 16, gpu: 1}
+
+	tests := []struct {
+		name   string
+		info   instanceTypeInformation
+		factor float64
+		want   bool
+	}{
+		{name: "same capacity",
+			info:   instanceTypeInformation{vCPU: 4, memory: 16, gpu: 1},
+			factor: 1,
+			want:   true,
+		},
+		{name: "larger",
+			info:   instanceTypeInformation{vCPU: 8, memory: 32, gpu: 2},
+			factor: 1,
+			want:   true,
+		},
+		{name: "not enough memory",
+			info:   instanceTypeInformation{vCPU: 8, memory: 8, gpu: 1},
+			factor: 1,
+			want:   false,
+		},
+		{name: "no GPUs",
+			info:   instanceTypeInformation{vCPU: 8, memory: 32},
+			factor: 1,
+			want:   false,
+		},
+		{name: "smaller, within the performance factor",
+			info:   instanceTypeInformation{vCPU: 2, memory: 8, gpu: 1},
+			factor: 0.5,
+			want:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.info.hasCapacityOf(existing, tt.factor); got != tt.want {
+				t.Errorf("hasCapacityOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package autospotting
+
+import (
+	"strings"
+)
+
+// splitInstanceTypes splits a comma separated list of instance types.
+func splitInstanceTypes(list string) []string {
+	var types []string
+
+	for _, t := range strings.Split(list, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// allowedInstanceType tells if the instance type is in the allowed list, an
+// empty list allowing any instance type.
+func allowedInstanceType(instanceType, current string, allowed []string) bool {
+
+	if len(allowed) == 0 {
+		return true
+	}
+
+	for _, t := range allowed {
+		if t == instanceType || (t == "current" && instanceType == current) {
+			return true
+		}
+	}
+	return false
+}
+
+// disallowedInstanceType tells if the instance type matches any of the
+// disallowed patterns.
+func disallowedInstanceType(instanceType string, disallowed []string) bool {
+	return matchesAny(instanceType, disallowed)
+}
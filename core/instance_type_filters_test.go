@@ -0,0 +1,57 @@
+package autospotting
+
+import "testing"
+
+func Test_allowedInstanceType(t *testing.T) {
+
+	allowed := splitInstanceTypes("c5.xlarge, m5.xlarge,current")
+
+	tests := []struct {
+		name         string
+		instanceType string
+		allowed      []string
+		want         bool
+	}{
+		{name: "no restriction", instanceType: "t3.large", want: true},
+		{name: "listed", instanceType: "m5.xlarge", allowed: allowed,
+			want: true},
+		{name: "current", instanceType: "r5.large", allowed: allowed,
+			want: true},
+		{name: "not listed", instanceType: "t3.large", allowed: allowed,
+			want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := allowedInstanceType(tt.instanceType, "r5.large",
+				tt.allowed); got != tt.want {
+				t.Errorf("allowedInstanceType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_disallowedInstanceType(t *testing.T) {
+
+	disallowed := splitInstanceTypes("t2.*,m3.*")
+
+	tests := []struct {
+		name         string
+		instanceType string
+		disallowed   []string
+		want         bool
+	}{
+		{name: "no restriction", instanceType: "t2.micro", want: false},
+		{name: "matching", instanceType: "m3.medium", disallowed: disallowed,
+			want: true},
+		{name: "not matching", instanceType: "t3.micro",
+			disallowed: disallowed, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := disallowedInstanceType(tt.instanceType,
+				tt.disallowed); got != tt.want {
+				t.Errorf("disallowedInstanceType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package autospotting
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+// InterruptionWarning is the detail type of the EventBridge events warning
+// about spot instance interruptions.
+const InterruptionWarning = "EC2 Spot Instance Interruption Warning"
+
+// instanceIDs splits a comma separated list of instance IDs.
+func instanceIDs(list string) []string {
+	var ids []string
+
+	for _, id := range strings.Split(list, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// interruptedInstances returns the IDs of the spot instances about to be
+// interrupted, given by the event triggering the run.
+func (c *Config) interruptedInstances() []string {
+	return instanceIDs(c.InterruptedInstances)
+}
+
+// interruptedPool tells if a spot instance of the instance type is about to be
+// interrupted in the availability zone, or is at an elevated risk of being
+// interrupted.
+func (r *region) interruptedPool(instanceType, az string) bool {
+
+	ids := append(r.conf.interruptedInstances(), r.conf.atRiskInstances()...)
+
+	for _, id := range ids {
+		inst := r.instances.get(id)
+		if inst != nil && inst.InstanceType != nil && inst.Placement != nil &&
+			*inst.InstanceType == instanceType &&
+			*inst.Placement.AvailabilityZone == az {
+			return true
+		}
+	}
+	return false
+}
+
+// detachInterruptedInstances detaches the group's spot instances about to be
+// interrupted, keeping the group's desired capacity so that AutoScaling
+// replaces them immediately.
+func (a *autoScalingGroup) detachInterruptedInstances() {
+
+	for _, id := range a.region.conf.interruptedInstances() {
+
+		member := a.findGroupInstance(id)
+		if member == nil || member.LifecycleState == nil ||
+			*member.LifecycleState != autoscaling.LifecycleStateInService {
+			continue
+		}
+
+		a.log().Println(a.region.name, a.name, "Spot instance", id,
+			"is about to be interrupted, detaching it so it's replaced right away")
+
+		input := &autoscaling.DetachInstancesInput{
+			AutoScalingGroupName:           aws.String(a.name),
+			InstanceIds:                    []*string{aws.String(id)},
+			ShouldDecrementDesiredCapacity: aws.Bool(false),
+		}
+
+		if a.skipsCall("DetachInstances", input) {
+			continue
+		}
+
+		a.deregisterIPTargets(aws.String(id))
+
+		if _, err := a.region.services.autoScaling.DetachInstances(input); err != nil {
+			a.log().at(LevelError).Println(a.name,
+				"Failed to detach interrupted spot instance", id, err.Error())
+			continue
+		}
+		a.recordAction("interrupted", "spot instance", id,
+			"detached ahead of its interruption")
+		a.waitUntilDetached(aws.String(id))
+	}
+}
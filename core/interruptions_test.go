@@ -0,0 +1,43 @@
+package autospotting
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_region_interruptedPool(t *testing.T) {
+
+	r := region{
+		conf: Config{InterruptedInstances: "i-spot, i-gone"},
+		instances: instances{catalog: map[string]*instance{
+			"i-spot": {Instance: &ec2.Instance{
+				InstanceId:   aws.String("i-spot"),
+				InstanceType: aws.String("m5.large"),
+				Placement: &ec2.Placement{
+					AvailabilityZone: aws.String("us-east-1a"),
+				},
+			}},
+		}},
+	}
+
+	tests := []struct {
+		name         string
+		instanceType string
+		az           string
+		want         bool
+	}{
+		{name: "interrupted pool", instanceType: "m5.large", az: "us-east-1a",
+			want: true},
+		{name: "other zone", instanceType: "m5.large", az: "us-east-1b"},
+		{name: "other instance type", instanceType: "c5.large", az: "us-east-1a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.interruptedPool(tt.instanceType, tt.az); got != tt.want {
+				t.Errorf("interruptedPool() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package autospotting
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+)
+
+// ipTargetGroupCandidates returns the ARNs of the group's target groups and of
+// those listed in its ip_target_groups tag.
+func (a *autoScalingGroup) ipTargetGroupCandidates() []*string {
+
+	arns := append([]*string{}, a.TargetGroupARNs...)
+
+	if tag := a.stringSetting("ip_target_groups"); tag != "" {
+		for _, arn := range strings.Split(tag, ",") {
+			if arn = strings.TrimSpace(arn); arn != "" {
+				arns = append(arns, aws.String(arn))
+			}
+		}
+	}
+	return arns
+}
+
+// ipTargetGroups returns the ARNs of the target groups of the group using the
+// ip target type.
+func (a *autoScalingGroup) ipTargetGroups() []*string {
+
+	candidates := a.ipTargetGroupCandidates()
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	var arns []*string
+
+	err := a.region.services.elbv2.DescribeTargetGroupsPages(
+		&elbv2.DescribeTargetGroupsInput{TargetGroupArns: candidates},
+		func(page *elbv2.DescribeTargetGroupsOutput, lastPage bool) bool {
+			for _, tg := range page.TargetGroups {
+				if tg.TargetType != nil &&
+					*tg.TargetType == elbv2.TargetTypeEnumIp {
+					arns = append(arns, tg.TargetGroupArn)
+				}
+			}
+			return true
+		})
+
+	if err != nil {
+		a.log().at(LevelError).Println(a.name,
+			"Failed to describe the target groups", err.Error())
+	}
+	return arns
+}
+
+// instanceIPTarget returns the target for the instance's private IP address.
+func (a *autoScalingGroup) instanceIPTarget(
+	instanceID *string) *elbv2.TargetDescription {
+
+	inst := a.region.instances.get(*instanceID)
+	if inst == nil || inst.PrivateIpAddress == nil {
+		a.log().Println(a.name, "The private IP address of", *instanceID,
+			"is unknown")
+		return nil
+	}
+	return &elbv2.TargetDescription{Id: inst.PrivateIpAddress}
+}
+
+// registerIPTargets registers the instance's IP address with the group's ip
+// target groups.
+func (a *autoScalingGroup) registerIPTargets(instanceID *string) {
+
+	arns := a.ipTargetGroups()
+	if len(arns) == 0 {
+		return
+	}
+
+	target := a.instanceIPTarget(instanceID)
+	if target == nil {
+		return
+	}
+
+	for _, arn := range arns {
+		input := &elbv2.RegisterTargetsInput{
+			TargetGroupArn: arn,
+			Targets:        []*elbv2.TargetDescription{target},
+		}
+
+		if a.skipsCall("RegisterTargets", input) {
+			continue
+		}
+
+		_, err := a.region.services.elbv2.RegisterTargets(input)
+
+		if err != nil {
+			a.log().at(LevelError).Println(a.name, "Failed to register",
+				*target.Id, "of", *instanceID, "with", *arn, err.Error())
+			continue
+		}
+		a.recordAction("registered", "IP address", *target.Id, "of",
+			*instanceID, "with", *arn)
+	}
+}
+
+// deregisterIPTargets deregisters the instance's IP address from the group's
+// ip target groups, which then drain its connections.
+func (a *autoScalingGroup) deregisterIPTargets(instanceID *string) {
+
+	arns := a.ipTargetGroups()
+	if len(arns) == 0 {
+		return
+	}
+
+	target := a.instanceIPTarget(instanceID)
+	if target == nil {
+		return
+	}
+
+	for _, arn := range arns {
+		input := &elbv2.DeregisterTargetsInput{
+			TargetGroupArn: arn,
+			Targets:        []*elbv2.TargetDescription{target},
+		}
+
+		if a.skipsCall("DeregisterTargets", input) {
+			continue
+		}
+
+		_, err := a.region.services.elbv2.DeregisterTargets(input)
+
+		if err != nil {
+			a.log().at(LevelError).Println(a.name, "Failed to deregister",
+				*target.Id, "of", *instanceID, "from", *arn, err.Error())
+			continue
+		}
+		a.recordAction("deregistered", "IP address", *target.Id, "of",
+			*instanceID, "from", *arn)
+	}
+}
@@ -0,0 +1,49 @@
+package autospotting
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+func Test_ipTargetGroupCandidates(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		targetGroups []*string
+		tag          *string
+		want         []*string
+	}{
+		{name: "no target groups",
+			want: []*string{},
+		},
+		{name: "group target groups",
+			targetGroups: []*string{aws.String("arn:tg1")},
+			want:         []*string{aws.String("arn:tg1")},
+		},
+		{name: "group and tagged target groups",
+			targetGroups: []*string{aws.String("arn:tg1")},
+			tag:          aws.String("arn:tg2, arn:tg3,"),
+			want: []*string{aws.String("arn:tg1"), aws.String("arn:tg2"),
+				aws.String("arn:tg3")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &autoScalingGroup{Group: &autoscaling.Group{
+				TargetGroupARNs: tt.targetGroups,
+			}}
+			if tt.tag != nil {
+				a.Tags = []*autoscaling.TagDescription{{
+					Key:   aws.String("ip_target_groups"),
+					Value: tt.tag,
+				}}
+			}
+			if got := a.ipTargetGroupCandidates(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ipTargetGroupCandidates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package autospotting
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// applyTemplatePlacement copies the placement of the launch template data to
+// the spot placement, returning an error if spot instances can't be launched
+// with that placement.
+func applyTemplatePlacement(data *ec2.ResponseLaunchTemplateData,
+	placement *ec2.SpotPlacement) error {
+
+	if p := data.Placement; p != nil {
+
+		if p.HostResourceGroupArn != nil && *p.HostResourceGroupArn != "" {
+			return fmt.Errorf("spot instances can't be launched in the host "+
+				"resource group %s", *p.HostResourceGroupArn)
+		}
+
+		if p.Tenancy != nil && *p.Tenancy != "" {
+			if *p.Tenancy == ec2.TenancyHost {
+				return fmt.Errorf("spot instances can't run on dedicated hosts")
+			}
+			placement.Tenancy = p.Tenancy
+		}
+
+		if p.GroupName != nil && *p.GroupName != "" {
+			placement.GroupName = p.GroupName
+		}
+	}
+
+	// spot instances never use capacity reservations, which is only a problem
+	// when the template requires a specific one
+	if c := data.CapacityReservationSpecification; c != nil {
+		if t := c.CapacityReservationTarget; t != nil &&
+			(t.CapacityReservationId != nil ||
+				t.CapacityReservationResourceGroupArn != nil) {
+			return fmt.Errorf("spot instances can't be launched into " +
+				"capacity reservations")
+		}
+	}
+
+	return nil
+}
+
+// applyLaunchTemplatePlacement carries over the placement of the group's
+// launch template to the spot launch specification.
+func (a *autoScalingGroup) applyLaunchTemplatePlacement(
+	ls *ec2.RequestSpotLaunchSpecification) error {
+
+	data := a.launchTemplateData()
+	if data == nil {
+		return nil
+	}
+	return applyTemplatePlacement(data, ls.Placement)
+}
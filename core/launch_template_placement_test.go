@@ -0,0 +1,85 @@
+package autospotting
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_applyTemplatePlacement(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		data    *ec2.ResponseLaunchTemplateData
+		want    *ec2.SpotPlacement
+		wantErr bool
+	}{
+		{name: "no placement",
+			data: &ec2.ResponseLaunchTemplateData{},
+			want: &ec2.SpotPlacement{AvailabilityZone: aws.String("us-east-1a")},
+		},
+		{name: "dedicated tenancy and placement group",
+			data: &ec2.ResponseLaunchTemplateData{
+				Placement: &ec2.LaunchTemplatePlacement{
+					Tenancy:   aws.String("dedicated"),
+					GroupName: aws.String("cluster"),
+				},
+			},
+			want: &ec2.SpotPlacement{
+				AvailabilityZone: aws.String("us-east-1a"),
+				Tenancy:          aws.String("dedicated"),
+				GroupName:        aws.String("cluster"),
+			},
+		},
+		{name: "dedicated hosts",
+			data: &ec2.ResponseLaunchTemplateData{
+				Placement: &ec2.LaunchTemplatePlacement{
+					Tenancy: aws.String("host"),
+				},
+			},
+			wantErr: true,
+		},
+		{name: "host resource group",
+			data: &ec2.ResponseLaunchTemplateData{
+				Placement: &ec2.LaunchTemplatePlacement{
+					HostResourceGroupArn: aws.String("arn:aws:resource-groups:hosts"),
+				},
+			},
+			wantErr: true,
+		},
+		{name: "open capacity reservations",
+			data: &ec2.ResponseLaunchTemplateData{
+				CapacityReservationSpecification: &ec2.LaunchTemplateCapacityReservationSpecificationResponse{
+					CapacityReservationPreference: aws.String("open"),
+				},
+			},
+			want: &ec2.SpotPlacement{AvailabilityZone: aws.String("us-east-1a")},
+		},
+		{name: "targeted capacity reservation",
+			data: &ec2.ResponseLaunchTemplateData{
+				CapacityReservationSpecification: &ec2.LaunchTemplateCapacityReservationSpecificationResponse{
+					CapacityReservationTarget: &ec2.CapacityReservationTargetResponse{
+						CapacityReservationId: aws.String("cr-1"),
+					},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			placement := &ec2.SpotPlacement{AvailabilityZone: aws.String("us-east-1a")}
+
+			err := applyTemplatePlacement(tt.data, placement)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyTemplatePlacement() error = %v, wantErr %v", err,
+					tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(placement, tt.want) {
+				t.Errorf("applyTemplatePlacement() = %v, want %v", placement, tt.want)
+			}
+		})
+	}
+}
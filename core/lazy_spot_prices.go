@@ -0,0 +1,163 @@
+package autospotting
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+const (
+	// instance types whose prices are fetched by a single request
+	spotPriceBatchSize = 50
+
+	// concurrent spot price requests of a group
+	spotPriceFetchers = 4
+)
+
+// lazySpotPrices tells if the spot prices are only fetched when first needed,
+// which isn't the case when the arbitrage report compares all of them.
+func (r *region) lazySpotPrices() bool {
+	return r.conf.SpotPriceFetch == "lazy" && !arbitrage.enabled()
+}
+
+// spotPrice returns the spot price of the instance type in the availability
+// zone, fetching it if needed, or 0 if the instance type isn't available on the
+// spot market there.
+func (r *region) spotPrice(instanceType, az string) float64 {
+
+	r.prefetchSpotPrices(az, []string{instanceType})
+
+	r.spotPriceLock.Lock()
+	defer r.spotPriceLock.Unlock()
+
+	return r.instanceTypeInformation[instanceType].pricing.spot[az]
+}
+
+// unfetchedTypes returns the instance types of the region whose spot prices
+// in the availability zone weren't fetched yet.
+func (r *region) unfetchedTypes(az string, types []string) []*string {
+	r.spotPriceLock.Lock()
+	defer r.spotPriceLock.Unlock()
+
+	var missing []*string
+	for _, t := range types {
+		if _, known := r.instanceTypeInformation[t]; !known ||
+			r.fetchedPools[poolKey(t, az)] {
+			continue
+		}
+		missing = append(missing, aws.String(t))
+	}
+	return missing
+}
+
+// storeSpotPrices records the fetched spot prices, the instance types missing
+// from them being unavailable on the spot market in the availability zone.
+func (r *region) storeSpotPrices(az string, types []string,
+	prices map[string]float64) {
+	r.spotPriceLock.Lock()
+	defer r.spotPriceLock.Unlock()
+
+	for _, t := range types {
+		if price, found := prices[t]; found {
+			r.instanceTypeInformation[t].pricing.spot[az] = price
+		}
+		r.fetchedPools[poolKey(t, az)] = true
+	}
+}
+
+// prefetchSpotPrices fetches in parallel batches the missing spot prices of the
+// instance types in the availability zone. Failed batches are retried the next
+// time they're needed.
+func (r *region) prefetchSpotPrices(az string, types []string) {
+
+	if r.fetchedPools == nil {
+		return
+	}
+
+	var wg sync.WaitGroup
+	fetchers := make(chan struct{}, spotPriceFetchers)
+
+	for _, batch := range batchNames(r.unfetchedTypes(az, types),
+		spotPriceBatchSize) {
+
+		wg.Add(1)
+		go func(batch []string) {
+			defer wg.Done()
+
+			fetchers <- struct{}{}
+			defer func() { <-fetchers }()
+
+			prices, err := r.fetchSpotPrices(az, aws.StringSlice(batch))
+			if err != nil {
+				r.log().at(LevelError).Println(r.name,
+					"Failed to fetch the spot prices of", len(batch),
+					"instance types in", az, err.Error())
+				return
+			}
+			r.storeSpotPrices(az, batch, prices)
+		}(batch)
+	}
+	wg.Wait()
+}
+
+// fetchSpotPrices returns the current spot prices of the instance types in the
+// availability zone.
+func (r *region) fetchSpotPrices(az string,
+	types []*string) (map[string]float64, error) {
+
+	prices := make(map[string]float64)
+
+	err := r.services.ec2.DescribeSpotPriceHistoryPages(
+		&ec2.DescribeSpotPriceHistoryInput{
+			ProductDescriptions: []*string{aws.String("Linux/UNIX")},
+			StartTime:           aws.Time(time.Now()),
+			AvailabilityZone:    aws.String(az),
+			InstanceTypes:       types,
+		},
+		func(page *ec2.DescribeSpotPriceHistoryOutput, lastPage bool) bool {
+			for _, p := range page.SpotPriceHistory {
+				price, err := strconv.ParseFloat(aws.StringValue(p.SpotPrice), 64)
+				if err != nil {
+					continue
+				}
+				// the most recent price comes first
+				if _, found := prices[*p.InstanceType]; !found {
+					prices[*p.InstanceType] = price
+				}
+			}
+			return true
+		})
+
+	return prices, err
+}
+
+// prefetchInstancePrices fetches at once the spot prices of the group's spot
+// instances.
+func (a *autoScalingGroup) prefetchInstancePrices() {
+
+	types := make(map[string][]string)
+	for _, inst := range a.Instances {
+		i := a.region.instances.get(*inst.InstanceId)
+		if i == nil || !i.isSpot() {
+			continue
+		}
+		az := *i.Placement.AvailabilityZone
+		types[az] = append(types[az], *i.InstanceType)
+	}
+
+	for az, t := range types {
+		a.region.prefetchSpotPrices(az, t)
+	}
+}
+
+// regionInstanceTypes returns all the instance types available in the region.
+func (r *region) regionInstanceTypes() []string {
+	var types []string
+	for t := range r.instanceTypeInformation {
+		types = append(types, t)
+	}
+	return types
+}
@@ -0,0 +1,54 @@
+package autospotting
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func Test_region_lazySpotPrices(t *testing.T) {
+
+	r := region{
+		instanceTypeInformation: map[string]instanceTypeInformation{
+			"m5.large": {instanceType: "m5.large",
+				pricing: prices{spot: spotPriceMap{}}},
+			"c5.large": {instanceType: "c5.large",
+				pricing: prices{spot: spotPriceMap{}}},
+			"x1.32xlarge": {instanceType: "x1.32xlarge",
+				pricing: prices{spot: spotPriceMap{}}},
+		},
+		fetchedPools: make(map[string]bool),
+	}
+
+	missing := aws.StringValueSlice(r.unfetchedTypes("us-east-1a",
+		[]string{"m5.large", "c5.large", "unknown.type"}))
+	if want := []string{"m5.large", "c5.large"}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("unfetchedTypes() = %v, want %v", missing, want)
+	}
+
+	// x1.32xlarge isn't available on the spot market in the zone
+	r.storeSpotPrices("us-east-1a", []string{"m5.large", "x1.32xlarge"},
+		map[string]float64{"m5.large": 0.03})
+
+	missing = aws.StringValueSlice(r.unfetchedTypes("us-east-1a",
+		[]string{"m5.large", "c5.large", "x1.32xlarge"}))
+	if want := []string{"c5.large"}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("unfetchedTypes() after storing = %v, want %v", missing, want)
+	}
+
+	tests := []struct {
+		instanceType string
+		want         float64
+	}{
+		{instanceType: "m5.large", want: 0.03},
+		{instanceType: "x1.32xlarge", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.instanceType, func(t *testing.T) {
+			if got := r.spotPrice(tt.instanceType, "us-east-1a"); got != tt.want {
+				t.Errorf("spotPrice() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
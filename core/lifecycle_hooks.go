@@ -0,0 +1,49 @@
+package autospotting
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+// launchHookNames returns the names of the group's launch lifecycle hooks.
+func launchHookNames(hooks []*autoscaling.LifecycleHook) []string {
+	var names []string
+
+	for _, h := range hooks {
+		if h.LifecycleTransition != nil && h.LifecycleHookName != nil &&
+			*h.LifecycleTransition == "autoscaling:EC2_INSTANCE_LAUNCHING" {
+			names = append(names, *h.LifecycleHookName)
+		}
+	}
+	return names
+}
+
+// hasLaunchHooks tells if the group has launch lifecycle hooks, describing
+// them once per run.
+func (a *autoScalingGroup) hasLaunchHooks() bool {
+
+	if a.launchHooks != nil {
+		return len(a.launchHooks) > 0
+	}
+	a.launchHooks = []string{}
+
+	resp, err := a.region.services.autoScaling.DescribeLifecycleHooks(
+		&autoscaling.DescribeLifecycleHooksInput{
+			AutoScalingGroupName: aws.String(a.name),
+		})
+
+	if err != nil {
+		a.log().at(LevelWarn).Println(a.name,
+			"Couldn't describe the lifecycle hooks", err.Error())
+		return false
+	}
+
+	a.launchHooks = append(a.launchHooks,
+		launchHookNames(resp.LifecycleHooks)...)
+
+	if len(a.launchHooks) > 0 {
+		a.log().Println(a.name, "Has the launch lifecycle hooks", a.launchHooks,
+			"replacing its instances using the Standby state")
+	}
+	return len(a.launchHooks) > 0
+}
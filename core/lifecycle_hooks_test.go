@@ -0,0 +1,31 @@
+package autospotting
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+func Test_launchHookNames(t *testing.T) {
+	hooks := []*autoscaling.LifecycleHook{
+		{
+			LifecycleHookName:   aws.String("join-cluster"),
+			LifecycleTransition: aws.String("autoscaling:EC2_INSTANCE_LAUNCHING"),
+		},
+		{
+			LifecycleHookName:   aws.String("drain"),
+			LifecycleTransition: aws.String("autoscaling:EC2_INSTANCE_TERMINATING"),
+		},
+	}
+
+	want := []string{"join-cluster"}
+	if got := launchHookNames(hooks); !reflect.DeepEqual(got, want) {
+		t.Errorf("launchHookNames() = %v, want %v", got, want)
+	}
+
+	if got := launchHookNames(nil); len(got) != 0 {
+		t.Errorf("launchHookNames() = %v, want none", got)
+	}
+}
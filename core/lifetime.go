@@ -0,0 +1,80 @@
+package autospotting
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+// agingSpotInstance returns the oldest spot instance of the group which is
+// within the margin of reaching the group's maximum instance lifetime.
+func (a *autoScalingGroup) agingSpotInstance(now time.Time) *instance {
+
+	if a.MaxInstanceLifetime == nil || *a.MaxInstanceLifetime <= 0 {
+		return nil
+	}
+
+	maxLifetime := time.Duration(*a.MaxInstanceLifetime) * time.Second
+	deadline := now.Add(-maxLifetime + a.region.conf.LifetimeMargin)
+
+	var oldest *instance
+
+	for _, inst := range a.instances.catalog {
+		if !inst.isSpot() || inst.LaunchTime == nil ||
+			*inst.State.Name != "running" || inst.LaunchTime.After(deadline) {
+			continue
+		}
+		if oldest == nil || inst.LaunchTime.Before(*oldest.LaunchTime) {
+			oldest = inst
+		}
+	}
+	return oldest
+}
+
+// recycleAgingSpotInstance terminates a spot instance about to reach the
+// maximum instance lifetime, letting AutoScaling replace it, and tells if any
+// was recycled.
+func (a *autoScalingGroup) recycleAgingSpotInstance() bool {
+
+	inst := a.agingSpotInstance(time.Now())
+	if inst == nil {
+		return false
+	}
+
+	attachFirst, allowed := replacementOrder(*a.DesiredCapacity, *a.MinSize,
+		a.healthyInstanceCount(), a.InstanceMaintenancePolicy)
+
+	if !allowed || attachFirst {
+		a.log().Println(a.name, "Spot instance", *inst.InstanceId,
+			"is about to reach the maximum instance lifetime, but the group",
+			"can't currently afford losing an instance")
+		return false
+	}
+
+	a.log().Println(a.name, "Recycling spot instance", *inst.InstanceId,
+		"launched at", *inst.LaunchTime, "before it reaches the maximum",
+		"instance lifetime")
+
+	input := &autoscaling.TerminateInstanceInAutoScalingGroupInput{
+		InstanceId:                     inst.InstanceId,
+		ShouldDecrementDesiredCapacity: aws.Bool(false),
+	}
+
+	if a.skipsCall("TerminateInstanceInAutoScalingGroup", input) {
+		return false
+	}
+
+	_, err := a.region.services.autoScaling.TerminateInstanceInAutoScalingGroup(
+		input)
+
+	if err != nil {
+		a.log().at(LevelError).Println(a.name,
+			"Failed to terminate spot instance", *inst.InstanceId, err.Error())
+		return false
+	}
+
+	a.recordAction("recycled", "spot instance", *inst.InstanceId,
+		"approaching the maximum instance lifetime")
+	return true
+}
@@ -0,0 +1,68 @@
+package autospotting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_agingSpotInstance(t *testing.T) {
+
+	now := time.Now()
+
+	newInstance := func(id string, age time.Duration, lifecycle *string) *instance {
+		return &instance{Instance: &ec2.Instance{
+			InstanceId:        aws.String(id),
+			InstanceLifecycle: lifecycle,
+			LaunchTime:        aws.Time(now.Add(-age)),
+			State:             &ec2.InstanceState{Name: aws.String("running")},
+		}}
+	}
+
+	catalog := map[string]*instance{
+		"young":    newInstance("young", time.Hour, aws.String("spot")),
+		"aging":    newInstance("aging", 23*time.Hour+30*time.Minute, aws.String("spot")),
+		"oldest":   newInstance("oldest", 23*time.Hour+45*time.Minute, aws.String("spot")),
+		"ondemand": newInstance("ondemand", 48*time.Hour, nil),
+	}
+
+	tests := []struct {
+		name        string
+		maxLifetime *int64
+		margin      time.Duration
+		want        *string
+	}{
+		{name: "No maximum lifetime",
+			maxLifetime: nil,
+			margin:      time.Hour,
+			want:        nil,
+		},
+		{name: "Oldest instance within the margin",
+			maxLifetime: aws.Int64(86400),
+			margin:      time.Hour,
+			want:        aws.String("oldest"),
+		},
+		{name: "No instances within the margin",
+			maxLifetime: aws.Int64(86400),
+			margin:      time.Minute,
+			want:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := autoScalingGroup{
+				Group:     &autoscaling.Group{MaxInstanceLifetime: tt.maxLifetime},
+				region:    &region{conf: Config{LifetimeMargin: tt.margin}},
+				instances: instances{catalog: catalog},
+			}
+			got := a.agingSpotInstance(now)
+			if (got == nil) != (tt.want == nil) ||
+				(got != nil && *got.InstanceId != *tt.want) {
+				t.Errorf("agingSpotInstance() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,35 +1,91 @@
 package autospotting
 
 import (
-	"io/ioutil"
-	"log"
 	"os"
 	"sync"
+	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 )
 
-var logger, debug *log.Logger
+var logger, debug *levelLogger
 
 // Run starts processing all AWS regions looking for AutoScaling groups
 // enabled and taking action by replacing more pricy on-demand instances with
-// compatible and cheaper spot instances.
-func Run(cfg Config) {
+// compatible and cheaper spot instances. It returns a summary of the outcome
+// of the run.
+func Run(cfg Config) RunResult {
 
-	logger = log.New(cfg.LogFile, "", cfg.LogFlag)
+	start := time.Now()
+	runResult.init(start)
 
-	if os.Getenv("AUTOSPOTTING_DEBUG") == "true" {
-		debug = log.New(cfg.LogFile, "", cfg.LogFlag)
-	} else {
-		debug = log.New(ioutil.Discard, "", 0)
+	if cfg.Deadline.IsZero() && cfg.TimeBudget > 0 {
+		cfg.Deadline = start.Add(cfg.TimeBudget)
 	}
 
+	initLogging(cfg)
+	awsSessions.init(cfg)
+
+	// before claiming the run, so state store failures can be alerted
+	notifications.init(cfg)
+	state = newStateStore(cfg)
+	migrateState(stateTables(cfg))
+
+	if !claimRun(cfg) {
+		result := runResult.finish(start)
+		result.Duplicate = true
+		quietLog.finish(result)
+		return result
+	}
+
+	dumps.init(cfg, debugScopes.enabled())
+	reports.init(cfg)
+	actions.init(cfg)
+	fleetState.init(cfg)
+	applications.init(cfg)
+	cloudWatch.init(cfg)
+	savingsHistory.init(cfg)
+	currency.init(cfg)
+	planner.init()
+	access.init()
+	regionalPrices.init()
+	arbitrage.init(cfg)
+	candidateExports.init(cfg)
+	continuation.load(cfg)
+
 	debug.Println(cfg)
 
 	processAllRegions(cfg)
 
+	stats.observeRun(start)
+
+	result := runResult.finish(start)
+	result.Continuation, result.Unfinished = continuation.marker()
+	quietLog.finish(result)
+	return result
+}
+
+// initLogging sets up the loggers and tells if debugging is enabled.
+func initLogging(cfg Config) bool {
+
+	level, err := parseLogLevel(cfg.LogLevel)
+
+	if os.Getenv("AUTOSPOTTING_DEBUG") == "true" {
+		level = LevelDebug
+	}
+
+	sink := newLogSink(cfg, quietLog.init(cfg), level)
+	sink.handler = quietLog.observe(sink.handler)
+	logger = newLogger(sink, LevelInfo)
+	debug = newLogger(sink, LevelDebug)
+
+	if err != nil {
+		logger.at(LevelWarn).Println(err.Error(), "using the info level")
+	}
+
+	debugEnabled := level == LevelDebug
+	debugScopes.init(cfg, sink, debugEnabled)
+	return debugEnabled
 }
 
 // processAllRegions iterates all regions in parallel, and replaces instances
@@ -38,13 +94,21 @@ func processAllRegions(cfg Config) {
 
 	var wg sync.WaitGroup
 
-	regions, err := getRegions()
+	regions, err := warmCache.regions(cfg.WarmCacheTTL)
 
 	if err != nil {
-		logger.Println(err.Error())
+		logger.at(LevelError).Println(err.Error())
+		notifyPlatformError(homeRegion(), "Failed to list the regions", err.Error())
 		return
 	}
 
+	savingsPlans.load(cfg)
+	healthEvents.load(cfg)
+	denyList.load(cfg)
+	groupChanges.load(cfg)
+	spotAdvisor.load(cfg)
+	spotShare.init(cfg, regions)
+
 	for _, r := range regions {
 
 		wg.Add(1)
@@ -55,14 +119,36 @@ func processAllRegions(cfg Config) {
 			if r.enabled() {
 				logger.Printf("Enabled to run in %s, processing region.\n", r.name)
 				r.processRegion()
+				runResult.addRegion(r.name, len(r.enabledASGs))
+				quietLog.addRegion(r.name, len(r.enabledASGs))
 			} else {
 				logger.Println("Not enabled to run in", r.name, "\nList of enabled regions:", regions)
 			}
+			spotShare.done(r.name)
 
 			wg.Done()
 		}()
 	}
 	wg.Wait()
+
+	if cfg.Plan {
+		planner.export()
+		return
+	}
+
+	if access.reportOnly() != "" {
+		planner.export()
+	}
+
+	savingsPlans.exportReport()
+	fleetState.export()
+	applications.export()
+	arbitrage.export()
+	candidateExports.export()
+	savingsHistory.store()
+	groupChanges.store()
+	cloudWatch.publish()
+	notifications.flush()
 }
 
 // getRegions generates a list of AWS regions.
@@ -72,20 +158,13 @@ func getRegions() ([]string, error) {
 	logger.Println("Scanning for available AWS regions")
 
 	// This turns out to be much faster when running locally than using region
-	// auto-detection, and anyway due to Lambda limitations we currently only
-	// support running it from this region.
-	currentRegion := "us-east-1"
-
-	svc := ec2.New(
-		session.New(
-			&aws.Config{
-				Region: aws.String(currentRegion),
-			}))
+	// auto-detection, and works on hosts without EC2 instance metadata.
+	svc := ec2.New(instrumentSession(homeSession()))
 
 	resp, err := svc.DescribeRegions(&ec2.DescribeRegionsInput{})
 
 	if err != nil {
-		logger.Println(err.Error())
+		logger.at(LevelError).Println(err.Error())
 		return nil, err
 	}
 
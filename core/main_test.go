@@ -1 +1,12 @@
 package autospotting
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	logger = discardLogger()
+	debug = discardLogger()
+	os.Exit(m.Run())
+}
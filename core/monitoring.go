@@ -0,0 +1,18 @@
+package autospotting
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// applyMonitoring overrides the detailed monitoring of the spot launch
+// specification according to the spot_monitoring setting.
+func applyMonitoring(ls *ec2.RequestSpotLaunchSpecification, mode string) {
+
+	switch mode {
+	case "enable":
+		ls.Monitoring = &ec2.RunInstancesMonitoringEnabled{Enabled: aws.Bool(true)}
+	case "disable":
+		ls.Monitoring = &ec2.RunInstancesMonitoringEnabled{Enabled: aws.Bool(false)}
+	}
+}
@@ -0,0 +1,45 @@
+package autospotting
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_applyMonitoring(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		lc      *bool
+		mode    string
+		enabled *bool
+	}{
+		{name: "copied", lc: aws.Bool(true), mode: "copy", enabled: aws.Bool(true)},
+		{name: "copied, unset", mode: "copy"},
+		{name: "unknown mode", lc: aws.Bool(true), mode: "",
+			enabled: aws.Bool(true)},
+		{name: "disabled", lc: aws.Bool(true), mode: "disable",
+			enabled: aws.Bool(false)},
+		{name: "enabled", mode: "enable", enabled: aws.Bool(true)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ls := &ec2.RequestSpotLaunchSpecification{}
+			if tt.lc != nil {
+				ls.Monitoring = &ec2.RunInstancesMonitoringEnabled{Enabled: tt.lc}
+			}
+
+			applyMonitoring(ls, tt.mode)
+
+			var got *bool
+			if ls.Monitoring != nil {
+				got = ls.Monitoring.Enabled
+			}
+			if aws.BoolValue(got) != aws.BoolValue(tt.enabled) ||
+				(got == nil) != (tt.enabled == nil) {
+				t.Errorf("applyMonitoring() enabled = %v, want %v", got, tt.enabled)
+			}
+		})
+	}
+}
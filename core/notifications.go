@@ -0,0 +1,370 @@
+package autospotting
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+)
+
+var notifications notifier
+
+// client of the webhook based sinks, so that a slow endpoint can't hold up the
+// run
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// actions recorded on the groups which also trigger notifications
+var notifiedActions = map[string]bool{
+	"bid-failed":    true,
+	"attach-failed": true,
+}
+
+// actions only included in the digests sent at the end of each run
+var digestActions = map[string]bool{
+	"adopted":     true,
+	"attached":    true,
+	"handed-over": true,
+	"queued":      true,
+	"recycled":    true,
+	"restored":    true,
+	"standby":     true,
+	"stopped":     true,
+	"terminated":  true,
+}
+
+type notification struct {
+	Time             time.Time `json:"time"`
+	Region           string    `json:"region"`
+	AutoScalingGroup string    `json:"autoscaling_group,omitempty"`
+	Event            string    `json:"event"`
+	Message          string    `json:"message"`
+
+	// current hourly savings of the group, set on the savings events
+	HourlySavings float64 `json:"hourly_savings,omitempty"`
+
+	// alerts about conditions threatening the capacity of the groups or the
+	// correctness of AutoSpotting, also delivered to the paging sinks
+	Critical bool `json:"critical,omitempty"`
+
+	// only delivered to the digest sinks
+	digestOnly bool
+}
+
+// shorten cuts the string to at most max bytes, ending it with an ellipsis.
+func shorten(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max - len("...")
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "..."
+}
+
+func (n notification) subject() string {
+	if n.AutoScalingGroup == "" {
+		return fmt.Sprintf("AutoSpotting %s in %s", n.Event, n.Region)
+	}
+	return fmt.Sprintf("AutoSpotting %s for %s in %s", n.Event,
+		n.AutoScalingGroup, n.Region)
+}
+
+type notificationSink interface {
+	send(n notification) error
+}
+
+// digestSink collects the notifications and delivers them all at once at the
+// end of the run.
+type digestSink interface {
+	notificationSink
+	flush() error
+}
+
+// notificationRoute sends the events of the groups tagged with key=value to
+// the target.
+type notificationRoute struct {
+	key, value, target string
+}
+
+type notifier struct {
+	sync.Mutex
+
+	routes []notificationRoute
+
+	// target of the platform-wide errors and of the unrouted group events
+	defaultTarget string
+
+	// targets also receiving all the alerts
+	alertTargets []string
+
+	// sinks already created, keyed by target
+	sinks map[string]notificationSink
+
+	// settings of the email sinks
+	emailSender   string
+	emailRegion   string
+	emailTemplate *template.Template
+}
+
+func (n *notifier) init(cfg Config) {
+	n.Lock()
+	defer n.Unlock()
+
+	n.routes = parseNotificationRoutes(cfg.NotificationRoutes)
+	n.defaultTarget = cfg.NotificationTarget
+
+	n.alertTargets = nil
+	for _, t := range strings.Split(cfg.AlertTargets, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			n.alertTargets = append(n.alertTargets, t)
+		}
+	}
+	n.sinks = make(map[string]notificationSink)
+	n.emailSender = cfg.EmailSender
+	n.emailRegion = cfg.EmailRegion
+	n.emailTemplate = loadDigestTemplate(cfg.EmailTemplate)
+}
+
+// parseNotificationRoutes parses a comma-separated list of routes, each given
+// as key=value:target, such as team=payments:arn:aws:sns:us-east-1:1234:pay.
+func parseNotificationRoutes(s string) []notificationRoute {
+	var routes []notificationRoute
+
+	for _, r := range strings.Split(s, ",") {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+
+		tag := strings.SplitN(r, "=", 2)
+		if len(tag) != 2 {
+			logger.at(LevelWarn).Println("Ignoring invalid notification route",
+				r)
+			continue
+		}
+
+		value := strings.SplitN(tag[1], ":", 2)
+		if len(value) != 2 || tag[0] == "" || value[1] == "" {
+			logger.at(LevelWarn).Println("Ignoring invalid notification route",
+				r)
+			continue
+		}
+		routes = append(routes, notificationRoute{
+			key:    tag[0],
+			value:  value[0],
+			target: value[1],
+		})
+	}
+	return routes
+}
+
+// notificationTarget returns where the events of the group are sent, the first
+// route matching the group's tags or otherwise the default target.
+func (a *autoScalingGroup) notificationTarget() string {
+
+	for _, route := range notifications.routes {
+		if tag := a.getTagValue(route.key); tag != nil && *tag == route.value {
+			return route.target
+		}
+	}
+	return notifications.defaultTarget
+}
+
+// notify sends a notification about an event concerning the group.
+func (a *autoScalingGroup) notify(event string, details ...interface{}) {
+	a.sendNotification(notification{
+		Event:   event,
+		Message: strings.TrimSpace(fmt.Sprintln(details...)),
+	})
+}
+
+// notifyDigest adds an event concerning the group to the digests.
+func (a *autoScalingGroup) notifyDigest(event string, details ...interface{}) {
+	a.sendNotification(notification{
+		Event:      event,
+		Message:    strings.TrimSpace(fmt.Sprintln(details...)),
+		digestOnly: true,
+	})
+}
+
+// notifySavings adds the current savings of the group to the digests.
+func (a *autoScalingGroup) notifySavings() {
+
+	savings := 0.0
+	for _, inst := range a.instances.catalog {
+		savings += inst.hourlySavings()
+	}
+
+	a.sendNotification(notification{
+		Event:         "savings",
+		HourlySavings: savings,
+		digestOnly:    true,
+	})
+}
+
+func (a *autoScalingGroup) sendNotification(n notification) {
+
+	target := a.notificationTarget()
+	if target == "" {
+		return
+	}
+
+	n.Time = time.Now().UTC()
+	n.Region, n.AutoScalingGroup = a.region.name, a.name
+	notifications.send(target, n)
+}
+
+// notifyPlatformError sends a notification about an error not concerning any
+// particular group to the default target.
+func notifyPlatformError(region string, details ...interface{}) {
+
+	runResult.addError(region, details...)
+
+	logger.at(LevelError).With(map[string]string{"region": region}).
+		Println(details...)
+
+	if notifications.defaultTarget == "" {
+		return
+	}
+
+	notifications.send(notifications.defaultTarget, notification{
+		Time:    time.Now().UTC(),
+		Region:  region,
+		Event:   "error",
+		Message: strings.TrimSpace(fmt.Sprintln(details...)),
+	})
+}
+
+func (n *notifier) send(target string, msg notification) {
+
+	sink := n.sink(target)
+	if sink == nil {
+		return
+	}
+
+	if _, ok := sink.(digestSink); msg.digestOnly && !ok {
+		return
+	}
+
+	if err := sink.send(msg); err != nil {
+		logger.at(LevelError).Println("Failed to send notification to", target,
+			err.Error())
+	}
+}
+
+// sink returns the sink delivering to the target, creating it if needed.
+func (n *notifier) sink(target string) notificationSink {
+	n.Lock()
+	defer n.Unlock()
+
+	if s, ok := n.sinks[target]; ok {
+		return s
+	}
+
+	var s notificationSink
+
+	switch {
+	case strings.HasPrefix(target, "arn:aws:sns:"):
+		s = &snsSink{topic: target}
+	case strings.HasPrefix(target, "https://"):
+		s = &slackSink{webhook: target}
+	case strings.HasPrefix(target, "pagerduty:"):
+		s = &pagerDutySink{routingKey: strings.TrimPrefix(target, "pagerduty:")}
+	case strings.HasPrefix(target, "opsgenie:"):
+		s = &opsGenieSink{apiKey: strings.TrimPrefix(target, "opsgenie:")}
+	case strings.HasPrefix(target, "mailto:"):
+		s = &emailSink{
+			recipient: strings.TrimPrefix(target, "mailto:"),
+			sender:    n.emailSender,
+			region:    n.emailRegion,
+			template:  n.emailTemplate,
+		}
+	default:
+		logger.Println("Unsupported notification target", target)
+	}
+
+	if n.sinks != nil {
+		n.sinks[target] = s
+	}
+	return s
+}
+
+// flush delivers the digests collected during the run.
+func (n *notifier) flush() {
+	n.Lock()
+	defer n.Unlock()
+
+	for target, sink := range n.sinks {
+		if d, ok := sink.(digestSink); ok {
+			if err := d.flush(); err != nil {
+				logger.at(LevelError).Println("Failed to send the digest to",
+					target, err.Error())
+			}
+		}
+	}
+}
+
+// SNS rejects the messages having longer subjects
+const maxSNSSubjectLength = 100
+
+// snsSink publishes to an SNS topic, connecting to the topic's region.
+type snsSink struct {
+	topic string
+
+	connect sync.Once
+	svc     *sns.SNS
+}
+
+func (s *snsSink) send(n notification) error {
+
+	s.connect.Do(func() {
+		// arn:aws:sns:region:account:name
+		region := strings.Split(s.topic, ":")[3]
+
+		s.svc = sns.New(instrumentSession(
+			newSession(&aws.Config{Region: aws.String(region)})))
+	})
+
+	_, err := s.svc.Publish(&sns.PublishInput{
+		TopicArn: aws.String(s.topic),
+		Subject:  aws.String(shorten(n.subject(), maxSNSSubjectLength)),
+		Message:  aws.String(n.Message),
+	})
+	return err
+}
+
+// slackSink posts to a Slack incoming webhook.
+type slackSink struct {
+	webhook string
+}
+
+func (s *slackSink) send(n notification) error {
+
+	body, err := json.Marshal(map[string]string{
+		"text": "*" + n.subject() + "*\n" + n.Message,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := webhookClient.Post(s.webhook, "application/json",
+		bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package autospotting
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+type fakeSink struct {
+	sent []notification
+}
+
+func (s *fakeSink) send(n notification) error {
+	s.sent = append(s.sent, n)
+	return nil
+}
+
+func Test_parseNotificationRoutes(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes string
+		want   []notificationRoute
+	}{
+		{
+			name:   "empty",
+			routes: "",
+			want:   nil,
+		},
+		{
+			name: "SNS topic and Slack webhook",
+			routes: "team=payments:arn:aws:sns:us-east-1:123456789012:payments, " +
+				"team=search:https://hooks.slack.com/services/T/B/X",
+			want: []notificationRoute{
+				{
+					key:    "team",
+					value:  "payments",
+					target: "arn:aws:sns:us-east-1:123456789012:payments",
+				},
+				{
+					key:    "team",
+					value:  "search",
+					target: "https://hooks.slack.com/services/T/B/X",
+				},
+			},
+		},
+		{
+			name:   "invalid routes are skipped",
+			routes: "team,team=payments,=x:arn:aws:sns:us-east-1:1:x",
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseNotificationRoutes(tt.routes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseNotificationRoutes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_notify(t *testing.T) {
+
+	payments, sre := &fakeSink{}, &fakeSink{}
+
+	notifications.init(Config{
+		NotificationRoutes: "team=payments:payments-target",
+		NotificationTarget: "sre-target",
+	})
+	notifications.sinks["payments-target"] = payments
+	notifications.sinks["sre-target"] = sre
+	defer notifications.init(Config{})
+
+	newGroup := func(team string) *autoScalingGroup {
+		return &autoScalingGroup{
+			name:   "asg-" + team,
+			region: &region{name: "us-east-1"},
+			Group: &autoscaling.Group{Tags: []*autoscaling.TagDescription{
+				{Key: aws.String("team"), Value: aws.String(team)},
+			}},
+		}
+	}
+
+	newGroup("payments").recordAction("attach-failed", "spot instance", "i-1")
+	newGroup("search").recordAction("attach-failed", "spot instance", "i-2")
+	newGroup("payments").recordAction("attached", "spot instance", "i-3")
+	newGroup("payments").notifySavings()
+	notifyPlatformError("eu-west-1", "Failed to describe AutoScaling groups")
+
+	if len(payments.sent) != 1 || payments.sent[0].AutoScalingGroup != "asg-payments" ||
+		payments.sent[0].Message != "spot instance i-1" {
+		t.Errorf("unexpected payments notifications %+v", payments.sent)
+	}
+
+	if len(sre.sent) != 2 || sre.sent[0].AutoScalingGroup != "asg-search" ||
+		sre.sent[1].Event != "error" || sre.sent[1].Region != "eu-west-1" {
+		t.Errorf("unexpected SRE notifications %+v", sre.sent)
+	}
+}
+
+func Test_shorten(t *testing.T) {
+
+	tests := []struct {
+		name string
+		s    string
+		max  int
+		want string
+	}{
+		{name: "short", s: "AutoSpotting bid-failed", max: 100,
+			want: "AutoSpotting bid-failed"},
+		{name: "long", s: "AutoSpotting bid-failed for web", max: 16,
+			want: "AutoSpotting ..."},
+		{name: "multi-byte character", s: "AutoSpottingü for web", max: 16,
+			want: "AutoSpotting..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := shorten(tt.s, tt.max)
+			if got != tt.want || len(got) > tt.max {
+				t.Errorf("shorten() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package autospotting
+
+import (
+	"math"
+)
+
+// onDemandTarget returns how many on-demand instances the group should keep.
+func onDemandTarget(desired int64, minNumber, percentage float64) int64 {
+
+	percentage = math.Min(percentage, 100)
+
+	byPercentage := math.Ceil(float64(desired) * percentage / 100)
+
+	return int64(math.Max(minNumber, byPercentage))
+}
+
+// onDemandCount returns the number of on-demand instances of the group.
+func (a *autoScalingGroup) onDemandCount() int64 {
+
+	var count int64
+	for _, inst := range a.instances.catalog {
+		if !inst.isSpot() {
+			count++
+		}
+	}
+	return count
+}
+
+// keepsOnDemandCapacity tells if replacing another on-demand instance would
+// leave the group below its on-demand target.
+func (a *autoScalingGroup) keepsOnDemandCapacity() bool {
+
+	target := onDemandTarget(*a.DesiredCapacity,
+		a.numberSetting("min_on_demand_number"),
+		a.numberSetting("min_on_demand_percentage"))
+
+	if target == 0 {
+		return false
+	}
+
+	onDemand := a.onDemandCount()
+	if onDemand > target {
+		return false
+	}
+
+	a.recordAction("skipped", "keeping", onDemand, "on-demand instances out of",
+		len(a.instances.catalog), "to meet the on-demand target of", target)
+	return true
+}
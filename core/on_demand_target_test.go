@@ -0,0 +1,29 @@
+package autospotting
+
+import "testing"
+
+func Test_onDemandTarget(t *testing.T) {
+	tests := []struct {
+		name       string
+		desired    int64
+		minNumber  float64
+		percentage float64
+		want       int64
+	}{
+		{name: "no target", desired: 10, want: 0},
+		{name: "absolute number", desired: 10, minNumber: 2, want: 2},
+		{name: "percentage", desired: 10, percentage: 30, want: 3},
+		{name: "percentage rounded up", desired: 5, percentage: 30, want: 2},
+		{name: "larger of both", desired: 10, minNumber: 4, percentage: 30,
+			want: 4},
+		{name: "percentage capped", desired: 4, percentage: 150, want: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := onDemandTarget(tt.desired, tt.minNumber,
+				tt.percentage); got != tt.want {
+				t.Errorf("onDemandTarget() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package autospotting
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+const hoursPerMonth = 730
+
+// maximum number of candidate instance types listed for each group
+const planCandidates = 3
+
+var planner accountPlan
+
+type groupPlan struct {
+	Region                  string   `json:"region"`
+	AutoScalingGroup        string   `json:"autoscaling_group"`
+	Enabled                 bool     `json:"enabled"`
+	OnDemandInstances       int      `json:"on_demand_instances"`
+	SpotInstances           int      `json:"spot_instances"`
+	CandidateTypes          []string `json:"candidate_types"`
+	MonthlyOnDemandCost     float64  `json:"monthly_on_demand_cost"`
+	ProjectedMonthlySavings float64  `json:"projected_monthly_savings"`
+	Risks                   []string `json:"risks"`
+
+	// Spot Advisor interruption frequency of the candidate types
+	InterruptionFrequency map[string]string `json:"interruption_frequency,omitempty"`
+}
+
+type accountPlan struct {
+	sync.Mutex
+	groups []groupPlan
+}
+
+func (p *accountPlan) init() {
+	p.Lock()
+	defer p.Unlock()
+
+	p.groups = nil
+}
+
+func (p *accountPlan) add(g groupPlan) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.groups = append(p.groups, g)
+}
+
+// ranked returns the plans of all groups, the largest savings first.
+func (p *accountPlan) ranked() []groupPlan {
+	p.Lock()
+	defer p.Unlock()
+
+	result := append([]groupPlan{}, p.groups...)
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].ProjectedMonthlySavings >
+			result[j].ProjectedMonthlySavings
+	})
+	return result
+}
+
+func (p *accountPlan) export() {
+	writeReport("plan", p.ranked())
+}
+
+// planRegion evaluates all the groups of the region.
+func (r *region) planRegion() {
+
+	r.scanAllAutoScalingGroups()
+
+	if !r.hasEnabledAutoScalingGroups() {
+		r.log().Println(r.name, "has no AutoScaling groups")
+		return
+	}
+
+	r.determineInstanceTypeInformation(r.conf)
+	if !r.hasFreshPricing() {
+		return
+	}
+
+	r.scanInstances()
+
+	for _, asg := range r.enabledASGs {
+		a := asg
+		a.scanInstances()
+		planner.add(a.plan())
+	}
+}
+
+// scanAllAutoScalingGroups loads all the groups from the current scope, whether
+// they're enabled or not.
+func (r *region) scanAllAutoScalingGroups() {
+
+	err := r.services.autoScaling.DescribeAutoScalingGroupsPages(
+		&autoscaling.DescribeAutoScalingGroupsInput{},
+		func(page *autoscaling.DescribeAutoScalingGroupsOutput, lastPage bool) bool {
+			for _, asg := range page.AutoScalingGroups {
+				if !r.inScope(*asg.AutoScalingGroupName) {
+					continue
+				}
+				r.enabledASGs = append(r.enabledASGs, autoScalingGroup{
+					Group:  asg,
+					name:   *asg.AutoScalingGroupName,
+					region: r,
+				})
+			}
+			return true
+		})
+
+	if err != nil {
+		r.log().at(LevelError).Println("Failed to describe AutoScaling groups in",
+			r.name, err.Error())
+	}
+}
+
+// plan evaluates the replacement of the group's on-demand instances.
+func (a *autoScalingGroup) plan() groupPlan {
+
+	p := groupPlan{
+		Region:           a.region.name,
+		AutoScalingGroup: a.name,
+		Enabled:          a.boolSetting("spot-enabled"),
+		CandidateTypes:   []string{},
+		Risks:            []string{},
+	}
+
+	candidates := make(map[string]float64)
+	platform := "Linux"
+
+	for _, inst := range a.instances.catalog {
+
+		if inst.isSpot() {
+			p.SpotInstances++
+			continue
+		}
+		p.OnDemandInstances++
+		p.MonthlyOnDemandCost += inst.price * hoursPerMonth
+
+		az := *inst.Placement.AvailabilityZone
+		platform = spotAdvisorOS(inst.pricingProfile())
+
+		types, err := a.getCompatibleSpotInstanceTypes(az, inst)
+		if err != nil || len(types) == 0 {
+			continue
+		}
+
+		cheapest := -1.0
+		for _, t := range types {
+			info := a.region.instanceTypeInformation[t]
+			price := a.region.normalizedPrice(info, a.region.spotPrice(t, az),
+				inst.pricingProfile())
+
+			if cheapest < 0 || price < cheapest {
+				cheapest = price
+			}
+			if known, ok := candidates[t]; !ok || price < known {
+				candidates[t] = price
+			}
+		}
+		p.ProjectedMonthlySavings += (inst.price - cheapest) * hoursPerMonth
+	}
+
+	var types []string
+	for t := range candidates {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return candidates[types[i]] < candidates[types[j]]
+	})
+	if len(types) > planCandidates {
+		types = types[:planCandidates]
+	}
+	p.CandidateTypes = append(p.CandidateTypes, types...)
+
+	for _, t := range types {
+		if label := spotAdvisor.label(a.region.name, platform, t); label != "" {
+			if p.InterruptionFrequency == nil {
+				p.InterruptionFrequency = make(map[string]string)
+			}
+			p.InterruptionFrequency[t] = label
+		}
+	}
+
+	p.Risks = append(p.Risks, a.planRisks(p)...)
+	return p
+}
+
+// planRisks notes the reasons why replacing the group's instances may be
+// risky or impossible.
+func (a *autoScalingGroup) planRisks(p groupPlan) []string {
+
+	var risks []string
+
+	if a.LaunchConfigurationName == nil {
+		risks = append(risks, "the group doesn't use a launch configuration, "+
+			"the spot instances are reconstructed from the on-demand instances")
+	}
+
+	if p.OnDemandInstances > 0 && len(p.CandidateTypes) == 0 {
+		risks = append(risks, "no cheaper compatible spot instance types")
+	}
+
+	if a.skipsSingleInstanceReplacement() {
+		risks = append(risks, "the group is limited to a single instance, "+
+			"which is only replaced when allow_single_instance_replacement is set")
+	} else if p.OnDemandInstances+p.SpotInstances == 1 {
+		risks = append(risks, "a single instance, unavailable while its spot "+
+			"replacement is interrupted")
+	}
+
+	if len(p.CandidateTypes) == 1 {
+		risks = append(risks, "a single compatible instance type, all the "+
+			"spot instances may be interrupted at once")
+	}
+
+	if len(a.LoadBalancerNames) == 0 && len(a.TargetGroupARNs) == 0 &&
+		a.HealthCheckType != nil && *a.HealthCheckType == "EC2" {
+		risks = append(risks, "no load balancer health checks, the spot "+
+			"instances are attached as soon as they're running")
+	}
+
+	for _, inst := range a.instances.catalog {
+		if !inst.isSpot() && len(inst.persistentVolumes()) > 0 {
+			risks = append(risks, "instances having volumes kept after "+
+				"termination, consider the data_volumes or replacement_profile "+
+				"tags")
+			break
+		}
+	}
+
+	return risks
+}
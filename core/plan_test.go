@@ -0,0 +1,66 @@
+package autospotting
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+func Test_accountPlan_ranked(t *testing.T) {
+
+	var p accountPlan
+	p.add(groupPlan{AutoScalingGroup: "small", ProjectedMonthlySavings: 10})
+	p.add(groupPlan{AutoScalingGroup: "none"})
+	p.add(groupPlan{AutoScalingGroup: "large", ProjectedMonthlySavings: 500})
+
+	var got []string
+	for _, g := range p.ranked() {
+		got = append(got, g.AutoScalingGroup)
+	}
+
+	if want := []string{"large", "small", "none"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ranked() = %v, want %v", got, want)
+	}
+}
+
+func Test_planRisks(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		group *autoscaling.Group
+		plan  groupPlan
+		want  int
+	}{
+		{
+			name: "diversified group behind a load balancer",
+			group: &autoscaling.Group{
+				LaunchConfigurationName: aws.String("lc"),
+				LoadBalancerNames:       []*string{aws.String("elb")},
+				HealthCheckType:         aws.String("ELB"),
+			},
+			plan: groupPlan{
+				OnDemandInstances: 3,
+				CandidateTypes:    []string{"m4.large", "m5.large"},
+			},
+			want: 0,
+		},
+		{
+			name: "single instance using a launch template without candidates",
+			group: &autoscaling.Group{
+				HealthCheckType: aws.String("EC2"),
+			},
+			plan: groupPlan{OnDemandInstances: 1},
+			want: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &autoScalingGroup{Group: tt.group}
+			if got := a.planRisks(tt.plan); len(got) != tt.want {
+				t.Errorf("planRisks() = %v, want %d risks", got, tt.want)
+			}
+		})
+	}
+}
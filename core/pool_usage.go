@@ -0,0 +1,73 @@
+package autospotting
+
+import "sync"
+
+// poolUsage counts the managed spot instances of a region by pool.
+type poolUsage struct {
+	sync.Mutex
+
+	// keyed by instance type and availability zone
+	pools map[string]int64
+
+	// all the instances of the enabled groups
+	total int64
+}
+
+// countPoolUsage counts the managed spot instances of the region by pool.
+func (r *region) countPoolUsage() {
+
+	r.pools.Lock()
+	defer r.pools.Unlock()
+
+	r.pools.pools = make(map[string]int64)
+	r.pools.total = 0
+
+	r.forEachManagedInstance(func(i *instance, attached bool) {
+		if attached {
+			r.pools.total++
+		}
+		if i.isSpot() && i.InstanceType != nil && i.Placement != nil &&
+			i.Placement.AvailabilityZone != nil {
+			r.pools.pools[poolKey(*i.InstanceType,
+				*i.Placement.AvailabilityZone)]++
+		}
+	})
+}
+
+// allowed tells if one more spot instance can be launched in the pool.
+func (u *poolUsage) allowed(instanceType, az string, max float64) bool {
+	u.Lock()
+	defer u.Unlock()
+
+	return concentrationAllowed(u.pools[poolKey(instanceType, az)], u.total,
+		max)
+}
+
+// reserve counts a spot instance about to be launched in the pool, unless the
+// pool reached the limit in the meantime.
+func (u *poolUsage) reserve(instanceType, az string, max float64) bool {
+	u.Lock()
+	defer u.Unlock()
+
+	key := poolKey(instanceType, az)
+
+	if !concentrationAllowed(u.pools[key], u.total, max) {
+		return false
+	}
+	if u.pools == nil {
+		u.pools = make(map[string]int64)
+	}
+	u.pools[key]++
+	return true
+}
+
+// release gives back the count reserved for a spot instance which wasn't
+// launched after all.
+func (u *poolUsage) release(instanceType, az string) {
+	u.Lock()
+	defer u.Unlock()
+
+	if key := poolKey(instanceType, az); u.pools[key] > 0 {
+		u.pools[key]--
+	}
+}
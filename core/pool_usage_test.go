@@ -0,0 +1,56 @@
+package autospotting
+
+import "testing"
+
+func Test_poolUsage_reserve(t *testing.T) {
+
+	u := &poolUsage{
+		pools: map[string]int64{"m5.large/us-east-1a": 1},
+		total: 10,
+	}
+
+	tests := []struct {
+		name         string
+		instanceType string
+		az           string
+		max          float64
+		want         bool
+	}{
+		{name: "first instance of a pool", instanceType: "c5.large", az: "us-east-1a", max: 10, want: true},
+		{name: "within the limit", instanceType: "m5.large", az: "us-east-1a", max: 10, want: true},
+		{name: "limit reached by the previous reservation", instanceType: "m5.large", az: "us-east-1a", max: 10, want: false},
+		{name: "disabled limit", instanceType: "m5.large", az: "us-east-1a", max: 0, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := u.reserve(tt.instanceType, tt.az, tt.max); got != tt.want {
+				t.Errorf("reserve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_poolUsage_release(t *testing.T) {
+
+	u := &poolUsage{
+		pools: map[string]int64{"m5.large/us-east-1a": 1},
+		total: 10,
+	}
+
+	if !u.reserve("m5.large", "us-east-1a", 10) {
+		t.Fatalf("reserve() = false, want true within the limit")
+	}
+	if u.reserve("m5.large", "us-east-1a", 10) {
+		t.Errorf("reserve() = true, want false once the limit is reached")
+	}
+
+	u.release("m5.large", "us-east-1a")
+	if !u.reserve("m5.large", "us-east-1a", 10) {
+		t.Errorf("reserve() = false, want true after releasing the instance")
+	}
+
+	u.release("c5.large", "us-east-1a")
+	if got := u.pools[poolKey("c5.large", "us-east-1a")]; got != 0 {
+		t.Errorf("release() of an unused pool left %d instances, want 0", got)
+	}
+}
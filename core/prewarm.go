@@ -0,0 +1,153 @@
+package autospotting
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+const prewarmTag = "prewarm_until"
+
+// prewarmUntil returns the end of the group's pre-warming, or the zero time if
+// it isn't set or invalid.
+func (a *autoScalingGroup) prewarmUntil() time.Time {
+
+	return a.timeSetting(prewarmTag)
+}
+
+func (a *autoScalingGroup) prewarming() bool {
+	return time.Now().Before(a.prewarmUntil())
+}
+
+// endExpiredPrewarm removes the pre-warming tag once its time has passed.
+func (a *autoScalingGroup) endExpiredPrewarm() {
+
+	until := a.prewarmUntil()
+	if until.IsZero() || time.Now().Before(until) {
+		return
+	}
+
+	if a.deleteGroupTag(prewarmTag) == nil {
+		a.recordAction("prewarm-ended", "the pre-warming ended at",
+			until.Format(time.RFC3339))
+	}
+}
+
+// prewarmPoolConcentration tightens the group's pool concentration limit while
+// pre-warming.
+func (a *autoScalingGroup) prewarmPoolConcentration(max float64) float64 {
+
+	prewarm := a.region.conf.PrewarmPoolConcentration
+	if prewarm <= 0 || !a.prewarming() {
+		return max
+	}
+	if max <= 0 || prewarm < max {
+		return prewarm
+	}
+	return max
+}
+
+// prewarmSpotInstances launches the additional spot instances of the run while
+// pre-warming, for the on-demand instances other than the one already being
+// replaced.
+func (a *autoScalingGroup) prewarmSpotInstances(replaced *instance) {
+
+	if !a.prewarming() {
+		return
+	}
+
+	var onDemand []*instance
+	for _, inst := range a.instances.catalog {
+		if !inst.isSpot() && inst != replaced && inst.State != nil &&
+			*inst.State.Name == "running" {
+			onDemand = append(onDemand, inst)
+		}
+	}
+	sort.Slice(onDemand, func(i, j int) bool {
+		return *onDemand[i].InstanceId < *onDemand[j].InstanceId
+	})
+
+	for i := 1; i < a.region.conf.PrewarmLaunches && i <= len(onDemand); i++ {
+
+		if !spotShare.reserve() {
+			return
+		}
+
+		az := onDemand[i-1].Placement.AvailabilityZone
+		a.log().Println(a.region.name, a.name, "Pre-warming, launching another",
+			"spot instance in", *az)
+		if !a.launchCheapestSpotInstance(az) {
+			spotShare.release()
+		}
+	}
+}
+
+// servePrewarm handles POST and DELETE /asgs/{name}/prewarm?region={region}.
+func servePrewarm(w http.ResponseWriter, r *http.Request) {
+
+	name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/asgs/"),
+		"/prewarm")
+	regionName := r.URL.Query().Get("region")
+
+	if name == "" || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if regionName == "" {
+		http.Error(w, "Missing region", http.StatusBadRequest)
+		return
+	}
+
+	tag := &autoscaling.Tag{
+		ResourceId:        aws.String(name),
+		ResourceType:      aws.String("auto-scaling-group"),
+		Key:               aws.String(prewarmTag),
+		PropagateAtLaunch: aws.Bool(false),
+	}
+
+	var update func(*autoscaling.AutoScaling) error
+
+	switch r.Method {
+	case http.MethodPost:
+		until, err := time.Parse(time.RFC3339, r.URL.Query().Get("until"))
+		if err != nil || !time.Now().Before(until) {
+			http.Error(w, "Invalid until time", http.StatusBadRequest)
+			return
+		}
+		tag.Value = aws.String(until.UTC().Format(time.RFC3339))
+
+		update = func(svc *autoscaling.AutoScaling) error {
+			_, err := svc.CreateOrUpdateTags(&autoscaling.CreateOrUpdateTagsInput{
+				Tags: []*autoscaling.Tag{tag},
+			})
+			return err
+		}
+
+	case http.MethodDelete:
+		update = func(svc *autoscaling.AutoScaling) error {
+			_, err := svc.DeleteTags(&autoscaling.DeleteTagsInput{
+				Tags: []*autoscaling.Tag{tag},
+			})
+			return err
+		}
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	svc := autoscaling.New(instrumentSession(
+		newSession(&aws.Config{Region: aws.String(regionName)})))
+
+	if err := update(svc); err != nil {
+		logger.at(LevelError).Println("Failed to update the pre-warming of",
+			name, "in", regionName, err.Error())
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,92 @@
+package autospotting
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+func Test_prewarmPoolConcentration(t *testing.T) {
+
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+
+	tests := []struct {
+		name    string
+		tag     string
+		prewarm float64
+		max     float64
+		want    float64
+	}{
+		{name: "not pre-warming", max: 20, prewarm: 10, want: 20},
+		{name: "pre-warming", tag: future, max: 20, prewarm: 10, want: 10},
+		{name: "pre-warming with a lower limit", tag: future, max: 5, prewarm: 10, want: 5},
+		{name: "pre-warming without a limit", tag: future, max: 0, prewarm: 10, want: 10},
+		{name: "pre-warming ended", tag: past, max: 20, prewarm: 10, want: 20},
+		{name: "invalid end", tag: "tomorrow", max: 20, prewarm: 10, want: 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &autoScalingGroup{
+				Group: &autoscaling.Group{},
+				region: &region{
+					conf: Config{PrewarmPoolConcentration: tt.prewarm},
+				},
+			}
+			if tt.tag != "" {
+				a.Tags = []*autoscaling.TagDescription{{
+					Key:   aws.String(prewarmTag),
+					Value: aws.String(tt.tag),
+				}}
+			}
+			if got := a.prewarmPoolConcentration(tt.max); got != tt.want {
+				t.Errorf("prewarmPoolConcentration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_servePrewarm(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		wantStatus int
+	}{
+		{name: "Missing region",
+			method:     "POST",
+			url:        "/asgs/web/prewarm?until=2100-01-01T00:00:00Z",
+			wantStatus: http.StatusBadRequest,
+		},
+		{name: "Invalid end time",
+			method:     "POST",
+			url:        "/asgs/web/prewarm?region=us-east-1&until=tomorrow",
+			wantStatus: http.StatusBadRequest,
+		},
+		{name: "End time in the past",
+			method:     "POST",
+			url:        "/asgs/web/prewarm?region=us-east-1&until=2000-01-01T00:00:00Z",
+			wantStatus: http.StatusBadRequest,
+		},
+		{name: "Unsupported method",
+			method:     "GET",
+			url:        "/asgs/web/prewarm?region=us-east-1",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			newServeMux().ServeHTTP(rec, httptest.NewRequest(tt.method, tt.url, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package autospotting
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// pricingStaleness returns why the pricing data of the region can't be trusted,
+// or an empty string if it's fresh enough.
+func (r *region) pricingStaleness(now time.Time) string {
+
+	if r.spotPricesFetched.IsZero() {
+		return "the spot prices couldn't be refreshed"
+	}
+
+	if max := r.conf.SpotPricingMaxAge; max > 0 &&
+		now.Sub(r.spotPricesFetched) > max {
+		return fmt.Sprint("the spot prices were refreshed at ",
+			r.spotPricesFetched.Format(time.RFC3339), ", more than ", max, " ago")
+	}
+
+	// the age of the on-demand prices is unknown when the data file was
+	// embedded without its modification time
+	if max := r.conf.OnDemandPricingMaxAge; max > 0 &&
+		!r.conf.PricingDataTime.IsZero() &&
+		now.Sub(r.conf.PricingDataTime) > max {
+		return fmt.Sprint("the on-demand prices are from ",
+			r.conf.PricingDataTime.Format(time.RFC3339), ", more than ", max,
+			" ago")
+	}
+
+	return ""
+}
+
+// hasFreshPricing tells if replacement decisions can be made in the region,
+// failing safe otherwise.
+func (r *region) hasFreshPricing() bool {
+
+	reason := r.pricingStaleness(time.Now())
+	if reason == "" {
+		return true
+	}
+
+	r.log().Println(r.name, "Not replacing any instances because", reason)
+	stats.observeStalePricing(r.name)
+	notifyPlatformError(r.name, "Not replacing any instances because", reason)
+	return false
+}
+
+// pricingProfile holds the instance attributes changing its effective hourly
+// cost compared to the Linux prices of its instance type.
+type pricingProfile struct {
+	windows      bool
+	dedicated    bool
+	ebsOptimized bool
+}
+
+func (it *instance) pricingProfile() pricingProfile {
+	return pricingProfile{
+		windows: it.Platform != nil && *it.Platform == "windows",
+		dedicated: it.Placement != nil && it.Placement.Tenancy != nil &&
+			*it.Placement.Tenancy == "dedicated",
+		ebsOptimized: it.EbsOptimized != nil && *it.EbsOptimized,
+	}
+}
+
+// normalizedPrice converts the Linux on-demand or spot price of an instance
+// type into the effective hourly cost of running it with the given profile, so
+// that the prices of different instance types can be compared. The Windows
+// license costs are estimated from the on-demand prices, since the spot prices
+// are only known for Linux.
+func (r *region) normalizedPrice(info instanceTypeInformation, price float64,
+	p pricingProfile) float64 {
+
+	if price <= 0 {
+		return price
+	}
+
+	if p.windows && info.pricing.windowsOnDemand > info.pricing.onDemand {
+		price += info.pricing.windowsOnDemand - info.pricing.onDemand
+	}
+
+	// the instance types older than the 4th generation charge an hourly fee
+	// for EBS optimization, the newer ones are EBS-optimized by default
+	if p.ebsOptimized && instanceGeneration(info.instanceType) < 4 {
+		price += r.conf.EBSOptimizationSurcharge
+	}
+
+	if p.dedicated && r.conf.DedicatedTenancyFactor > 0 {
+		price *= r.conf.DedicatedTenancyFactor
+	}
+
+	return price
+}
+
+// cheapestSpotPrice returns the lowest spot price of the instance type across
+// the availability zones of its region, and that availability zone.
+func cheapestSpotPrice(info instanceTypeInformation) (float64, string) {
+
+	price, zone := 0.0, ""
+	for az, p := range info.pricing.spot {
+		if p <= 0 {
+			continue
+		}
+		if zone == "" || p < price || (p == price && az < zone) {
+			price, zone = p, az
+		}
+	}
+	return price, zone
+}
+
+// regionalPrice is the cheapest spot price of an instance type in a region.
+type regionalPrice struct {
+	region, az string
+	spot       float64
+}
+
+var regionalPrices regionalPriceIndex
+
+// regionalPriceIndex aggregates the cheapest spot prices of the instance types
+// across the regions processed by the run.
+type regionalPriceIndex struct {
+	sync.Mutex
+
+	// keyed by instance type and region
+	prices map[string]map[string]regionalPrice
+}
+
+func (p *regionalPriceIndex) init() {
+	p.Lock()
+	defer p.Unlock()
+
+	p.prices = make(map[string]map[string]regionalPrice)
+}
+
+// add indexes the spot prices of the region.
+func (p *regionalPriceIndex) add(region string,
+	info map[string]instanceTypeInformation) {
+	p.Lock()
+	defer p.Unlock()
+
+	for instanceType, typeInfo := range info {
+		price, az := cheapestSpotPrice(typeInfo)
+		if price <= 0 {
+			continue
+		}
+		if p.prices[instanceType] == nil {
+			p.prices[instanceType] = make(map[string]regionalPrice)
+		}
+		p.prices[instanceType][region] = regionalPrice{
+			region: region,
+			az:     az,
+			spot:   price,
+		}
+	}
+}
+
+// get returns the cheapest spot price of the instance type in the region.
+func (p *regionalPriceIndex) get(instanceType,
+	region string) (regionalPrice, bool) {
+	p.Lock()
+	defer p.Unlock()
+
+	price, found := p.prices[instanceType][region]
+	return price, found
+}
+
+// cheapestElsewhere returns the cheapest spot price of the instance type in
+// the other regions.
+func (p *regionalPriceIndex) cheapestElsewhere(instanceType,
+	region string) (regionalPrice, bool) {
+	p.Lock()
+	defer p.Unlock()
+
+	var cheapest regionalPrice
+	found := false
+
+	for other, price := range p.prices[instanceType] {
+		if other == region {
+			continue
+		}
+		if !found || price.spot < cheapest.spot ||
+			(price.spot == cheapest.spot && other < cheapest.region) {
+			cheapest, found = price, true
+		}
+	}
+	return cheapest, found
+}
@@ -0,0 +1,153 @@
+package autospotting
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func Test_pricingStaleness(t *testing.T) {
+
+	now := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		region  *region
+		wantErr bool
+	}{
+		{
+			name:    "spot prices never fetched",
+			region:  &region{},
+			wantErr: true,
+		},
+		{
+			name: "fresh prices",
+			region: &region{
+				spotPricesFetched: now.Add(-time.Minute),
+				conf: Config{
+					SpotPricingMaxAge:     time.Hour,
+					OnDemandPricingMaxAge: 24 * time.Hour,
+					PricingDataTime:       now.Add(-time.Hour),
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "stale spot prices",
+			region: &region{
+				spotPricesFetched: now.Add(-2 * time.Hour),
+				conf:              Config{SpotPricingMaxAge: time.Hour},
+			},
+			wantErr: true,
+		},
+		{
+			name: "stale spot prices with the check disabled",
+			region: &region{
+				spotPricesFetched: now.Add(-2 * time.Hour),
+			},
+			wantErr: false,
+		},
+		{
+			name: "stale on-demand prices",
+			region: &region{
+				spotPricesFetched: now,
+				conf: Config{
+					OnDemandPricingMaxAge: 24 * time.Hour,
+					PricingDataTime:       now.Add(-48 * time.Hour),
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown age of the on-demand prices",
+			region: &region{
+				spotPricesFetched: now,
+				conf:              Config{OnDemandPricingMaxAge: 24 * time.Hour},
+			},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.region.pricingStaleness(now)
+			if (got != "") != tt.wantErr {
+				t.Errorf("pricingStaleness() = %q, wantErr %v", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_normalizedPrice(t *testing.T) {
+
+	r := &region{conf: Config{
+		EBSOptimizationSurcharge: 0.025,
+		DedicatedTenancyFactor:   1.1,
+	}}
+
+	m3 := instanceTypeInformation{
+		instanceType: "m3.medium",
+		pricing:      prices{onDemand: 0.1, windowsOnDemand: 0.15},
+	}
+	m5 := instanceTypeInformation{
+		instanceType: "m5.large",
+		pricing:      prices{onDemand: 0.1, windowsOnDemand: 0.15},
+	}
+
+	tests := []struct {
+		name    string
+		info    instanceTypeInformation
+		price   float64
+		profile pricingProfile
+		want    float64
+	}{
+		{
+			name:  "Linux default tenancy",
+			info:  m5,
+			price: 0.03,
+			want:  0.03,
+		},
+		{
+			name:    "Windows license added to the spot price",
+			info:    m5,
+			price:   0.03,
+			profile: pricingProfile{windows: true},
+			want:    0.08,
+		},
+		{
+			name:    "EBS optimization fee of older instance types",
+			info:    m3,
+			price:   0.03,
+			profile: pricingProfile{ebsOptimized: true},
+			want:    0.055,
+		},
+		{
+			name:    "EBS-optimized by default",
+			info:    m5,
+			price:   0.03,
+			profile: pricingProfile{ebsOptimized: true},
+			want:    0.03,
+		},
+		{
+			name:    "dedicated tenancy",
+			info:    m5,
+			price:   0.1,
+			profile: pricingProfile{dedicated: true},
+			want:    0.11,
+		},
+		{
+			name:    "missing price",
+			info:    m3,
+			price:   0,
+			profile: pricingProfile{windows: true, ebsOptimized: true},
+			want:    0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := r.normalizedPrice(tt.info, tt.price, tt.profile)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("normalizedPrice() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package autospotting
+
+import (
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// tag set by the probe on the instances whose application is ready
+const readyTag = "autospotting-ready"
+
+// ProbeConfig configures the readiness probe run on the instances.
+type ProbeConfig struct {
+	// Shell command exiting successfully once the application is ready, the
+	// instance is reported ready right away when empty.
+	Command string
+
+	// Delay between the attempts of running the command.
+	Interval time.Duration
+
+	// How long to wait for the command to succeed.
+	Timeout time.Duration
+}
+
+// Probe waits for the instance it runs on to be ready, then tags it as such.
+func Probe(cfg Config, p ProbeConfig) error {
+
+	initLogging(cfg)
+
+	sess := session.New()
+	doc, err := ec2metadata.New(sess).GetInstanceIdentityDocument()
+	if err != nil {
+		return fmt.Errorf("couldn't read the instance identity document: %s",
+			err.Error())
+	}
+
+	if err := waitUntilReady(p, runProbeCommand); err != nil {
+		return err
+	}
+
+	svc := ec2.New(sess, &aws.Config{Region: aws.String(doc.Region)})
+	_, err = svc.CreateTags(&ec2.CreateTagsInput{
+		Resources: []*string{aws.String(doc.InstanceID)},
+		Tags: []*ec2.Tag{
+			{Key: aws.String(readyTag), Value: aws.String("true")},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Println("Reported instance", doc.InstanceID, "as ready")
+	return nil
+}
+
+func runProbeCommand(command string) error {
+	return exec.Command("sh", "-c", command).Run()
+}
+
+// waitUntilReady runs the readiness command until it succeeds or times out.
+func waitUntilReady(p ProbeConfig, run func(string) error) error {
+
+	if p.Command == "" {
+		return nil
+	}
+
+	deadline := time.Now().Add(p.Timeout)
+
+	for {
+		err := run(p.Command)
+		if err == nil {
+			return nil
+		}
+
+		if !time.Now().Add(p.Interval).Before(deadline) {
+			return fmt.Errorf("the readiness command didn't succeed within %s: %s",
+				p.Timeout, err.Error())
+		}
+
+		logger.Println("The application isn't ready yet:", err.Error())
+		time.Sleep(p.Interval)
+	}
+}
+
+// probeReady tells if the spot instance reported its readiness.
+func probeReady(inst *instance) bool {
+	ready := findTagValue(inst.Tags, readyTag)
+	return ready != nil && *ready == "true"
+}
+
+// waitsForProbe tells if the spot instance shouldn't be attached yet because
+// its readiness probe didn't report it ready, terminating the instance once
+// the probe timed out.
+func (a *autoScalingGroup) waitsForProbe(inst *instance) bool {
+
+	if !a.boolSetting("readiness_probe") || probeReady(inst) {
+		return false
+	}
+
+	if timeout := a.region.conf.ProbeTimeout; timeout > 0 &&
+		time.Since(*inst.LaunchTime) > timeout {
+		a.recordAction("terminated", "spot instance", *inst.InstanceId,
+			"didn't report its readiness within", timeout)
+		a.terminateInstance(inst)
+		return true
+	}
+
+	a.log().Println(a.name, "Waiting for spot instance", *inst.InstanceId,
+		"to report its readiness")
+	return true
+}
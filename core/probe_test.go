@@ -0,0 +1,49 @@
+package autospotting
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_waitUntilReady(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		command  string
+		failures int
+		wantErr  bool
+		wantRuns int
+	}{
+		{name: "no command", wantRuns: 0},
+		{name: "ready right away", command: "true", wantRuns: 1},
+		{name: "ready after retries", command: "check", failures: 2,
+			wantRuns: 3},
+		{name: "timed out", command: "check", failures: 100, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs := 0
+			run := func(string) error {
+				runs++
+				if runs <= tt.failures {
+					return errors.New("not ready")
+				}
+				return nil
+			}
+
+			err := waitUntilReady(ProbeConfig{
+				Command:  tt.command,
+				Interval: time.Millisecond,
+				Timeout:  20 * time.Millisecond,
+			}, run)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("waitUntilReady() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && runs != tt.wantRuns {
+				t.Errorf("waitUntilReady() ran %d times, want %d", runs, tt.wantRuns)
+			}
+		})
+	}
+}
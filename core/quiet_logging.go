@@ -0,0 +1,148 @@
+package autospotting
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// buffered log size beyond which the log is written out right away
+const maxQuietLogBytes = 64 << 20
+
+var quietLog quietLogBuffer
+
+type quietLogBuffer struct {
+	sync.Mutex
+
+	out io.Writer
+
+	// buffering, until the run turns out not to be idle or too much was
+	// logged
+	buffering bool
+	buf       bytes.Buffer
+
+	// anything was logged at the warn or error level
+	warned bool
+
+	// enabled groups of each region
+	regions map[string]int
+}
+
+// init returns the writer the logger of the run writes to.
+func (b *quietLogBuffer) init(cfg Config) io.Writer {
+	b.Lock()
+	defer b.Unlock()
+
+	b.out = cfg.LogFile
+	b.buffering = cfg.QuietWhenIdle
+	b.buf.Reset()
+	b.warned = false
+	b.regions = make(map[string]int)
+
+	if !b.buffering {
+		return cfg.LogFile
+	}
+	return b
+}
+
+func (b *quietLogBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	if !b.buffering {
+		return b.out.Write(p)
+	}
+
+	if b.buf.Len()+len(p) > maxQuietLogBytes {
+		b.flush()
+		return b.out.Write(p)
+	}
+	return b.buf.Write(p)
+}
+
+// flush writes out the buffered log and stops buffering, and must be called
+// with the lock held.
+func (b *quietLogBuffer) flush() {
+	b.buffering = false
+	b.out.Write(b.buf.Bytes())
+	b.buf.Reset()
+}
+
+// observe returns a handler noting the warnings and errors passed on to the
+// given handler.
+func (b *quietLogBuffer) observe(h LogHandler) LogHandler {
+	return &quietLogHandler{LogHandler: h, b: b}
+}
+
+type quietLogHandler struct {
+	LogHandler
+	b *quietLogBuffer
+}
+
+func (h *quietLogHandler) Handle(e LogEntry) {
+	if e.Level >= LevelWarn {
+		h.b.Lock()
+		h.b.warned = true
+		h.b.Unlock()
+	}
+	h.LogHandler.Handle(e)
+}
+
+func (b *quietLogBuffer) addRegion(region string, groups int) {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.regions != nil {
+		b.regions[region] += groups
+	}
+}
+
+// idleRun tells if the run changed nothing and had no errors.
+func idleRun(result RunResult) bool {
+
+	if len(result.Errors) > 0 || result.Unfinished {
+		return false
+	}
+
+	for action, count := range result.Actions {
+		if count > 0 && action != "skipped" && action != "deferred" {
+			return false
+		}
+	}
+	return true
+}
+
+// finish writes out the buffered log of the run, or only the summary of each
+// region if the run was idle.
+func (b *quietLogBuffer) finish(result RunResult) {
+	b.Lock()
+	defer b.Unlock()
+
+	if !b.buffering {
+		return
+	}
+
+	if b.warned || !idleRun(result) {
+		b.flush()
+		return
+	}
+
+	b.buffering = false
+	b.buf.Reset()
+
+	var regions []string
+	for region := range b.regions {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	for _, region := range regions {
+		fmt.Fprintln(b.out, region, "Idle run: no changes to the",
+			b.regions[region], "enabled AutoScaling groups")
+	}
+	if len(regions) == 0 {
+		fmt.Fprintln(b.out, "Idle run: no enabled regions were processed")
+	}
+}
@@ -0,0 +1,67 @@
+package autospotting
+
+import (
+	"bytes"
+	"testing"
+)
+
+func Test_quietLogBuffer_finish(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		quiet  bool
+		level  LogLevel
+		result RunResult
+		want   string
+	}{
+		{
+			name:   "not quiet",
+			result: RunResult{Actions: map[string]int{"skipped": 1}},
+			want:   "evaluating candidates\n",
+		},
+		{
+			name:   "idle",
+			quiet:  true,
+			result: RunResult{Actions: map[string]int{"skipped": 2, "deferred": 1}},
+			want: "eu-west-1 Idle run: no changes to the 1 enabled AutoScaling " +
+				"groups\nus-east-1 Idle run: no changes to the 2 enabled " +
+				"AutoScaling groups\n",
+		},
+		{
+			name:   "changes",
+			quiet:  true,
+			result: RunResult{Actions: map[string]int{"attached": 1}},
+			want:   "evaluating candidates\n",
+		},
+		{
+			name:   "errors",
+			quiet:  true,
+			result: RunResult{Errors: []string{"us-east-1: failure"}},
+			want:   "evaluating candidates\n",
+		},
+		{
+			name:   "warnings",
+			quiet:  true,
+			level:  LevelWarn,
+			result: RunResult{Actions: map[string]int{"skipped": 1}},
+			want:   "evaluating candidates\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			var b quietLogBuffer
+
+			w := b.init(Config{LogFile: &out, QuietWhenIdle: tt.quiet})
+			sink := &logSink{handler: b.observe(newTextLogHandler(w, 0))}
+			newLogger(sink, tt.level).Println("evaluating candidates")
+			b.addRegion("us-east-1", 2)
+			b.addRegion("eu-west-1", 1)
+			b.finish(tt.result)
+
+			if got := out.String(); got != tt.want {
+				t.Errorf("finish() logged %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
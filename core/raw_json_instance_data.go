@@ -2,9 +2,21 @@ package autospotting
 
 // In this file we generate a raw data structure unmarshaled from the
 // ec2instances.info JSON file, embedded into the binary at build time using go-
-// bindata.
-
-import "encoding/json"
+// bindata, or loaded at startup from an external, optionally gzip-compressed,
+// artifact stored in S3 or on the local disk. In order to save memory, only the
+// pricing of the regions being processed is kept.
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
 
 // AWS Instances JSON Structure Definitions
 type jsonInstance struct {
@@ -39,6 +51,7 @@ type jsonInstance struct {
 
 	Memory          float32 `json:"memory"`
 	EBSMaxBandwidth float32 `json:"ebs_max_bandwidth"`
+	GPU             int     `json:"GPU"`
 }
 
 type storageConfiguration struct {
@@ -56,10 +69,14 @@ type regionPrices struct {
 		// Reserved interface{} `json:"reserved"`
 	} `json:"linux"`
 
+	// only used for estimating the Windows license costs
+	Mswin struct {
+		OnDemand string `json:"ondemand"`
+	} `json:"mswin"`
+
 	// ignored for now, not useful
 	// Mswinsqlweb interface{}  `json:"mswinSQLWeb"`
 	// Mswinsql    interface{}  `json:"mswinSQL"`
-	// Mswin       interface{}  `json:"mswin"`
 }
 
 //------------------------------------------------------------------------------
@@ -74,9 +91,95 @@ func (ii *RawInstanceData) LoadFromAssetContent(contents []byte) error {
 
 	err := json.Unmarshal(contents, &ii)
 	if err != nil {
-		logger.Println(err.Error())
+		logger.at(LevelError).Println(err.Error())
 		return err
 	}
 
 	return nil
 }
+
+// LoadInstanceData loads the instance data from the given location, which is
+// either an s3://bucket/key URL or a local file, falling back to the embedded
+// content when no location is given. Only the pricing of the given
+// comma-separated regions is kept, or of all of them if none are given.
+func LoadInstanceData(location, regions string,
+	embedded []byte) (RawInstanceData, error) {
+
+	content := embedded
+
+	if location != "" {
+		var err error
+		if content, err = readInstanceData(location); err != nil {
+			return nil, err
+		}
+		if content == nil {
+			return nil, fmt.Errorf("missing instance data %s", location)
+		}
+	}
+
+	var keep []string
+	for _, r := range strings.Split(regions, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			keep = append(keep, r)
+		}
+	}
+
+	return decodeInstanceData(bytes.NewReader(content), keep)
+}
+
+func readInstanceData(location string) ([]byte, error) {
+
+	if strings.HasPrefix(location, "s3://") {
+		parts := strings.SplitN(strings.TrimPrefix(location, "s3://"), "/", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, fmt.Errorf("invalid S3 location %s", location)
+		}
+		bucket := &s3Bucket{name: parts[0]}
+		return bucket.get(parts[1])
+	}
+
+	return localDirectory(filepath.Dir(location)).get(filepath.Base(location))
+}
+
+// decodeInstanceData decodes the instance types one by one from the plain or
+// gzip-compressed JSON array, dropping the pricing of the regions which aren't
+// kept.
+func decodeInstanceData(r io.Reader, regions []string) (RawInstanceData, error) {
+
+	buffered := bufio.NewReader(r)
+	if magic, err := buffered.Peek(2); err == nil &&
+		magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = buffered
+	}
+
+	dec := json.NewDecoder(r)
+	if t, err := dec.Token(); err != nil || t != json.Delim('[') {
+		return nil, errors.New("the instance data isn't a JSON array")
+	}
+
+	var data RawInstanceData
+	for dec.More() {
+		var it jsonInstance
+		if err := dec.Decode(&it); err != nil {
+			return nil, err
+		}
+		if len(regions) > 0 {
+			pricing := make(map[string]regionPrices)
+			for _, region := range regions {
+				if p, found := it.Pricing[region]; found {
+					pricing[region] = p
+				}
+			}
+			it.Pricing = pricing
+		}
+		data = append(data, it)
+	}
+	return data, nil
+}
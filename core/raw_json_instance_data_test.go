@@ -0,0 +1,110 @@
+package autospotting
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+const testInstanceData = `[
+	{"instance_type": "m5.large", "vCPU": 2, "memory": 8,
+	 "pricing": {
+		"us-east-1": {"linux": {"ondemand": "0.096"}},
+		"eu-west-1": {"linux": {"ondemand": "0.107"}},
+		"ap-south-1": {"linux": {"ondemand": "0.101"}}}},
+	{"instance_type": "c5.large", "vCPU": 2, "memory": 4,
+	 "pricing": {
+		"us-east-1": {"linux": {"ondemand": "0.085"}}}}
+]`
+
+func gzipped(t *testing.T, content string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	gz.Close()
+	return buf.Bytes()
+}
+
+func Test_decodeInstanceData(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		content []byte
+		regions []string
+		want    map[string][]string
+		wantErr bool
+	}{
+		{name: "all regions",
+			content: []byte(testInstanceData),
+			want: map[string][]string{
+				"m5.large": {"ap-south-1", "eu-west-1", "us-east-1"},
+				"c5.large": {"us-east-1"},
+			},
+		},
+		{name: "active regions only",
+			content: []byte(testInstanceData),
+			regions: []string{"eu-west-1", "us-east-1"},
+			want: map[string][]string{
+				"m5.large": {"eu-west-1", "us-east-1"},
+				"c5.large": {"us-east-1"},
+			},
+		},
+		{name: "gzip-compressed",
+			content: gzipped(t, testInstanceData),
+			regions: []string{"eu-west-1"},
+			want: map[string][]string{
+				"m5.large": {"eu-west-1"},
+				"c5.large": {},
+			},
+		},
+		{name: "not an array",
+			content: []byte(`{"instance_type": "m5.large"}`),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := decodeInstanceData(bytes.NewReader(tt.content), tt.regions)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeInstanceData() error = %v, wantErr %v", err,
+					tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			got := make(map[string][]string)
+			for _, it := range data {
+				regions := []string{}
+				for r := range it.Pricing {
+					regions = append(regions, r)
+				}
+				sort.Strings(regions)
+				got[it.InstanceType] = regions
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeInstanceData() regions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadInstanceData(t *testing.T) {
+
+	data, err := LoadInstanceData("", "us-east-1", []byte(testInstanceData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 2 || data[0].Pricing["us-east-1"].Linux.OnDemand != "0.096" {
+		t.Errorf("LoadInstanceData() = %v", data)
+	}
+
+	if _, err := LoadInstanceData("/nonexistent/instances.json", "",
+		nil); err == nil {
+		t.Errorf("LoadInstanceData() succeeded for a missing file")
+	}
+}
@@ -0,0 +1,100 @@
+package autospotting
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+var access accessMonitor
+
+type accessMonitor struct {
+	sync.Mutex
+
+	// the denied API call, empty as long as none was denied
+	denied string
+}
+
+func (m *accessMonitor) init() {
+	m.Lock()
+	defer m.Unlock()
+
+	m.denied = ""
+}
+
+// accessDenied tells if the error code is about missing permissions.
+func accessDenied(code string) bool {
+	switch code {
+	case "AccessDenied", "AccessDeniedException", "UnauthorizedOperation":
+		return true
+	}
+	return false
+}
+
+// mutatingOperation tells if the EC2 or AutoScaling API operation changes any
+// resources.
+func mutatingOperation(service, operation string) bool {
+
+	if service != "ec2" && service != "autoscaling" {
+		return false
+	}
+
+	for _, prefix := range []string{"Describe", "List", "Get"} {
+		if strings.HasPrefix(operation, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// observe switches to report-only mode when a mutating API call was denied.
+func (m *accessMonitor) observe(r *request.Request) {
+
+	aerr, ok := r.Error.(awserr.Error)
+	if !ok || !accessDenied(aerr.Code()) ||
+		!mutatingOperation(r.ClientInfo.ServiceName, r.Operation.Name) {
+		return
+	}
+
+	m.Lock()
+	first := m.denied == ""
+	if first {
+		m.denied = r.ClientInfo.ServiceName + ":" + r.Operation.Name
+	}
+	m.Unlock()
+
+	if first {
+		logger.at(LevelWarn).Println("Missing the permissions for",
+			m.reportOnly(), "switching to report-only mode:", aerr.Message())
+		notifyPlatformError(homeRegion(), "Missing the permissions for",
+			m.reportOnly(), "only reporting for the rest of the run")
+	}
+}
+
+// reportOnly returns the denied API call which switched the run to
+// report-only mode, or an empty string.
+func (m *accessMonitor) reportOnly() string {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.denied
+}
+
+// reportsOnly evaluates the group into the plan report instead of processing
+// it, when running in report-only mode.
+func (a *autoScalingGroup) reportsOnly() bool {
+
+	denied := access.reportOnly()
+	if denied == "" {
+		return false
+	}
+
+	a.scanInstances()
+	planner.add(a.plan())
+	a.recordSavings()
+	a.recordAction("skipped", "report-only mode, missing the permissions for",
+		denied)
+	return true
+}
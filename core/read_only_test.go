@@ -0,0 +1,57 @@
+package autospotting
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+func Test_accessMonitor_observe(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		service   string
+		operation string
+		err       error
+		want      string
+	}{
+		{name: "successful call", service: "ec2",
+			operation: "RequestSpotInstances"},
+		{name: "other error", service: "ec2", operation: "RequestSpotInstances",
+			err: awserr.New("InsufficientInstanceCapacity", "", nil)},
+		{name: "not an AWS error", service: "ec2",
+			operation: "RequestSpotInstances", err: errors.New("timeout")},
+		{name: "denied read", service: "autoscaling",
+			operation: "DescribeLifecycleHooks",
+			err:       awserr.New("AccessDenied", "", nil)},
+		{name: "denied state store write", service: "dynamodb",
+			operation: "PutItem",
+			err:       awserr.New("AccessDeniedException", "", nil)},
+		{name: "denied bid", service: "ec2", operation: "RequestSpotInstances",
+			err:  awserr.New("UnauthorizedOperation", "", nil),
+			want: "ec2:RequestSpotInstances"},
+		{name: "denied attach", service: "autoscaling",
+			operation: "AttachInstances",
+			err:       awserr.New("AccessDenied", "", nil),
+			want:      "autoscaling:AttachInstances"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m accessMonitor
+			m.init()
+
+			m.observe(&request.Request{
+				ClientInfo: metadata.ClientInfo{ServiceName: tt.service},
+				Operation:  &request.Operation{Name: tt.operation},
+				Error:      tt.err,
+			})
+
+			if got := m.reportOnly(); got != tt.want {
+				t.Errorf("reportOnly() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
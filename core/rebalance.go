@@ -0,0 +1,119 @@
+package autospotting
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// RebalanceRecommendation is the detail type of the EventBridge events
+// recommending the replacement of spot instances at an elevated risk of being
+// interrupted.
+const RebalanceRecommendation = "EC2 Instance Rebalance Recommendation"
+
+// atRiskInstances returns the IDs of the spot instances at an elevated risk of
+// being interrupted, given by the event triggering the run.
+func (c *Config) atRiskInstances() []string {
+	return instanceIDs(c.AtRiskInstances)
+}
+
+// replacingBid tells if a spot request already replaces the instance.
+func replacingBid(requests []*ec2.SpotInstanceRequest, instanceID string) bool {
+
+	for _, req := range requests {
+		if req.State == nil ||
+			(*req.State != "open" && *req.State != "active") {
+			continue
+		}
+		if id := findTagValue(req.Tags, "original-instance-id"); id != nil &&
+			*id == instanceID {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceAtRiskSpotInstances bids for the replacements of the group's spot
+// instances at risk of being interrupted, telling if it placed any bid.
+func (a *autoScalingGroup) replaceAtRiskSpotInstances() bool {
+
+	launched := false
+
+	for _, id := range a.region.conf.atRiskInstances() {
+
+		inst := a.instances.get(id)
+		if inst == nil || !inst.isSpot() {
+			continue
+		}
+
+		if replacingBid(a.spotInstanceRequests, id) {
+			a.log().Println(a.name, "Spot instance", id, "at risk of interruption",
+				"is already being replaced")
+			continue
+		}
+
+		a.log().Println(a.region.name, a.name, "Spot instance", id,
+			"is at risk of interruption, launching its replacement")
+
+		// the replacement may cost up to the on-demand price
+		base := *inst
+		base.price = a.region.normalizedPrice(inst.typeInfo,
+			inst.typeInfo.pricing.onDemand, inst.pricingProfile())
+
+		a.launchSpotInstanceFor(&base, inst.Placement.AvailabilityZone)
+		launched = true
+	}
+	return launched
+}
+
+// atRiskSpotInstanceFor returns the spot instance of the group replaced by the
+// new spot instance, or nil if the new spot instance replaces an on-demand
+// instance.
+func (a *autoScalingGroup) atRiskSpotInstanceFor(spotInst *instance) *instance {
+
+	id := findTagValue(spotInst.Tags, "original-instance-id")
+	if id == nil {
+		return nil
+	}
+
+	if original := a.instances.get(*id); original != nil && original.isSpot() {
+		return original
+	}
+	return nil
+}
+
+// replaceAtRiskSpotInstance attaches the new spot instance, then detaches and
+// terminates the at-risk spot instance it replaces.
+func (a *autoScalingGroup) replaceAtRiskSpotInstance(atRisk *instance,
+	spotInstanceID *string) {
+
+	a.log().Println(a.name, "Replacing spot instance", *atRisk.InstanceId,
+		"at risk of interruption with spot instance", *spotInstanceID)
+
+	if a.attachSpotInstance(spotInstanceID) != nil {
+		return
+	}
+
+	input := &autoscaling.DetachInstancesInput{
+		AutoScalingGroupName:           aws.String(a.name),
+		InstanceIds:                    []*string{atRisk.InstanceId},
+		ShouldDecrementDesiredCapacity: aws.Bool(true),
+	}
+
+	if a.skipsCall("DetachInstances", input) {
+		return
+	}
+
+	a.deregisterIPTargets(atRisk.InstanceId)
+
+	if _, err := a.region.services.autoScaling.DetachInstances(input); err != nil {
+		a.log().at(LevelError).Println(a.name, "Failed to detach spot instance",
+			*atRisk.InstanceId, err.Error())
+		return
+	}
+	a.waitUntilDetached(atRisk.InstanceId)
+
+	a.terminateInstance(atRisk)
+	a.recordAction("terminated", "spot instance", *atRisk.InstanceId,
+		"at risk of interruption, replaced by", *spotInstanceID)
+}
@@ -0,0 +1,50 @@
+package autospotting
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_replacingBid(t *testing.T) {
+
+	request := func(state, original string) *ec2.SpotInstanceRequest {
+		return &ec2.SpotInstanceRequest{
+			State: aws.String(state),
+			Tags: []*ec2.Tag{{
+				Key:   aws.String("original-instance-id"),
+				Value: aws.String(original),
+			}},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		requests []*ec2.SpotInstanceRequest
+		want     bool
+	}{
+		{name: "no requests"},
+		{name: "open request replacing the instance",
+			requests: []*ec2.SpotInstanceRequest{request("open", "i-risky")},
+			want:     true,
+		},
+		{name: "active request replacing the instance",
+			requests: []*ec2.SpotInstanceRequest{request("active", "i-risky")},
+			want:     true,
+		},
+		{name: "closed request replacing the instance",
+			requests: []*ec2.SpotInstanceRequest{request("closed", "i-risky")},
+		},
+		{name: "request replacing another instance",
+			requests: []*ec2.SpotInstanceRequest{request("open", "i-other")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replacingBid(tt.requests, "i-risky"); got != tt.want {
+				t.Errorf("replacingBid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package autospotting
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+// desiredCapacityCorrection returns the desired capacity the group should be
+// set to after a replacement, and false if it needs no correction.
+func desiredCapacityCorrection(expected int64,
+	group *autoscaling.Group) (int64, bool) {
+
+	drift := *group.DesiredCapacity - expected
+
+	if drift != 1 && drift != -1 {
+		return 0, false
+	}
+
+	if expected < *group.MinSize || expected > *group.MaxSize {
+		return 0, false
+	}
+	return expected, true
+}
+
+// healthyInServiceCount returns the number of in-service and healthy instances
+// of the group.
+func healthyInServiceCount(group *autoscaling.Group) int64 {
+	var count int64
+
+	for _, inst := range group.Instances {
+		if inst.HealthStatus != nil && *inst.HealthStatus == "Healthy" &&
+			inst.LifecycleState != nil && *inst.LifecycleState == "InService" {
+			count++
+		}
+	}
+	return count
+}
+
+// currentDesiredCapacity returns the group's desired capacity right before a
+// replacement, which may have changed since the group was described at the
+// start of the run.
+func (a *autoScalingGroup) currentDesiredCapacity() int64 {
+
+	group, err := a.describeGroup()
+	if err != nil {
+		a.log().at(LevelWarn).Println(a.name,
+			"Couldn't describe the group, using its initial desired capacity",
+			err.Error())
+		return *a.DesiredCapacity
+	}
+	return *group.DesiredCapacity
+}
+
+// reconcileCapacity verifies the group's capacity after a replacement which
+// attached or detached any instances, against the desired capacity it had right
+// before the replacement, correcting the drift if enabled and alerting if the
+// group is left short of instances.
+func (a *autoScalingGroup) reconcileCapacity(expected int64) {
+
+	group, err := a.describeGroup()
+	if err != nil {
+		a.log().at(LevelWarn).Println(a.name,
+			"Couldn't check the capacity of the group", err.Error())
+		return
+	}
+
+	a.log().Println(a.name, "Desired capacity:", *group.DesiredCapacity,
+		"expected:", expected, "in service and healthy:",
+		healthyInServiceCount(group))
+
+	if *group.DesiredCapacity != expected {
+
+		desired, correct := desiredCapacityCorrection(expected, group)
+
+		if !correct || !a.region.conf.ReconcileCapacity {
+			a.log().Println(a.name, "The desired capacity changed from", expected,
+				"to", *group.DesiredCapacity, "during the replacement,",
+				"leaving it unchanged")
+		} else if a.setDesiredCapacity(desired) == nil {
+			a.recordAction("reconciled", "desired capacity from",
+				*group.DesiredCapacity, "back to", desired)
+			group.DesiredCapacity = aws.Int64(desired)
+		}
+	}
+
+	a.checkCapacity(group)
+}
+
+func (a *autoScalingGroup) setDesiredCapacity(desired int64) error {
+
+	input := &autoscaling.SetDesiredCapacityInput{
+		AutoScalingGroupName: aws.String(a.name),
+		DesiredCapacity:      aws.Int64(desired),
+		HonorCooldown:        aws.Bool(false),
+	}
+
+	if a.skipsCall("SetDesiredCapacity", input) {
+		return nil
+	}
+
+	_, err := a.region.services.autoScaling.SetDesiredCapacity(input)
+
+	if err != nil {
+		a.log().at(LevelError).Println(a.name,
+			"Failed to set the desired capacity to", desired, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,107 @@
+package autospotting
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+func Test_desiredCapacityCorrection(t *testing.T) {
+
+	group := func(desired, min, max int64) *autoscaling.Group {
+		return &autoscaling.Group{
+			DesiredCapacity: aws.Int64(desired),
+			MinSize:         aws.Int64(min),
+			MaxSize:         aws.Int64(max),
+		}
+	}
+
+	tests := []struct {
+		name        string
+		expected    int64
+		group       *autoscaling.Group
+		want        int64
+		wantCorrect bool
+	}{
+		{
+			name:     "unchanged",
+			expected: 3,
+			group:    group(3, 1, 5),
+		},
+		{
+			name:        "spot instance attached but on-demand not detached",
+			expected:    3,
+			group:       group(4, 1, 5),
+			want:        3,
+			wantCorrect: true,
+		},
+		{
+			name:        "on-demand detached but spot instance not attached",
+			expected:    3,
+			group:       group(2, 1, 5),
+			want:        3,
+			wantCorrect: true,
+		},
+		{
+			name:     "scaled by a scaling activity",
+			expected: 3,
+			group:    group(6, 1, 10),
+		},
+		{
+			name:     "expected capacity no longer within the group's limits",
+			expected: 3,
+			group:    group(2, 1, 2),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, correct := desiredCapacityCorrection(tt.expected, tt.group)
+			if got != tt.want || correct != tt.wantCorrect {
+				t.Errorf("desiredCapacityCorrection() = %v, %v, want %v, %v",
+					got, correct, tt.want, tt.wantCorrect)
+			}
+		})
+	}
+}
+
+func Test_autoScalingGroup_currentDesiredCapacity(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		group *autoscaling.Group
+		want  int64
+	}{
+		{
+			name:  "changed since the start of the run",
+			group: &autoscaling.Group{DesiredCapacity: aws.Int64(5)},
+			want:  5,
+		},
+		{
+			name: "not found",
+			want: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			services, _ := fakeConnections(func(r *request.Request) {
+				out, ok := r.Data.(*autoscaling.DescribeAutoScalingGroupsOutput)
+				if ok && tt.group != nil {
+					out.AutoScalingGroups = []*autoscaling.Group{tt.group}
+				}
+			})
+
+			a := autoScalingGroup{
+				Group:  &autoscaling.Group{DesiredCapacity: aws.Int64(3)},
+				name:   "web",
+				region: &region{name: "us-east-1", services: services},
+			}
+
+			if got := a.currentDesiredCapacity(); got != tt.want {
+				t.Errorf("currentDesiredCapacity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -8,9 +8,9 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/davecgh/go-spew/spew"
 )
 
 // data structure that stores information about a region
@@ -26,12 +26,44 @@ type region struct {
 	enabledASGs []autoScalingGroup
 	services    connections
 
+	// names of the groups targeted by ongoing CodeDeploy deployments
+	deployingASGs map[string]bool
+
+	// Elastic Beanstalk environments being launched or updated, with their
+	// status
+	deployingEnvironments map[string]string
+
+	// spot requests placed by AutoSpotting, described once per run, and the
+	// error of describing them
+	spotRequests    []*ec2.SpotInstanceRequest
+	spotRequestsErr error
+
+	// recent spot capacity failures, shared by all the groups of the region
+	capacity capacityBreaker
+
+	// spot instances adopted by the groups of the region during this run
+	adoptions adoptionClaims
+
+	// managed spot instances by pool, shared by all the groups of the region
+	pools poolUsage
+
+	// when the spot prices were last refreshed successfully
+	spotPricesFetched time.Time
+
+	// pools whose spot prices were fetched lazily, nil when all the spot prices
+	// were fetched eagerly, guarded by spotPriceLock
+	fetchedPools  map[string]bool
+	spotPriceLock sync.Mutex
+
 	wg sync.WaitGroup
 }
 
 type prices struct {
 	onDemand float64
 	spot     spotPriceMap
+
+	// on-demand price of Windows instances, including the license costs
+	windowsOnDemand float64
 }
 
 // The key in this map is the availavility zone
@@ -56,31 +88,89 @@ func (r *region) enabled() bool {
 	return false
 }
 
+// inScope tells if the AutoScaling group should be processed, when the groups
+// were restricted to a list of names.
+func (r *region) inScope(asgName string) bool {
+
+	if r.conf.AutoScalingGroups == "" {
+		return true
+	}
+
+	for _, name := range strings.Split(r.conf.AutoScalingGroups, ",") {
+		if strings.TrimSpace(name) == asgName {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *region) processRegion() {
 
-	logger.Println("Creating connections to the required AWS services in", r.name)
+	if healthEvents.blocks(r.name, nil) {
+		// the instances replaced earlier are still terminated when due
+		if !r.conf.Plan {
+			r.services.connect(r.name)
+			r.processTerminationQueue()
+		}
+		return
+	}
+
+	r.log().Println("Creating connections to the required AWS services in", r.name)
 	r.services.connect(r.name)
+
+	if r.conf.Plan {
+		r.log().Println("Planning the replacements of all the AutoScaling groups in",
+			r.name)
+		r.planRegion()
+		return
+	}
 	// only process the regions where we have AutoScaling groups set to be handled
 
-	logger.Println("Scanning for enabled AutoScaling groups in ", r.name)
+	r.log().Println("Scanning for enabled AutoScaling groups in ", r.name)
 	r.scanForEnabledAutoScalingGroups()
 
 	// only process further the region if there are any enabled autoscaling groups
 	// within it
 	if r.hasEnabledAutoScalingGroups() {
 
-		logger.Println("Scanning full instance information in", r.name)
+		r.log().Println("Scanning full instance information in", r.name)
 		r.determineInstanceTypeInformation(r.conf)
 
-		debug.Println(spew.Sdump(r.instanceTypeInformation))
+		debugDump(r.name, "", "instanceTypeInformation",
+			r.instanceTypeInformation)
+
+		if !r.hasFreshPricing() {
+			return
+		}
+		r.recordRegionalPrices()
 
-		logger.Println("Scanning instances in", r.name)
+		r.log().Println("Scanning instances in", r.name)
 		r.scanInstances()
 
-		logger.Println("Processing enabled AutoScaling groups in", r.name)
+		r.processTerminationQueue()
+
+		r.log().Println("Scanning the spot requests in", r.name)
+		r.scanSpotRequests()
+		r.scanCapacityFailures()
+		r.scanInterruptions()
+
+		r.log().Println("Scanning ongoing deployments in", r.name)
+		r.scanActiveDeployments()
+		r.scanUpdatingEnvironments()
+
+		spot, total := r.countManagedInstances()
+		spotShare.add(r.name, spot, total)
+		r.countPoolUsage()
+
+		if continuation.expired(time.Now()) {
+			continuation.skipRegion(r.name)
+			return
+		}
+
+		r.log().Println("Processing enabled AutoScaling groups in", r.name)
 		r.processEnabledAutoScalingGroups()
 	} else {
-		logger.Println(r.name, "has no enabled AutoScaling groups")
+		r.log().Println(r.name, "has no enabled AutoScaling groups")
 	}
 }
 
@@ -93,6 +183,8 @@ func (r *region) scanInstances() error {
 				Values: []*string{
 					aws.String("running"),
 					aws.String("pending"),
+					aws.String("stopping"),
+					aws.String("stopped"),
 				},
 			},
 		},
@@ -103,7 +195,7 @@ func (r *region) scanInstances() error {
 		return err
 	}
 
-	debug.Println(resp)
+	r.debugLog().Println(resp)
 
 	r.instances.catalog = make(map[string]*instance)
 
@@ -117,18 +209,30 @@ func (r *region) scanInstances() error {
 					Instance: inst,
 					typeInfo: r.instanceTypeInformation[*inst.InstanceType],
 				}
-				debug.Println("Type Info:", *inst.InstanceType, spew.Sdump(i.typeInfo))
+				// stopped instances are gone, except for the spot instances
+				// stopped or hibernated by interruptions, which are resumed
+				// once capacity is available again
+				if *inst.State.Name != "running" && *inst.State.Name != "pending" &&
+					!i.isRecoverable() {
+					continue
+				}
+
+				debugDump(r.name, "", "typeInfo", i.typeInfo)
 				r.instances.add(&i)
 
 			}
 		}
 	}
-	debug.Println(spew.Sdump(r.instances))
+	debugDump(r.name, "", "instances", r.instances)
 	return nil
 }
 
 func (r *region) determineInstanceTypeInformation(cfg Config) {
 
+	if r.reuseCatalog() {
+		return
+	}
+
 	r.instanceTypeInformation = make(map[string]instanceTypeInformation)
 
 	var info instanceTypeInformation
@@ -137,12 +241,15 @@ func (r *region) determineInstanceTypeInformation(cfg Config) {
 
 		var price prices
 
-		debug.Println(it)
+		r.debugLog().Println(it)
 
 		// populate on-demand information
 		price.onDemand, _ = strconv.ParseFloat(
 			it.Pricing[r.name].Linux.OnDemand, 64)
 
+		price.windowsOnDemand, _ = strconv.ParseFloat(
+			it.Pricing[r.name].Mswin.OnDemand, 64)
+
 		price.spot = make(spotPriceMap)
 
 		// if at this point the instance price is still zero, then that
@@ -155,8 +262,10 @@ func (r *region) determineInstanceTypeInformation(cfg Config) {
 				instanceType:        it.InstanceType,
 				vCPU:                it.VCPU,
 				memory:              it.Memory,
+				gpu:                 it.GPU,
 				pricing:             price,
 				virtualizationTypes: it.LinuxVirtualizationTypes,
+				architectures:       it.Arch,
 			}
 
 			if it.Storage != nil {
@@ -165,7 +274,7 @@ func (r *region) determineInstanceTypeInformation(cfg Config) {
 				info.instanceStoreDeviceCount = it.Storage.Devices
 				info.instanceStoreIsSSD = it.Storage.SSD
 			}
-			debug.Println(info)
+			r.debugLog().Println(info)
 			r.instanceTypeInformation[it.InstanceType] = info
 		}
 	}
@@ -173,11 +282,17 @@ func (r *region) determineInstanceTypeInformation(cfg Config) {
 	// return entries about the available instance types, so no invalid instance
 	// types would be returned
 
-	if err := r.requestSpotPrices(); err != nil {
-		logger.Println(err.Error())
+	if r.lazySpotPrices() {
+		// the spot prices are fetched when first needed, so they're always
+		// fresh
+		r.fetchedPools = make(map[string]bool)
+		r.spotPricesFetched = time.Now()
+	} else if err := r.requestSpotPrices(); err != nil {
+		r.log().at(LevelError).Println(err.Error())
 	}
+	r.cacheCatalog()
 
-	debug.Println(spew.Sdump(r.instanceTypeInformation))
+	debugDump(r.name, "", "instanceTypeInformation", r.instanceTypeInformation)
 }
 
 func (r *region) requestSpotPrices() error {
@@ -192,7 +307,7 @@ func (r *region) requestSpotPrices() error {
 		return errors.New("Couldn't fetch spot prices in" + r.name)
 	}
 
-	// logger.Println("Spot Price list in ", r.name, ":\n", s.data)
+	// r.log().Println("Spot Price list in ", r.name, ":\n", s.data)
 
 	for _, priceInfo := range s.data {
 
@@ -202,13 +317,13 @@ func (r *region) requestSpotPrices() error {
 		// spot market
 		price, err := strconv.ParseFloat(*priceInfo.SpotPrice, 64)
 		if err != nil {
-			logger.Println(r.name, "Instance type ", instType,
+			r.log().Println(r.name, "Instance type ", instType,
 				"is not available on the spot market")
 			continue
 		}
 
 		if r.instanceTypeInformation[instType].pricing.spot == nil {
-			logger.Println(r.name, "Instance data missing for", instType, "in", az,
+			r.log().Println(r.name, "Instance data missing for", instType, "in", az,
 				"skipping because this region is currently not supported")
 			continue
 		}
@@ -217,35 +332,68 @@ func (r *region) requestSpotPrices() error {
 
 	}
 
+	r.spotPricesFetched = time.Now()
 	return nil
 }
 
+// maximum number of group names accepted by DescribeAutoScalingGroups
+const describeGroupsBatchSize = 50
+
+// enabledGroupTagFilters selects the spot-enabled=true tags, restricted to the
+// groups of the current scope when given, so that DescribeTags cheaply returns
+// only the opted-in groups without loading the definitions of all the others.
+func (r *region) enabledGroupTagFilters() []*autoscaling.Filter {
+
+	filters := []*autoscaling.Filter{
+		{Name: aws.String("key"), Values: []*string{aws.String("spot-enabled")}},
+		{Name: aws.String("value"), Values: []*string{aws.String("true")}},
+	}
+
+	if r.conf.AutoScalingGroups != "" {
+		var names []*string
+		for _, name := range strings.Split(r.conf.AutoScalingGroups, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, aws.String(name))
+			}
+		}
+		filters = append(filters, &autoscaling.Filter{
+			Name:   aws.String("auto-scaling-group"),
+			Values: names,
+		})
+	}
+	return filters
+}
+
 func (r *region) scanForEnabledAutoScalingGroupsByTag(asgs *[]*string) {
 	svc := r.services.autoScaling
 
 	input := autoscaling.DescribeTagsInput{
-		Filters: []*autoscaling.Filter{
-			{Name: aws.String("key"), Values: []*string{aws.String("spot-enabled")}},
-			{Name: aws.String("value"), Values: []*string{aws.String("true")}},
-		},
+		Filters: r.enabledGroupTagFilters(),
 	}
 	pageNum := 0
 	err := svc.DescribeTagsPages(
 		&input,
 		func(page *autoscaling.DescribeTagsOutput, lastPage bool) bool {
 			pageNum++
-			logger.Println("Processing page", pageNum, "of DescribeTagsPages for", r.name)
+			r.log().Println("Processing page", pageNum, "of DescribeTagsPages for", r.name)
 			for _, tag := range page.Tags {
-				logger.Println(r.name, "has enabled ASG:", *tag.ResourceId)
+				if !r.inScope(*tag.ResourceId) {
+					r.log().Println(r.name, "Skipping enabled ASG", *tag.ResourceId,
+						"which is out of the current scope")
+					continue
+				}
+				r.log().Println(r.name, "has enabled ASG:", *tag.ResourceId)
 				*asgs = append(*asgs, tag.ResourceId)
 			}
 			return true
 		},
 	)
 	if err != nil {
-		logger.Println("Failed to describe AutoScaling tags in",
+		r.log().at(LevelError).Println("Failed to describe AutoScaling tags in",
 			r.name,
 			err.Error())
+		notifyPlatformError(r.name, "Failed to describe AutoScaling tags",
+			err.Error())
 		return
 	}
 }
@@ -261,34 +409,38 @@ func (r *region) scanForEnabledAutoScalingGroups() {
 
 	svc := r.services.autoScaling
 
-	input := autoscaling.DescribeAutoScalingGroupsInput{
-		AutoScalingGroupNames: asgNames,
-	}
-	pageNum := 0
-	err := svc.DescribeAutoScalingGroupsPages(
-		&input,
-		func(page *autoscaling.DescribeAutoScalingGroupsOutput, lastPage bool) bool {
-			pageNum++
-			logger.Println("Processing page", pageNum, "of DescribeAutoScalingGroupsPages for", r.name)
-			for _, asg := range page.AutoScalingGroups {
-				group := autoScalingGroup{
-					Group:  asg,
-					name:   *asg.AutoScalingGroupName,
-					region: r,
+	for _, batch := range batchNames(asgNames, describeGroupsBatchSize) {
+
+		input := autoscaling.DescribeAutoScalingGroupsInput{
+			AutoScalingGroupNames: aws.StringSlice(batch),
+		}
+		pageNum := 0
+		err := svc.DescribeAutoScalingGroupsPages(
+			&input,
+			func(page *autoscaling.DescribeAutoScalingGroupsOutput, lastPage bool) bool {
+				pageNum++
+				r.log().Println("Processing page", pageNum, "of DescribeAutoScalingGroupsPages for", r.name)
+				for _, asg := range page.AutoScalingGroups {
+					group := autoScalingGroup{
+						Group:  asg,
+						name:   *asg.AutoScalingGroupName,
+						region: r,
+					}
+					r.enabledASGs = append(r.enabledASGs, group)
 				}
-				r.enabledASGs = append(r.enabledASGs, group)
-			}
-			return true
-		},
-	)
+				return true
+			},
+		)
 
-	if err != nil {
-		logger.Println("Failed to describe AutoScaling groups in",
-			r.name,
-			err.Error())
-		return
+		if err != nil {
+			r.log().at(LevelError).Println(
+				"Failed to describe AutoScaling groups in",
+				r.name, err.Error())
+			notifyPlatformError(r.name, "Failed to describe AutoScaling groups",
+				err.Error())
+			return
+		}
 	}
-
 }
 
 func (r *region) hasEnabledAutoScalingGroups() bool {
@@ -298,20 +450,50 @@ func (r *region) hasEnabledAutoScalingGroups() bool {
 }
 
 func (r *region) processEnabledAutoScalingGroups() {
-	for _, asg := range r.enabledASGs {
+
+	groups, indexes := rotateGroups(r.enabledASGs, continuation.cursor(r.name))
+
+	var slots chan struct{}
+	if r.conf.MaxConcurrentGroups > 0 {
+		slots = make(chan struct{}, r.conf.MaxConcurrentGroups)
+	}
+
+	next, finished := 0, true
+
+	for i, asg := range groups {
+		if slots != nil {
+			slots <- struct{}{}
+		}
+
+		if continuation.expired(time.Now()) {
+			r.log().Println(r.name, "Not processing the remaining",
+				len(groups)-i, "AutoScaling groups since the deadline of the run",
+				"passed")
+			next, finished = indexes[i], false
+			break
+		}
+
 		r.wg.Add(1)
 		go func(a autoScalingGroup) {
 			a.process()
+			if slots != nil {
+				<-slots
+			}
 			r.wg.Done()
 		}(asg)
 	}
 	r.wg.Wait()
+
+	continuation.advance(r.name, next, finished)
 }
 
+// how many times the tagging of an instance is attempted, 5 seconds apart
+const tagAttempts = 5
+
 func (r *region) tagInstance(instanceID *string, tags []*ec2.Tag) {
 
 	if len(tags) == 0 {
-		logger.Println(r.name, "Tagging spot instance", *instanceID,
+		r.log().Println(r.name, "Tagging spot instance", *instanceID,
 			"no tags were defined, skipping...")
 		return
 	}
@@ -322,20 +504,34 @@ func (r *region) tagInstance(instanceID *string, tags []*ec2.Tag) {
 		Tags:      tags,
 	}
 
-	logger.Println(r.name, "Tagging spot instance", *instanceID)
+	r.log().Println(r.name, "Tagging spot instance", *instanceID)
 
-	for _, err := svc.CreateTags(&params); err != nil; _, err =
-		svc.CreateTags(&params) {
+	for attempt := 1; ; attempt++ {
 
-		logger.Println(r.name,
-			"Failed to create tags for the spot instance", *instanceID, err.Error())
+		req, _ := svc.CreateTagsRequest(&params)
+		err := req.Send()
+		if err == nil {
+			break
+		}
+
+		// missing permissions switch the run to report-only mode
+		access.observe(req)
+
+		r.log().at(LevelError).Println(r.name,
+			"Failed to create tags for the spot instance", *instanceID,
+			err.Error())
+
+		if aerr, ok := err.(awserr.Error); (ok && accessDenied(aerr.Code())) ||
+			attempt == tagAttempts {
+			return
+		}
 
-		logger.Println(r.name,
+		r.log().Println(r.name,
 			"Sleeping for 5 seconds before retrying")
 
 		time.Sleep(5 * time.Second)
 	}
 
-	logger.Println("Instance", *instanceID,
+	r.log().Println("Instance", *instanceID,
 		"was tagged with the following tags:", tags)
 }
@@ -0,0 +1,102 @@
+package autospotting
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_region_inScope(t *testing.T) {
+	tests := []struct {
+		name   string
+		groups string
+		asg    string
+		want   bool
+	}{
+		{name: "All groups by default",
+			groups: "",
+			asg:    "web",
+			want:   true,
+		},
+		{name: "Listed group",
+			groups: "db, web",
+			asg:    "web",
+			want:   true,
+		},
+		{name: "Unlisted group",
+			groups: "db,worker",
+			asg:    "web",
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := region{conf: Config{AutoScalingGroups: tt.groups}}
+			if got := r.inScope(tt.asg); got != tt.want {
+				t.Errorf("inScope() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_region_enabledGroupTagFilters(t *testing.T) {
+	tests := []struct {
+		name   string
+		groups string
+		want   map[string][]string
+	}{
+		{name: "All groups",
+			groups: "",
+			want: map[string][]string{
+				"key":   {"spot-enabled"},
+				"value": {"true"},
+			},
+		},
+		{name: "Listed groups",
+			groups: "db, web",
+			want: map[string][]string{
+				"key":                {"spot-enabled"},
+				"value":              {"true"},
+				"auto-scaling-group": {"db", "web"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := region{conf: Config{AutoScalingGroups: tt.groups}}
+
+			got := make(map[string][]string)
+			for _, f := range r.enabledGroupTagFilters() {
+				got[*f.Name] = aws.StringValueSlice(f.Values)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("enabledGroupTagFilters() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_region_tagInstance_denied(t *testing.T) {
+	access.init()
+	defer access.init()
+
+	services, calls := fakeConnections(func(r *request.Request) {
+		r.Error = awserr.New("UnauthorizedOperation", "not allowed", nil)
+	})
+	r := &region{name: "us-east-1", services: services}
+
+	r.tagInstance(aws.String("i-1"), []*ec2.Tag{
+		{Key: aws.String("Name"), Value: aws.String("web")},
+	})
+
+	if len(*calls) != 1 {
+		t.Errorf("tagInstance() made %d calls, want a single one", len(*calls))
+	}
+	if got := access.reportOnly(); got != "ec2:CreateTags" {
+		t.Errorf("reportOnly() = %q, want ec2:CreateTags", got)
+	}
+}
@@ -0,0 +1,69 @@
+package autospotting
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+var reports reportWriter
+
+type reportWriter struct {
+	bucket *s3Bucket
+	dir    localDirectory
+
+	// all the reports of a run are grouped under the run's start time
+	runTime time.Time
+}
+
+func (w *reportWriter) init(cfg Config) {
+	w.runTime = time.Now().UTC()
+
+	w.bucket = nil
+	if cfg.ReportBucket != "" {
+		w.bucket = &s3Bucket{name: cfg.ReportBucket}
+	}
+	w.dir = localDirectory(cfg.ReportDir)
+}
+
+// writeReport exports the given data structure as a JSON report.
+func writeReport(name string, v interface{}) {
+
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		logger.at(LevelError).Println("Couldn't encode the", name, "report",
+			err.Error())
+		return
+	}
+
+	writeReportFile(name, "json", content)
+}
+
+// writeReportFile exports the content of a report in the given format.
+func writeReportFile(name, format string, content []byte) {
+
+	key := fmt.Sprintf("reports/%s/%s.%s",
+		reports.runTime.Format("2006-01-02T15-04-05"), name, format)
+
+	if reports.bucket != nil {
+		if err := reports.bucket.put(key, content); err == nil {
+			logger.Println("Wrote the", name, "report to",
+				"s3://"+reports.bucket.name+"/"+key)
+			return
+		}
+	}
+
+	if reports.dir != "" {
+		err := reports.dir.put(key, content)
+		if err == nil {
+			logger.Println("Wrote the", name, "report to",
+				filepath.Join(string(reports.dir), key))
+			return
+		}
+		logger.at(LevelError).Println("Couldn't write the", name, "report to",
+			reports.dir, err.Error())
+	}
+
+	logger.Println("Report", name+":", string(content))
+}
@@ -0,0 +1,98 @@
+package autospotting
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// handOverSpotInstance retags the spot instance's request for another group
+// which could use it, and tells if any was found.
+func (a *autoScalingGroup) handOverSpotInstance(spotInst *instance) bool {
+
+	if spotInst.SpotInstanceRequestId == nil {
+		return false
+	}
+
+	for i := range a.region.enabledASGs {
+
+		// only the data which isn't changed while the other groups are being
+		// processed concurrently is used here
+		other := &a.region.enabledASGs[i]
+		if other.name == a.name {
+			continue
+		}
+
+		lc := other.getLaunchConfiguration()
+		if lc == nil || !other.canAdopt(spotInst, lc) {
+			continue
+		}
+
+		victim := other.onDemandInstanceReplaceableBy(spotInst)
+		if victim == nil {
+			continue
+		}
+
+		if !a.region.adoptions.claim(*spotInst.InstanceId) {
+			return false
+		}
+
+		input := &ec2.CreateTagsInput{
+			Resources: []*string{spotInst.SpotInstanceRequestId},
+			Tags: []*ec2.Tag{{
+				Key:   aws.String("launched-for-asg"),
+				Value: aws.String(other.name),
+			}},
+		}
+
+		if a.skipsCall("CreateTags", input) {
+			return false
+		}
+
+		_, err := a.region.services.ec2.CreateTags(input)
+
+		if err != nil {
+			a.log().at(LevelError).Println(a.name,
+				"Failed to hand over spot instance", *spotInst.InstanceId, "to",
+				other.name, err.Error())
+			return false
+		}
+
+		a.recordAction("handed-over", "spot instance", *spotInst.InstanceId,
+			"to", other.name, "which can use it to replace", *victim.InstanceId)
+		return true
+	}
+	return false
+}
+
+// onDemandInstanceReplaceableBy returns a running on-demand instance of the
+// group, from the spot instance's availability zone, which is more expensive
+// and not bigger than the spot instance.
+func (a *autoScalingGroup) onDemandInstanceReplaceableBy(
+	spotInst *instance) *instance {
+
+	spotType := a.region.instanceTypeInformation[*spotInst.InstanceType]
+	az := *spotInst.Placement.AvailabilityZone
+
+	for _, member := range a.Instances {
+
+		inst := a.region.instances.get(*member.InstanceId)
+		if inst == nil || inst.isSpot() || *inst.State.Name != "running" ||
+			*inst.Placement.AvailabilityZone != az {
+			continue
+		}
+
+		odType := a.region.instanceTypeInformation[*inst.InstanceType]
+		profile := inst.pricingProfile()
+
+		odPrice := a.region.normalizedPrice(odType, odType.pricing.onDemand,
+			profile)
+		spotPrice := a.region.normalizedPrice(spotType,
+			a.region.spotPrice(spotType.instanceType, az), profile)
+
+		if spotPrice > 0 && spotPrice < odPrice &&
+			spotType.vCPU >= odType.vCPU && spotType.memory >= odType.memory {
+			return inst
+		}
+	}
+	return nil
+}
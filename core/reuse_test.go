@@ -0,0 +1,75 @@
+package autospotting
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_onDemandInstanceReplaceableBy(t *testing.T) {
+
+	newInstance := func(id, instanceType, az string, spot bool) *instance {
+		i := &instance{Instance: &ec2.Instance{
+			InstanceId:   aws.String(id),
+			InstanceType: aws.String(instanceType),
+			State:        &ec2.InstanceState{Name: aws.String("running")},
+			Placement:    &ec2.Placement{AvailabilityZone: aws.String(az)},
+		}}
+		if spot {
+			i.InstanceLifecycle = aws.String("spot")
+		}
+		return i
+	}
+
+	r := &region{
+		instanceTypeInformation: map[string]instanceTypeInformation{
+			"m4.large": {
+				instanceType: "m4.large", vCPU: 2, memory: 8,
+				pricing: prices{onDemand: 0.1,
+					spot: spotPriceMap{"us-east-1a": 0.03}},
+			},
+			"m4.xlarge": {
+				instanceType: "m4.xlarge", vCPU: 4, memory: 16,
+				pricing: prices{onDemand: 0.2,
+					spot: spotPriceMap{"us-east-1a": 0.06}},
+			},
+		},
+		instances: instances{catalog: map[string]*instance{
+			"i-small": newInstance("i-small", "m4.large", "us-east-1a", false),
+			"i-big":   newInstance("i-big", "m4.xlarge", "us-east-1a", false),
+			"i-other": newInstance("i-other", "m4.large", "us-east-1b", false),
+		}},
+	}
+
+	newGroup := func(ids ...string) *autoScalingGroup {
+		g := &autoScalingGroup{Group: &autoscaling.Group{}, region: r}
+		for _, id := range ids {
+			g.Instances = append(g.Instances,
+				&autoscaling.Instance{InstanceId: aws.String(id)})
+		}
+		return g
+	}
+
+	spot := newInstance("i-spot", "m4.large", "us-east-1a", true)
+
+	tests := []struct {
+		name  string
+		group *autoScalingGroup
+		want  string
+	}{
+		{name: "same size", group: newGroup("i-big", "i-small"), want: "i-small"},
+		{name: "too small", group: newGroup("i-big"), want: ""},
+		{name: "other zone", group: newGroup("i-other"), want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.group.onDemandInstanceReplaceableBy(spot)
+			if (got == nil && tt.want != "") ||
+				(got != nil && *got.InstanceId != tt.want) {
+				t.Errorf("onDemandInstanceReplaceableBy() = %v, want %q", got, tt.want)
+			}
+		})
+	}
+}
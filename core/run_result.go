@@ -0,0 +1,105 @@
+package autospotting
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RunResult summarizes the outcome of a run.
+type RunResult struct {
+	Started  time.Time `json:"started"`
+	Duration string    `json:"duration"`
+
+	// the run exited right away since its event was already processed
+	Duplicate bool `json:"duplicate,omitempty"`
+
+	Regions []string `json:"regions"`
+	Groups  int      `json:"groups"`
+
+	// spot instances attached in place of on-demand instances
+	Replacements int `json:"replacements"`
+
+	// groups skipped or whose replacement was deferred
+	Skipped int `json:"skipped"`
+
+	// number of times each action was taken on the groups
+	Actions map[string]int `json:"actions"`
+
+	Errors []string `json:"errors"`
+
+	// some regions or groups were left unprocessed when the deadline of the
+	// run passed, and the index of the first group the next run starts from in
+	// each region
+	Unfinished   bool           `json:"unfinished,omitempty"`
+	Continuation map[string]int `json:"continuation,omitempty"`
+}
+
+var runResult runSummary
+
+type runSummary struct {
+	sync.Mutex
+	result RunResult
+}
+
+func (s *runSummary) init(start time.Time) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.result = RunResult{
+		Started: start.UTC(),
+		Regions: []string{},
+		Actions: make(map[string]int),
+		Errors:  []string{},
+	}
+}
+
+func (s *runSummary) addRegion(region string, groups int) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.result.Regions = append(s.result.Regions, region)
+	s.result.Groups += groups
+}
+
+func (s *runSummary) addAction(action string) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.result.Actions == nil {
+		return
+	}
+
+	s.result.Actions[action]++
+	switch action {
+	case "attached":
+		s.result.Replacements++
+	case "skipped", "deferred":
+		s.result.Skipped++
+	}
+}
+
+func (s *runSummary) addError(region string, details ...interface{}) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.result.Errors == nil {
+		return
+	}
+
+	s.result.Errors = append(s.result.Errors, region+": "+
+		strings.TrimSpace(fmt.Sprintln(details...)))
+}
+
+// finish returns the result of the run which started at the given time.
+func (s *runSummary) finish(start time.Time) RunResult {
+	s.Lock()
+	defer s.Unlock()
+
+	result := s.result
+	result.Duration = time.Since(start).String()
+	sort.Strings(result.Regions)
+	return result
+}
@@ -0,0 +1,51 @@
+package autospotting
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_runSummary(t *testing.T) {
+
+	start := time.Now()
+
+	var s runSummary
+
+	// ignored before the run is initialized
+	s.addAction("attached")
+	s.addError("us-east-1", "ignored")
+
+	s.init(start)
+	s.addRegion("us-east-1", 2)
+	s.addRegion("eu-west-1", 1)
+	s.addAction("attached")
+	s.addAction("terminated")
+	s.addAction("skipped")
+	s.addAction("deferred")
+	s.addError("eu-west-1", "Failed to describe AutoScaling groups", "throttled")
+
+	got := s.finish(start)
+
+	want := RunResult{
+		Started:      start.UTC(),
+		Duration:     got.Duration,
+		Regions:      []string{"eu-west-1", "us-east-1"},
+		Groups:       3,
+		Replacements: 1,
+		Skipped:      2,
+		Actions: map[string]int{
+			"attached":   1,
+			"terminated": 1,
+			"skipped":    1,
+			"deferred":   1,
+		},
+		Errors: []string{
+			"eu-west-1: Failed to describe AutoScaling groups throttled",
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("finish() = %+v, want %+v", got, want)
+	}
+}
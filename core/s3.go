@@ -0,0 +1,103 @@
+package autospotting
+
+import (
+	"bytes"
+	"io/ioutil"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// s3Bucket uploads and downloads objects to and from a user-provided bucket,
+// which may be located in a different region than any of the regions being
+// processed.
+type s3Bucket struct {
+	sync.Mutex
+	name string
+	svc  *s3.S3
+}
+
+func (b *s3Bucket) put(key string, content []byte) error {
+
+	svc, err := b.client()
+	if err != nil {
+		logger.at(LevelError).Println("Couldn't connect to the S3 bucket",
+			b.name, err.Error())
+		return err
+	}
+
+	_, err = svc.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(b.name),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(content),
+	})
+
+	if err != nil {
+		logger.at(LevelError).Println("Failed to upload", key, "to", b.name,
+			err.Error())
+	}
+	return err
+}
+
+// get downloads the object, returning nothing if it doesn't exist.
+func (b *s3Bucket) get(key string) ([]byte, error) {
+
+	svc, err := b.client()
+	if err != nil {
+		logger.at(LevelError).Println("Couldn't connect to the S3 bucket",
+			b.name, err.Error())
+		return nil, err
+	}
+
+	resp, err := svc.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(b.name),
+		Key:    aws.String(key),
+	})
+
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+		return nil, nil
+	}
+	if err != nil {
+		logger.at(LevelError).Println("Failed to download", key, "from", b.name,
+			err.Error())
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// client lazily connects to S3 in the region of the bucket.
+func (b *s3Bucket) client() (*s3.S3, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.svc != nil {
+		return b.svc, nil
+	}
+
+	svc := s3.New(instrumentSession(
+		homeSession()))
+
+	resp, err := svc.GetBucketLocation(&s3.GetBucketLocationInput{
+		Bucket: aws.String(b.name),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the location constraint is empty for buckets created in us-east-1
+	bucketRegion := "us-east-1"
+	if resp.LocationConstraint != nil && *resp.LocationConstraint != "" {
+		bucketRegion = *resp.LocationConstraint
+		if bucketRegion == "EU" {
+			bucketRegion = "eu-west-1"
+		}
+	}
+
+	b.svc = s3.New(instrumentSession(
+		newSession(&aws.Config{Region: aws.String(bucketRegion)})))
+	return b.svc, nil
+}
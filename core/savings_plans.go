@@ -0,0 +1,206 @@
+package autospotting
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	"github.com/aws/aws-sdk-go/service/savingsplans"
+)
+
+var savingsPlans savingsPlansCoverage
+
+type savingsPlansCoverage struct {
+	sync.Mutex
+
+	enabled bool
+
+	// any active Compute Savings Plan
+	compute bool
+
+	// keyed by region and instance family, such as "us-east-1/m5"
+	active map[string]bool
+
+	// Cost Explorer recommendations, keyed like the active plans
+	recommended map[string]bool
+
+	comparison []savingsPlansComparison
+}
+
+type savingsPlansComparison struct {
+	Region           string  `json:"region"`
+	AutoScalingGroup string  `json:"autoscaling_group"`
+	InstanceID       string  `json:"instance_id"`
+	InstanceType     string  `json:"instance_type"`
+	Coverage         string  `json:"coverage"`
+	OnDemandPrice    float64 `json:"on_demand_price"`
+	SpotInstanceType string  `json:"spot_instance_type"`
+	SpotPrice        float64 `json:"spot_price"`
+	HourlySavings    float64 `json:"hourly_spot_savings"`
+}
+
+// load fetches the Savings Plans information, both APIs are only available in
+// us-east-1.
+func (s *savingsPlansCoverage) load(cfg Config) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.enabled = cfg.SavingsPlansAware
+	s.compute = false
+	s.active = make(map[string]bool)
+	s.recommended = make(map[string]bool)
+	s.comparison = nil
+
+	if !s.enabled {
+		return
+	}
+
+	sess := instrumentSession(
+		newSession(&aws.Config{Region: aws.String("us-east-1")}))
+
+	input := &savingsplans.DescribeSavingsPlansInput{
+		States: []*string{
+			aws.String(savingsplans.SavingsPlanStateActive),
+			aws.String(savingsplans.SavingsPlanStatePaymentPending),
+			aws.String(savingsplans.SavingsPlanStateQueued),
+		},
+	}
+	svc := savingsplans.New(sess)
+
+	for {
+		plans, err := svc.DescribeSavingsPlans(input)
+		if err != nil {
+			logger.at(LevelError).Println("Failed to describe Savings Plans",
+				err.Error())
+			break
+		}
+
+		for _, p := range plans.SavingsPlans {
+			switch aws.StringValue(p.SavingsPlanType) {
+			case savingsplans.SavingsPlanTypeCompute:
+				s.compute = true
+			case savingsplans.SavingsPlanTypeEc2instance:
+				if p.Region != nil && p.Ec2InstanceFamily != nil {
+					s.active[*p.Region+"/"+*p.Ec2InstanceFamily] = true
+				}
+			}
+		}
+
+		if aws.StringValue(plans.NextToken) == "" {
+			break
+		}
+		input.NextToken = plans.NextToken
+	}
+
+	recommendations, err := costexplorer.New(sess).
+		GetSavingsPlansPurchaseRecommendation(
+			&costexplorer.GetSavingsPlansPurchaseRecommendationInput{
+				LookbackPeriodInDays: aws.String(costexplorer.LookbackPeriodInDaysThirtyDays),
+				PaymentOption:        aws.String(costexplorer.PaymentOptionNoUpfront),
+				SavingsPlansType:     aws.String(costexplorer.SupportedSavingsPlansTypeEc2InstanceSp),
+				TermInYears:          aws.String(costexplorer.TermInYearsOneYear),
+			})
+
+	if err != nil {
+		logger.at(LevelError).Println(
+			"Failed to get Savings Plans recommendations",
+			err.Error())
+	} else if r := recommendations.SavingsPlansPurchaseRecommendation; r != nil {
+		for _, d := range r.SavingsPlansPurchaseRecommendationDetails {
+			if p := d.SavingsPlansDetails; p != nil &&
+				p.InstanceFamily != nil && p.Region != nil {
+				s.recommended[regionCode(*p.Region)+"/"+*p.InstanceFamily] = true
+			}
+		}
+	}
+
+	logger.Println("Loaded Savings Plans coverage, compute:", s.compute,
+		"active:", s.active, "recommended:", s.recommended)
+}
+
+// regionCode returns the code of a region given either by code or by name, such
+// as "US East (N. Virginia)", as used by Cost Explorer.
+func regionCode(region string) string {
+	for _, p := range endpoints.DefaultPartitions() {
+		for code, r := range p.Regions() {
+			if region == code || region == r.Description() {
+				return code
+			}
+		}
+	}
+	return region
+}
+
+// covers tells if the instance type is covered by an active or recommended
+// Savings Plan in the given region, and describes the coverage.
+func (s *savingsPlansCoverage) covers(region, instanceType string) (string, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	if !s.enabled {
+		return "", false
+	}
+
+	key := region + "/" + instanceFamily(instanceType)
+
+	if s.compute {
+		return "active compute savings plan", true
+	}
+	if s.active[key] {
+		return "active savings plan", true
+	}
+	if s.recommended[key] {
+		return "recommended savings plan", true
+	}
+	return "", false
+}
+
+func (s *savingsPlansCoverage) record(c savingsPlansComparison) {
+	s.Lock()
+	defer s.Unlock()
+	s.comparison = append(s.comparison, c)
+}
+
+func (s *savingsPlansCoverage) exportReport() {
+	s.Lock()
+	defer s.Unlock()
+
+	if !s.enabled || len(s.comparison) == 0 {
+		return
+	}
+	writeReport("savings-plans-comparison", s.comparison)
+}
+
+// deferForSavingsPlans checks if the replacement of the base instance should be
+// skipped because its family is covered by Savings Plans, in which case the
+// hypothetical spot savings are recorded for the comparison report.
+func (a *autoScalingGroup) deferForSavingsPlans(baseInstance *instance,
+	spotInstanceType string, spotPrice float64) bool {
+
+	coverage, covered := savingsPlans.covers(a.region.name,
+		*baseInstance.InstanceType)
+
+	if !covered {
+		return false
+	}
+
+	a.log().Println(a.name, "Instance", *baseInstance.InstanceId, "of type",
+		*baseInstance.InstanceType, "is covered by a", coverage,
+		"deferring its replacement, hypothetical hourly spot savings:",
+		strconv.FormatFloat(baseInstance.price-spotPrice, 'f', 4, 64))
+
+	savingsPlans.record(savingsPlansComparison{
+		Region:           a.region.name,
+		AutoScalingGroup: a.name,
+		InstanceID:       *baseInstance.InstanceId,
+		InstanceType:     *baseInstance.InstanceType,
+		Coverage:         coverage,
+		OnDemandPrice:    baseInstance.price,
+		SpotInstanceType: spotInstanceType,
+		SpotPrice:        spotPrice,
+		HourlySavings:    baseInstance.price - spotPrice,
+	})
+	return true
+}
@@ -0,0 +1,71 @@
+package autospotting
+
+import (
+	"testing"
+)
+
+func Test_savingsPlansCoverage_covers(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		coverage     *savingsPlansCoverage
+		region       string
+		instanceType string
+		want         string
+	}{
+		{
+			name: "disabled",
+			coverage: &savingsPlansCoverage{
+				active: map[string]bool{"us-east-1/m5": true}},
+			region:       "us-east-1",
+			instanceType: "m5.large",
+		},
+		{
+			name: "active in the region",
+			coverage: &savingsPlansCoverage{enabled: true,
+				active: map[string]bool{"us-east-1/m5": true}},
+			region:       "us-east-1",
+			instanceType: "m5.large",
+			want:         "active savings plan",
+		},
+		{
+			name: "recommended in another region",
+			coverage: &savingsPlansCoverage{enabled: true,
+				recommended: map[string]bool{"eu-west-1/m5": true}},
+			region:       "us-east-1",
+			instanceType: "m5.large",
+		},
+		{
+			name:         "compute savings plan",
+			coverage:     &savingsPlansCoverage{enabled: true, compute: true},
+			region:       "us-east-1",
+			instanceType: "c5.xlarge",
+			want:         "active compute savings plan",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, covered := tt.coverage.covers(tt.region, tt.instanceType)
+			if got != tt.want || covered != (tt.want != "") {
+				t.Errorf("covers() = %q, %v, want %q", got, covered, tt.want)
+			}
+		})
+	}
+}
+
+func Test_regionCode(t *testing.T) {
+
+	tests := []struct {
+		region string
+		want   string
+	}{
+		{region: "us-east-1", want: "us-east-1"},
+		{region: "US East (N. Virginia)", want: "us-east-1"},
+		{region: "Europe (Ireland)", want: "eu-west-1"},
+	}
+	for _, tt := range tests {
+		if got := regionCode(tt.region); got != tt.want {
+			t.Errorf("regionCode(%q) = %q, want %q", tt.region, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package autospotting
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var savingsHistory savingsRecorder
+
+// how long the daily savings are kept, covering the monthly totals of a year
+const savingsRetention = 400 * 24 * time.Hour
+
+type savingsRecorder struct {
+	sync.Mutex
+
+	table string
+
+	// current hourly savings, keyed by region/group
+	hourly map[string]float64
+
+	// rolled up at the end of the run
+	trend *savingsTrend
+}
+
+// savingsDay is the daily savings estimate of a group, averaged over the
+// samples taken by the runs of that day.
+type savingsDay struct {
+	Day     string
+	Group   string
+	Samples float64
+	Sum     float64
+}
+
+func (d savingsDay) estimate() float64 {
+	if d.Samples == 0 {
+		return 0
+	}
+	return d.Sum / d.Samples * 24
+}
+
+type savingsPeriod struct {
+	Period  string  `json:"period"`
+	Days    int     `json:"days"`
+	Savings float64 `json:"savings"`
+}
+
+type savingsTrend struct {
+	// currency of the savings, US dollars if empty
+	Currency string `json:"currency,omitempty"`
+
+	Weekly  []savingsPeriod `json:"weekly"`
+	Monthly []savingsPeriod `json:"monthly"`
+}
+
+// init sets the savings table, whose DynamoDB table needs a "day" string
+// partition key and a "group" string sort key, with the TTL set on "expires".
+func (s *savingsRecorder) init(cfg Config) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.table = cfg.SavingsTable
+	s.hourly = make(map[string]float64)
+	s.trend = nil
+}
+
+// recordSavings samples the current hourly savings of the group.
+func (a *autoScalingGroup) recordSavings() {
+
+	if savingsHistory.table == "" {
+		return
+	}
+
+	savings := 0.0
+	for _, inst := range a.instances.catalog {
+		savings += inst.hourlySavings()
+	}
+
+	savingsHistory.Lock()
+	defer savingsHistory.Unlock()
+
+	savingsHistory.hourly[a.region.name+"/"+a.name] = savings
+}
+
+// store adds the samples of the current run to the items of the day, then
+// rolls up the whole history.
+func (s *savingsRecorder) store() {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.table == "" {
+		return
+	}
+
+	now := time.Now().UTC()
+	day := now.Format("2006-01-02")
+	expires := float64(now.Add(savingsRetention).Unix())
+
+	for group, savings := range s.hourly {
+		err := state.increment(s.table, stateItem{
+			Keys: map[string]string{"day": day, "group": group},
+			Numbers: map[string]float64{
+				"samples":        1,
+				"hourly_savings": savings,
+				"expires":        expires,
+			},
+		})
+		if err != nil {
+			logger.at(LevelError).Println("Failed to store the savings of",
+				group, "in", s.table, err.Error())
+		}
+	}
+
+	days, err := s.load()
+	if err != nil {
+		logger.at(LevelError).Println("Failed to load the savings history from",
+			s.table, err.Error())
+		return
+	}
+
+	s.trend = rollupSavings(days)
+	writeReport("savings-trend", currency.savingsTrend(s.trend))
+}
+
+func (s *savingsRecorder) load() ([]savingsDay, error) {
+
+	items, err := state.scan(s.table)
+
+	var days []savingsDay
+	for _, item := range items {
+		days = append(days, savingsDay{
+			Day:     item.Keys["day"],
+			Group:   item.Keys["group"],
+			Samples: item.Numbers["samples"],
+			Sum:     item.Numbers["hourly_savings"],
+		})
+	}
+	return days, err
+}
+
+// rollupSavings sums up the daily estimates of all the groups into weekly and
+// monthly totals, ordered chronologically.
+func rollupSavings(days []savingsDay) *savingsTrend {
+
+	weekly := make(map[string]*savingsPeriod)
+	monthly := make(map[string]*savingsPeriod)
+
+	// the days covered by each period, across all groups
+	covered := make(map[string]map[string]bool)
+
+	add := func(periods map[string]*savingsPeriod, key, day string,
+		savings float64) {
+
+		p := periods[key]
+		if p == nil {
+			p = &savingsPeriod{Period: key}
+			periods[key] = p
+			covered[key] = make(map[string]bool)
+		}
+		p.Savings += savings
+		covered[key][day] = true
+		p.Days = len(covered[key])
+	}
+
+	for _, d := range days {
+		t, err := time.Parse("2006-01-02", d.Day)
+		if err != nil {
+			continue
+		}
+		year, week := t.ISOWeek()
+		add(weekly, fmt.Sprintf("%d-W%02d", year, week), d.Day, d.estimate())
+		add(monthly, t.Format("2006-01"), d.Day, d.estimate())
+	}
+
+	sorted := func(periods map[string]*savingsPeriod) []savingsPeriod {
+		result := []savingsPeriod{}
+		for _, p := range periods {
+			result = append(result, *p)
+		}
+		sort.Slice(result, func(i, j int) bool {
+			return result[i].Period < result[j].Period
+		})
+		return result
+	}
+
+	return &savingsTrend{Weekly: sorted(weekly), Monthly: sorted(monthly)}
+}
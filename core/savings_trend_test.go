@@ -0,0 +1,35 @@
+package autospotting
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_rollupSavings(t *testing.T) {
+
+	days := []savingsDay{
+		// 2017-05-31 is a Wednesday of the 22nd ISO week
+		{Day: "2017-05-31", Group: "us-east-1/web", Samples: 2, Sum: 1},
+		{Day: "2017-05-31", Group: "eu-west-1/web", Samples: 1, Sum: 1},
+		{Day: "2017-06-01", Group: "us-east-1/web", Samples: 4, Sum: 2},
+		// the 23rd week
+		{Day: "2017-06-05", Group: "us-east-1/web", Samples: 1, Sum: 0.5},
+		{Day: "invalid", Group: "us-east-1/web", Samples: 1, Sum: 100},
+		{Day: "2017-06-06", Group: "us-east-1/web", Samples: 0, Sum: 0},
+	}
+
+	want := &savingsTrend{
+		Weekly: []savingsPeriod{
+			{Period: "2017-W22", Days: 2, Savings: 48},
+			{Period: "2017-W23", Days: 2, Savings: 12},
+		},
+		Monthly: []savingsPeriod{
+			{Period: "2017-05", Days: 1, Savings: 36},
+			{Period: "2017-06", Days: 3, Savings: 24},
+		},
+	}
+
+	if got := rollupSavings(days); !reflect.DeepEqual(got, want) {
+		t.Errorf("rollupSavings() = %+v, want %+v", got, want)
+	}
+}
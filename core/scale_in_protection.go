@@ -0,0 +1,55 @@
+package autospotting
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+// needsScaleInProtection tells if the instance attached to the group should be
+// protected from scale-in, and isn't protected yet.
+func needsScaleInProtection(group *autoscaling.Group, instanceID string) bool {
+
+	if group.NewInstancesProtectedFromScaleIn == nil ||
+		!*group.NewInstancesProtectedFromScaleIn {
+		return false
+	}
+
+	for _, inst := range group.Instances {
+		if inst.InstanceId != nil && *inst.InstanceId == instanceID {
+			return inst.ProtectedFromScaleIn == nil || !*inst.ProtectedFromScaleIn
+		}
+	}
+
+	// not listed yet by the eventually consistent group description
+	return true
+}
+
+// protectFromScaleIn protects the attached spot instance from scale-in if the
+// group does so for its new instances.
+func (a *autoScalingGroup) protectFromScaleIn(instanceID *string) {
+
+	if !needsScaleInProtection(a.Group, *instanceID) {
+		return
+	}
+
+	input := &autoscaling.SetInstanceProtectionInput{
+		AutoScalingGroupName: aws.String(a.name),
+		InstanceIds:          []*string{instanceID},
+		ProtectedFromScaleIn: aws.Bool(true),
+	}
+
+	if a.skipsCall("SetInstanceProtection", input) {
+		return
+	}
+
+	_, err := a.region.services.autoScaling.SetInstanceProtection(input)
+
+	if err != nil {
+		a.log().at(LevelError).Println(a.name,
+			"Failed to protect spot instance",
+			*instanceID, "from scale-in", err.Error())
+		return
+	}
+	a.log().Println(a.name, "Protected spot instance", *instanceID,
+		"from scale-in, like the group's new instances")
+}
@@ -0,0 +1,55 @@
+package autospotting
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+func Test_needsScaleInProtection(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		group *autoscaling.Group
+		want  bool
+	}{
+		{name: "group not protecting its new instances",
+			group: &autoscaling.Group{},
+			want:  false,
+		},
+		{name: "unprotected instance",
+			group: &autoscaling.Group{
+				NewInstancesProtectedFromScaleIn: aws.Bool(true),
+				Instances: []*autoscaling.Instance{{
+					InstanceId:           aws.String("i-spot"),
+					ProtectedFromScaleIn: aws.Bool(false),
+				}},
+			},
+			want: true,
+		},
+		{name: "already protected instance",
+			group: &autoscaling.Group{
+				NewInstancesProtectedFromScaleIn: aws.Bool(true),
+				Instances: []*autoscaling.Instance{{
+					InstanceId:           aws.String("i-spot"),
+					ProtectedFromScaleIn: aws.Bool(true),
+				}},
+			},
+			want: false,
+		},
+		{name: "instance not listed yet",
+			group: &autoscaling.Group{
+				NewInstancesProtectedFromScaleIn: aws.Bool(true),
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := needsScaleInProtection(tt.group, "i-spot"); got != tt.want {
+				t.Errorf("needsScaleInProtection() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
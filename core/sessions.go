@@ -0,0 +1,68 @@
+package autospotting
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// hasInteractiveSessions tells if the instance has active SSM sessions, or
+// sessions started within the configured time window.
+func (r *region) hasInteractiveSessions(instanceID *string) bool {
+
+	if !r.conf.CheckInteractiveSessions {
+		return false
+	}
+
+	filters := []*ssm.SessionFilter{
+		{Key: aws.String(ssm.SessionFilterKeyTarget), Value: instanceID},
+	}
+
+	if r.hasSessions(ssm.SessionStateActive, filters) {
+		r.log().Println(r.name, "Instance", *instanceID,
+			"has active SSM sessions")
+		return true
+	}
+
+	if r.conf.InteractiveSessionWindow <= 0 {
+		return false
+	}
+
+	since := time.Now().Add(-r.conf.InteractiveSessionWindow).UTC()
+
+	filters = append(filters, &ssm.SessionFilter{
+		Key:   aws.String(ssm.SessionFilterKeyInvokedAfter),
+		Value: aws.String(since.Format(time.RFC3339)),
+	})
+
+	if r.hasSessions(ssm.SessionStateHistory, filters) {
+		r.log().Println(r.name, "Instance", *instanceID,
+			"had SSM sessions started in the last", r.conf.InteractiveSessionWindow)
+		return true
+	}
+	return false
+}
+
+// hasSessions tells if any SSM sessions match the filters, going through the
+// result pages until finding one, since they may also be empty.
+func (r *region) hasSessions(state string, filters []*ssm.SessionFilter) bool {
+
+	found := false
+
+	err := r.services.ssm.DescribeSessionsPages(&ssm.DescribeSessionsInput{
+		State:   aws.String(state),
+		Filters: filters,
+	}, func(page *ssm.DescribeSessionsOutput, lastPage bool) bool {
+		found = len(page.Sessions) > 0
+		return !found
+	})
+
+	if err != nil {
+		r.log().at(LevelError).Println(r.name,
+			"Failed to describe SSM sessions",
+			err.Error())
+		return false
+	}
+	return found
+}
@@ -0,0 +1,118 @@
+package autospotting
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+func Test_region_hasInteractiveSessions(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		pages [][]*ssm.Session
+		want  bool
+	}{
+		{
+			name:  "no sessions",
+			pages: [][]*ssm.Session{nil},
+			want:  false,
+		},
+		{
+			name:  "session after an empty page",
+			pages: [][]*ssm.Session{nil, {{SessionId: aws.String("s-1")}}},
+			want:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			page := 0
+			services, _ := fakeConnections(func(r *request.Request) {
+				out := r.Data.(*ssm.DescribeSessionsOutput)
+				out.Sessions = tt.pages[page]
+				page++
+				if page < len(tt.pages) {
+					out.NextToken = aws.String("next")
+				}
+			})
+
+			r := &region{
+				name:     "us-east-1",
+				conf:     Config{CheckInteractiveSessions: true},
+				services: services,
+			}
+
+			if got := r.hasInteractiveSessions(aws.String("i-1")); got != tt.want {
+				t.Errorf("hasInteractiveSessions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_autoScalingGroup_launchCheapestSpotInstance_interactive(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		sessions     []*ssm.Session
+		wantDeferred bool
+	}{
+		{
+			name:         "instance used interactively",
+			sessions:     []*ssm.Session{{SessionId: aws.String("s-1")}},
+			wantDeferred: true,
+		},
+		{
+			name:         "instance without sessions",
+			wantDeferred: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions = actionHistory{}
+			actions.init(Config{HistorySize: 10})
+
+			services, _ := fakeConnections(func(r *request.Request) {
+				if out, ok := r.Data.(*ssm.DescribeSessionsOutput); ok {
+					out.Sessions = tt.sessions
+				}
+			})
+
+			a := autoScalingGroup{
+				Group: &autoscaling.Group{AutoScalingGroupName: aws.String("web")},
+				name:  "web",
+				region: &region{
+					name:     "us-east-1",
+					conf:     Config{CheckInteractiveSessions: true},
+					services: services,
+				},
+				instances: instances{catalog: map[string]*instance{
+					"i-od": {Instance: &ec2.Instance{
+						InstanceId:   aws.String("i-od"),
+						InstanceType: aws.String("m5.large"),
+						State:        &ec2.InstanceState{Name: aws.String("running")},
+						Placement: &ec2.Placement{
+							AvailabilityZone: aws.String("us-east-1a")},
+					}},
+				}},
+			}
+
+			if a.launchCheapestSpotInstance(aws.String("us-east-1a")) {
+				t.Errorf("launchCheapestSpotInstance() = true, want false")
+			}
+
+			deferred := false
+			for _, e := range actions.get("web", "us-east-1") {
+				deferred = deferred || e.Action == "deferred"
+			}
+			if deferred != tt.wantDeferred {
+				t.Errorf("launchCheapestSpotInstance() deferred the bid: %v, "+
+					"want %v", deferred, tt.wantDeferred)
+			}
+		})
+	}
+}
@@ -0,0 +1,541 @@
+package autospotting
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// settingSchema is the JSON schema of a setting.
+type settingSchema struct {
+	Type        string      `json:"type"`
+	Format      string      `json:"format,omitempty"`
+	Pattern     string      `json:"pattern,omitempty"`
+	Enum        []string    `json:"enum,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+	Description string      `json:"description"`
+}
+
+// validate checks the string value of a group setting against its schema.
+func (s settingSchema) validate(value string) error {
+
+	if len(s.Enum) > 0 {
+		for _, v := range s.Enum {
+			if v == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q isn't one of %s", value, strings.Join(s.Enum, ", "))
+	}
+
+	if s.Pattern != "" && !regexp.MustCompile(s.Pattern).MatchString(value) {
+		return fmt.Errorf("%q doesn't match %s", value, s.Pattern)
+	}
+
+	if s.Format == "date-time" {
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return fmt.Errorf("%q isn't an RFC 3339 time", value)
+		}
+	}
+	return nil
+}
+
+// patterns of the group setting values, which are always strings
+const (
+	booleanPattern        = "^(true|false)$"
+	numberPattern         = "^[0-9]+(\\.[0-9]+)?$"
+	positiveNumberPattern = "^(0*[1-9][0-9]*(\\.[0-9]+)?|0*\\.[0-9]*[1-9][0-9]*)$"
+)
+
+// the sources of the group settings
+const (
+	sourceTag     = "tag"
+	sourceEnv     = "env"
+	sourceFile    = "file"
+	sourceDefault = "default"
+)
+
+// groupSetting is a setting of a group.
+type groupSetting struct {
+	name   string
+	schema settingSchema
+
+	// only read from the group's tag, for the settings concerning a single
+	// group or changed by AutoSpotting itself
+	tagOnly bool
+
+	// the global setting used as default value, otherwise the schema default
+	global func(*Config) string
+}
+
+var groupSettings = []groupSetting{
+	{
+		name:    "spot-enabled",
+		tagOnly: true,
+		schema: settingSchema{
+			Type: "string", Enum: []string{"true", "false"},
+			Description: "Enables the replacement of the group's on-demand " +
+				"instances with spot instances",
+		},
+	},
+	{
+		name: "allowed-instance-types",
+		schema: settingSchema{
+			Type:    "string",
+			Pattern: "^[a-z0-9.-]+( *, *[a-z0-9.-]+)*$",
+			Description: "Comma separated instance types the group's spot " +
+				"instances are restricted to, current standing for the type of " +
+				"the replaced instance",
+		},
+	},
+	{
+		name: "disallowed-instance-types",
+		schema: settingSchema{
+			Type:    "string",
+			Pattern: "^[a-z0-9.*?-]+( *, *[a-z0-9.*?-]+)*$",
+			Description: "Comma separated glob patterns of the instance " +
+				"types the group's spot instances can't use, such as t2.*",
+		},
+	},
+	{
+		name: "performance_factor",
+		schema: settingSchema{
+			Type: "string", Pattern: positiveNumberPattern, Default: "1",
+			Description: "Multiplier applied to the CPU and memory of the " +
+				"on-demand instances when searching for compatible instance types",
+		},
+	},
+	{
+		name: "sticky_price_band",
+		schema: settingSchema{
+			Type: "string", Pattern: numberPattern,
+			Description: "Keep launching the group's last instance type while " +
+				"its price is at most this fraction above the cheapest one, " +
+				"defaulting to the sticky_price_band setting",
+		},
+		global: func(c *Config) string { return formatNumber(c.StickyPriceBand) },
+	},
+	{
+		name: "data_volumes",
+		schema: settingSchema{
+			Type: "string", Enum: []string{"snapshot", "reattach"},
+			Description: "Carry over the non-root EBS volumes of the replaced " +
+				"on-demand instances",
+		},
+	},
+	{
+		name: "replacement_profile",
+		schema: settingSchema{
+			Type: "string", Enum: []string{"stateful"},
+			Description: "Move the identity of the replaced on-demand " +
+				"instances over to their spot replacements",
+		},
+	},
+	{
+		name: "cross_az_replacement",
+		schema: settingSchema{
+			Type: "string", Pattern: booleanPattern, Default: "false",
+			Description: "Replace on-demand instances from other availability " +
+				"zones when none are left in the spot instance's zone",
+		},
+	},
+	{
+		name: "instance_launch_configuration",
+		schema: settingSchema{
+			Type: "string", Pattern: booleanPattern, Default: "false",
+			Description: "Base each spot instance on the launch configuration " +
+				"of the on-demand instance it replaces",
+		},
+	},
+	{
+		name: "allow_single_instance_replacement",
+		schema: settingSchema{
+			Type: "string", Pattern: booleanPattern, Default: "false",
+			Description: "Replace the instance of groups limited to a single " +
+				"instance",
+		},
+	},
+	{
+		name: "benchmark_document",
+		schema: settingSchema{
+			Type: "string",
+			Description: "SSM document benchmarking each attached spot " +
+				"instance, printing its score as the last line of its output",
+		},
+	},
+	{
+		name: "architecture_amis",
+		schema: settingSchema{
+			Type:    "string",
+			Pattern: "^[a-z0-9_]+=ami-[0-9a-f]+(,[a-z0-9_]+=ami-[0-9a-f]+)*$",
+			Description: "AMIs of each architecture, such as " +
+				"x86_64=ami-0123,arm64=ami-4567",
+		},
+	},
+	{
+		name: "max_pool_concentration",
+		schema: settingSchema{
+			Type: "string", Pattern: numberPattern,
+			Description: "Maximum percentage of the group's desired capacity " +
+				"in the same spot pool, defaulting to the max_pool_concentration " +
+				"setting",
+		},
+		global: func(c *Config) string {
+			return formatNumber(c.MaxPoolConcentration)
+		},
+	},
+	{
+		name: "bid_strategy",
+		schema: settingSchema{
+			Type: "string", Pattern: bidStrategyPattern,
+			Description: "Bid pricing strategy, such as on-demand, " +
+				"on-demand-percentage:80, spot-buffer:20 or fixed:0.12, defaulting " +
+				"to the bid_strategy setting",
+		},
+		global: func(c *Config) string { return c.BidStrategy },
+	},
+	{
+		name: "victim_selection",
+		schema: settingSchema{
+			Type: "string", Pattern: "^[a-z0-9_-]+$", Default: "any",
+			Description: "Which on-demand instance is replaced next: any, " +
+				"oldest, cheapest-to-lose, az-balanced, or the name of a victim " +
+				"selector registered in library mode",
+		},
+	},
+	{
+		name: "audit_topic",
+		schema: settingSchema{
+			Type: "string", Pattern: "^(arn:aws[a-z-]*:sns:[a-z0-9-]+:[0-9]+:.+)?$",
+			Description: "SNS topic ARN receiving the audit messages about " +
+				"the group's replacements, failed bids and rollbacks, " +
+				"defaulting to the audit_topic setting",
+		},
+		global: func(c *Config) string { return c.AuditTopic },
+	},
+	{
+		name: "spot_diversification",
+		schema: settingSchema{
+			Type: "string", Pattern: numberPattern,
+			Description: "Number of the cheapest compatible instance types " +
+				"the group's spot instances are spread across, defaulting to the " +
+				"spot_diversification setting",
+		},
+		global: func(c *Config) string {
+			return formatNumber(c.SpotDiversification)
+		},
+	},
+	{
+		name:    "prewarm_until",
+		tagOnly: true,
+		schema: settingSchema{
+			Type: "string", Format: "date-time",
+			Description: "Pre-warm the group's spot capacity across more pools " +
+				"until this RFC 3339 time",
+		},
+	},
+	{
+		name: "ip_target_groups",
+		schema: settingSchema{
+			Type: "string",
+			Description: "Comma-separated ARNs of target groups using the ip " +
+				"target type, which the group's instances are registered with",
+		},
+	},
+	{
+		name: "readiness_probe",
+		schema: settingSchema{
+			Type: "string", Pattern: booleanPattern, Default: "false",
+			Description: "Only attach the spot instances once they report " +
+				"their readiness by running autospotting probe",
+		},
+	},
+	{
+		name: "dry_run",
+		schema: settingSchema{
+			Type: "string", Pattern: booleanPattern,
+			Description: "Only log the API calls replacing the group's " +
+				"instances instead of making them, defaulting to the dry_run " +
+				"setting",
+		},
+		global: func(c *Config) string { return strconv.FormatBool(c.DryRun) },
+	},
+	{
+		name: "min_on_demand_number",
+		schema: settingSchema{
+			Type: "string", Pattern: "^[0-9]+$", Default: "0",
+			Description: "Number of on-demand instances kept in the group",
+		},
+	},
+	{
+		name: "min_on_demand_percentage",
+		schema: settingSchema{
+			Type: "string", Pattern: numberPattern, Default: "0",
+			Description: "Percentage of the group's desired capacity kept " +
+				"on-demand",
+		},
+	},
+	{
+		name: "max_hourly_budget",
+		schema: settingSchema{
+			Type: "string", Pattern: positiveNumberPattern,
+			Description: "Maximum hourly cost of all the group's instances " +
+				"after replacing one of them",
+		},
+	},
+	{
+		name: "spot_monitoring",
+		schema: settingSchema{
+			Type: "string", Enum: []string{"copy", "enable", "disable"},
+			Description: "Detailed monitoring of the spot instances, copied " +
+				"from the launch configuration or forcibly enabled or disabled, " +
+				"defaulting to the spot_monitoring setting",
+		},
+		global: func(c *Config) string { return c.SpotMonitoring },
+	},
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func lookupGroupSetting(name string) *groupSetting {
+	for i := range groupSettings {
+		if groupSettings[i].name == name {
+			return &groupSettings[i]
+		}
+	}
+	return nil
+}
+
+// groupSettingTags returns the tags used for configuring the groups.
+func groupSettingTags() []string {
+	var tags []string
+	for _, s := range groupSettings {
+		tags = append(tags, s.name)
+	}
+	return tags
+}
+
+// settingEnvVar returns the environment variable overriding the default value
+// of a group setting.
+func settingEnvVar(name string) string {
+	return "AUTOSPOTTING_" + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
+
+// LoadSettingsFile reads the default values of the group settings from a JSON
+// object keyed by the setting names.
+func LoadSettingsFile(file string) (map[string]string, error) {
+
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return nil, err
+	}
+
+	settings := make(map[string]string)
+	for name, v := range raw {
+		s := lookupGroupSetting(name)
+		if s == nil || s.tagOnly {
+			return nil, fmt.Errorf("%s: unsupported setting %s", file, name)
+		}
+
+		value := fmt.Sprint(v)
+		if err := s.schema.validate(value); err != nil {
+			return nil, fmt.Errorf("%s: invalid %s: %s", file, name, err.Error())
+		}
+		settings[name] = value
+	}
+	return settings, nil
+}
+
+// effectiveSetting is the value of a group setting and where it comes from.
+type effectiveSetting struct {
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// setting resolves the group setting from its sources in order of precedence,
+// skipping the invalid values.
+func (a *autoScalingGroup) setting(name string) effectiveSetting {
+
+	s := lookupGroupSetting(name)
+	if s == nil {
+		return effectiveSetting{}
+	}
+
+	var conf *Config
+	if a.region != nil {
+		conf = &a.region.conf
+	}
+
+	type candidate struct {
+		source string
+		value  *string
+	}
+	candidates := []candidate{{sourceTag, a.getTagValue(name)}}
+
+	if !s.tagOnly {
+		if value, found := os.LookupEnv(settingEnvVar(name)); found {
+			candidates = append(candidates, candidate{sourceEnv, &value})
+		}
+		if value, found := conf.fileSetting(name); found {
+			candidates = append(candidates, candidate{sourceFile, &value})
+		}
+	}
+
+	for _, c := range candidates {
+		if c.value == nil {
+			continue
+		}
+		if err := s.schema.validate(*c.value); err != nil {
+			a.log().at(LevelWarn).Println(a.name, "Ignoring invalid", name,
+				"from the", c.source, err.Error())
+			continue
+		}
+		return effectiveSetting{Value: *c.value, Source: c.source}
+	}
+
+	if s.global != nil && conf != nil {
+		return effectiveSetting{Value: s.global(conf), Source: sourceDefault}
+	}
+
+	value, _ := s.schema.Default.(string)
+	return effectiveSetting{Value: value, Source: sourceDefault}
+}
+
+func (c *Config) fileSetting(name string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	value, found := c.FileSettings[name]
+	return value, found
+}
+
+func (a *autoScalingGroup) stringSetting(name string) string {
+	return a.setting(name).Value
+}
+
+func (a *autoScalingGroup) boolSetting(name string) bool {
+	return a.setting(name).Value == "true"
+}
+
+func (a *autoScalingGroup) numberSetting(name string) float64 {
+	f, _ := strconv.ParseFloat(a.setting(name).Value, 64)
+	return f
+}
+
+// timeSetting returns the time given by the setting, or the zero time if it
+// isn't set.
+func (a *autoScalingGroup) timeSetting(name string) time.Time {
+	t, _ := time.Parse(time.RFC3339, a.setting(name).Value)
+	return t
+}
+
+// effectiveSettings returns all the settings of the group.
+func (a *autoScalingGroup) effectiveSettings() map[string]effectiveSetting {
+	settings := make(map[string]effectiveSetting)
+	for _, s := range groupSettings {
+		settings[s.name] = a.setting(s.name)
+	}
+	return settings
+}
+
+// flagSchema returns the schema of a global setting given as a flag.
+func flagSchema(f *flag.Flag) settingSchema {
+
+	s := settingSchema{Type: "string", Description: f.Usage}
+
+	getter, ok := f.Value.(flag.Getter)
+	if !ok {
+		return s
+	}
+
+	switch getter.Get().(type) {
+	case bool:
+		s.Type = "boolean"
+		s.Default, _ = strconv.ParseBool(f.DefValue)
+	case int, int64, uint, uint64:
+		s.Type = "integer"
+		s.Default, _ = strconv.ParseInt(f.DefValue, 10, 64)
+	case float64:
+		s.Type = "number"
+		s.Default, _ = strconv.ParseFloat(f.DefValue, 64)
+	case time.Duration:
+		s.Format = "duration"
+		s.Default = f.DefValue
+	default:
+		if f.DefValue != "" {
+			s.Default = f.DefValue
+		}
+	}
+	return s
+}
+
+// configSchema returns the JSON schema of the global settings given by the
+// flags and of the group tags.
+func configSchema(flags *flag.FlagSet) map[string]interface{} {
+
+	global := make(map[string]settingSchema)
+	flags.VisitAll(func(f *flag.Flag) {
+		global[f.Name] = flagSchema(f)
+	})
+
+	tags := make(map[string]settingSchema)
+	for _, s := range groupSettings {
+		tags[s.name] = s.schema
+	}
+
+	object := func(title string, properties map[string]settingSchema) map[string]interface{} {
+		return map[string]interface{}{
+			"title":      title,
+			"type":       "object",
+			"properties": properties,
+		}
+	}
+
+	return map[string]interface{}{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"title":   "AutoSpotting configuration",
+		"definitions": map[string]interface{}{
+			"settings": object("Global settings, given as command line flags",
+				global),
+			"tags": object("Tags configuring each AutoScaling group", tags),
+		},
+	}
+}
+
+// ConfigSchema returns the JSON schema of the global settings defined as
+// command line flags and of the group tags.
+func ConfigSchema() ([]byte, error) {
+	return json.MarshalIndent(configSchema(flag.CommandLine), "", "  ")
+}
+
+// serveConfigSchema handles GET /config-schema
+func serveConfigSchema(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	content, err := ConfigSchema()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.Write(content)
+}
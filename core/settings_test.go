@@ -0,0 +1,274 @@
+package autospotting
+
+import (
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+func Test_settingSchema_validate(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		setting string
+		value   string
+		wantErr bool
+	}{
+		{name: "boolean", setting: "cross_az_replacement", value: "true"},
+		{name: "invalid boolean", setting: "cross_az_replacement", value: "yes",
+			wantErr: true},
+		{name: "enum", setting: "data_volumes", value: "snapshot"},
+		{name: "invalid enum", setting: "data_volumes", value: "copy",
+			wantErr: true},
+		{name: "positive number", setting: "performance_factor", value: "0.5"},
+		{name: "zero positive number", setting: "performance_factor", value: "0",
+			wantErr: true},
+		{name: "negative number", setting: "sticky_price_band", value: "-1",
+			wantErr: true},
+		{name: "date-time", setting: "prewarm_until",
+			value: "2020-01-02T03:04:05Z"},
+		{name: "invalid date-time", setting: "prewarm_until", value: "tomorrow",
+			wantErr: true},
+		{name: "free-form", setting: "benchmark_document", value: "anything"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := lookupGroupSetting(tt.setting).schema.validate(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_autoScalingGroup_setting(t *testing.T) {
+
+	tag := func(key, value string) []*autoscaling.TagDescription {
+		return []*autoscaling.TagDescription{{
+			Key: aws.String(key), Value: aws.String(value),
+		}}
+	}
+
+	tests := []struct {
+		name    string
+		setting string
+		tags    []*autoscaling.TagDescription
+		env     string
+		file    map[string]string
+		want    effectiveSetting
+	}{
+		{name: "schema default",
+			setting: "performance_factor",
+			want:    effectiveSetting{Value: "1", Source: sourceDefault},
+		},
+		{name: "global default",
+			setting: "sticky_price_band",
+			want:    effectiveSetting{Value: "0.1", Source: sourceDefault},
+		},
+		{name: "file overrides the default",
+			setting: "sticky_price_band",
+			file:    map[string]string{"sticky_price_band": "0.2"},
+			want:    effectiveSetting{Value: "0.2", Source: sourceFile},
+		},
+		{name: "environment overrides the file",
+			setting: "sticky_price_band",
+			env:     "0.3",
+			file:    map[string]string{"sticky_price_band": "0.2"},
+			want:    effectiveSetting{Value: "0.3", Source: sourceEnv},
+		},
+		{name: "tag overrides the environment",
+			setting: "sticky_price_band",
+			tags:    tag("sticky_price_band", "0.4"),
+			env:     "0.3",
+			want:    effectiveSetting{Value: "0.4", Source: sourceTag},
+		},
+		{name: "invalid tag ignored",
+			setting: "sticky_price_band",
+			tags:    tag("sticky_price_band", "cheap"),
+			env:     "0.3",
+			want:    effectiveSetting{Value: "0.3", Source: sourceEnv},
+		},
+		{name: "tag-only setting ignores the environment",
+			setting: "spot-enabled",
+			env:     "true",
+			want:    effectiveSetting{Source: sourceDefault},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != "" {
+				os.Setenv(settingEnvVar(tt.setting), tt.env)
+				defer os.Unsetenv(settingEnvVar(tt.setting))
+			}
+
+			a := autoScalingGroup{
+				Group: &autoscaling.Group{Tags: tt.tags},
+				region: &region{conf: Config{
+					StickyPriceBand: 0.1,
+					FileSettings:    tt.file,
+				}},
+			}
+			if got := a.setting(tt.setting); got != tt.want {
+				t.Errorf("setting() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadSettingsFile(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "valid settings",
+			content: `{"performance_factor": 1.5, "cross_az_replacement": true}`,
+			want: map[string]string{
+				"performance_factor":   "1.5",
+				"cross_az_replacement": "true",
+			},
+		},
+		{name: "unsupported setting",
+			content: `{"color": "blue"}`,
+			wantErr: true,
+		},
+		{name: "tag-only setting",
+			content: `{"spot-enabled": "true"}`,
+			wantErr: true,
+		},
+		{name: "invalid value",
+			content: `{"data_volumes": "copy"}`,
+			wantErr: true,
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "settings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(dir, "settings.json")
+			if err := ioutil.WriteFile(file, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := LoadSettingsFile(file)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadSettingsFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadSettingsFile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_flagSchema(t *testing.T) {
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("region", "", "Regions")
+	fs.String("backend", "dynamodb", "Backend")
+	fs.Bool("dry_run", false, "Dry run")
+	fs.Int("retries", 3, "Retries")
+	fs.Float64("factor", 1.5, "Factor")
+	fs.Duration("interval", 5*time.Minute, "Interval")
+
+	tests := []struct {
+		flag string
+		want settingSchema
+	}{
+		{flag: "region",
+			want: settingSchema{Type: "string", Description: "Regions"},
+		},
+		{flag: "backend",
+			want: settingSchema{Type: "string", Default: "dynamodb",
+				Description: "Backend"},
+		},
+		{flag: "dry_run",
+			want: settingSchema{Type: "boolean", Default: false,
+				Description: "Dry run"},
+		},
+		{flag: "retries",
+			want: settingSchema{Type: "integer", Default: int64(3),
+				Description: "Retries"},
+		},
+		{flag: "factor",
+			want: settingSchema{Type: "number", Default: 1.5,
+				Description: "Factor"},
+		},
+		{flag: "interval",
+			want: settingSchema{Type: "string", Format: "duration",
+				Default: "5m0s", Description: "Interval"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.flag, func(t *testing.T) {
+			if got := flagSchema(fs.Lookup(tt.flag)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("flagSchema() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_configSchema(t *testing.T) {
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Bool("daemon", false, "Daemon mode")
+
+	definitions := configSchema(fs)["definitions"].(map[string]interface{})
+
+	settings := definitions["settings"].(map[string]interface{})["properties"].(map[string]settingSchema)
+	if _, ok := settings["daemon"]; !ok || len(settings) != 1 {
+		t.Errorf("settings = %v, want only daemon", settings)
+	}
+
+	tags := definitions["tags"].(map[string]interface{})["properties"].(map[string]settingSchema)
+	for _, tag := range groupSettingTags() {
+		if tags[tag].Description == "" {
+			t.Errorf("tag %s has no description", tag)
+		}
+	}
+}
+
+func Test_serveConfigSchema(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		method     string
+		wantStatus int
+	}{
+		{name: "Schema served",
+			method:     "GET",
+			wantStatus: http.StatusOK,
+		},
+		{name: "Unsupported method",
+			method:     "POST",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			newServeMux().ServeHTTP(rec,
+				httptest.NewRequest(tt.method, "/config-schema", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package autospotting
+
+func isSingleInstanceGroup(minSize, maxSize int64) bool {
+	return minSize == 1 && maxSize == 1
+}
+
+// skipsSingleInstanceReplacement tells if the group is limited to a single
+// instance without allowing its replacement.
+func (a *autoScalingGroup) skipsSingleInstanceReplacement() bool {
+
+	if a.MinSize == nil || a.MaxSize == nil ||
+		!isSingleInstanceGroup(*a.MinSize, *a.MaxSize) {
+		return false
+	}
+
+	return !a.boolSetting("allow_single_instance_replacement")
+}
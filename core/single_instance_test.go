@@ -0,0 +1,42 @@
+package autospotting
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+func Test_skipsSingleInstanceReplacement(t *testing.T) {
+
+	allowed := []*autoscaling.TagDescription{{
+		Key:   aws.String("allow_single_instance_replacement"),
+		Value: aws.String("true"),
+	}}
+
+	tests := []struct {
+		name     string
+		min, max int64
+		tags     []*autoscaling.TagDescription
+		want     bool
+	}{
+		{name: "single instance group", min: 1, max: 1, want: true},
+		{name: "single instance group allowed", min: 1, max: 1, tags: allowed,
+			want: false},
+		{name: "may scale out", min: 1, max: 2, want: false},
+		{name: "may scale in to zero", min: 0, max: 1, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &autoScalingGroup{Group: &autoscaling.Group{
+				MinSize: aws.Int64(tt.min),
+				MaxSize: aws.Int64(tt.max),
+				Tags:    tt.tags,
+			}}
+			if got := a.skipsSingleInstanceReplacement(); got != tt.want {
+				t.Errorf("skipsSingleInstanceReplacement() = %v, want %v",
+					got, tt.want)
+			}
+		})
+	}
+}
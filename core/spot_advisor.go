@@ -0,0 +1,169 @@
+package autospotting
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// how long the fetched dataset is used before being fetched again
+const spotAdvisorRefresh = 24 * time.Hour
+
+// the dataset is a few megabytes, but fetching it shouldn't hold up the run
+var spotAdvisorClient = &http.Client{Timeout: 30 * time.Second}
+
+var spotAdvisor spotAdvisorCache
+
+type spotAdvisorRange struct {
+	Index int    `json:"index"`
+	Label string `json:"label"`
+	Max   int    `json:"max"`
+}
+
+type spotAdvisorRating struct {
+	Savings int `json:"s"`
+	Range   int `json:"r"`
+}
+
+// spotAdvisorData is the part of the Spot Advisor dataset we use, the ratings
+// are keyed by region, operating system and instance type.
+type spotAdvisorData struct {
+	Ranges  []spotAdvisorRange                                 `json:"ranges"`
+	Ratings map[string]map[string]map[string]spotAdvisorRating `json:"spot_advisor"`
+}
+
+type spotAdvisorCache struct {
+	sync.Mutex
+
+	url     string
+	fetched time.Time
+	data    *spotAdvisorData
+}
+
+// load fetches the dataset unless it was fetched recently, keeping the cached
+// data if the fetch fails.
+func (s *spotAdvisorCache) load(cfg Config) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.url != cfg.SpotAdvisorURL {
+		s.url, s.fetched, s.data = cfg.SpotAdvisorURL, time.Time{}, nil
+	}
+
+	if s.url == "" || time.Since(s.fetched) < spotAdvisorRefresh {
+		return
+	}
+
+	data, err := fetchSpotAdvisorData(s.url)
+	if err != nil {
+		logger.at(LevelError).Println(
+			"Failed to fetch the Spot Advisor data from",
+			s.url, err.Error())
+		return
+	}
+	s.data, s.fetched = data, time.Now()
+}
+
+func fetchSpotAdvisorData(url string) (*spotAdvisorData, error) {
+
+	resp, err := spotAdvisorClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+
+	var data spotAdvisorData
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+func spotAdvisorOS(p pricingProfile) string {
+	if p.windows {
+		return "Windows"
+	}
+	return "Linux"
+}
+
+// rating returns the interruption frequency range of the instance type, and
+// false if it's unknown.
+func (s *spotAdvisorCache) rating(region, os,
+	instanceType string) (spotAdvisorRange, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.data == nil {
+		return spotAdvisorRange{}, false
+	}
+
+	rating, found := s.data.Ratings[region][os][instanceType]
+	if !found {
+		return spotAdvisorRange{}, false
+	}
+
+	for _, r := range s.data.Ranges {
+		if r.Index == rating.Range {
+			return r, true
+		}
+	}
+	return spotAdvisorRange{}, false
+}
+
+// label returns the interruption frequency label of the instance type, such as
+// "<5%", or an empty string if unknown.
+func (s *spotAdvisorCache) label(region, os, instanceType string) string {
+	r, _ := s.rating(region, os, instanceType)
+	return r.Label
+}
+
+// minimumFrequency returns the lowest interruption frequency in percent of the
+// given range, which starts where the previous range ends.
+func (s *spotAdvisorCache) minimumFrequency(r spotAdvisorRange) float64 {
+	s.Lock()
+	defer s.Unlock()
+
+	min := 0
+	for _, other := range s.data.Ranges {
+		if other.Index == r.Index-1 {
+			min = other.Max
+		}
+	}
+	return float64(min)
+}
+
+// riskAdjustedPrice increases the price of the instance type by the
+// interruption penalty for each rating step above the lowest one.
+func (r *region) riskAdjustedPrice(instanceType string, price float64,
+	p pricingProfile) float64 {
+
+	if r.conf.InterruptionPenalty <= 0 {
+		return price
+	}
+
+	rating, found := spotAdvisor.rating(r.name, spotAdvisorOS(p), instanceType)
+	if !found {
+		return price
+	}
+	return price * (1 + r.conf.InterruptionPenalty/100*float64(rating.Index))
+}
+
+// frequentlyInterrupted tells if the Spot Advisor rates the instance type's
+// interruption frequency above the interruption threshold.
+func (r *region) frequentlyInterrupted(instanceType string,
+	p pricingProfile) bool {
+
+	if r.conf.InterruptionThreshold <= 0 {
+		return false
+	}
+
+	rating, found := spotAdvisor.rating(r.name, spotAdvisorOS(p), instanceType)
+	return found &&
+		spotAdvisor.minimumFrequency(rating) >= r.conf.InterruptionThreshold
+}
@@ -0,0 +1,124 @@
+package autospotting
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testSpotAdvisorData = `{
+  "ranges": [
+    {"index": 0, "label": "<5%", "dots": 0, "max": 5},
+    {"index": 1, "label": "5-10%", "dots": 1, "max": 11},
+    {"index": 2, "label": "10-15%", "dots": 2, "max": 16},
+    {"index": 3, "label": "15-20%", "dots": 3, "max": 22},
+    {"index": 4, "label": ">20%", "dots": 4, "max": 100}
+  ],
+  "spot_advisor": {
+    "us-east-1": {
+      "Linux": {
+        "m5.large": {"s": 70, "r": 0},
+        "c5.large": {"s": 60, "r": 2},
+        "r5.large": {"s": 65, "r": 4}
+      },
+      "Windows": {
+        "m5.large": {"s": 40, "r": 3}
+      }
+    }
+  }
+}`
+
+func Test_spotAdvisorCache_load(t *testing.T) {
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			fmt.Fprint(w, testSpotAdvisorData)
+		}))
+	defer server.Close()
+
+	s := &spotAdvisorCache{}
+	s.load(Config{SpotAdvisorURL: server.URL})
+	s.load(Config{SpotAdvisorURL: server.URL})
+
+	if requests != 1 {
+		t.Errorf("fetched the dataset %d times, want it cached", requests)
+	}
+	if got := s.label("us-east-1", "Linux", "c5.large"); got != "10-15%" {
+		t.Errorf("label() = %q, want %q", got, "10-15%")
+	}
+	if got := s.label("us-east-1", "Linux", "t3.nano"); got != "" {
+		t.Errorf("label() = %q, want no rating", got)
+	}
+}
+
+func Test_region_spotAdvisorRatings(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, testSpotAdvisorData)
+		}))
+	defer server.Close()
+
+	spotAdvisor = spotAdvisorCache{}
+	spotAdvisor.load(Config{SpotAdvisorURL: server.URL})
+	defer func() { spotAdvisor = spotAdvisorCache{} }()
+
+	r := &region{name: "us-east-1", conf: Config{
+		InterruptionPenalty:   10,
+		InterruptionThreshold: 15,
+	}}
+
+	tests := []struct {
+		name         string
+		instanceType string
+		profile      pricingProfile
+		price        float64
+		wantFrequent bool
+	}{
+		{
+			name:         "rarely interrupted",
+			instanceType: "m5.large",
+			price:        0.1,
+		},
+		{
+			name:         "rated below the threshold",
+			instanceType: "c5.large",
+			price:        0.12,
+		},
+		{
+			name:         "rated above the threshold",
+			instanceType: "r5.large",
+			price:        0.14,
+			wantFrequent: true,
+		},
+		{
+			name:         "rated separately for Windows",
+			instanceType: "m5.large",
+			profile:      pricingProfile{windows: true},
+			price:        0.13,
+			wantFrequent: true,
+		},
+		{
+			name:         "not rated",
+			instanceType: "t3.nano",
+			price:        0.1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.riskAdjustedPrice(tt.instanceType, 0.1,
+				tt.profile); fmt.Sprintf("%.4f", got) !=
+				fmt.Sprintf("%.4f", tt.price) {
+				t.Errorf("riskAdjustedPrice() = %v, want %v", got, tt.price)
+			}
+			if got := r.frequentlyInterrupted(tt.instanceType,
+				tt.profile); got != tt.wantFrequent {
+				t.Errorf("frequentlyInterrupted() = %v, want %v", got,
+					tt.wantFrequent)
+			}
+		})
+	}
+}
@@ -37,7 +37,8 @@ func (s *spotPrices) fetch(product string,
 	resp, err := ec2Conn.DescribeSpotPriceHistory(params)
 
 	if err != nil {
-		logger.Println(s.conn.region, "Failed requesting spot prices:", err.Error())
+		logger.at(LevelError).Println(s.conn.region,
+			"Failed requesting spot prices:", err.Error())
 		return err
 	}
 
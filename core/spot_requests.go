@@ -0,0 +1,69 @@
+package autospotting
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// scanSpotRequests describes all the spot requests placed by AutoSpotting in
+// the region, which EC2 keeps for a few hours after they are closed.
+func (r *region) scanSpotRequests() {
+
+	r.spotRequests = nil
+
+	input := &ec2.DescribeSpotInstanceRequestsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("tag-key"),
+				Values: []*string{aws.String("launched-for-asg")},
+			},
+		},
+		MaxResults: aws.Int64(1000),
+	}
+
+	r.spotRequestsErr = r.services.ec2.DescribeSpotInstanceRequestsPages(input,
+		func(page *ec2.DescribeSpotInstanceRequestsOutput, lastPage bool) bool {
+			r.spotRequests = append(r.spotRequests, page.SpotInstanceRequests...)
+			return true
+		})
+
+	if r.spotRequestsErr != nil {
+		r.log().at(LevelError).Println(r.name,
+			"Failed to describe spot instance requests",
+			r.spotRequestsErr.Error())
+	}
+}
+
+// groupSpotRequests returns the spot requests placed for the given group.
+func (r *region) groupSpotRequests(group string) []*ec2.SpotInstanceRequest {
+
+	var reqs []*ec2.SpotInstanceRequest
+
+	for _, req := range r.spotRequests {
+		if asg := findTagValue(req.Tags, "launched-for-asg"); asg != nil &&
+			*asg == group {
+			reqs = append(reqs, req)
+		}
+	}
+	return reqs
+}
+
+// poolSkipReason tells why no bid should currently be placed by the group in
+// the spot pool, or returns an empty string if the pool can be used.
+func (a *autoScalingGroup) poolSkipReason(instanceType, az string) string {
+
+	switch {
+	case a.region.capacityExhausted(instanceType, az):
+		return "spot capacity recently exhausted"
+
+	case a.region.interruptedPool(instanceType, az):
+		return "spot instance being interrupted in the pool"
+
+	case denyList.denied(a.region.name, instanceType, az):
+		return "frequently interrupted pool"
+
+	case a.failedBid(instanceType, az):
+		return "recent bid failed"
+	}
+	return ""
+}
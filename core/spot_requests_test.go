@@ -0,0 +1,50 @@
+package autospotting
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_region_scanSpotRequests(t *testing.T) {
+
+	spotRequest := func(id, group string) *ec2.SpotInstanceRequest {
+		return &ec2.SpotInstanceRequest{
+			SpotInstanceRequestId: aws.String(id),
+			Tags: []*ec2.Tag{
+				{Key: aws.String("launched-for-asg"), Value: aws.String(group)},
+			},
+		}
+	}
+
+	services, calls := fakeConnections(func(r *request.Request) {
+		in := r.Params.(*ec2.DescribeSpotInstanceRequestsInput)
+		out := r.Data.(*ec2.DescribeSpotInstanceRequestsOutput)
+
+		if in.NextToken == nil {
+			out.SpotInstanceRequests = []*ec2.SpotInstanceRequest{
+				spotRequest("sir-1", "web"), spotRequest("sir-2", "api")}
+			out.NextToken = aws.String("page-2")
+			return
+		}
+		out.SpotInstanceRequests = []*ec2.SpotInstanceRequest{
+			spotRequest("sir-3", "web")}
+	})
+
+	r := &region{name: "us-east-1", services: services}
+	r.scanSpotRequests()
+
+	if len(*calls) != 2 {
+		t.Errorf("scanSpotRequests() made %d calls, want 2", len(*calls))
+	}
+
+	var got []string
+	for _, req := range r.groupSpotRequests("web") {
+		got = append(got, *req.SpotInstanceRequestId)
+	}
+	if len(got) != 2 || got[0] != "sir-1" || got[1] != "sir-3" {
+		t.Errorf("groupSpotRequests() = %v, want [sir-1 sir-3]", got)
+	}
+}
@@ -0,0 +1,152 @@
+package autospotting
+
+import (
+	"sync"
+)
+
+var spotShare spotShareLimit
+
+type spotShareLimit struct {
+	sync.Mutex
+
+	// maximum percentage of the managed instances allowed to be spot, 0 means
+	// unlimited
+	max float64
+
+	spot, total int
+
+	// regions still counting their instances
+	counting sync.WaitGroup
+	counted  map[string]bool
+}
+
+func (s *spotShareLimit) init(cfg Config, regions []string) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.max = cfg.MaxSpotPercentage
+	s.spot, s.total = 0, 0
+	s.counted = make(map[string]bool)
+
+	if s.max > 0 {
+		s.counting = sync.WaitGroup{}
+		s.counting.Add(len(regions))
+	}
+}
+
+func (s *spotShareLimit) enabled() bool {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.max > 0
+}
+
+// add counts the managed instances of a region, then waits for the other
+// regions to be counted.
+func (s *spotShareLimit) add(region string, spot, total int) {
+
+	if !s.enabled() {
+		return
+	}
+
+	s.Lock()
+	s.spot += spot
+	s.total += total
+	s.Unlock()
+
+	s.done(region)
+	s.counting.Wait()
+}
+
+// done marks the region as counted, it's safe to call it multiple times so
+// that regions returning early, before counting their instances, don't block
+// the others.
+func (s *spotShareLimit) done(region string) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.max <= 0 || s.counted[region] {
+		return
+	}
+	s.counted[region] = true
+	s.counting.Done()
+}
+
+// reserve tells if another instance can be replaced by a spot instance without
+// exceeding the limit, and counts it as spot if so.
+func (s *spotShareLimit) reserve() bool {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.max <= 0 {
+		return true
+	}
+
+	if !spotShareAllowed(s.spot+1, s.total, s.max) {
+		return false
+	}
+	s.spot++
+	return true
+}
+
+// release gives back the share reserved for a spot instance which wasn't
+// launched after all.
+func (s *spotShareLimit) release() {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.max > 0 && s.spot > 0 {
+		s.spot--
+	}
+}
+
+// spotShareAllowed tells if having the given number of spot instances out of
+// the total stays within the maximum percentage.
+func spotShareAllowed(spot, total int, max float64) bool {
+	if total == 0 {
+		return false
+	}
+	return float64(spot)*100 <= max*float64(total)
+}
+
+// countManagedInstances returns how many of the instances of the enabled
+// groups are spot instances, counting those waiting to be attached, and how
+// many instances are managed in total.
+func (r *region) countManagedInstances() (spot, total int) {
+
+	r.forEachManagedInstance(func(i *instance, attached bool) {
+		if attached {
+			total++
+		}
+		if i.isSpot() {
+			spot++
+		}
+	})
+	return spot, total
+}
+
+// forEachManagedInstance calls the function for each instance of the enabled
+// groups, and for each spot instance launched for them but not yet attached.
+func (r *region) forEachManagedInstance(fn func(i *instance, attached bool)) {
+
+	enabled := make(map[string]bool)
+
+	for _, asg := range r.enabledASGs {
+		enabled[asg.name] = true
+
+		for _, inst := range asg.Instances {
+			if i := r.instances.get(*inst.InstanceId); i != nil {
+				fn(i, true)
+			}
+		}
+	}
+
+	for _, i := range r.instances.catalog {
+		launchedFor := findTagValue(i.Tags, "launched-for-asg")
+		if !i.isSpot() || launchedFor == nil || !enabled[*launchedFor] ||
+			findTagValue(i.Tags, "aws:autoscaling:groupName") != nil {
+			continue
+		}
+		fn(i, false)
+	}
+}
@@ -0,0 +1,56 @@
+package autospotting
+
+import (
+	"testing"
+)
+
+func Test_spotShareAllowed(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		spot, total int
+		max         float64
+		want        bool
+	}{
+		{name: "no managed instances", spot: 1, total: 0, max: 70, want: false},
+		{name: "below the limit", spot: 6, total: 10, max: 70, want: true},
+		{name: "at the limit", spot: 7, total: 10, max: 70, want: true},
+		{name: "above the limit", spot: 8, total: 10, max: 70, want: false},
+		{name: "all spot allowed", spot: 10, total: 10, max: 100, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := spotShareAllowed(tt.spot, tt.total, tt.max); got != tt.want {
+				t.Errorf("spotShareAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_spotShareLimit_reserve(t *testing.T) {
+
+	s := &spotShareLimit{}
+	s.init(Config{MaxSpotPercentage: 50}, []string{"us-east-1", "eu-west-1"})
+
+	s.done("eu-west-1")
+	s.done("eu-west-1")
+	s.add("us-east-1", 1, 4)
+
+	if !s.reserve() {
+		t.Errorf("reserve() = false, want true for the 2nd of 4 instances")
+	}
+	if s.reserve() {
+		t.Errorf("reserve() = true, want false for the 3rd of 4 instances")
+	}
+	s.release()
+	if !s.reserve() {
+		t.Errorf("reserve() = false, want true after releasing the 2nd instance")
+	}
+
+	unlimited := &spotShareLimit{}
+	unlimited.init(Config{}, []string{"us-east-1"})
+	unlimited.add("us-east-1", 4, 4)
+	if !unlimited.reserve() {
+		t.Errorf("reserve() = false, want true when unlimited")
+	}
+}
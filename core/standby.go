@@ -0,0 +1,209 @@
+package autospotting
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// Tag set on the on-demand instances moved to Standby, pointing to the spot
+// instance that replaced them. Other Standby instances are never touched.
+const standbyTag = "autospotting-standby-for"
+
+func (a *autoScalingGroup) usesStandbyReplacement() bool {
+	return a.region.conf.ReplacementMethod == "standby" || a.hasLaunchHooks()
+}
+
+func (a *autoScalingGroup) replaceOnDemandInstanceUsingStandby(
+	odInst *instance, spotInstanceID *string) {
+
+	a.log().Println(a.name, "Replacing on-demand instance", *odInst.InstanceId,
+		"with spot instance", *spotInstanceID, "using the Standby state")
+
+	if a.attachSpotInstance(spotInstanceID) != nil {
+		return
+	}
+	a.enterStandby(odInst, spotInstanceID)
+}
+
+// replaceOnDemandInstanceStandbyFirst moves the on-demand instance to Standby
+// before attaching the spot instance, for groups at their MaxSize which can't
+// be increased temporarily. Its termination is then completed by
+// processStandbyInstances like for the Standby replacement.
+func (a *autoScalingGroup) replaceOnDemandInstanceStandbyFirst(
+	odInst *instance, spotInstanceID *string) {
+
+	a.log().Println(a.name, "Moving on-demand instance", *odInst.InstanceId,
+		"to Standby before attaching spot instance", *spotInstanceID)
+
+	if a.enterStandby(odInst, spotInstanceID) == nil {
+		a.attachSpotInstance(spotInstanceID)
+	}
+}
+
+// enterStandby moves the on-demand instance replaced by the spot instance to
+// Standby, decrementing the group's desired capacity.
+func (a *autoScalingGroup) enterStandby(odInst *instance,
+	spotInstanceID *string) error {
+
+	input := &autoscaling.EnterStandbyInput{
+		AutoScalingGroupName:           aws.String(a.name),
+		InstanceIds:                    []*string{odInst.InstanceId},
+		ShouldDecrementDesiredCapacity: aws.Bool(true),
+	}
+
+	if a.skipsCall("EnterStandby", input) {
+		return nil
+	}
+
+	// tag it before moving it to Standby, so it is recognized in the next runs
+	// even if this run is interrupted
+	a.tagInstance(odInst.InstanceId, []*ec2.Tag{
+		{Key: aws.String(standbyTag), Value: spotInstanceID},
+	})
+
+	a.deregisterIPTargets(odInst.InstanceId)
+
+	_, err := a.region.services.autoScaling.EnterStandby(input)
+
+	if err != nil {
+		a.log().at(LevelError).Println(a.name, "Failed to move instance",
+			*odInst.InstanceId, "to Standby", err.Error())
+		return err
+	}
+	a.capacityChanges++
+	a.recordAction("standby", "on-demand instance", *odInst.InstanceId,
+		"replaced by spot instance", *spotInstanceID)
+	return nil
+}
+
+// processStandbyInstances completes the replacements started in previous runs,
+// and tells if any of them is still waiting for its spot instance to become
+// healthy.
+func (a *autoScalingGroup) processStandbyInstances() bool {
+
+	pending := false
+
+	for _, inst := range a.Instances {
+
+		if aws.StringValue(inst.LifecycleState) !=
+			autoscaling.LifecycleStateStandby {
+			continue
+		}
+
+		odInst := a.region.instances.get(*inst.InstanceId)
+		if odInst == nil {
+			continue
+		}
+
+		spotInstanceID := findTagValue(odInst.Tags, standbyTag)
+		if spotInstanceID == nil {
+			a.log().Println(a.name, "Instance", *inst.InstanceId,
+				"was not moved to Standby by us, leaving it alone")
+			continue
+		}
+
+		if !terminateAfter(odInst.Instance).IsZero() {
+			a.log().Println(a.name, "Standby instance", *inst.InstanceId,
+				"is already queued for termination")
+			continue
+		}
+
+		spotInst := a.findGroupInstance(*spotInstanceID)
+
+		switch {
+		case spotInst == nil:
+			a.exitStandby(inst.InstanceId)
+			a.recordAction("restored", "on-demand instance", *inst.InstanceId,
+				"since spot instance", *spotInstanceID, "is gone")
+			a.auditRollback(odInst.Instance, odInst.price, *spotInstanceID,
+				"since spot instance "+*spotInstanceID+" is gone")
+
+		case a.region.hasInteractiveSessions(inst.InstanceId):
+			a.log().at(LevelWarn).Println(a.name, "WARNING: Standby instance",
+				*inst.InstanceId,
+				"is used interactively, delaying its termination")
+			pending = true
+
+		case aws.StringValue(spotInst.LifecycleState) ==
+			autoscaling.LifecycleStateInService &&
+			aws.StringValue(spotInst.HealthStatus) == "Healthy":
+			a.log().Println(a.name, "Spot instance", *spotInstanceID,
+				"is healthy, terminating the Standby instance", *inst.InstanceId)
+			if a.queuesTerminations() &&
+				a.detachStandbyInstance(inst.InstanceId) != nil {
+				pending = true
+				continue
+			}
+			a.carryOverState(odInst, spotInstanceID)
+			if !a.retireOnDemandInstance(odInst) {
+				a.recordAction("terminated", "Standby instance", *inst.InstanceId,
+					"after spot instance", *spotInstanceID, "became healthy")
+			}
+
+		default:
+			a.log().Println(a.name, "Spot instance", *spotInstanceID,
+				"is not yet healthy, keeping", *inst.InstanceId, "in Standby")
+			pending = true
+		}
+	}
+	return pending
+}
+
+// findGroupInstance returns the AutoScaling view of a group member instance
+func (a *autoScalingGroup) findGroupInstance(id string) *autoscaling.Instance {
+	for _, inst := range a.Instances {
+		if *inst.InstanceId == id {
+			return inst
+		}
+	}
+	return nil
+}
+
+func (a *autoScalingGroup) exitStandby(instanceID *string) error {
+
+	input := &autoscaling.ExitStandbyInput{
+		AutoScalingGroupName: aws.String(a.name),
+		InstanceIds:          []*string{instanceID},
+	}
+
+	if a.skipsCall("ExitStandby", input) {
+		return nil
+	}
+
+	_, err := a.region.services.autoScaling.ExitStandby(input)
+
+	if err != nil {
+		a.log().at(LevelError).Println(a.name, "Failed to move instance",
+			*instanceID, "out of Standby", err.Error())
+		return err
+	}
+	a.registerIPTargets(instanceID)
+	return nil
+}
+
+// detachStandbyInstance removes the replaced Standby instance from the group
+// before it's queued for termination, so that it isn't processed again by the
+// next runs while waiting to be terminated.
+func (a *autoScalingGroup) detachStandbyInstance(instanceID *string) error {
+
+	input := &autoscaling.DetachInstancesInput{
+		AutoScalingGroupName:           aws.String(a.name),
+		InstanceIds:                    []*string{instanceID},
+		ShouldDecrementDesiredCapacity: aws.Bool(true),
+	}
+
+	if a.skipsCall("DetachInstances", input) {
+		return nil
+	}
+
+	_, err := a.region.services.autoScaling.DetachInstances(input)
+
+	if err != nil {
+		a.log().at(LevelError).Println(a.name, "Failed to detach Standby instance",
+			*instanceID, err.Error())
+		return err
+	}
+	a.waitUntilDetached(instanceID)
+	return nil
+}
@@ -0,0 +1,197 @@
+package autospotting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_autoScalingGroup_replaceOnDemandInstanceUsingStandby(t *testing.T) {
+	actions = actionHistory{}
+	actions.init(Config{HistorySize: 10})
+
+	var tagged []string
+
+	services, calls := fakeConnections(func(r *request.Request) {
+		switch in := r.Params.(type) {
+		case *autoscaling.AttachInstancesInput:
+			r.Error = awserr.New("ValidationError", "instance not running", nil)
+		case *ec2.CreateTagsInput:
+			tagged = append(tagged, aws.StringValueSlice(in.Resources)...)
+		}
+	})
+
+	odInst := &instance{Instance: &ec2.Instance{
+		InstanceId: aws.String("i-od"),
+	}}
+
+	a := autoScalingGroup{
+		Group: &autoscaling.Group{AutoScalingGroupName: aws.String("web")},
+		name:  "web",
+		region: &region{
+			name:     "us-east-1",
+			services: services,
+			instances: instances{catalog: map[string]*instance{
+				"i-od": odInst,
+			}},
+		},
+	}
+
+	a.replaceOnDemandInstanceUsingStandby(odInst, aws.String("i-spot"))
+
+	for _, call := range *calls {
+		if call == "autoscaling:EnterStandby" {
+			t.Errorf("moved the on-demand instance to Standby after the failed " +
+				"attachment")
+		}
+	}
+	for _, id := range tagged {
+		if id == "i-od" {
+			t.Errorf("tagged the on-demand instance after the failed attachment")
+		}
+	}
+}
+
+func Test_autoScalingGroup_processStandbyInstances(t *testing.T) {
+
+	tests := []struct {
+		name string
+		spot *autoscaling.Instance
+		want bool
+	}{
+		{
+			name: "spot instance without lifecycle state",
+			spot: &autoscaling.Instance{InstanceId: aws.String("i-spot")},
+			want: true,
+		},
+		{
+			name: "spot instance pending",
+			spot: &autoscaling.Instance{
+				InstanceId:     aws.String("i-spot"),
+				LifecycleState: aws.String(autoscaling.LifecycleStatePending),
+				HealthStatus:   aws.String("Healthy"),
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			odInst := &instance{Instance: &ec2.Instance{
+				InstanceId: aws.String("i-od"),
+				Tags: []*ec2.Tag{
+					{Key: aws.String(standbyTag), Value: aws.String("i-spot")},
+				},
+			}}
+
+			a := autoScalingGroup{
+				Group: &autoscaling.Group{
+					AutoScalingGroupName: aws.String("web"),
+					Instances: []*autoscaling.Instance{
+						{
+							InstanceId: aws.String("i-od"),
+							LifecycleState: aws.String(
+								autoscaling.LifecycleStateStandby),
+						},
+						{InstanceId: aws.String("i-none")},
+						tt.spot,
+					},
+				},
+				name: "web",
+				region: &region{
+					name: "us-east-1",
+					instances: instances{catalog: map[string]*instance{
+						"i-od": odInst,
+					}},
+				},
+			}
+
+			if got := a.processStandbyInstances(); got != tt.want {
+				t.Errorf("processStandbyInstances() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_autoScalingGroup_processStandbyInstances_queued(t *testing.T) {
+	actions = actionHistory{}
+	actions.init(Config{HistorySize: 10})
+
+	services, calls := fakeConnections(func(r *request.Request) {
+		if out, ok := r.Data.(*autoscaling.DescribeAutoScalingGroupsOutput); ok {
+			out.AutoScalingGroups = []*autoscaling.Group{{}}
+		}
+	})
+
+	odInst := &instance{Instance: &ec2.Instance{
+		InstanceId: aws.String("i-od"),
+		Tags: []*ec2.Tag{
+			{Key: aws.String(standbyTag), Value: aws.String("i-spot")},
+		},
+	}}
+
+	a := autoScalingGroup{
+		Group: &autoscaling.Group{
+			AutoScalingGroupName: aws.String("web"),
+			Instances: []*autoscaling.Instance{
+				{
+					InstanceId: aws.String("i-od"),
+					LifecycleState: aws.String(
+						autoscaling.LifecycleStateStandby),
+				},
+				{
+					InstanceId: aws.String("i-spot"),
+					LifecycleState: aws.String(
+						autoscaling.LifecycleStateInService),
+					HealthStatus: aws.String("Healthy"),
+				},
+			},
+		},
+		name: "web",
+		region: &region{
+			name:     "us-east-1",
+			conf:     Config{TerminationDelay: time.Hour},
+			services: services,
+			instances: instances{catalog: map[string]*instance{
+				"i-od": odInst,
+			}},
+		},
+	}
+
+	count := func(call string) int {
+		n := 0
+		for _, c := range *calls {
+			if c == call {
+				n++
+			}
+		}
+		return n
+	}
+
+	a.processStandbyInstances()
+
+	if count("autoscaling:DetachInstances") != 1 ||
+		count("ec2:CreateTags") != 1 {
+		t.Errorf("processStandbyInstances() made the calls %v, want the "+
+			"Standby instance detached and queued once", *calls)
+	}
+
+	// the next run finds the instance queued, in case it's still listed
+	odInst.Tags = append(odInst.Tags, &ec2.Tag{
+		Key:   aws.String(terminateAfterTag),
+		Value: aws.String(time.Now().Add(time.Hour).UTC().Format(time.RFC3339)),
+	})
+	*calls = nil
+
+	a.processStandbyInstances()
+
+	if len(*calls) != 0 {
+		t.Errorf("processStandbyInstances() made the calls %v for the queued "+
+			"Standby instance, want none", *calls)
+	}
+}
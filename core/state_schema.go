@@ -0,0 +1,110 @@
+package autospotting
+
+// the version of the state schema written by this version of AutoSpotting,
+// bumped together with appending the migration to stateMigrations
+const stateSchemaVersion = 1
+
+// the number attribute holding the schema version of each item
+const schemaVersionAttribute = "schema_version"
+
+// stateMigration converts an item of the given kind of table from the previous
+// schema version.
+type stateMigration func(kind string, item stateItem) stateItem
+
+// stateMigrations holds at index i the migration from version i to i+1.
+var stateMigrations = []stateMigration{
+	// the unversioned items already had the format of the first version
+	func(kind string, item stateItem) stateItem { return item },
+}
+
+// stamped returns a copy of the item carrying the current schema version.
+func stamped(item stateItem) stateItem {
+	numbers := map[string]float64{schemaVersionAttribute: stateSchemaVersion}
+	for name, value := range item.Numbers {
+		if name != schemaVersionAttribute {
+			numbers[name] = value
+		}
+	}
+	return stateItem{Keys: item.Keys, Numbers: numbers}
+}
+
+// schemaVersion returns the version an item was written with, the items
+// written before versioning being version 0.
+func schemaVersion(item stateItem) int {
+	return int(item.Numbers[schemaVersionAttribute])
+}
+
+// migrateItem converts the item to the current schema version, telling if it
+// needed any migration.
+func migrateItem(kind string, item stateItem) (stateItem, bool) {
+
+	version := schemaVersion(item)
+	if version >= stateSchemaVersion {
+		return item, false
+	}
+
+	for ; version < stateSchemaVersion; version++ {
+		item = stateMigrations[version](kind, item)
+	}
+	return stamped(item), true
+}
+
+// migrateState migrates the items of all the configured state tables, keyed by
+// their kind, to the current schema version.
+func migrateState(tables map[string]string) {
+
+	for kind, table := range tables {
+
+		if table == "" {
+			continue
+		}
+
+		items, err := state.scan(table)
+		if err != nil {
+			logger.at(LevelError).Println("Couldn't load the", kind, "table",
+				table, "for migrating it", err.Error())
+			continue
+		}
+
+		migrated, newer := 0, 0
+		for _, item := range items {
+
+			if schemaVersion(item) > stateSchemaVersion {
+				newer++
+				continue
+			}
+
+			current, changed := migrateItem(kind, item)
+			if !changed {
+				continue
+			}
+
+			if err := state.put(table, current); err != nil {
+				logger.at(LevelError).Println("Couldn't migrate", item.id(),
+					"in the", kind, "table", table, err.Error())
+				continue
+			}
+			migrated++
+		}
+
+		if migrated > 0 {
+			logger.Println("Migrated", migrated, "items of the", kind, "table",
+				table, "to the state schema version", stateSchemaVersion)
+		}
+		if newer > 0 {
+			logger.Println("The", kind, "table", table, "has", newer, "items",
+				"written by a newer version of AutoSpotting, leaving them as is")
+		}
+	}
+}
+
+// stateTables returns the configured state tables, keyed by their kind.
+func stateTables(cfg Config) map[string]string {
+	return map[string]string{
+		"idempotency":  cfg.IdempotencyTable,
+		"savings":      cfg.SavingsTable,
+		"deny-list":    cfg.DenyListTable,
+		"changes":      cfg.ChangesTable,
+		"continuation": cfg.ContinuationTable,
+	}
+}
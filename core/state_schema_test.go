@@ -0,0 +1,103 @@
+package autospotting
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test_migrateItem(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		item        stateItem
+		want        stateItem
+		wantChanged bool
+	}{
+		{
+			name: "unversioned item",
+			item: stateItem{
+				Keys:    map[string]string{"pool": "us-east-1/m5.large/us-east-1a"},
+				Numbers: map[string]float64{"expires": 100},
+			},
+			want: stateItem{
+				Keys: map[string]string{"pool": "us-east-1/m5.large/us-east-1a"},
+				Numbers: map[string]float64{
+					"expires":              100,
+					schemaVersionAttribute: stateSchemaVersion,
+				},
+			},
+			wantChanged: true,
+		},
+		{
+			name: "current item",
+			item: stateItem{
+				Keys: map[string]string{"id": "event"},
+				Numbers: map[string]float64{
+					schemaVersionAttribute: stateSchemaVersion,
+				},
+			},
+			want: stateItem{
+				Keys: map[string]string{"id": "event"},
+				Numbers: map[string]float64{
+					schemaVersionAttribute: stateSchemaVersion,
+				},
+			},
+		},
+		{
+			name: "newer item",
+			item: stateItem{
+				Keys: map[string]string{"id": "event"},
+				Numbers: map[string]float64{
+					schemaVersionAttribute: stateSchemaVersion + 1,
+				},
+			},
+			want: stateItem{
+				Keys: map[string]string{"id": "event"},
+				Numbers: map[string]float64{
+					schemaVersionAttribute: stateSchemaVersion + 1,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := migrateItem("deny-list", tt.item)
+			if !reflect.DeepEqual(got, tt.want) || changed != tt.wantChanged {
+				t.Errorf("migrateItem() = %v, %v, want %v, %v", got, changed,
+					tt.want, tt.wantChanged)
+			}
+		})
+	}
+}
+
+func Test_migrateState(t *testing.T) {
+
+	dir := localDirectory(t.TempDir())
+	defer func(s stateStore) { state = s }(state)
+	state = &documentStore{blobs: dir}
+
+	expires := time.Now().Add(time.Hour).Unix()
+
+	// a table written by the unversioned document store
+	legacy := `[{"keys":{"id":"event"},"numbers":{"expires":` +
+		strconv.FormatInt(expires, 10) + `}}]`
+	if err := dir.put("claims.json", []byte(legacy)); err != nil {
+		t.Fatalf("put() error = %v", err)
+	}
+
+	migrateState(map[string]string{"idempotency": "claims", "savings": ""})
+
+	got, err := state.scan("claims")
+	want := []stateItem{{
+		Keys: map[string]string{"id": "event"},
+		Numbers: map[string]float64{
+			"expires":              float64(expires),
+			schemaVersionAttribute: stateSchemaVersion,
+		},
+	}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("scan() = %v, %v, want %v", got, err, want)
+	}
+}
@@ -0,0 +1,183 @@
+package autospotting
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// per-instance tags carried over to the spot instances by the stateful profile
+var hostnameTags = []string{"Name", "hostname"}
+
+func (a *autoScalingGroup) isStateful() bool {
+	return a.stringSetting("replacement_profile") == "stateful"
+}
+
+// carryOverState moves over everything the on-demand instance carries that
+// needs to survive its replacement by the spot instance, as configured on the
+// group.
+func (a *autoScalingGroup) carryOverState(odInst *instance,
+	spotInstanceID *string) {
+
+	if a.isStateful() {
+		a.carryOverHostname(odInst, spotInstanceID)
+		a.carryOverNetworkInterfaces(odInst, spotInstanceID)
+		a.carryOverAddress(odInst, spotInstanceID)
+	}
+
+	a.carryOverDataVolumes(odInst, spotInstanceID)
+}
+
+func (a *autoScalingGroup) carryOverHostname(odInst *instance,
+	spotInstanceID *string) {
+
+	var tags []*ec2.Tag
+	for _, key := range hostnameTags {
+		if value := findTagValue(odInst.Tags, key); value != nil {
+			tags = append(tags, &ec2.Tag{Key: aws.String(key), Value: value})
+		}
+	}
+
+	if len(tags) > 0 {
+		a.tagInstance(spotInstanceID, tags)
+	}
+}
+
+// carryOverNetworkInterfaces moves the secondary network interfaces to the spot
+// instance, on the same device indexes.
+func (a *autoScalingGroup) carryOverNetworkInterfaces(odInst *instance,
+	spotInstanceID *string) {
+
+	svc := a.region.services.ec2
+
+	for _, eni := range odInst.NetworkInterfaces {
+
+		if eni.Attachment == nil || eni.Attachment.DeviceIndex == nil ||
+			*eni.Attachment.DeviceIndex == 0 {
+			continue
+		}
+
+		a.log().Println(a.name, "Moving network interface",
+			*eni.NetworkInterfaceId, "from", *odInst.InstanceId, "to",
+			*spotInstanceID)
+
+		detachInput := &ec2.DetachNetworkInterfaceInput{
+			AttachmentId: eni.Attachment.AttachmentId,
+		}
+		attachInput := &ec2.AttachNetworkInterfaceInput{
+			NetworkInterfaceId: eni.NetworkInterfaceId,
+			InstanceId:         spotInstanceID,
+			DeviceIndex:        eni.Attachment.DeviceIndex,
+		}
+
+		if a.skipsCall("DetachNetworkInterface", detachInput) {
+			a.skipsCall("AttachNetworkInterface", attachInput)
+			continue
+		}
+
+		_, err := svc.DetachNetworkInterface(detachInput)
+
+		if err != nil {
+			a.log().at(LevelError).Println(a.name,
+				"Failed to detach network interface", *eni.NetworkInterfaceId,
+				err.Error())
+			continue
+		}
+
+		err = svc.WaitUntilNetworkInterfaceAvailable(
+			&ec2.DescribeNetworkInterfacesInput{
+				NetworkInterfaceIds: []*string{eni.NetworkInterfaceId},
+			})
+
+		if err != nil {
+			a.log().at(LevelError).Println(a.name,
+				"Error waiting for network interface", *eni.NetworkInterfaceId,
+				"to be detached", err.Error())
+			continue
+		}
+
+		_, err = svc.AttachNetworkInterface(attachInput)
+
+		if err != nil {
+			a.log().at(LevelError).Println(a.name,
+				"Failed to attach network interface", *eni.NetworkInterfaceId,
+				"to", *spotInstanceID, err.Error())
+			continue
+		}
+
+		a.recordAction("reattached", "network interface",
+			*eni.NetworkInterfaceId, "from", *odInst.InstanceId, "to",
+			*spotInstanceID)
+	}
+}
+
+// carryOverAddress moves the Elastic IP address of the primary network
+// interface to the spot instance, the addresses of the secondary interfaces
+// are moved along with their interfaces.
+func (a *autoScalingGroup) carryOverAddress(odInst *instance,
+	spotInstanceID *string) {
+
+	svc := a.region.services.ec2
+
+	resp, err := svc.DescribeAddresses(&ec2.DescribeAddressesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("instance-id"),
+				Values: []*string{odInst.InstanceId},
+			},
+		},
+	})
+
+	if err != nil {
+		a.log().at(LevelError).Println(a.name,
+			"Failed to describe the addresses of", *odInst.InstanceId,
+			err.Error())
+		return
+	}
+
+	for _, addr := range resp.Addresses {
+
+		if !isPrimaryInterface(odInst, addr.NetworkInterfaceId) {
+			continue
+		}
+
+		input := &ec2.AssociateAddressInput{
+			InstanceId:         spotInstanceID,
+			AllowReassociation: aws.Bool(true),
+		}
+		if addr.AllocationId != nil {
+			input.AllocationId = addr.AllocationId
+		} else {
+			input.PublicIp = addr.PublicIp
+		}
+
+		if a.skipsCall("AssociateAddress", input) {
+			continue
+		}
+
+		if _, err := svc.AssociateAddress(input); err != nil {
+			a.log().at(LevelError).Println(a.name, "Failed to move address",
+				*addr.PublicIp, "to", *spotInstanceID, err.Error())
+			continue
+		}
+
+		a.recordAction("reassociated", "address", *addr.PublicIp, "from",
+			*odInst.InstanceId, "to", *spotInstanceID)
+	}
+}
+
+// isPrimaryInterface tells if the network interface is the primary one of the
+// instance, which is also the case for EC2 Classic addresses, having none.
+func isPrimaryInterface(inst *instance, eniID *string) bool {
+
+	if eniID == nil {
+		return true
+	}
+
+	for _, eni := range inst.NetworkInterfaces {
+		if *eni.NetworkInterfaceId == *eniID {
+			return eni.Attachment != nil && eni.Attachment.DeviceIndex != nil &&
+				*eni.Attachment.DeviceIndex == 0
+		}
+	}
+	return false
+}
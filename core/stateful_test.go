@@ -0,0 +1,46 @@
+package autospotting
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_isPrimaryInterface(t *testing.T) {
+
+	inst := &instance{Instance: &ec2.Instance{
+		NetworkInterfaces: []*ec2.InstanceNetworkInterface{
+			{
+				NetworkInterfaceId: aws.String("eni-primary"),
+				Attachment: &ec2.InstanceNetworkInterfaceAttachment{
+					DeviceIndex: aws.Int64(0),
+				},
+			},
+			{
+				NetworkInterfaceId: aws.String("eni-secondary"),
+				Attachment: &ec2.InstanceNetworkInterfaceAttachment{
+					DeviceIndex: aws.Int64(1),
+				},
+			},
+		},
+	}}
+
+	tests := []struct {
+		name  string
+		eniID *string
+		want  bool
+	}{
+		{name: "EC2 Classic address", eniID: nil, want: true},
+		{name: "Primary interface", eniID: aws.String("eni-primary"), want: true},
+		{name: "Secondary interface", eniID: aws.String("eni-secondary"), want: false},
+		{name: "Unknown interface", eniID: aws.String("eni-other"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPrimaryInterface(inst, tt.eniID); got != tt.want {
+				t.Errorf("isPrimaryInterface() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
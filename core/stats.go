@@ -0,0 +1,154 @@
+package autospotting
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+var stats runtimeStats
+
+type apiCallStats struct {
+	count   int
+	errors  int
+	seconds float64
+}
+
+type runtimeStats struct {
+	sync.Mutex
+
+	runs            int
+	lastRunSeconds  float64
+	lastRunFinished time.Time
+
+	// keyed by service name and operation name
+	apiCalls map[[2]string]*apiCallStats
+
+	// regions skipped because of stale pricing data, keyed by region name
+	stalePricing map[string]int
+}
+
+// instrumentSession makes all the API calls done through the session be
+// accounted in the runtime statistics.
+func instrumentSession(s *session.Session) *session.Session {
+	s.Handlers.Complete.PushBack(stats.observeAPICall)
+	s.Handlers.Complete.PushBack(access.observe)
+	return s
+}
+
+func (s *runtimeStats) observeAPICall(r *request.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.apiCalls == nil {
+		s.apiCalls = make(map[[2]string]*apiCallStats)
+	}
+
+	key := [2]string{r.ClientInfo.ServiceName, r.Operation.Name}
+
+	call := s.apiCalls[key]
+	if call == nil {
+		call = &apiCallStats{}
+		s.apiCalls[key] = call
+	}
+
+	call.count++
+	call.seconds += time.Since(r.Time).Seconds()
+	if r.Error != nil {
+		call.errors++
+	}
+}
+
+func (s *runtimeStats) observeRun(start time.Time) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.runs++
+	s.lastRunFinished = time.Now()
+	s.lastRunSeconds = s.lastRunFinished.Sub(start).Seconds()
+}
+
+func (s *runtimeStats) observeStalePricing(region string) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.stalePricing == nil {
+		s.stalePricing = make(map[string]int)
+	}
+	s.stalePricing[region]++
+}
+
+func (s *runtimeStats) write(w io.Writer) {
+
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	fmt.Fprintln(w, "# TYPE go_goroutines gauge")
+	fmt.Fprintln(w, "go_goroutines", runtime.NumGoroutine())
+	fmt.Fprintln(w, "# TYPE go_memstats_heap_alloc_bytes gauge")
+	fmt.Fprintln(w, "go_memstats_heap_alloc_bytes", m.HeapAlloc)
+	fmt.Fprintln(w, "# TYPE go_memstats_heap_inuse_bytes gauge")
+	fmt.Fprintln(w, "go_memstats_heap_inuse_bytes", m.HeapInuse)
+	fmt.Fprintln(w, "# TYPE go_memstats_heap_objects gauge")
+	fmt.Fprintln(w, "go_memstats_heap_objects", m.HeapObjects)
+	fmt.Fprintln(w, "# TYPE go_memstats_sys_bytes gauge")
+	fmt.Fprintln(w, "go_memstats_sys_bytes", m.Sys)
+	fmt.Fprintln(w, "# TYPE go_gc_runs_total counter")
+	fmt.Fprintln(w, "go_gc_runs_total", m.NumGC)
+
+	s.Lock()
+	defer s.Unlock()
+
+	fmt.Fprintln(w, "# TYPE autospotting_runs_total counter")
+	fmt.Fprintln(w, "autospotting_runs_total", s.runs)
+	fmt.Fprintln(w, "# TYPE autospotting_last_run_duration_seconds gauge")
+	fmt.Fprintln(w, "autospotting_last_run_duration_seconds", s.lastRunSeconds)
+
+	var keys [][2]string
+	for key := range s.apiCalls {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i][0]+"."+keys[i][1] < keys[j][0]+"."+keys[j][1]
+	})
+
+	fmt.Fprintln(w, "# TYPE autospotting_api_call_duration_seconds summary")
+	for _, key := range keys {
+		labels := fmt.Sprintf("{service=%q,operation=%q}", key[0], key[1])
+		fmt.Fprintf(w, "autospotting_api_call_duration_seconds_sum%s %g\n",
+			labels, s.apiCalls[key].seconds)
+		fmt.Fprintf(w, "autospotting_api_call_duration_seconds_count%s %d\n",
+			labels, s.apiCalls[key].count)
+	}
+
+	fmt.Fprintln(w, "# TYPE autospotting_api_call_errors_total counter")
+	for _, key := range keys {
+		fmt.Fprintf(w,
+			"autospotting_api_call_errors_total{service=%q,operation=%q} %d\n",
+			key[0], key[1], s.apiCalls[key].errors)
+	}
+
+	var regions []string
+	for region := range s.stalePricing {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	fmt.Fprintln(w, "# TYPE autospotting_stale_pricing_skips_total counter")
+	for _, region := range regions {
+		fmt.Fprintf(w, "autospotting_stale_pricing_skips_total{region=%q} %d\n",
+			region, s.stalePricing[region])
+	}
+}
+
+func serveStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	stats.write(w)
+}
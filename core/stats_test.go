@@ -0,0 +1,43 @@
+package autospotting
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+func Test_runtimeStats(t *testing.T) {
+	var s runtimeStats
+
+	call := func(err error) *request.Request {
+		return &request.Request{
+			ClientInfo: metadata.ClientInfo{ServiceName: "ec2"},
+			Operation:  &request.Operation{Name: "DescribeInstances"},
+			Time:       time.Now(),
+			Error:      err,
+		}
+	}
+
+	s.observeAPICall(call(nil))
+	s.observeAPICall(call(errors.New("throttled")))
+	s.observeRun(time.Now())
+
+	var out bytes.Buffer
+	s.write(&out)
+
+	for _, want := range []string{
+		"autospotting_runs_total 1",
+		`autospotting_api_call_duration_seconds_count{service="ec2",operation="DescribeInstances"} 2`,
+		`autospotting_api_call_errors_total{service="ec2",operation="DescribeInstances"} 1`,
+		"go_goroutines ",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("write() output is missing %q:\n%s", want, out.String())
+		}
+	}
+}
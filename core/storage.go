@@ -0,0 +1,341 @@
+package autospotting
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// stateItem is an item of a state table.
+type stateItem struct {
+	Keys    map[string]string  `json:"keys"`
+	Numbers map[string]float64 `json:"numbers"`
+}
+
+// id identifies the item within its table.
+func (i stateItem) id() string {
+	var names []string
+	for name := range i.Keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var parts []string
+	for _, name := range names {
+		parts = append(parts, name+"="+i.Keys[name])
+	}
+	return strings.Join(parts, "/")
+}
+
+type stateStore interface {
+	// claim stores the item unless an item with the same keys exists, and
+	// tells if it was stored
+	claim(table string, item stateItem) (bool, error)
+
+	// put stores the item, replacing any item with the same keys
+	put(table string, item stateItem) error
+
+	// increment adds the number attributes of the item to those of the stored
+	// item with the same keys, creating it if missing, except for the
+	// "expires" epoch which is replaced
+	increment(table string, item stateItem) error
+
+	scan(table string) ([]stateItem, error)
+}
+
+// blobStore stores whole documents, get returns nil for missing documents.
+type blobStore interface {
+	get(key string) ([]byte, error)
+	put(key string, content []byte) error
+}
+
+var state stateStore = &dynamoDBStore{}
+
+// newStateStore returns the configured state store.
+func newStateStore(cfg Config) stateStore {
+	switch cfg.StateBackend {
+	case "s3":
+		return &documentStore{blobs: &s3Bucket{name: cfg.StateLocation},
+			prefix: "state/"}
+	case "file":
+		return &documentStore{blobs: localDirectory(cfg.StateLocation)}
+	default:
+		return &dynamoDBStore{}
+	}
+}
+
+// dynamoDBStore keeps each state table in the DynamoDB table of the same name.
+type dynamoDBStore struct{}
+
+func (s *dynamoDBStore) client() *dynamodb.DynamoDB {
+	return dynamodb.New(instrumentSession(
+		homeSession()))
+}
+
+func dynamoDBAttributes(item stateItem) map[string]*dynamodb.AttributeValue {
+	attributes := make(map[string]*dynamodb.AttributeValue)
+	for name, value := range item.Keys {
+		attributes[name] = &dynamodb.AttributeValue{S: aws.String(value)}
+	}
+	for name, value := range item.Numbers {
+		attributes[name] = &dynamodb.AttributeValue{
+			N: aws.String(strconv.FormatFloat(value, 'f', -1, 64))}
+	}
+	return attributes
+}
+
+func (s *dynamoDBStore) claim(table string, item stateItem) (bool, error) {
+
+	var conditions []string
+	names := make(map[string]*string)
+	for name := range item.Keys {
+		placeholder := fmt.Sprintf("#k%d", len(names))
+		names[placeholder] = aws.String(name)
+		conditions = append(conditions, "attribute_not_exists("+placeholder+")")
+	}
+	sort.Strings(conditions)
+
+	_, err := s.client().PutItem(&dynamodb.PutItemInput{
+		TableName:                aws.String(table),
+		Item:                     dynamoDBAttributes(stamped(item)),
+		ConditionExpression:      aws.String(strings.Join(conditions, " AND ")),
+		ExpressionAttributeNames: names,
+	})
+
+	if aerr, ok := err.(awserr.Error); ok &&
+		aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *dynamoDBStore) put(table string, item stateItem) error {
+	_, err := s.client().PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(table),
+		Item:      dynamoDBAttributes(stamped(item)),
+	})
+	return err
+}
+
+func (s *dynamoDBStore) increment(table string, item stateItem) error {
+
+	key := dynamoDBAttributes(stateItem{Keys: item.Keys})
+
+	var additions []string
+	names := make(map[string]*string)
+	values := make(map[string]*dynamodb.AttributeValue)
+
+	// the schema version and the expiration are set rather than added up
+	names["#sv"] = aws.String(schemaVersionAttribute)
+	values[":sv"] = &dynamodb.AttributeValue{
+		N: aws.String(strconv.Itoa(stateSchemaVersion))}
+	assignments := []string{"#sv = :sv"}
+
+	for name, value := range item.Numbers {
+		i := len(names)
+		names[fmt.Sprintf("#n%d", i)] = aws.String(name)
+		values[fmt.Sprintf(":v%d", i)] = &dynamodb.AttributeValue{
+			N: aws.String(strconv.FormatFloat(value, 'f', -1, 64))}
+		if name == "expires" {
+			assignments = append(assignments, fmt.Sprintf("#n%d = :v%d", i, i))
+			continue
+		}
+		additions = append(additions, fmt.Sprintf("#n%d :v%d", i, i))
+	}
+
+	_, err := s.client().UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(table),
+		Key:       key,
+		UpdateExpression: aws.String("ADD " + strings.Join(additions, ", ") +
+			" SET " + strings.Join(assignments, ", ")),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	return err
+}
+
+func (s *dynamoDBStore) scan(table string) ([]stateItem, error) {
+
+	var items []stateItem
+
+	err := s.client().ScanPages(&dynamodb.ScanInput{TableName: aws.String(table)},
+		func(page *dynamodb.ScanOutput, lastPage bool) bool {
+			for _, attributes := range page.Items {
+				item := stateItem{
+					Keys:    make(map[string]string),
+					Numbers: make(map[string]float64),
+				}
+				for name, v := range attributes {
+					switch {
+					case v.S != nil:
+						item.Keys[name] = *v.S
+					case v.N != nil:
+						item.Numbers[name], _ = strconv.ParseFloat(*v.N, 64)
+					}
+				}
+				items = append(items, item)
+			}
+			return true
+		})
+
+	return items, err
+}
+
+// stateDocument is the format of the documents of the documentStore, which
+// were plain lists of items before being versioned.
+type stateDocument struct {
+	Version int         `json:"version"`
+	Items   []stateItem `json:"items"`
+}
+
+// documentStore keeps each state table as a JSON document in a blob store.
+// The documents are rewritten on every change, so it's only meant for a single
+// AutoSpotting process.
+type documentStore struct {
+	sync.Mutex
+
+	blobs  blobStore
+	prefix string
+}
+
+func (s *documentStore) load(table string) (map[string]stateItem, error) {
+
+	items := make(map[string]stateItem)
+
+	content, err := s.blobs.get(s.prefix + table + ".json")
+	if err != nil || content == nil {
+		return items, err
+	}
+
+	var doc stateDocument
+	if err := json.Unmarshal(content, &doc); err != nil {
+		if err := json.Unmarshal(content, &doc.Items); err != nil {
+			return nil, err
+		}
+	}
+
+	now := float64(time.Now().Unix())
+	for _, item := range doc.Items {
+		if expires, found := item.Numbers["expires"]; found && expires < now {
+			continue
+		}
+		items[item.id()] = item
+	}
+	return items, nil
+}
+
+func (s *documentStore) save(table string, items map[string]stateItem) error {
+
+	list := []stateItem{}
+	for _, item := range items {
+		list = append(list, item)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].id() < list[j].id() })
+
+	content, err := json.MarshalIndent(
+		stateDocument{Version: stateSchemaVersion, Items: list}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return s.blobs.put(s.prefix+table+".json", content)
+}
+
+// update applies the change to the table, saving it if the change says so.
+func (s *documentStore) update(table string,
+	change func(map[string]stateItem) bool) error {
+	s.Lock()
+	defer s.Unlock()
+
+	items, err := s.load(table)
+	if err != nil {
+		return err
+	}
+	if !change(items) {
+		return nil
+	}
+	return s.save(table, items)
+}
+
+func (s *documentStore) claim(table string, item stateItem) (bool, error) {
+	claimed := false
+	err := s.update(table, func(items map[string]stateItem) bool {
+		if _, found := items[item.id()]; found {
+			return false
+		}
+		items[item.id()], claimed = stamped(item), true
+		return true
+	})
+	return claimed && err == nil, err
+}
+
+func (s *documentStore) put(table string, item stateItem) error {
+	return s.update(table, func(items map[string]stateItem) bool {
+		items[item.id()] = stamped(item)
+		return true
+	})
+}
+
+func (s *documentStore) increment(table string, item stateItem) error {
+	return s.update(table, func(items map[string]stateItem) bool {
+		stored, found := items[item.id()]
+		if !found {
+			stored = stateItem{Keys: item.Keys, Numbers: map[string]float64{}}
+		}
+		for name, value := range item.Numbers {
+			if name == "expires" {
+				stored.Numbers[name] = value
+				continue
+			}
+			stored.Numbers[name] += value
+		}
+		items[item.id()] = stamped(stored)
+		return true
+	})
+}
+
+func (s *documentStore) scan(table string) ([]stateItem, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	items, err := s.load(table)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []stateItem
+	for _, item := range items {
+		list = append(list, item)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].id() < list[j].id() })
+	return list, nil
+}
+
+// localDirectory stores the documents as files in a local directory.
+type localDirectory string
+
+func (d localDirectory) get(key string) ([]byte, error) {
+	content, err := ioutil.ReadFile(filepath.Join(string(d), key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return content, err
+}
+
+func (d localDirectory) put(key string, content []byte) error {
+	file := filepath.Join(string(d), key)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, content, 0644)
+}
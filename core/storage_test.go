@@ -0,0 +1,77 @@
+package autospotting
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_documentStore(t *testing.T) {
+
+	s := &documentStore{blobs: localDirectory(t.TempDir()), prefix: "state/"}
+
+	item := stateItem{
+		Keys:    map[string]string{"id": "event"},
+		Numbers: map[string]float64{"expires": float64(time.Now().Add(time.Hour).Unix())},
+	}
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "first claim", want: true},
+		{name: "duplicate claim", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.claim("claims", item)
+			if err != nil || got != tt.want {
+				t.Errorf("claim() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+
+	expired := stateItem{
+		Keys:    map[string]string{"id": "expired"},
+		Numbers: map[string]float64{"expires": float64(time.Now().Add(-time.Hour).Unix())},
+	}
+	if err := s.put("claims", expired); err != nil {
+		t.Fatalf("put() error = %v", err)
+	}
+	if got, err := s.claim("claims", expired); err != nil || !got {
+		t.Errorf("claim() of an expired item = %v, %v, want true", got, err)
+	}
+
+	dayExpires := float64(time.Now().Add(time.Hour).Unix())
+	day := stateItem{
+		Keys: map[string]string{"day": "2020-01-01", "group": "r/g"},
+		Numbers: map[string]float64{
+			"samples":        1,
+			"hourly_savings": 0.5,
+			"expires":        dayExpires,
+		},
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.increment("savings", day); err != nil {
+			t.Fatalf("increment() error = %v", err)
+		}
+	}
+
+	got, err := s.scan("savings")
+	want := []stateItem{{
+		Keys: map[string]string{"day": "2020-01-01", "group": "r/g"},
+		Numbers: map[string]float64{
+			"samples":              2,
+			"hourly_savings":       1,
+			"expires":              dayExpires,
+			schemaVersionAttribute: stateSchemaVersion,
+		},
+	}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("scan() = %v, %v, want %v", got, err, want)
+	}
+
+	if got, err := s.scan("missing"); err != nil || len(got) != 0 {
+		t.Errorf("scan() of a missing table = %v, %v, want nothing", got, err)
+	}
+}
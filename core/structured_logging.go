@@ -0,0 +1,317 @@
+package autospotting
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogLevel is the severity of a log entry.
+type LogLevel int
+
+// The supported log levels, by increasing severity.
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var logLevelNames = []string{"debug", "info", "warn", "error"}
+
+func (l LogLevel) String() string {
+	if l < LevelDebug || l > LevelError {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return logLevelNames[l]
+}
+
+// parseLogLevel parses the name of a log level, defaulting to info.
+func parseLogLevel(name string) (LogLevel, error) {
+	if name == "" {
+		return LevelInfo, nil
+	}
+	for l, n := range logLevelNames {
+		if strings.EqualFold(name, n) {
+			return LogLevel(l), nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q", name)
+}
+
+// LogEntry is a single log message with its context.
+type LogEntry struct {
+	Time    time.Time
+	Level   LogLevel
+	Message string
+
+	// the source file and line of the logging call, if known
+	File string
+	Line int
+
+	// such as region, asg, instance_id and action
+	Fields map[string]string
+}
+
+// LogHandler writes out the log entries.
+type LogHandler interface {
+	Handle(entry LogEntry)
+}
+
+// sortedFields returns the names of the fields in alphabetical order.
+func sortedFields(fields map[string]string) []string {
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// textLogHandler writes the entries as free text lines.
+type textLogHandler struct {
+	l *log.Logger
+
+	// the file name flags of the standard library loggers, applied to the
+	// caller of the entry instead of the handler
+	fileFlag int
+}
+
+func newTextLogHandler(w io.Writer, flag int) *textLogHandler {
+	fileFlag := flag & (log.Lshortfile | log.Llongfile)
+	return &textLogHandler{
+		l:        log.New(w, "", flag&^fileFlag),
+		fileFlag: fileFlag,
+	}
+}
+
+func (h *textLogHandler) Handle(e LogEntry) {
+
+	msg := e.Message
+	if h.fileFlag != 0 && e.File != "" {
+		file := e.File
+		if h.fileFlag&log.Lshortfile != 0 {
+			file = filepath.Base(file)
+		}
+		msg = fmt.Sprintf("%s:%d: %s", file, e.Line, msg)
+	}
+	for _, name := range sortedFields(e.Fields) {
+		msg += " " + name + "=" + e.Fields[name]
+	}
+	h.l.Println(msg)
+}
+
+// jsonLogHandler writes the entries as JSON objects, one per line.
+type jsonLogHandler struct {
+	sync.Mutex
+	w io.Writer
+}
+
+func (h *jsonLogHandler) Handle(e LogEntry) {
+
+	var b bytes.Buffer
+
+	field := func(name, value string) {
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		} else {
+			b.WriteByte('{')
+		}
+		n, _ := json.Marshal(name)
+		v, _ := json.Marshal(value)
+		b.Write(n)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+
+	field("time", e.Time.UTC().Format(time.RFC3339Nano))
+	field("level", e.Level.String())
+	field("msg", e.Message)
+	for _, name := range sortedFields(e.Fields) {
+		if name != "time" && name != "level" && name != "msg" {
+			field(name, e.Fields[name])
+		}
+	}
+	b.WriteString("}\n")
+
+	h.Lock()
+	defer h.Unlock()
+	h.w.Write(b.Bytes())
+}
+
+// logSink is the handler shared by the loggers of all levels.
+type logSink struct {
+	handler LogHandler
+	level   LogLevel
+}
+
+// levelLogger logs at a given level, with a given set of fields, and offers
+// the Println and Printf methods of the standard library loggers.
+type levelLogger struct {
+	sink   *logSink
+	level  LogLevel
+	fields map[string]string
+
+	// logs regardless of the configured level, such as the scoped debug
+	// output
+	forced bool
+
+	// the number of wrapper functions between the logging call and Println
+	depth int
+}
+
+// newLogger returns the logger of the given level.
+func newLogger(sink *logSink, level LogLevel) *levelLogger {
+	return &levelLogger{sink: sink, level: level}
+}
+
+// discardLogger returns a logger discarding everything.
+func discardLogger() *levelLogger {
+	return &levelLogger{}
+}
+
+func (l *levelLogger) enabled() bool {
+	return l != nil && l.sink != nil && l.sink.handler != nil &&
+		(l.forced || l.level >= l.sink.level)
+}
+
+// With returns a logger adding the given fields to the entries, ignoring the
+// empty ones.
+func (l *levelLogger) With(fields map[string]string) *levelLogger {
+
+	if l == nil {
+		return discardLogger()
+	}
+
+	merged := make(map[string]string)
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		if v != "" {
+			merged[k] = v
+		}
+	}
+
+	c := *l
+	c.fields = merged
+	return &c
+}
+
+// at returns a logger of the given level, keeping the fields.
+func (l *levelLogger) at(level LogLevel) *levelLogger {
+	c := *l
+	c.level = level
+	return &c
+}
+
+// skip returns a logger reporting the caller of the given number of wrapper
+// functions as the origin of the entries.
+func (l *levelLogger) skip(depth int) *levelLogger {
+	c := *l
+	c.depth += depth
+	return &c
+}
+
+// log is only called by Println and Printf, the caller being two frames up.
+func (l *levelLogger) log(msg string) {
+	if !l.enabled() {
+		return
+	}
+	_, file, line, _ := runtime.Caller(2 + l.depth)
+	l.sink.handler.Handle(LogEntry{
+		Time:    time.Now(),
+		Level:   l.level,
+		Message: msg,
+		File:    file,
+		Line:    line,
+		Fields:  l.fields,
+	})
+}
+
+// Println logs the operands formatted as by fmt.Println.
+func (l *levelLogger) Println(v ...interface{}) {
+	if l.enabled() {
+		l.log(strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
+	}
+}
+
+// Printf logs the operands formatted as by fmt.Printf.
+func (l *levelLogger) Printf(format string, v ...interface{}) {
+	if l.enabled() {
+		l.log(strings.TrimSuffix(fmt.Sprintf(format, v...), "\n"))
+	}
+}
+
+// newLogSink returns the sink of the run's log output, writing to the given
+// writer unless a custom handler is configured.
+func newLogSink(cfg Config, w io.Writer, level LogLevel) *logSink {
+
+	sink := &logSink{handler: cfg.LogHandler, level: level}
+
+	if sink.handler == nil {
+		if cfg.LogFormat == "json" {
+			sink.handler = &jsonLogHandler{w: w}
+		} else {
+			sink.handler = newTextLogHandler(w, cfg.LogFlag)
+		}
+	}
+	return sink
+}
+
+// log returns the logger of the group, adding its region and name to the
+// entries.
+func (a *autoScalingGroup) log() *levelLogger {
+	fields := map[string]string{"asg": a.name}
+	if a.region != nil {
+		fields["region"] = a.region.name
+	}
+	return logger.With(fields)
+}
+
+// log returns the logger of the region, adding its name to the entries.
+func (r *region) log() *levelLogger {
+	return logger.With(map[string]string{"region": r.name})
+}
+
+var instanceIDPattern = regexp.MustCompile(`^i-[0-9a-f]+$`)
+
+// actionFields returns the fields of an action taken on the group, including
+// the first instance ID found in its details.
+func (a *autoScalingGroup) actionFields(action string,
+	details ...interface{}) map[string]string {
+
+	fields := map[string]string{
+		"asg":    a.name,
+		"action": action,
+	}
+	if a.region != nil {
+		fields["region"] = a.region.name
+	}
+
+	for _, d := range details {
+		var s string
+		switch v := d.(type) {
+		case string:
+			s = v
+		case *string:
+			if v != nil {
+				s = *v
+			}
+		}
+		if instanceIDPattern.MatchString(s) {
+			fields["instance_id"] = s
+			break
+		}
+	}
+	return fields
+}
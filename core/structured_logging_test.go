@@ -0,0 +1,156 @@
+package autospotting
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func Test_levelLogger(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		format string
+		level  string
+		log    func(l *levelLogger)
+		want   string
+	}{
+		{
+			name: "text",
+			log: func(l *levelLogger) {
+				l.Println("Attaching", "i-1")
+			},
+			want: "Attaching i-1\n",
+		},
+		{
+			name: "text with fields",
+			log: func(l *levelLogger) {
+				l.With(map[string]string{"region": "us-east-1", "asg": "web",
+					"instance_id": ""}).Printf("Attaching %s\n", "i-1")
+			},
+			want: "Attaching i-1 asg=web region=us-east-1\n",
+		},
+		{
+			name:  "below the level",
+			level: "warn",
+			log: func(l *levelLogger) {
+				l.Println("Attaching", "i-1")
+			},
+		},
+		{
+			name:  "at the level",
+			level: "warn",
+			log: func(l *levelLogger) {
+				l.at(LevelError).Println("Failed")
+			},
+			want: "Failed\n",
+		},
+		{
+			name:   "json",
+			format: "json",
+			log: func(l *levelLogger) {
+				l.With(map[string]string{"action": "attached", "asg": "web"}).
+					Println("web attached \"i-1\"")
+			},
+			want: `{"level":"info","msg":"web attached \"i-1\"",` +
+				`"action":"attached","asg":"web"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			cfg := Config{LogFormat: tt.format}
+			level, _ := parseLogLevel(tt.level)
+			tt.log(newLogger(newLogSink(cfg, &out, level), LevelInfo))
+
+			got := out.String()
+			if tt.format == "json" && got != "" {
+				var entry map[string]string
+				if err := json.Unmarshal([]byte(got), &entry); err != nil {
+					t.Fatalf("logged invalid JSON %q: %v", got, err)
+				}
+				if entry["time"] == "" {
+					t.Errorf("logged %q without time", got)
+				}
+				delete(entry, "time")
+				var want map[string]string
+				json.Unmarshal([]byte(tt.want), &want)
+				if !reflect.DeepEqual(entry, want) {
+					t.Errorf("logged %v, want %v", entry, want)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("logged %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_textLogHandler_caller(t *testing.T) {
+
+	var out bytes.Buffer
+	l := newLogger(newLogSink(Config{LogFlag: log.Lshortfile}, &out, LevelInfo),
+		LevelInfo)
+
+	wrapper := func(msg string) {
+		l.skip(1).Println(msg)
+	}
+
+	l.Println("direct")
+	wrapper("wrapped")
+
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if !strings.HasPrefix(line, "structured_logging_test.go:") {
+			t.Errorf("logged %q, want the caller in the test file", line)
+		}
+	}
+}
+
+func Test_autoScalingGroup_actionFields(t *testing.T) {
+
+	a := &autoScalingGroup{name: "web", region: &region{name: "us-east-1"}}
+
+	got := a.actionFields("terminated", "on-demand instance",
+		aws.String("i-0123abcd"), "i-4567")
+	want := map[string]string{
+		"region":      "us-east-1",
+		"asg":         "web",
+		"action":      "terminated",
+		"instance_id": "i-0123abcd",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("actionFields() = %v, want %v", got, want)
+	}
+}
+
+func Test_autoScalingGroup_log(t *testing.T) {
+
+	var out bytes.Buffer
+	logger = newLogger(newLogSink(Config{LogFormat: "json"}, &out, LevelInfo),
+		LevelInfo)
+	defer func() { logger = discardLogger() }()
+
+	r := &region{name: "us-east-1"}
+	a := &autoScalingGroup{name: "web", region: r}
+
+	a.log().Println("group entry")
+	r.log().Println("region entry")
+
+	want := []string{
+		`{"time":`,
+		`"msg":"group entry","asg":"web","region":"us-east-1"}`,
+		`"msg":"region entry","region":"us-east-1"}`,
+	}
+	for _, w := range want {
+		if !strings.Contains(out.String(), w) {
+			t.Errorf("logged %q, want it to contain %q", out.String(), w)
+		}
+	}
+}
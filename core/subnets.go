@@ -0,0 +1,108 @@
+package autospotting
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// groupSubnets returns the subnets the group launches its instances in.
+func (a *autoScalingGroup) groupSubnets() []string {
+
+	var subnets []string
+
+	if a.VPCZoneIdentifier == nil {
+		return subnets
+	}
+
+	for _, subnet := range strings.Split(*a.VPCZoneIdentifier, ",") {
+		if subnet = strings.TrimSpace(subnet); subnet != "" {
+			subnets = append(subnets, subnet)
+		}
+	}
+	return subnets
+}
+
+// pickSubnet returns the subnet of the availability zone to launch in,
+// preferring the given subnet if it still has free IP addresses, or the one
+// having the most free addresses otherwise. It returns an empty string if none
+// of the subnets of the availability zone has any free address.
+func pickSubnet(subnets []*ec2.Subnet, az, preferred string) string {
+
+	var eligible []*ec2.Subnet
+
+	for _, s := range subnets {
+		if s.SubnetId == nil || s.AvailabilityZone == nil ||
+			*s.AvailabilityZone != az {
+			continue
+		}
+		if s.AvailableIpAddressCount == nil || *s.AvailableIpAddressCount <= 0 {
+			continue
+		}
+		if *s.SubnetId == preferred {
+			return preferred
+		}
+		eligible = append(eligible, s)
+	}
+
+	if len(eligible) == 0 {
+		return ""
+	}
+
+	sort.SliceStable(eligible, func(i, j int) bool {
+		if *eligible[i].AvailableIpAddressCount !=
+			*eligible[j].AvailableIpAddressCount {
+			return *eligible[i].AvailableIpAddressCount >
+				*eligible[j].AvailableIpAddressCount
+		}
+		return *eligible[i].SubnetId < *eligible[j].SubnetId
+	})
+
+	return *eligible[0].SubnetId
+}
+
+// launchSubnet returns the subnet to launch the spot instance in, and whether
+// there is any subnet having free IP addresses in the availability zone. The
+// base instance's subnet is kept when the free addresses can't be determined.
+func (a *autoScalingGroup) launchSubnet(az string,
+	baseInstance *instance) (*string, bool) {
+
+	preferred := ""
+	if baseInstance.SubnetId != nil {
+		preferred = *baseInstance.SubnetId
+	}
+
+	subnets := a.groupSubnets()
+	if len(subnets) == 0 {
+		if preferred == "" {
+			return baseInstance.SubnetId, true
+		}
+		subnets = []string{preferred}
+	}
+
+	resp, err := a.region.services.ec2.DescribeSubnets(&ec2.DescribeSubnetsInput{
+		SubnetIds: aws.StringSlice(subnets),
+	})
+
+	if err != nil {
+		a.log().at(LevelWarn).Println(a.name,
+			"Couldn't check the free IP addresses of the", "subnets", subnets,
+			err.Error())
+		return baseInstance.SubnetId, true
+	}
+
+	subnet := pickSubnet(resp.Subnets, az, preferred)
+	if subnet == "" {
+		a.log().Println(a.name, "None of the subnets", subnets, "has free IP",
+			"addresses in", az)
+		return nil, false
+	}
+
+	if subnet != preferred {
+		a.log().Println(a.name, "Launching in subnet", subnet, "since", preferred,
+			"has no free IP addresses")
+	}
+	return aws.String(subnet), true
+}
@@ -0,0 +1,72 @@
+package autospotting
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func TestPickSubnet(t *testing.T) {
+
+	subnet := func(id, az string, free int64) *ec2.Subnet {
+		return &ec2.Subnet{
+			SubnetId:                aws.String(id),
+			AvailabilityZone:        aws.String(az),
+			AvailableIpAddressCount: aws.Int64(free),
+		}
+	}
+
+	tests := []struct {
+		name      string
+		subnets   []*ec2.Subnet
+		preferred string
+		expected  string
+	}{
+		{
+			name: "preferred subnet has free addresses",
+			subnets: []*ec2.Subnet{
+				subnet("subnet-1", "us-east-1a", 3),
+				subnet("subnet-2", "us-east-1a", 100),
+			},
+			preferred: "subnet-1",
+			expected:  "subnet-1",
+		},
+		{
+			name: "preferred subnet is full",
+			subnets: []*ec2.Subnet{
+				subnet("subnet-1", "us-east-1a", 0),
+				subnet("subnet-2", "us-east-1a", 10),
+				subnet("subnet-3", "us-east-1a", 50),
+			},
+			preferred: "subnet-1",
+			expected:  "subnet-3",
+		},
+		{
+			name: "subnets from other zones are ignored",
+			subnets: []*ec2.Subnet{
+				subnet("subnet-1", "us-east-1a", 0),
+				subnet("subnet-2", "us-east-1b", 100),
+			},
+			preferred: "subnet-1",
+			expected:  "",
+		},
+		{
+			name: "ties are broken by subnet ID",
+			subnets: []*ec2.Subnet{
+				subnet("subnet-2", "us-east-1a", 10),
+				subnet("subnet-1", "us-east-1a", 10),
+			},
+			expected: "subnet-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pickSubnet(tt.subnets, "us-east-1a",
+				tt.preferred); got != tt.expected {
+				t.Errorf("pickSubnet() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
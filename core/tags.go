@@ -0,0 +1,138 @@
+package autospotting
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// parseTags converts a comma separated list of key=value pairs into EC2 tags,
+// skipping any malformed entries.
+func parseTags(list string) []*ec2.Tag {
+	var tags []*ec2.Tag
+
+	for _, pair := range strings.Split(list, ",") {
+
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+
+		if len(kv) != 2 || kv[0] == "" {
+			continue
+		}
+		tags = append(tags, &ec2.Tag{
+			Key:   aws.String(strings.TrimSpace(kv[0])),
+			Value: aws.String(strings.TrimSpace(kv[1])),
+		})
+	}
+	return tags
+}
+
+// tagTemplateData is the data the templates in tag values are executed on.
+type tagTemplateData struct {
+	AutoScalingGroup string
+	Region           string
+
+	// when the spot instance was requested, in RFC 3339 format and UTC
+	Timestamp string
+
+	// the build of AutoSpotting
+	Version string
+}
+
+// renderTags executes the templates found in the tag values, keeping the
+// values whose template is invalid as they are.
+func renderTags(tags []*ec2.Tag, data tagTemplateData) []*ec2.Tag {
+	var rendered []*ec2.Tag
+
+	for _, tag := range tags {
+		value := *tag.Value
+
+		if strings.Contains(value, "{{") {
+			var out bytes.Buffer
+			t, err := template.New(*tag.Key).Option("missingkey=error").Parse(value)
+			if err == nil {
+				err = t.Execute(&out, data)
+			}
+			if err != nil {
+				logger.at(LevelWarn).Println(
+					"Couldn't render the value of the tag",
+					*tag.Key, err.Error())
+			} else {
+				value = out.String()
+			}
+		}
+
+		rendered = append(rendered, &ec2.Tag{
+			Key:   tag.Key,
+			Value: aws.String(value),
+		})
+	}
+	return rendered
+}
+
+// spotTags returns the configured spot tags of the group, rendered for a spot
+// instance requested at the given time.
+func (a *autoScalingGroup) spotTags(requested time.Time) []*ec2.Tag {
+	return renderTags(parseTags(a.region.conf.SpotTags), tagTemplateData{
+		AutoScalingGroup: a.name,
+		Region:           a.region.name,
+		Timestamp:        requested.UTC().Format(time.RFC3339),
+		Version:          a.region.conf.BuildNumber,
+	})
+}
+
+// propagatedTags returns the group's tags propagated at launch to its
+// instances, such as its cost allocation tags, converted to EC2 tags.
+func (a *autoScalingGroup) propagatedTags() []*ec2.Tag {
+	var tags []*ec2.Tag
+
+	for _, tag := range a.Tags {
+		if tag.Key == nil || tag.Value == nil ||
+			tag.PropagateAtLaunch == nil || !*tag.PropagateAtLaunch {
+			continue
+		}
+		tags = append(tags, &ec2.Tag{Key: tag.Key, Value: tag.Value})
+	}
+	return filterReservedTags(tags)
+}
+
+// mergeTags appends the overrides to the base tags, the values set in the
+// overrides win for keys present in both lists, since EC2 doesn't accept
+// duplicate keys in the same CreateTags call.
+func mergeTags(base []*ec2.Tag, overrides []*ec2.Tag) []*ec2.Tag {
+	var merged []*ec2.Tag
+
+	for _, tag := range base {
+		if findTagValue(overrides, *tag.Key) == nil {
+			merged = append(merged, tag)
+		}
+	}
+	return append(merged, overrides...)
+}
+
+// filterReservedTags removes the reserved tags, which start with the "aws:"
+// prefix and can't be set by users.
+func filterReservedTags(tags []*ec2.Tag) []*ec2.Tag {
+	var filteredTags []*ec2.Tag
+
+	for _, tag := range tags {
+		if !strings.HasPrefix(*tag.Key, "aws:") {
+			filteredTags = append(filteredTags, tag)
+		}
+	}
+	return filteredTags
+}
+
+// findTagValue returns the value of the tag having the given key, or nil if
+// there is no such tag.
+func findTagValue(tags []*ec2.Tag, key string) *string {
+	for _, tag := range tags {
+		if tag.Key != nil && *tag.Key == key {
+			return tag.Value
+		}
+	}
+	return nil
+}
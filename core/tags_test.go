@@ -0,0 +1,134 @@
+package autospotting
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_parseTags(t *testing.T) {
+	tests := []struct {
+		name string
+		list string
+		want []*ec2.Tag
+	}{
+		{name: "Empty list",
+			list: "",
+			want: nil,
+		},
+		{name: "Multiple tags with whitespace",
+			list: "team=payments, cost-center = 1234",
+			want: []*ec2.Tag{
+				{Key: aws.String("team"), Value: aws.String("payments")},
+				{Key: aws.String("cost-center"), Value: aws.String("1234")},
+			},
+		},
+		{name: "Malformed entries are skipped",
+			list: "team,=x,env=prod=1",
+			want: []*ec2.Tag{
+				{Key: aws.String("env"), Value: aws.String("prod=1")},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseTags(tt.list); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_mergeTags(t *testing.T) {
+	base := []*ec2.Tag{
+		{Key: aws.String("Name"), Value: aws.String("web")},
+		{Key: aws.String("team"), Value: aws.String("old")},
+	}
+	overrides := []*ec2.Tag{
+		{Key: aws.String("team"), Value: aws.String("payments")},
+	}
+
+	want := []*ec2.Tag{
+		{Key: aws.String("Name"), Value: aws.String("web")},
+		{Key: aws.String("team"), Value: aws.String("payments")},
+	}
+
+	if got := mergeTags(base, overrides); !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeTags() = %v, want %v", got, want)
+	}
+}
+
+func Test_propagatedTags(t *testing.T) {
+	a := autoScalingGroup{Group: &autoscaling.Group{
+		Tags: []*autoscaling.TagDescription{
+			{Key: aws.String("cost-center"), Value: aws.String("1234"),
+				PropagateAtLaunch: aws.Bool(true)},
+			{Key: aws.String("spot-enabled"), Value: aws.String("true"),
+				PropagateAtLaunch: aws.Bool(false)},
+			{Key: aws.String("aws:cloudformation:stack-name"),
+				Value: aws.String("web"), PropagateAtLaunch: aws.Bool(true)},
+		},
+	}}
+
+	want := []*ec2.Tag{
+		{Key: aws.String("cost-center"), Value: aws.String("1234")},
+	}
+
+	if got := a.propagatedTags(); !reflect.DeepEqual(got, want) {
+		t.Errorf("propagatedTags() = %v, want %v", got, want)
+	}
+}
+
+func Test_renderTags(t *testing.T) {
+
+	data := tagTemplateData{
+		AutoScalingGroup: "web",
+		Region:           "eu-west-1",
+		Timestamp:        "2020-01-02T03:04:05Z",
+		Version:          "42",
+	}
+
+	tests := []struct {
+		name string
+		tags []*ec2.Tag
+		want []*ec2.Tag
+	}{
+		{name: "Plain values are kept",
+			tags: []*ec2.Tag{{Key: aws.String("team"), Value: aws.String("payments")}},
+			want: []*ec2.Tag{{Key: aws.String("team"), Value: aws.String("payments")}},
+		},
+		{name: "Templates are rendered",
+			tags: []*ec2.Tag{
+				{Key: aws.String("owner"),
+					Value: aws.String("{{.AutoScalingGroup}}-{{.Region}}")},
+				{Key: aws.String("launched"),
+					Value: aws.String("{{.Timestamp}} by build {{.Version}}")},
+			},
+			want: []*ec2.Tag{
+				{Key: aws.String("owner"), Value: aws.String("web-eu-west-1")},
+				{Key: aws.String("launched"),
+					Value: aws.String("2020-01-02T03:04:05Z by build 42")},
+			},
+		},
+		{name: "Invalid templates are kept as they are",
+			tags: []*ec2.Tag{
+				{Key: aws.String("a"), Value: aws.String("{{.Missing}}")},
+				{Key: aws.String("b"), Value: aws.String("{{.Region")},
+			},
+			want: []*ec2.Tag{
+				{Key: aws.String("a"), Value: aws.String("{{.Missing}}")},
+				{Key: aws.String("b"), Value: aws.String("{{.Region")},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderTags(tt.tags, data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("renderTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
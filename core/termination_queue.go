@@ -0,0 +1,316 @@
+package autospotting
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+const (
+	// when the queued on-demand instance is terminated, in RFC 3339 format
+	terminateAfterTag = "autospotting-terminate-after"
+
+	// the group the queued on-demand instance was removed from
+	replacedFromTag = "autospotting-replaced-from"
+
+	// set on a queued instance in order to restore it to its group
+	cancelTerminationTag = "autospotting-cancel-termination"
+)
+
+// queuesTerminations tells if the replaced on-demand instances are queued for
+// termination rather than terminated right away.
+func (a *autoScalingGroup) queuesTerminations() bool {
+	return a.region.conf.StopGracePeriod > 0 || a.region.conf.TerminationDelay > 0
+}
+
+// retireOnDemandInstance terminates the replaced on-demand instance, or queues
+// it for termination, and tells if it was queued.
+func (a *autoScalingGroup) retireOnDemandInstance(inst *instance) bool {
+
+	stop := a.region.conf.StopGracePeriod > 0
+
+	delay := a.region.conf.TerminationDelay
+	if stop {
+		delay = a.region.conf.StopGracePeriod
+	}
+
+	if delay <= 0 {
+		a.terminateInstance(inst)
+		return false
+	}
+
+	until := time.Now().Add(delay).UTC().Format(time.RFC3339)
+
+	a.tagInstance(inst.InstanceId, []*ec2.Tag{
+		{Key: aws.String(terminateAfterTag), Value: aws.String(until)},
+		{Key: aws.String(replacedFromTag), Value: aws.String(a.name)},
+	})
+
+	if !stop {
+		a.recordAction("queued", "on-demand instance", *inst.InstanceId,
+			"for termination after", until)
+		return true
+	}
+
+	input := &ec2.StopInstancesInput{InstanceIds: []*string{inst.InstanceId}}
+
+	if a.skipsCall("StopInstances", input) {
+		return true
+	}
+
+	_, err := a.region.services.ec2.StopInstances(input)
+
+	if err != nil {
+		a.log().at(LevelWarn).Println(a.name, "Failed to stop",
+			*inst.InstanceId,
+			err.Error(), "terminating it instead")
+		a.terminateInstance(inst)
+		return false
+	}
+
+	a.recordAction("stopped", "on-demand instance", *inst.InstanceId,
+		"it will be terminated after", until)
+	return true
+}
+
+// terminateAfter returns when the queued on-demand instance should be
+// terminated, or the zero time if it wasn't queued by AutoSpotting.
+func terminateAfter(inst *ec2.Instance) time.Time {
+
+	tag := findTagValue(inst.Tags, terminateAfterTag)
+	if tag == nil {
+		return time.Time{}
+	}
+
+	until, err := time.Parse(time.RFC3339, *tag)
+	if err != nil {
+		return time.Time{}
+	}
+	return until
+}
+
+// processTerminationQueue terminates the queued on-demand instances of the
+// region whose time has come, and restores those whose termination was
+// cancelled.
+func (r *region) processTerminationQueue() {
+
+	now := time.Now()
+
+	err := r.services.ec2.DescribeInstancesPages(&ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("tag-key"),
+				Values: []*string{aws.String(terminateAfterTag)},
+			},
+			{
+				Name: aws.String("instance-state-name"),
+				Values: []*string{aws.String("pending"), aws.String("running"),
+					aws.String("stopped")},
+			},
+		},
+	}, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, res := range page.Reservations {
+			for _, inst := range res.Instances {
+
+				if findTagValue(inst.Tags, cancelTerminationTag) != nil {
+					r.restoreQueuedInstance(inst)
+					continue
+				}
+
+				until := terminateAfter(inst)
+				if until.IsZero() || now.Before(until) {
+					continue
+				}
+
+				r.log().Println(r.name, "The termination delay of the queued",
+					"instance", *inst.InstanceId, "has passed, terminating it")
+				r.queuedInstanceGroup(inst).terminateInstance(
+					&instance{Instance: inst})
+			}
+		}
+		return true
+	})
+
+	if err != nil {
+		r.log().at(LevelError).Println(r.name,
+			"Failed to describe the queued instances", err.Error())
+	}
+}
+
+// queuedInstanceGroup returns the group the queued instance was removed from,
+// as scanned in this run if it's still enabled, so that its settings such as
+// dry_run apply to the queued instance.
+func (r *region) queuedInstanceGroup(inst *ec2.Instance) *autoScalingGroup {
+
+	name := ""
+	if group := findTagValue(inst.Tags, replacedFromTag); group != nil {
+		name = *group
+	}
+
+	for i := range r.enabledASGs {
+		if r.enabledASGs[i].name == name {
+			return &r.enabledASGs[i]
+		}
+	}
+
+	return &autoScalingGroup{
+		Group:  &autoscaling.Group{AutoScalingGroupName: aws.String(name)},
+		name:   name,
+		region: r,
+	}
+}
+
+// restoreQueuedInstance starts the queued instance if it was stopped, and once
+// running attaches it back to its group, pausing the group.
+func (r *region) restoreQueuedInstance(inst *ec2.Instance) {
+
+	group := findTagValue(inst.Tags, replacedFromTag)
+	if group == nil {
+		r.log().Println(r.name, "The group of the queued instance",
+			*inst.InstanceId, "is unknown, not restoring it")
+		return
+	}
+
+	a := r.queuedInstanceGroup(inst)
+
+	switch *inst.State.Name {
+	case ec2.InstanceStateNameStopped:
+		r.log().Println(r.name, "Starting the queued instance",
+			*inst.InstanceId,
+			"in order to restore it to", *group)
+
+		input := &ec2.StartInstancesInput{InstanceIds: []*string{inst.InstanceId}}
+		if a.skipsCall("StartInstances", input) {
+			return
+		}
+
+		_, err := r.services.ec2.StartInstances(input)
+		if err != nil {
+			r.log().at(LevelError).Println(r.name, "Failed to start",
+				*inst.InstanceId, err.Error())
+		}
+		return
+
+	case ec2.InstanceStateNameRunning:
+
+	default:
+		return
+	}
+
+	// the dry runs stop after logging the call returning the instance
+	if a.returnQueuedInstance(inst.InstanceId) != nil || a.dryRun() {
+		return
+	}
+
+	_, err := r.services.ec2.DeleteTags(&ec2.DeleteTagsInput{
+		Resources: []*string{inst.InstanceId},
+		Tags: []*ec2.Tag{
+			{Key: aws.String(terminateAfterTag)},
+			{Key: aws.String(replacedFromTag)},
+			{Key: aws.String(cancelTerminationTag)},
+		},
+	})
+	if err != nil {
+		r.log().at(LevelError).Println(r.name,
+			"Failed to untag the restored instance", *inst.InstanceId,
+			err.Error())
+	}
+
+	a.recordAction("restored", "on-demand instance", *inst.InstanceId,
+		"after its termination was cancelled, pausing the group")
+	a.auditRollback(inst, 0, "", "after its termination was cancelled")
+	a.setGroupTag(pausedTag, "on-demand instance "+*inst.InstanceId+
+		" was restored")
+}
+
+// returnQueuedInstance puts the queued instance back in service in its group,
+// moving it out of Standby if it's still a member of the group, otherwise
+// attaching it again.
+func (a *autoScalingGroup) returnQueuedInstance(instanceID *string) error {
+
+	resp, err := a.region.services.autoScaling.DescribeAutoScalingInstances(
+		&autoscaling.DescribeAutoScalingInstancesInput{
+			InstanceIds: []*string{instanceID},
+		})
+	if err != nil {
+		a.log().at(LevelError).Println(a.region.name,
+			"Failed to describe the queued instance", *instanceID, err.Error())
+		return err
+	}
+
+	for _, member := range resp.AutoScalingInstances {
+		if aws.StringValue(member.AutoScalingGroupName) == a.name &&
+			aws.StringValue(member.LifecycleState) ==
+				autoscaling.LifecycleStateStandby {
+			return a.exitStandby(instanceID)
+		}
+	}
+
+	input := &autoscaling.AttachInstancesInput{
+		AutoScalingGroupName: aws.String(a.name),
+		InstanceIds:          []*string{instanceID},
+	}
+
+	if a.skipsCall("AttachInstances", input) {
+		return nil
+	}
+
+	_, err = a.region.services.autoScaling.AttachInstances(input)
+	if err != nil {
+		a.log().at(LevelError).Println(a.region.name,
+			"Failed to attach the queued instance", *instanceID, "back to",
+			a.name, err.Error())
+	}
+	return err
+}
+
+// serveRestore handles POST /instances/{id}/restore?region={region}, cancelling
+// the pending termination of a queued instance.
+func serveRestore(w http.ResponseWriter, r *http.Request) {
+
+	path := strings.TrimPrefix(r.URL.Path, "/instances/")
+	if !strings.HasSuffix(path, "/restore") {
+		http.NotFound(w, r)
+		return
+	}
+	id := strings.TrimSuffix(path, "/restore")
+
+	if id == "" || strings.Contains(id, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	regionName := r.URL.Query().Get("region")
+	if regionName == "" {
+		http.Error(w, "Missing region", http.StatusBadRequest)
+		return
+	}
+
+	svc := ec2.New(instrumentSession(
+		newSession(&aws.Config{Region: aws.String(regionName)})))
+
+	_, err := svc.CreateTags(&ec2.CreateTagsInput{
+		Resources: []*string{aws.String(id)},
+		Tags: []*ec2.Tag{{
+			Key:   aws.String(cancelTerminationTag),
+			Value: aws.String(time.Now().UTC().Format(time.RFC3339)),
+		}},
+	})
+
+	if err != nil {
+		logger.at(LevelError).Println("Failed to cancel the termination of", id,
+			"in", regionName, err.Error())
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,82 @@
+package autospotting
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_terminateAfter(t *testing.T) {
+
+	tests := []struct {
+		name string
+		tags []*ec2.Tag
+		want time.Time
+	}{
+		{name: "not queued by AutoSpotting",
+			want: time.Time{},
+		},
+		{name: "queued by AutoSpotting",
+			tags: []*ec2.Tag{{
+				Key:   aws.String(terminateAfterTag),
+				Value: aws.String("2020-01-02T03:04:05Z"),
+			}},
+			want: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		{name: "invalid time",
+			tags: []*ec2.Tag{{
+				Key:   aws.String(terminateAfterTag),
+				Value: aws.String("tomorrow"),
+			}},
+			want: time.Time{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := terminateAfter(&ec2.Instance{Tags: tt.tags})
+			if !got.Equal(tt.want) {
+				t.Errorf("terminateAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_serveRestore(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		wantStatus int
+	}{
+		{name: "Missing region",
+			method:     "POST",
+			url:        "/instances/i-1/restore",
+			wantStatus: http.StatusBadRequest,
+		},
+		{name: "Unsupported method",
+			method:     "GET",
+			url:        "/instances/i-1/restore?region=us-east-1",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{name: "Unknown path",
+			method:     "POST",
+			url:        "/instances/i-1",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			newServeMux().ServeHTTP(rec, httptest.NewRequest(tt.method, tt.url, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
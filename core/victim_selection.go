@@ -0,0 +1,146 @@
+package autospotting
+
+import (
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// VictimCandidate is a running on-demand instance which can be replaced.
+type VictimCandidate struct {
+	*ec2.Instance
+
+	// hourly on-demand price of the instance
+	Price float64
+
+	// number of the group's running on-demand instances in the instance's
+	// availability zone
+	ZoneOnDemandInstances int
+}
+
+// VictimSelector picks the on-demand instance to replace next.
+type VictimSelector interface {
+
+	// SelectVictim returns the index of the chosen candidate, or -1 for not
+	// replacing any of them. It's never called without candidates.
+	SelectVictim(candidates []VictimCandidate) int
+}
+
+// VictimSelectorFunc adapts a function to the VictimSelector interface.
+type VictimSelectorFunc func(candidates []VictimCandidate) int
+
+// SelectVictim calls the function.
+func (f VictimSelectorFunc) SelectVictim(candidates []VictimCandidate) int {
+	return f(candidates)
+}
+
+var builtinVictimSelectors = map[string]VictimSelector{
+	"any":              VictimSelectorFunc(selectAnyVictim),
+	"oldest":           VictimSelectorFunc(selectOldestVictim),
+	"cheapest-to-lose": VictimSelectorFunc(selectCheapestVictim),
+	"az-balanced":      VictimSelectorFunc(selectZoneBalancedVictim),
+}
+
+func selectAnyVictim(candidates []VictimCandidate) int {
+	return 0
+}
+
+// launchedBefore tells if the first candidate was launched before the second.
+func launchedBefore(c, other VictimCandidate) bool {
+	return c.LaunchTime != nil &&
+		(other.LaunchTime == nil || c.LaunchTime.Before(*other.LaunchTime))
+}
+
+func selectOldestVictim(candidates []VictimCandidate) int {
+
+	chosen := 0
+	for i, c := range candidates {
+		if launchedBefore(c, candidates[chosen]) {
+			chosen = i
+		}
+	}
+	return chosen
+}
+
+func selectCheapestVictim(candidates []VictimCandidate) int {
+
+	chosen := 0
+	for i, c := range candidates {
+		best := candidates[chosen]
+		if c.Price < best.Price ||
+			(c.Price == best.Price && launchedBefore(best, c)) {
+			chosen = i
+		}
+	}
+	return chosen
+}
+
+func selectZoneBalancedVictim(candidates []VictimCandidate) int {
+
+	chosen := 0
+	for i, c := range candidates {
+		if c.ZoneOnDemandInstances > candidates[chosen].ZoneOnDemandInstances {
+			chosen = i
+		}
+	}
+	return chosen
+}
+
+// victimSelector returns the group's victim selector, falling back to the
+// first on-demand instance for unknown selectors.
+func (a *autoScalingGroup) victimSelector() VictimSelector {
+
+	name := a.stringSetting("victim_selection")
+
+	if a.region != nil {
+		if s, ok := a.region.conf.VictimSelectors[name]; ok && s != nil {
+			return s
+		}
+	}
+	if s, ok := builtinVictimSelectors[name]; ok {
+		return s
+	}
+
+	if name != "" {
+		a.log().Println(a.name, "Unknown victim selection", name,
+			"replacing any on-demand instance")
+	}
+	return builtinVictimSelectors["any"]
+}
+
+// selectVictim returns the running on-demand instance to replace, from the
+// given availability zone if set, or nil if there is none.
+func (a *autoScalingGroup) selectVictim(availabilityZone *string) *instance {
+
+	zoneCounts := make(map[string]int)
+	var running []*instance
+
+	for _, i := range a.instances.catalog {
+		if *i.State.Name != "running" || i.isSpot() {
+			continue
+		}
+		zoneCounts[*i.Placement.AvailabilityZone]++
+
+		if availabilityZone == nil ||
+			*availabilityZone == *i.Placement.AvailabilityZone {
+			running = append(running, i)
+		}
+	}
+
+	if len(running) == 0 {
+		return nil
+	}
+
+	candidates := make([]VictimCandidate, len(running))
+	for n, i := range running {
+		candidates[n] = VictimCandidate{
+			Instance:              i.Instance,
+			Price:                 i.price,
+			ZoneOnDemandInstances: zoneCounts[*i.Placement.AvailabilityZone],
+		}
+	}
+
+	chosen := a.victimSelector().SelectVictim(candidates)
+	if chosen < 0 || chosen >= len(running) {
+		return nil
+	}
+	return running[chosen]
+}
@@ -0,0 +1,101 @@
+package autospotting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_autoScalingGroup_selectVictim(t *testing.T) {
+
+	now := time.Now()
+
+	newInstance := func(id, az string, price float64, age time.Duration,
+		spot bool) *instance {
+		i := &instance{price: price, Instance: &ec2.Instance{
+			InstanceId: aws.String(id),
+			LaunchTime: aws.Time(now.Add(-age)),
+			State:      &ec2.InstanceState{Name: aws.String("running")},
+			Placement:  &ec2.Placement{AvailabilityZone: aws.String(az)},
+		}}
+		if spot {
+			i.InstanceLifecycle = aws.String("spot")
+		}
+		return i
+	}
+
+	catalog := map[string]*instance{
+		"i-a1": newInstance("i-a1", "us-east-1a", 0.2, time.Hour, false),
+		"i-a2": newInstance("i-a2", "us-east-1a", 0.1, 2*time.Hour, true),
+		"i-b1": newInstance("i-b1", "us-east-1b", 0.1, 3*time.Hour, false),
+		"i-b2": newInstance("i-b2", "us-east-1b", 0.1, time.Minute, false),
+		"i-b3": newInstance("i-b3", "us-east-1b", 0.3, 4*time.Hour, false),
+	}
+
+	youngest := VictimSelectorFunc(func(candidates []VictimCandidate) int {
+		chosen := 0
+		for i, c := range candidates {
+			if c.LaunchTime.After(*candidates[chosen].LaunchTime) {
+				chosen = i
+			}
+		}
+		return chosen
+	})
+
+	tests := []struct {
+		name      string
+		selection string
+		az        *string
+		want      []string
+	}{
+		{name: "any", selection: "any",
+			want: []string{"i-a1", "i-b1", "i-b2", "i-b3"}},
+		{name: "oldest", selection: "oldest", want: []string{"i-b3"}},
+		{name: "oldest in zone", selection: "oldest",
+			az: aws.String("us-east-1a"), want: []string{"i-a1"}},
+		{name: "cheapest to lose", selection: "cheapest-to-lose",
+			want: []string{"i-b2"}},
+		{name: "zone balanced", selection: "az-balanced",
+			want: []string{"i-b1", "i-b2", "i-b3"}},
+		{name: "library selector", selection: "youngest",
+			want: []string{"i-b2"}},
+		{name: "unknown selector", selection: "newest",
+			want: []string{"i-a1", "i-b1", "i-b2", "i-b3"}},
+		{name: "no on-demand instance in zone", selection: "oldest",
+			az: aws.String("us-east-1c")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &autoScalingGroup{
+				Group: &autoscaling.Group{Tags: []*autoscaling.TagDescription{{
+					Key:   aws.String("victim_selection"),
+					Value: aws.String(tt.selection),
+				}}},
+				region: &region{conf: Config{
+					VictimSelectors: map[string]VictimSelector{"youngest": youngest},
+				}},
+				instances: instances{catalog: catalog},
+			}
+
+			got := a.selectVictim(tt.az)
+
+			if got == nil {
+				if len(tt.want) > 0 {
+					t.Errorf("selectVictim() = nil, want one of %v", tt.want)
+				}
+				return
+			}
+			found := false
+			for _, id := range tt.want {
+				found = found || *got.InstanceId == id
+			}
+			if !found {
+				t.Errorf("selectVictim() = %s, want one of %v", *got.InstanceId,
+					tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package autospotting
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// persistentVolumes returns the IDs of the instance's EBS volumes which are not
+// deleted when the instance is terminated.
+func (it *instance) persistentVolumes() []string {
+	var volumes []string
+
+	for _, bdm := range it.BlockDeviceMappings {
+		if bdm.Ebs != nil && bdm.Ebs.VolumeId != nil &&
+			bdm.Ebs.DeleteOnTermination != nil && !*bdm.Ebs.DeleteOnTermination {
+			volumes = append(volumes, *bdm.Ebs.VolumeId)
+		}
+	}
+	return volumes
+}
+
+// applyDeleteOnTermination makes the DeleteOnTermination flags of the spot
+// block device mappings consistent with the volumes of the original instance
+// attached on the same devices, warning about any mismatch with the launch
+// configuration.
+func applyDeleteOnTermination(bdms []*ec2.BlockDeviceMapping,
+	baseInstance *instance) {
+
+	original := make(map[string]*bool)
+	for _, bdm := range baseInstance.BlockDeviceMappings {
+		if bdm.DeviceName != nil && bdm.Ebs != nil {
+			original[*bdm.DeviceName] = bdm.Ebs.DeleteOnTermination
+		}
+	}
+
+	for _, bdm := range bdms {
+		if bdm.DeviceName == nil || bdm.Ebs == nil {
+			continue
+		}
+
+		flag := original[*bdm.DeviceName]
+		if flag == nil {
+			continue
+		}
+
+		if bdm.Ebs.DeleteOnTermination != nil &&
+			*bdm.Ebs.DeleteOnTermination != *flag {
+			logger.at(LevelWarn).Println("WARNING: the launch configuration sets",
+				"DeleteOnTermination to", *bdm.Ebs.DeleteOnTermination, "for",
+				*bdm.DeviceName, "but it's", *flag, "on the original instance",
+				*baseInstance.InstanceId, "using the latter")
+		}
+		bdm.Ebs.DeleteOnTermination = aws.Bool(*flag)
+	}
+}
+
+// volumeTagsByDevice returns the user tags of the instance's EBS volumes,
+// keyed by device name.
+func (r *region) volumeTagsByDevice(instanceID *string) map[string][]*ec2.Tag {
+
+	inst := r.instances.get(*instanceID)
+	if inst == nil {
+		return nil
+	}
+
+	devices := make(map[string]string)
+	var volumeIDs []*string
+
+	for _, bdm := range inst.BlockDeviceMappings {
+		if bdm.DeviceName != nil && bdm.Ebs != nil && bdm.Ebs.VolumeId != nil {
+			devices[*bdm.Ebs.VolumeId] = *bdm.DeviceName
+			volumeIDs = append(volumeIDs, bdm.Ebs.VolumeId)
+		}
+	}
+
+	if len(volumeIDs) == 0 {
+		return nil
+	}
+
+	resp, err := r.services.ec2.DescribeVolumes(&ec2.DescribeVolumesInput{
+		VolumeIds: volumeIDs,
+	})
+
+	if err != nil {
+		r.log().at(LevelError).Println(r.name,
+			"Failed to describe the volumes of instance", *instanceID,
+			err.Error())
+		return nil
+	}
+
+	tags := make(map[string][]*ec2.Tag)
+	for _, v := range resp.Volumes {
+		tags[devices[*v.VolumeId]] = filterReservedTags(v.Tags)
+	}
+	return tags
+}
+
+// tagInstanceVolumes sets the given tags on all the EBS volumes attached to an
+// instance, along with the device tags of the volume attached on the same
+// device of the original instance.
+func (r *region) tagInstanceVolumes(instanceID *string, tags []*ec2.Tag,
+	deviceTags map[string][]*ec2.Tag) {
+
+	svc := r.services.ec2
+
+	resp, err := svc.DescribeInstances(&ec2.DescribeInstancesInput{
+		InstanceIds: []*string{instanceID},
+	})
+
+	if err != nil {
+		r.log().at(LevelError).Println(r.name, "Failed to describe instance",
+			*instanceID, err.Error())
+		return
+	}
+
+	for _, res := range resp.Reservations {
+		for _, inst := range res.Instances {
+			for _, bdm := range inst.BlockDeviceMappings {
+
+				if bdm.Ebs == nil || bdm.Ebs.VolumeId == nil {
+					continue
+				}
+
+				volumeTags := tags
+				if bdm.DeviceName != nil {
+					volumeTags = mergeTags(deviceTags[*bdm.DeviceName], tags)
+				}
+
+				if len(volumeTags) == 0 {
+					continue
+				}
+
+				_, err = svc.CreateTags(&ec2.CreateTagsInput{
+					Resources: []*string{bdm.Ebs.VolumeId},
+					Tags:      volumeTags,
+				})
+
+				if err != nil {
+					r.log().at(LevelError).Println(r.name,
+						"Failed to tag volume", *bdm.Ebs.VolumeId,
+						"of instance", *instanceID, err.Error())
+					continue
+				}
+
+				r.log().Println(r.name, "Tagged volume", *bdm.Ebs.VolumeId,
+					"of instance", *instanceID)
+			}
+		}
+	}
+}
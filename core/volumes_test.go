@@ -0,0 +1,66 @@
+package autospotting
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func Test_applyDeleteOnTermination(t *testing.T) {
+
+	base := &instance{Instance: &ec2.Instance{
+		InstanceId: aws.String("i-1"),
+		BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
+			{
+				DeviceName: aws.String("/dev/xvda"),
+				Ebs:        &ec2.EbsInstanceBlockDevice{DeleteOnTermination: aws.Bool(true)},
+			},
+			{
+				DeviceName: aws.String("/dev/xvdb"),
+				Ebs:        &ec2.EbsInstanceBlockDevice{DeleteOnTermination: aws.Bool(false)},
+			},
+		},
+	}}
+
+	bdms := []*ec2.BlockDeviceMapping{
+		{DeviceName: aws.String("/dev/xvda"), Ebs: &ec2.EbsBlockDevice{}},
+		{
+			DeviceName: aws.String("/dev/xvdb"),
+			Ebs:        &ec2.EbsBlockDevice{DeleteOnTermination: aws.Bool(true)},
+		},
+		{DeviceName: aws.String("/dev/xvdc"), Ebs: &ec2.EbsBlockDevice{}},
+	}
+
+	applyDeleteOnTermination(bdms, base)
+
+	want := []*bool{aws.Bool(true), aws.Bool(false), nil}
+	for i, bdm := range bdms {
+		if !reflect.DeepEqual(bdm.Ebs.DeleteOnTermination, want[i]) {
+			t.Errorf("%s DeleteOnTermination = %v, want %v", *bdm.DeviceName,
+				aws.BoolValue(bdm.Ebs.DeleteOnTermination), aws.BoolValue(want[i]))
+		}
+	}
+}
+
+func Test_persistentVolumes(t *testing.T) {
+
+	inst := &instance{Instance: &ec2.Instance{
+		BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
+			{Ebs: &ec2.EbsInstanceBlockDevice{
+				VolumeId:            aws.String("vol-root"),
+				DeleteOnTermination: aws.Bool(true),
+			}},
+			{Ebs: &ec2.EbsInstanceBlockDevice{
+				VolumeId:            aws.String("vol-data"),
+				DeleteOnTermination: aws.Bool(false),
+			}},
+		},
+	}}
+
+	if got := inst.persistentVolumes(); !reflect.DeepEqual(got,
+		[]string{"vol-data"}) {
+		t.Errorf("persistentVolumes() = %v, want [vol-data]", got)
+	}
+}
@@ -0,0 +1,106 @@
+package autospotting
+
+import (
+	"sync"
+	"time"
+)
+
+var warmCache bootstrapCache
+
+// regionCatalog is the instance type catalog of a region and its spot prices.
+type regionCatalog struct {
+	info              map[string]instanceTypeInformation
+	spotPricesFetched time.Time
+	fetchedPools      map[string]bool
+	built             time.Time
+}
+
+type bootstrapCache struct {
+	sync.Mutex
+
+	regionNames    []string
+	regionsFetched time.Time
+
+	catalogs map[string]regionCatalog
+}
+
+func (c *bootstrapCache) fresh(built time.Time, ttl time.Duration,
+	now time.Time) bool {
+	return ttl > 0 && !built.IsZero() && now.Sub(built) < ttl
+}
+
+// regions returns the names of the regions, reusing the recent list.
+func (c *bootstrapCache) regions(ttl time.Duration) ([]string, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.fresh(c.regionsFetched, ttl, time.Now()) {
+		logger.Println("Reusing the list of regions fetched at",
+			c.regionsFetched.Format(time.RFC3339))
+		return c.regionNames, nil
+	}
+
+	regions, err := getRegions()
+	if err != nil {
+		return nil, err
+	}
+
+	c.regionNames, c.regionsFetched = regions, time.Now()
+	return regions, nil
+}
+
+// catalog returns the recent instance type catalog of the region.
+func (c *bootstrapCache) catalog(region string, ttl time.Duration,
+	now time.Time) (regionCatalog, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	rc, found := c.catalogs[region]
+	if !found || !c.fresh(rc.built, ttl, now) {
+		return regionCatalog{}, false
+	}
+	return rc, true
+}
+
+func (c *bootstrapCache) storeCatalog(region string, rc regionCatalog) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.catalogs == nil {
+		c.catalogs = make(map[string]regionCatalog)
+	}
+	c.catalogs[region] = rc
+}
+
+// reuseCatalog loads the recent instance type catalog of the region, if any.
+func (r *region) reuseCatalog() bool {
+
+	rc, found := warmCache.catalog(r.name, r.conf.WarmCacheTTL, time.Now())
+	if !found {
+		return false
+	}
+
+	r.log().Println("Reusing the instance type catalog of", r.name, "built at",
+		rc.built.Format(time.RFC3339))
+
+	r.instanceTypeInformation = rc.info
+	r.spotPricesFetched = rc.spotPricesFetched
+	r.fetchedPools = rc.fetchedPools
+	return true
+}
+
+// cacheCatalog keeps the instance type catalog of the region for the next
+// runs, unless its spot prices couldn't be fetched.
+func (r *region) cacheCatalog() {
+
+	if r.conf.WarmCacheTTL <= 0 || r.spotPricesFetched.IsZero() {
+		return
+	}
+
+	warmCache.storeCatalog(r.name, regionCatalog{
+		info:              r.instanceTypeInformation,
+		spotPricesFetched: r.spotPricesFetched,
+		fetchedPools:      r.fetchedPools,
+		built:             time.Now(),
+	})
+}
@@ -0,0 +1,82 @@
+package autospotting
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_bootstrapCache_catalog(t *testing.T) {
+
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		built time.Time
+		ttl   time.Duration
+		want  bool
+	}{
+		{name: "recent catalog",
+			built: now.Add(-time.Minute),
+			ttl:   5 * time.Minute,
+			want:  true,
+		},
+		{name: "expired catalog",
+			built: now.Add(-10 * time.Minute),
+			ttl:   5 * time.Minute,
+			want:  false,
+		},
+		{name: "cache disabled",
+			built: now.Add(-time.Minute),
+			ttl:   0,
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c bootstrapCache
+			c.storeCatalog("us-east-1", regionCatalog{built: tt.built})
+
+			if _, got := c.catalog("us-east-1", tt.ttl, now); got != tt.want {
+				t.Errorf("catalog() found = %v, want %v", got, tt.want)
+			}
+			if _, got := c.catalog("eu-west-1", tt.ttl, now); got {
+				t.Errorf("catalog() found the catalog of another region")
+			}
+		})
+	}
+}
+
+func Test_region_cacheCatalog(t *testing.T) {
+
+	tests := []struct {
+		name              string
+		spotPricesFetched time.Time
+		want              bool
+	}{
+		{name: "spot prices fetched",
+			spotPricesFetched: time.Now(),
+			want:              true,
+		},
+		{name: "spot prices missing",
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warmCache = bootstrapCache{}
+			defer func() { warmCache = bootstrapCache{} }()
+
+			r := region{
+				name:              "us-east-1",
+				conf:              Config{WarmCacheTTL: time.Minute},
+				spotPricesFetched: tt.spotPricesFetched,
+			}
+			r.cacheCatalog()
+
+			reused := region{name: "us-east-1", conf: r.conf}
+			if got := reused.reuseCatalog(); got != tt.want {
+				t.Errorf("reuseCatalog() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
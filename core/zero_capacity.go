@@ -0,0 +1,45 @@
+package autospotting
+
+// handleZeroCapacity cleans up after groups scaled to zero and tells if the
+// group should be skipped.
+func (a *autoScalingGroup) handleZeroCapacity() bool {
+
+	if a.DesiredCapacity == nil || *a.DesiredCapacity > 0 || len(a.Instances) > 0 {
+		return false
+	}
+
+	a.log().Println(a.name, "is scaled to zero, cleaning up its spot requests")
+
+	if err := a.findSpotInstanceRequests(); err != nil {
+		a.log().at(LevelError).Println(a.name,
+			"Failed to find the spot requests of the group", err.Error())
+		return true
+	}
+
+	for _, req := range a.spotInstanceRequests {
+
+		switch *req.State {
+
+		case "open":
+			a.cancelSpotInstanceRequest(req)
+			a.recordAction("cancelled", "spot request", *req.SpotInstanceRequestId,
+				"since the group is scaled to zero")
+
+		case "active":
+			spotInst := a.region.instances.get(*req.InstanceId)
+			if spotInst == nil || !spotInst.isSpot() ||
+				findTagValue(spotInst.Tags, "aws:autoscaling:groupName") != nil {
+				continue
+			}
+
+			if a.handOverSpotInstance(spotInst) {
+				continue
+			}
+
+			a.terminateInstance(spotInst)
+			a.recordAction("terminated", "spot instance", *req.InstanceId,
+				"since the group is scaled to zero")
+		}
+	}
+	return true
+}
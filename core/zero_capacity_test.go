@@ -0,0 +1,46 @@
+package autospotting
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+func Test_handleZeroCapacity(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		group *autoscaling.Group
+		want  bool
+	}{
+		{
+			name:  "unknown desired capacity",
+			group: &autoscaling.Group{},
+			want:  false,
+		},
+		{
+			name:  "scaled up",
+			group: &autoscaling.Group{DesiredCapacity: aws.Int64(2)},
+			want:  false,
+		},
+		{
+			name: "scaled to zero with instances still being terminated",
+			group: &autoscaling.Group{
+				DesiredCapacity: aws.Int64(0),
+				Instances: []*autoscaling.Instance{
+					{InstanceId: aws.String("i-1")},
+				},
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &autoScalingGroup{Group: tt.group, name: "asg"}
+			if got := a.handleZeroCapacity(); got != tt.want {
+				t.Errorf("handleZeroCapacity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}